@@ -0,0 +1,50 @@
+//! Embeds build information shown by `goto --version --json`
+
+use std::env;
+use std::path::Path;
+use std::process::Command;
+use std::time::{SystemTime, UNIX_EPOCH};
+
+/// Run a command and return its trimmed stdout, if it succeeded
+fn command_output(program: &str, args: &[&str]) -> Option<String> {
+    let output = Command::new(program).args(args).output().ok()?;
+    if !output.status.success() {
+        return None;
+    }
+    let text = String::from_utf8(output.stdout).ok()?.trim().to_string();
+    (!text.is_empty()).then_some(text)
+}
+
+fn main() {
+    // Packagers building from a tarball can pass the commit in explicitly
+    let commit = env::var("GOTO_BUILD_COMMIT")
+        .ok()
+        .or_else(|| command_output("git", &["rev-parse", "--short=12", "HEAD"]))
+        .unwrap_or_else(|| "unknown".to_string());
+
+    // SOURCE_DATE_EPOCH keeps reproducible builds reproducible
+    let build_time = env::var("SOURCE_DATE_EPOCH")
+        .ok()
+        .and_then(|s| s.parse::<u64>().ok())
+        .unwrap_or_else(|| {
+            SystemTime::now()
+                .duration_since(UNIX_EPOCH)
+                .map(|d| d.as_secs())
+                .unwrap_or(0)
+        });
+
+    let rustc = env::var("RUSTC").unwrap_or_else(|_| "rustc".to_string());
+    let rustc_version = command_output(&rustc, &["--version"]).unwrap_or_else(|| "unknown".to_string());
+
+    println!("cargo:rustc-env=GOTO_BUILD_COMMIT={}", commit);
+    println!("cargo:rustc-env=GOTO_BUILD_TIME={}", build_time);
+    println!("cargo:rustc-env=GOTO_RUSTC_VERSION={}", rustc_version);
+
+    println!("cargo:rerun-if-env-changed=GOTO_BUILD_COMMIT");
+    println!("cargo:rerun-if-env-changed=SOURCE_DATE_EPOCH");
+    println!("cargo:rerun-if-changed=build.rs");
+    if Path::new(".git/HEAD").exists() {
+        println!("cargo:rerun-if-changed=.git/HEAD");
+        println!("cargo:rerun-if-changed=.git/index");
+    }
+}
@@ -33,10 +33,33 @@ goto() {
         return $?
     fi
 
+    # Global flags (--db <dir>, --no-track, -y, ...) may come first: dispatch on what follows them
+    local -a rest=()
+    local arg skip_next=0
+    for arg in "$@"; do
+        if (( skip_next )); then
+            skip_next=0
+            continue
+        fi
+        case "$arg" in
+            --db) skip_next=1 ;;
+            --db=*|--no-track|-y|--yes|--no-check|--no-fuzzy|--threshold=*|--suggestions=*|--profile|--timings-file=*|--memstats-file=*) ;;
+            *) rest+=("$arg") ;;
+        esac
+    done
+    local cmd="${rest[0]}"
+
+    # NUL-separated output can't survive $(...), --serve and --maintain never finish,
+    # and --open/--edit may need the terminal: pass them straight through
+    if [[ "$cmd" == "--serve" || "$cmd" == "--maintain" || "$cmd" == "--open" || "$cmd" == "--edit" || " $* " == *" -0 "* || " $* " == *" --print0 "* ]]; then
+        goto-bin "$@"
+        return $?
+    fi
+
     output=$(goto-bin "$@")
     exit_code=$?
 
-    case "$1" in
+    case "$cmd" in
         -h|--help|-v|--version|-l|--list|-c|--cleanup|-x|--expand|--list-aliases|--names-only)
             echo "$output"
             ;;
@@ -46,13 +69,13 @@ goto() {
         --export|--stats|--tags|--tags-raw|--config)
             echo "$output"
             ;;
-        --rename|--tag|--untag)
+        --rename|--tag|--untag|--repath|--reset-stats|--recent-list|--show|--suggest|--retag-auto|--tag-where|--set|--restore-backup|--context|--copy|--rewrite-paths|--which|--copy-path|--tree|--audit|--digest|--gc|--fsck|--cdpath|--env-file)
             echo "$output"
             ;;
         --recent|--recent-clear)
-            # --recent can either display or navigate
-            if [[ "$1" == "--recent" && -n "$2" && "$2" =~ ^[0-9]+$ && "$2" -le 20 && $# -eq 2 ]]; then
-                # Navigation to Nth recent
+            # --recent can either display or navigate (Nth recent or -i selection)
+            if [[ "$cmd" == "--recent" && ( ( -n "${rest[1]}" && "${rest[1]}" =~ ^[0-9]+$ && "${rest[1]}" -le 20 && ${#rest[@]} -eq 2 ) || " $* " == *" -i "* || " $* " == *" --interactive "* ) ]]; then
+                # Navigation to selected recent
                 if [[ $exit_code -eq 0 && -n "$output" && -d "$output" ]]; then
                     cd "$output" || return 1
                 else
@@ -66,7 +89,7 @@ goto() {
         --import)
             echo "$output"
             ;;
-        -p|--push|-o|--pop|*)
+        -p|--push|-o|--pop|--swap|--root|*)
             if [[ $exit_code -eq 0 && -n "$output" && -d "$output" ]]; then
                 cd "$output" || return 1
             else
@@ -78,6 +101,35 @@ goto() {
     return $exit_code
 }
 
+# Record visited directories for 'goto --suggest' (set GOTO_DIR_HISTORY=0 to disable)
+_goto_record_dir() {
+    [[ "${GOTO_DIR_HISTORY:-1}" == "0" || "$PWD" == "$_GOTO_LAST_DIR" ]] && return
+    _GOTO_LAST_DIR="$PWD"
+    (goto-bin --record-dir "$PWD" >/dev/null 2>&1 &)
+}
+if [[ "$PROMPT_COMMAND" != *_goto_record_dir* ]]; then
+    PROMPT_COMMAND="_goto_record_dir${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+
+# Complete alias names. Namespaced names ("work:api") contain ':', which bash
+# treats as a word break, so match the whole word and drop what bash already has.
+_goto_complete_names() {
+    local word="${COMP_LINE:0:COMP_POINT}"
+    word="${word##* }"
+    COMPREPLY=($(compgen -W "$(goto-bin --names-only 2>/dev/null)" -- "$word"))
+    if [[ "$word" == *:* && "$COMP_WORDBREAKS" == *:* ]]; then
+        local colon_prefix="${word%"${word##*:}"}"
+        COMPREPLY=("${COMPREPLY[@]#"$colon_prefix"}")
+    fi
+}
+
+# goto dev/src/<TAB>: directories below the alias, without a space so the next level can follow
+_goto_complete_subpath() {
+    local word="$1"
+    COMPREPLY=($(goto-bin --complete-subpath "${word%%/*}" "${word#*/}" 2>/dev/null))
+    compopt -o nospace 2>/dev/null
+}
+
 # Bash completion
 _goto_completions() {
     local cur="${COMP_WORDS[COMP_CWORD]}"
@@ -85,32 +137,77 @@ _goto_completions() {
 
     # Complete flags
     if [[ "$cur" == -* ]]; then
-        COMPREPLY=($(compgen -W "--export --import --rename --stats --recent --recent-clear --tag --untag --tags --filter= --sort= --config -l -r -u -p -c -h -v -x -o" -- "$cur"))
+        COMPREPLY=($(compgen -W "--export --import --rename --stats --recent --recent-clear --tag --untag --tags --filter= --sort= --format= --portable --repath --touch --reset-stats --last-used --since= --recent-list --recent-go --status --broken-only --group= --reverse --limit= --offset= --path= --regex= --regex-paths --show --setup --suggest --namespace= --retag-auto --tag-where --set --long --restore-backup --no-track --db --context --dry-run --yes --update --if-missing --copy --rewrite-paths --print0 --which --no-check --skip-check --serve --listen= --random --stdin --sha256= --json --generate-man --batch --swap --tree --depth= --desc= --discover-workspaces --root --register= --no-fuzzy --threshold= --suggestions= --copy-path --du --git --type --audit --unused-days= --digest --gc --keep-days= --fsck --fix --maintain --interval= --last --session --tmux --cdpath --env-file --scan --expires= --open --edit --parent --profile --timings-file= --memstats-file= --config -l -r -u -p -c -h -v -x -o -y" -- "$cur"))
         return
     fi
 
     # Handle --filter= and --sort= completion (value after =)
-    if [[ "$cur" == --filter=* ]]; then
+    if [[ "$cur" == --filter=* || "$cur" == --tag=* ]]; then
         local prefix="${cur%%=*}="
         local val="${cur#*=}"
         COMPREPLY=($(compgen -W "$(goto-bin --tags-raw 2>/dev/null)" -- "$val"))
         COMPREPLY=("${COMPREPLY[@]/#/$prefix}")
         return
     fi
+    if [[ "$cur" == --namespace=* ]]; then
+        local prefix="${cur%%=*}="
+        local val="${cur#*=}"
+        COMPREPLY=($(compgen -W "$(goto-bin --namespaces-raw 2>/dev/null)" -- "$val"))
+        COMPREPLY=("${COMPREPLY[@]/#/$prefix}")
+        return
+    fi
     if [[ "$cur" == --sort=* ]]; then
         local prefix="${cur%%=*}="
         local val="${cur#*=}"
-        COMPREPLY=($(compgen -W "alpha usage recent" -- "$val"))
+        COMPREPLY=($(compgen -W "alpha usage recent created path size" -- "$val"))
+        COMPREPLY=("${COMPREPLY[@]/#/$prefix}")
+        return
+    fi
+    if [[ "$cur" == --group=* ]]; then
+        local prefix="${cur%%=*}="
+        local val="${cur#*=}"
+        COMPREPLY=($(compgen -W "tag path" -- "$val"))
+        COMPREPLY=("${COMPREPLY[@]/#/$prefix}")
+        return
+    fi
+    if [[ "$cur" == --format=* ]]; then
+        local prefix="${cur%%=*}="
+        local val="${cur#*=}"
+        COMPREPLY=($(compgen -W "toml json csv" -- "$val"))
         COMPREPLY=("${COMPREPLY[@]/#/$prefix}")
         return
     fi
 
+    # Second arg of --repath is the new directory
+    if [[ ${COMP_CWORD} -eq 3 && "${COMP_WORDS[1]}" == "--repath" ]]; then
+        COMPREPLY=($(compgen -d -- "$cur"))
+        return
+    fi
+
     case "$prev" in
         --import)
             # Complete with files
             COMPREPLY=($(compgen -f -- "$cur"))
             return
             ;;
+        --db|--rewrite-paths|--discover-workspaces|--scan)
+            COMPREPLY=($(compgen -d -- "$cur"))
+            return
+            ;;
+        --context)
+            COMPREPLY=($(compgen -W "use clear list" -- "$cur"))
+            return
+            ;;
+        --session)
+            COMPREPLY=($(compgen -W "save restore list delete" -- "$cur"))
+            return
+            ;;
+        use)
+            if [[ "${COMP_WORDS[1]}" == "--context" ]]; then
+                COMPREPLY=($(compgen -W "$(goto-bin --namespaces-raw 2>/dev/null)" -- "$cur"))
+                return
+            fi
+            ;;
         --tag|--untag)
             # After --tag/--untag, first arg is alias, second is tag
             # Count how many args after the flag
@@ -124,18 +221,21 @@ _goto_completions() {
             local args_after_flag=$((COMP_CWORD - flag_pos))
             if [[ $args_after_flag -eq 1 ]]; then
                 # First arg: alias names
-                COMPREPLY=($(compgen -W "$(goto-bin --names-only 2>/dev/null)" -- "$cur"))
+                _goto_complete_names
+            elif [[ $args_after_flag -eq 2 && "${COMP_WORDS[flag_pos]}" == "--untag" ]]; then
+                # Second arg of --untag: only tags the alias has
+                COMPREPLY=($(compgen -W "$(goto-bin --tags-of "${COMP_WORDS[flag_pos+1]}" 2>/dev/null)" -- "$cur"))
             elif [[ $args_after_flag -eq 2 ]]; then
                 # Second arg: tag names
                 COMPREPLY=($(compgen -W "$(goto-bin --tags-raw 2>/dev/null)" -- "$cur"))
             fi
             return
             ;;
-        --rename)
-            # After --rename, first arg is old alias, second is new name
+        --rename|--copy)
+            # After --rename/--copy, first arg is old alias, second is new name
             local flag_pos=-1
             for ((i=0; i<${#COMP_WORDS[@]}; i++)); do
-                if [[ "${COMP_WORDS[i]}" == "--rename" ]]; then
+                if [[ "${COMP_WORDS[i]}" == "--rename" || "${COMP_WORDS[i]}" == "--copy" ]]; then
                     flag_pos=$i
                     break
                 fi
@@ -143,11 +243,15 @@ _goto_completions() {
             local args_after_flag=$((COMP_CWORD - flag_pos))
             if [[ $args_after_flag -eq 1 ]]; then
                 # First arg: existing alias names
-                COMPREPLY=($(compgen -W "$(goto-bin --names-only 2>/dev/null)" -- "$cur"))
+                _goto_complete_names
             fi
             # Second arg: new name (no completion)
             return
             ;;
+        --repath)
+            _goto_complete_names
+            return
+            ;;
         -r|--register)
             # First arg is alias name (no completion), second is directory
             if [[ ${COMP_CWORD} -eq 3 ]]; then
@@ -155,20 +259,22 @@ _goto_completions() {
             fi
             return
             ;;
-        -u|--unregister|-x|--expand|-p|--push)
-            COMPREPLY=($(compgen -W "$(goto-bin --names-only 2>/dev/null)" -- "$cur"))
+        -u|--unregister|-x|--expand|-p|--push|--skip-check|--which|--copy-path|--open|--edit|--set|--show|--tree|--reset-stats|--touch)
+            _goto_complete_names
             return
             ;;
         goto)
             if [[ "$cur" == -* ]]; then
-                COMPREPLY=($(compgen -W "--export --import --rename --stats --recent --recent-clear --tag --untag --tags --filter= --sort= --config -l -r -u -p -x -c -o -v -h" -- "$cur"))
+                COMPREPLY=($(compgen -W "--export --import --rename --stats --recent --recent-clear --tag --untag --tags --filter= --sort= --format= --portable --repath --touch --reset-stats --last-used --since= --recent-list --recent-go --status --broken-only --group= --reverse --limit= --offset= --path= --regex= --regex-paths --show --setup --suggest --namespace= --retag-auto --tag-where --set --long --restore-backup --no-track --db --context --dry-run --yes --update --if-missing --copy --rewrite-paths --print0 --which --no-check --skip-check --serve --listen= --random --stdin --sha256= --json --generate-man --batch --swap --tree --depth= --desc= --discover-workspaces --root --register= --no-fuzzy --threshold= --suggestions= --copy-path --du --git --type --audit --unused-days= --digest --gc --keep-days= --fsck --fix --maintain --interval= --last --session --tmux --cdpath --env-file --scan --expires= --open --edit --parent --profile --timings-file= --memstats-file= --config -l -r -u -p -x -c -o -v -h -y" -- "$cur"))
+            elif [[ "$cur" == */* ]]; then
+                _goto_complete_subpath "$cur"
             else
-                COMPREPLY=($(compgen -W "$(goto-bin --names-only 2>/dev/null)" -- "$cur"))
+                _goto_complete_names
             fi
             return
             ;;
         *)
-            COMPREPLY=($(compgen -W "$(goto-bin --names-only 2>/dev/null)" -- "$cur"))
+            _goto_complete_names
             return
             ;;
     esac
@@ -28,16 +28,40 @@ function goto
         return $status
     end
 
+    # Global flags (--db <dir>, --no-track, -y, ...) may come first: dispatch on what follows them
+    set -l rest
+    set -l skip_next 0
+    for arg in $argv
+        if test $skip_next = 1
+            set skip_next 0
+            continue
+        end
+        switch $arg
+            case --db
+                set skip_next 1
+            case '--db=*' --no-track -y --yes --no-check --no-fuzzy '--threshold=*' '--suggestions=*' --profile '--timings-file=*' '--memstats-file=*'
+            case '*'
+                set -a rest $arg
+        end
+    end
+
+    # NUL-separated output can't survive command substitution, --serve and --maintain never finish,
+    # and --open/--edit may need the terminal
+    if contains -- "$rest[1]" --serve --maintain --open --edit; or contains -- -0 $argv; or contains -- --print0 $argv
+        goto-bin $argv
+        return $status
+    end
+
     set -l output (goto-bin $argv)
     set -l exit_code $status
 
-    switch "$argv[1]"
-        case -h --help -v --version -l --list -c --cleanup -x --expand --list-aliases --names-only -r --register -u --unregister --export --stats --tags --tags-raw --config --rename --tag --untag --import
+    switch "$rest[1]"
+        case -h --help -v --version -l --list -c --cleanup -x --expand --list-aliases --names-only -r --register -u --unregister --export --stats --tags --tags-raw --config --rename --tag --untag --import --repath --reset-stats --recent-list --show --suggest --retag-auto --tag-where --set --restore-backup --context --copy --rewrite-paths --which --copy-path --tree --audit --digest --gc --fsck --cdpath --env-file
             echo $output
         case --recent --recent-clear
-            # --recent can either display or navigate
-            if test "$argv[1]" = "--recent" -a (count $argv) -eq 2 -a "$argv[2]" -le 20 2>/dev/null
-                # Navigation to Nth recent
+            # --recent can either display or navigate (Nth recent or -i selection)
+            if test "$rest[1]" = "--recent" -a (count $rest) -eq 2 -a "$rest[2]" -le 20 2>/dev/null; or contains -- -i $argv; or contains -- --interactive $argv
+                # Navigation to selected recent
                 if test $exit_code -eq 0 -a -n "$output" -a -d "$output"
                     cd $output
                 else
@@ -58,19 +82,35 @@ function goto
     return $exit_code
 end
 
+# Record visited directories for 'goto --suggest' (set GOTO_DIR_HISTORY=0 to disable)
+function __goto_record_dir --on-variable PWD
+    test "$GOTO_DIR_HISTORY" = 0; and return
+    command goto-bin --record-dir "$PWD" >/dev/null 2>&1 &
+    disown 2>/dev/null
+end
+
 # Fish completions
 complete -c goto -f
 
 # Default: complete with alias names when no flag
-complete -c goto -n "not __fish_seen_subcommand_from -r --register -u --unregister -l --list -x --expand -c --cleanup -p --push -o --pop -v --version -h --help --export --import --rename --stats --recent --recent-clear --tag --untag --tags --filter --sort --config" -a "(goto-bin --names-only 2>/dev/null)"
+complete -c goto -n "not __fish_seen_subcommand_from -r --register -u --unregister -l --list -x --expand -c --cleanup -p --push -o --pop -v --version -h --help --export --import --rename --stats --recent --recent-clear --tag --untag --tags --filter --sort --config --repath --touch --reset-stats --recent-list --recent-go --show --setup --suggest --retag-auto --tag-where --set --restore-backup --context --copy --rewrite-paths --which --skip-check --serve --random --generate-man --batch --swap --tree --discover-workspaces --root --copy-path --audit --digest --gc --fsck --maintain --last --session --cdpath --env-file --scan --open --edit" -a "(goto-bin --complete aliases 2>/dev/null)"
+
+# goto dev/src/<TAB>: directories below the alias
+function __goto_complete_subpath
+    set -l token (commandline -ct)
+    string match -q -- '*/*' $token; or return
+    set -l parts (string split -m 1 / -- $token)
+    goto-bin --complete-subpath $parts[1] $parts[2] 2>/dev/null
+end
+complete -c goto -n "test (count (commandline -opc)) -eq 1" -a "(__goto_complete_subpath)"
 
 # Basic options
 complete -c goto -s r -l register -d "Register alias" -r -F
-complete -c goto -s u -l unregister -d "Unregister alias" -ra "(goto-bin --names-only 2>/dev/null)"
+complete -c goto -s u -l unregister -d "Unregister alias" -ra "(goto-bin --complete aliases 2>/dev/null)"
 complete -c goto -s l -l list -d "List aliases"
-complete -c goto -s x -l expand -d "Expand alias" -ra "(goto-bin --names-only 2>/dev/null)"
+complete -c goto -s x -l expand -d "Expand alias" -ra "(goto-bin --complete aliases 2>/dev/null)"
 complete -c goto -s c -l cleanup -d "Cleanup invalid aliases"
-complete -c goto -s p -l push -d "Push and goto" -ra "(goto-bin --names-only 2>/dev/null)"
+complete -c goto -s p -l push -d "Push and goto" -ra "(goto-bin --complete aliases 2>/dev/null)"
 complete -c goto -s o -l pop -d "Pop directory"
 complete -c goto -s v -l version -d "Show version"
 complete -c goto -s h -l help -d "Show help"
@@ -78,24 +118,148 @@ complete -c goto -s h -l help -d "Show help"
 # Export/Import
 complete -c goto -l export -d "Export aliases to TOML"
 complete -c goto -l import -d "Import aliases from file" -r
+complete -c goto -l format= -d "Export/import format" -xa "toml json csv"
+complete -c goto -l portable -d 'Export paths under $HOME as ~/...'
 
 # Rename
-complete -c goto -l rename -d "Rename an alias" -ra "(goto-bin --names-only 2>/dev/null)"
+complete -c goto -l rename -d "Rename an alias" -ra "(goto-bin --complete aliases 2>/dev/null)"
+complete -c goto -l copy -d "Copy an alias under a new name" -ra "(goto-bin --complete aliases 2>/dev/null)"
+complete -c goto -l repath -d "Point alias at a new directory" -ra "(goto-bin --complete aliases 2>/dev/null)"
+complete -c goto -l show -d "Show alias details" -ra "(goto-bin --complete aliases 2>/dev/null)"
 
 # Statistics and recent
 complete -c goto -l stats -d "Show usage statistics"
 complete -c goto -l recent -d "Show recently visited"
+complete -c goto -l since= -d "Only recent visits since (7d, 2w, date)" -x
 complete -c goto -l recent-clear -d "Clear recent history"
+complete -c goto -l recent-list -d "List recent directories" -x
+complete -c goto -l recent-go -d "Navigate to Nth recent directory" -x
+complete -c goto -l last -d "Navigate to the most recently used alias"
+complete -c goto -l reset-stats -d "Zero usage counters" -a "(goto-bin --complete aliases 2>/dev/null)"
+complete -c goto -l last-used -d "Also clear last-visited times (with --reset-stats)"
+complete -c goto -l touch -d "Record a use without navigating" -ra "(goto-bin --complete aliases 2>/dev/null)"
 
 # Tags
-complete -c goto -l tag -d "Add tag to alias" -ra "(goto-bin --names-only 2>/dev/null)"
-complete -c goto -l untag -d "Remove tag from alias" -ra "(goto-bin --names-only 2>/dev/null)"
+complete -c goto -l tag -d "Add tag to alias" -ra "(goto-bin --complete aliases 2>/dev/null)"
+complete -c goto -l untag -d "Remove tag from alias" -ra "(goto-bin --complete aliases 2>/dev/null)"
+complete -c goto -n "__fish_seen_subcommand_from --untag; and test (count (commandline -opc)) -eq 3" -f -a "(goto-bin --tags-of (commandline -opc)[3] 2>/dev/null)"
+complete -c goto -l tag-where -d "Tag every alias whose path matches --path"
 complete -c goto -l tags -d "List all tags"
+complete -c goto -l retag-auto -d "Apply auto-tag rules to existing aliases"
 
 # Filtering and sorting (used with --list)
 # Note: These use --filter=<tag> and --sort=<order> format
 complete -c goto -l filter= -d "Filter by tag" -xa "(goto-bin --tags-raw 2>/dev/null)"
-complete -c goto -l sort= -d "Sort list" -xa "alpha usage recent"
+complete -c goto -l namespace= -d "Filter list by namespace" -xa '(goto-bin --namespaces-raw 2>/dev/null)'
+complete -c goto -l path= -d "Filter list by path prefix or glob" -xa '(__fish_complete_directories)'
+complete -c goto -l regex= -d "Filter list by name regex" -x
+complete -c goto -l regex-paths -d "Also match --regex against paths"
+complete -c goto -l sort= -d "Sort list" -xa "alpha usage recent created path size"
+complete -c goto -l reverse -d "Invert list sort order"
+complete -c goto -l du -d "Show disk usage of each directory in list"
+complete -c goto -l git -d "Show git branch and dirty state in list"
+complete -c goto -l type -d "Show project type in list"
+complete -c goto -l limit= -d "Show at most N aliases" -x
+complete -c goto -l offset= -d "Skip the first N aliases" -x
+complete -c goto -l group= -d "Group list as a tree" -xa "tag path"
+complete -c goto -l status -d "Mark missing directories in list"
+complete -c goto -l broken-only -d "List only aliases with missing directories"
+
+complete -c goto -l set -d "Set key=value metadata on alias" -ra "(goto-bin --complete aliases 2>/dev/null)"
+
+complete -c goto -l long -d "Show created, last used, uses and tags columns"
+
+complete -c goto -l restore-backup -d "List or restore database backups"
+
+complete -c goto -l no-track -d "Do not record usage for this command"
+
+complete -c goto -l db -d "Use another database directory for this command" -r -F
+
+complete -c goto -l context -d "Show, switch, clear or list the active context"
+complete -c goto -n "__fish_seen_subcommand_from --context; and not __fish_seen_subcommand_from use clear list" -f -a "use clear list"
+complete -c goto -n "__fish_seen_subcommand_from --context; and __fish_seen_subcommand_from use" -f -a "(goto-bin --namespaces-raw 2>/dev/null)"
+complete -c goto -l session -d "Save, restore, list or delete a directory session"
+complete -c goto -n "__fish_seen_subcommand_from --session; and not __fish_seen_subcommand_from save restore list delete" -f -a "save restore list delete"
+complete -c goto -l tmux -d "Open a tmux window per session directory"
+
+complete -c goto -l dry-run -d "Preview changes without applying them"
+
+complete -c goto -s y -l yes -d "Skip confirmation prompts"
+
+complete -c goto -l update -d "Update goto, or with -r create or repoint an alias"
+
+complete -c goto -l if-missing -d "Register only if the alias does not exist"
+
+complete -c goto -l rewrite-paths -d "Rewrite a path prefix in all aliases" -r -F
+
+complete -c goto -s 0 -l print0 -d "NUL-separated paths (list, expand, recent)"
+
+complete -c goto -l which -d "Explain how a name resolves" -ra "(goto-bin --complete aliases 2>/dev/null)"
+
+complete -c goto -l no-check -d "Navigate without checking the directory"
+
+complete -c goto -l skip-check -d "Never check an alias directory before jumping" -ra "(goto-bin --complete aliases 2>/dev/null)"
+
+complete -c goto -l serve -d "Serve the HTTP API"
+complete -c goto -l listen= -d "Address for --serve" -x
+
+complete -c goto -l random -d "Navigate to a random alias"
+
+complete -c goto -l stdin -d "Read directories from stdin (with -r)"
+
+complete -c goto -l sha256= -d "Expected SHA-256 of an imported file"
+
+complete -c goto -l json -d "Print version details as JSON (with -v)"
+
+complete -c goto -l generate-man -d "Print the man page"
+
+complete -c goto -l batch -d "Run alias commands from stdin"
+
+complete -c goto -l swap -d "Swap current directory with the stack top"
+
+complete -c goto -l tree -d "Show subdirectories below an alias" -ra "(goto-bin --complete aliases 2>/dev/null)"
+
+complete -c goto -l depth= -d "Levels for --tree"
+
+complete -c goto -l desc= -d "One-line description for -r"
+complete -c goto -l expires= -d "Make the alias temporary (30d, 12h, 2w)"
+
+complete -c goto -l discover-workspaces -d "Register workspace members" -xa '(__fish_complete_directories)'
+complete -c goto -l scan -d "Merge aliases from .goto-aliases files" -xa '(__fish_complete_directories)'
+
+complete -c goto -l root -d "Go to the repository root"
+
+complete -c goto -l register= -d "Alias name for --root"
+
+complete -c goto -l no-fuzzy -d "Only navigate on an exact name"
+complete -c goto -l parent -d "Go to the directory containing the alias"
+
+complete -c goto -l threshold= -d "Similarity needed to offer a fuzzy match"
+
+complete -c goto -l suggestions= -d "Offer at most N similar names"
+complete -c goto -l profile -d "Time the phases of this run"
+complete -c goto -l timings-file= -d "Write the phase timings to a file" -rF
+complete -c goto -l memstats-file= -d "Write memory use from /proc to a file" -rF
+
+complete -c goto -l copy-path -d "Copy an alias's path to the clipboard"
+complete -c goto -l open -d "Open an alias's file or directory with the default application"
+complete -c goto -l edit -d "Edit an alias's file in \$VISUAL or \$EDITOR"
+
+complete -c goto -l audit -d "Score aliases for common problems"
+complete -c goto -l unused-days= -d "Days without use before audit flags an alias"
+complete -c goto -l digest -d "Summarize the past week"
+complete -c goto -l gc -d "Compact the navigation log and drop surplus backups"
+complete -c goto -l keep-days= -d "Days of the log to keep uncompacted"
+complete -c goto -l fsck -d "Check the database for problems"
+complete -c goto -l fix -d "Repair what --fsck found"
+complete -c goto -l maintain -d "Run housekeeping and write a report file"
+complete -c goto -l interval= -d "Repeat --maintain at this interval"
+
+# Shell helpers
+complete -c goto -l cdpath -d "Print alias parent directories as CDPATH"
+complete -c goto -l env-file -d "Print aliases as shell export lines"
 
 # Config
 complete -c goto -l config -d "Show configuration"
+complete -c goto -l setup -d "Run the first-time setup wizard"
+complete -c goto -l suggest -d "Suggest aliases for often-visited directories"
@@ -3,15 +3,41 @@
 use chrono::{DateTime, Utc};
 use regex::Regex;
 use serde::{Deserialize, Serialize};
-use std::sync::LazyLock;
+use std::collections::BTreeMap;
+use std::sync::{LazyLock, OnceLock};
 use thiserror::Error;
+use unicode_normalization::UnicodeNormalization;
 
-static VALID_ALIAS_PATTERN: LazyLock<Regex> =
-    LazyLock::new(|| Regex::new(r"^[a-zA-Z0-9][a-zA-Z0-9_.-]*$").unwrap());
+static VALID_ALIAS_PATTERN: LazyLock<Regex> = LazyLock::new(|| {
+    Regex::new(r"^([a-zA-Z0-9][a-zA-Z0-9_.-]*:)?[a-zA-Z0-9][a-zA-Z0-9_.-]*$").unwrap()
+});
+
+/// Like `VALID_ALIAS_PATTERN`, with any script's letters, digits and combining marks
+static VALID_UNICODE_ALIAS_PATTERN: LazyLock<Regex> = LazyLock::new(|| {
+    Regex::new(r"^([\p{L}\p{N}][\p{L}\p{N}\p{M}_.-]*:)?[\p{L}\p{N}][\p{L}\p{N}\p{M}_.-]*$").unwrap()
+});
+
+/// Whether alias names may use non-ASCII letters (`general.unicode_names`)
+static UNICODE_NAMES: OnceLock<bool> = OnceLock::new();
+
+/// Allow or forbid non-ASCII letters in new alias names for this process
+///
+/// Set once from the config at startup; names are allowed until then.
+pub fn set_unicode_names(allow: bool) {
+    let _ = UNICODE_NAMES.set(allow);
+}
+
+/// An alias name in NFC, so `projëkt` typed precomposed or decomposed is the same name
+pub fn normalize_name(name: &str) -> String {
+    name.nfc().collect()
+}
 
 static VALID_TAG_PATTERN: LazyLock<Regex> =
     LazyLock::new(|| Regex::new(r"^[a-zA-Z0-9][a-zA-Z0-9_-]*$").unwrap());
 
+static VALID_META_KEY_PATTERN: LazyLock<Regex> =
+    LazyLock::new(|| Regex::new(r"^[a-zA-Z0-9][a-zA-Z0-9_.-]*$").unwrap());
+
 /// Errors that can occur during alias operations
 #[derive(Error, Debug)]
 pub enum AliasError {
@@ -27,12 +53,64 @@ pub enum AliasError {
     #[error("directory does not exist: {0}")]
     DirectoryNotFound(String),
 
+    #[error("not a directory: {0}")]
+    NotADirectory(String),
+
+    #[error("file does not exist: {0}")]
+    FileNotFound(String),
+
+    #[error("not a file: {0}")]
+    NotAFile(String),
+
+    #[error("permission denied: cannot enter {0}")]
+    PermissionDenied(String),
+
+    #[error("timed out after {1:.1}s checking {0} (unresponsive mount? retry with --no-check)")]
+    CheckTimedOut(String, f64),
+
+    #[error("check of {0} cancelled")]
+    CheckCancelled(String),
+
     #[error("invalid tag '{tag}': {reason}")]
     InvalidTag { tag: String, reason: String },
+
+    #[error("path not allowed: {0} is {1} (see [register] in config.toml)")]
+    PathNotAllowed(String, String),
+
+    #[error("alias '{0}' expired on {1} (re-register it with --force to keep it, or remove it with 'goto -c')")]
+    Expired(String, String),
+
+    #[error("tag or metadata '{0}' not found on any alias")]
+    NoneTagged(String),
+
+    #[error("{0} not found")]
+    Missing(String),
+
+    #[error("row {0} not found in the last listing (run 'goto -l' or 'goto --recent' first)")]
+    NoSuchRow(String),
+
+    #[error("repository root not found above {0} (no .git or .hg)")]
+    NoRepository(String),
+
+    #[error("aliases matching '{0}' with '{1}' not found")]
+    NoneMatching(String, String),
+
+    #[error("{0} cancelled")]
+    Cancelled(String),
+
+    #[error("Navigation to '{0}' cancelled by pre-navigate hook '{1}' ({2})")]
+    HookVetoed(String, String, String),
+
+    #[error("invalid metadata key '{0}': must start with letter/digit and contain only letters, digits, hyphens, underscores, dots")]
+    InvalidMetaKey(String),
 }
 
 /// Validate that an alias name is acceptable
 pub fn validate_alias(name: &str) -> Result<(), AliasError> {
+    check_alias_name(name, *UNICODE_NAMES.get().unwrap_or(&true))
+}
+
+fn check_alias_name(name: &str, unicode: bool) -> Result<(), AliasError> {
     if name.is_empty() {
         return Err(AliasError::InvalidAlias {
             alias: name.to_string(),
@@ -40,16 +118,34 @@ pub fn validate_alias(name: &str) -> Result<(), AliasError> {
         });
     }
 
-    if !VALID_ALIAS_PATTERN.is_match(name) {
+    let pattern = if unicode {
+        &VALID_UNICODE_ALIAS_PATTERN
+    } else {
+        &VALID_ALIAS_PATTERN
+    };
+    if !pattern.is_match(name) {
+        let reason = if unicode || name.is_ascii() {
+            "must start with letter/digit and contain only letters, digits, hyphens, underscores, dots (optionally prefixed by 'namespace:')"
+        } else {
+            "only ASCII letters are allowed (set unicode_names = true in config.toml)"
+        };
         return Err(AliasError::InvalidAlias {
             alias: name.to_string(),
-            reason: "must start with letter/digit and contain only letters, digits, hyphens, underscores, dots".to_string(),
+            reason: reason.to_string(),
         });
     }
 
     Ok(())
 }
 
+/// Split a name like "work:api" into its namespace and local name
+pub fn split_namespace(name: &str) -> (Option<&str>, &str) {
+    match name.split_once(':') {
+        Some((namespace, local)) => (Some(namespace), local),
+        None => (None, name),
+    }
+}
+
 /// Validate that a tag name is acceptable
 pub fn validate_tag(tag: &str) -> Result<(), AliasError> {
     if tag.is_empty() {
@@ -69,6 +165,41 @@ pub fn validate_tag(tag: &str) -> Result<(), AliasError> {
     Ok(())
 }
 
+/// Validate that a metadata key is acceptable
+pub fn validate_meta_key(key: &str) -> Result<(), AliasError> {
+    if !VALID_META_KEY_PATTERN.is_match(key) {
+        return Err(AliasError::InvalidMetaKey(key.to_string()));
+    }
+    Ok(())
+}
+
+/// What an alias points to
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Default, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum AliasKind {
+    #[default]
+    Dir,
+    /// A file bookmark: navigation goes to its directory
+    File,
+}
+
+impl AliasKind {
+    fn is_dir(&self) -> bool {
+        *self == AliasKind::Dir
+    }
+
+    /// The directory of a target of this kind: the path itself, or a file's parent
+    pub fn directory(self, path: &str) -> String {
+        match self {
+            AliasKind::Dir => path.to_string(),
+            AliasKind::File => std::path::Path::new(path)
+                .parent()
+                .map(|p| p.to_string_lossy().to_string())
+                .unwrap_or_else(|| path.to_string()),
+        }
+    }
+}
+
 /// Represents a directory alias with metadata
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct Alias {
@@ -76,9 +207,15 @@ pub struct Alias {
     pub name: String,
     /// The absolute path this alias points to
     pub path: String,
+    /// Directory or file; saved only for files
+    #[serde(default, skip_serializing_if = "AliasKind::is_dir")]
+    pub kind: AliasKind,
     /// Tags associated with this alias
     #[serde(default)]
     pub tags: Vec<String>,
+    /// One-line description of what the directory is for
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub description: Option<String>,
     /// Number of times this alias has been used
     #[serde(default)]
     pub use_count: u64,
@@ -88,21 +225,44 @@ pub struct Alias {
     /// Timestamp when the alias was created
     #[serde(default = "Utc::now")]
     pub created_at: DateTime<Utc>,
+    /// Host-specific paths keyed by hostname, with an optional "default" entry
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub paths: BTreeMap<String, String>,
+    /// Free-form key=value metadata (owner, ticket, ...)
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub meta: BTreeMap<String, String>,
+    /// Never stat the target before navigating (autofs, mount-on-access FUSE paths)
+    #[serde(default, skip_serializing_if = "std::ops::Not::not")]
+    pub skip_check: bool,
+    /// Project type detected from marker files (`rust`, `go`, ...), cached for listings
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub project_type: Option<String>,
+    /// When a temporary alias (`--expires=`) stops working for navigation
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub expires_at: Option<DateTime<Utc>>,
 }
 
 impl Alias {
     /// Create a new alias with the given name and path
     pub fn new(name: &str, path: &str) -> Result<Self, AliasError> {
-        validate_alias(name)?;
+        let name = normalize_name(name);
+        validate_alias(&name)?;
         Self::validate_path(path)?;
 
         Ok(Self {
-            name: name.to_string(),
+            name,
             path: path.to_string(),
+            kind: AliasKind::Dir,
             tags: Vec::new(),
+            description: None,
             use_count: 0,
             last_used: None,
             created_at: Utc::now(),
+            paths: BTreeMap::new(),
+            meta: BTreeMap::new(),
+            skip_check: false,
+            project_type: None,
+            expires_at: None,
         })
     }
 
@@ -118,6 +278,37 @@ impl Alias {
         Ok(())
     }
 
+    /// Pick the path for a host: exact hostname, then its short name, then
+    /// the "default" entry, then the plain `path`
+    pub fn path_for_host(&self, host: Option<&str>) -> &str {
+        if let Some(host) = host {
+            let short = host.split('.').next().unwrap_or(host);
+            for key in [host, short] {
+                if let Some(path) = self.paths.get(key) {
+                    return path;
+                }
+            }
+        }
+
+        self.paths.get("default").unwrap_or(&self.path)
+    }
+
+    /// The path this alias points to on the current machine
+    pub fn resolved_path(&self) -> String {
+        let path = self.path_for_host(crate::config::hostname().as_deref());
+        if path.starts_with('~') {
+            if let Ok(expanded) = crate::config::expand_path(path) {
+                return expanded.to_string_lossy().to_string();
+            }
+        }
+        path.to_string()
+    }
+
+    /// The directory navigation goes to: the path itself, or a file's parent
+    pub fn directory(&self) -> String {
+        self.kind.directory(&self.resolved_path())
+    }
+
     /// Record a use of this alias
     pub fn record_use(&mut self) {
         self.use_count += 1;
@@ -143,10 +334,32 @@ impl Alias {
         }
     }
 
+    /// The namespace of this alias ("work" for "work:api"), if any
+    pub fn namespace(&self) -> Option<&str> {
+        split_namespace(&self.name).0
+    }
+
+    /// Whether the alias has expired as of `now`
+    pub fn is_expired(&self, now: DateTime<Utc>) -> bool {
+        self.expires_at.is_some_and(|at| at <= now)
+    }
+
     /// Check if this alias has a specific tag
     pub fn has_tag(&self, tag: &str) -> bool {
         self.tags.iter().any(|t| t == tag)
     }
+
+    /// Match a `--filter=` value: `key=value` compares metadata, anything
+    /// else is a tag (both case-insensitive)
+    pub fn matches_filter(&self, filter: &str) -> bool {
+        match filter.split_once('=') {
+            Some((key, value)) => self
+                .meta
+                .get(key)
+                .is_some_and(|v| v.eq_ignore_ascii_case(value)),
+            None => self.tags.iter().any(|t| t.eq_ignore_ascii_case(filter)),
+        }
+    }
 }
 
 #[cfg(test)]
@@ -219,6 +432,53 @@ mod tests {
         assert!(!alias.remove_tag("nonexistent"));
     }
 
+    #[test]
+    fn test_path_for_host() {
+        let mut alias = Alias::new("dev", "/srv/dev").unwrap();
+        assert_eq!(alias.path_for_host(Some("anyhost")), "/srv/dev");
+
+        alias.paths.insert("default".to_string(), "~/dev".to_string());
+        alias.paths.insert("worklaptop".to_string(), "/Users/me/dev".to_string());
+
+        assert_eq!(alias.path_for_host(Some("worklaptop")), "/Users/me/dev");
+        assert_eq!(alias.path_for_host(Some("worklaptop.local")), "/Users/me/dev");
+        assert_eq!(alias.path_for_host(Some("desktop")), "~/dev");
+        assert_eq!(alias.path_for_host(None), "~/dev");
+    }
+
+    #[test]
+    fn test_paths_serialization() {
+        let mut alias = Alias::new("dev", "/srv/dev").unwrap();
+        let toml_str = toml::to_string(&alias).unwrap();
+        assert!(!toml_str.contains("paths"));
+
+        alias.paths.insert("worklaptop".to_string(), "/Users/me/dev".to_string());
+        let toml_str = toml::to_string(&alias).unwrap();
+        let parsed: Alias = toml::from_str(&toml_str).unwrap();
+        assert_eq!(parsed.paths.get("worklaptop").unwrap(), "/Users/me/dev");
+    }
+
+    #[test]
+    fn test_matches_filter() {
+        let mut alias = Alias::new("dev", "/srv/dev").unwrap();
+        alias.add_tag("work");
+        alias.meta.insert("owner".to_string(), "antti".to_string());
+
+        assert!(alias.matches_filter("work"));
+        assert!(alias.matches_filter("WORK"));
+        assert!(alias.matches_filter("owner=antti"));
+        assert!(!alias.matches_filter("owner=someone"));
+        assert!(!alias.matches_filter("ticket=ACME-12"));
+    }
+
+    #[test]
+    fn test_validate_meta_key() {
+        assert!(validate_meta_key("owner").is_ok());
+        assert!(validate_meta_key("ci.url").is_ok());
+        assert!(validate_meta_key("").is_err());
+        assert!(validate_meta_key("bad key").is_err());
+    }
+
     // Tests for validate_alias function
     #[test]
     fn test_validate_alias_empty() {
@@ -258,7 +518,48 @@ mod tests {
         assert!(validate_alias("hello world").is_err());
         assert!(validate_alias("hello@world").is_err());
         assert!(validate_alias("hello/world").is_err());
-        assert!(validate_alias("hello:world").is_err());
+        // A single colon is a namespace separator (see test_validate_alias_namespaced)
+        assert!(validate_alias("hello::world").is_err());
+    }
+
+    #[test]
+    fn test_validate_alias_namespaced() {
+        assert!(validate_alias("work:api").is_ok());
+        assert!(validate_alias("oss:api-v2").is_ok());
+        assert!(validate_alias(":api").is_err());
+        assert!(validate_alias("work:").is_err());
+        assert!(validate_alias("a:b:c").is_err());
+        assert!(validate_alias("work:-api").is_err());
+    }
+
+    #[test]
+    fn test_validate_alias_unicode() {
+        assert!(validate_alias("projëkt").is_ok());
+        assert!(validate_alias("работа:проект").is_ok());
+        assert!(validate_alias("日本語").is_ok());
+        assert!(validate_alias("projëkt v2").is_err());
+        assert!(validate_alias("—dash").is_err());
+
+        let err = check_alias_name("projëkt", false).unwrap_err().to_string();
+        assert!(err.contains("only ASCII letters"), "{}", err);
+        assert!(check_alias_name("project", false).is_ok());
+    }
+
+    #[test]
+    fn test_alias_name_normalized_to_nfc() {
+        let decomposed = "proje\u{0308}kt";
+        let alias = Alias::new(decomposed, "/tmp").unwrap();
+        assert_eq!(alias.name, "projëkt");
+        assert_eq!(normalize_name(decomposed), normalize_name("projëkt"));
+    }
+
+    #[test]
+    fn test_split_namespace() {
+        assert_eq!(split_namespace("work:api"), (Some("work"), "api"));
+        assert_eq!(split_namespace("api"), (None, "api"));
+
+        let alias = Alias::new("oss:api", "/tmp").unwrap();
+        assert_eq!(alias.namespace(), Some("oss"));
     }
 
     // Tests for validate_tag function
@@ -1,6 +1,12 @@
 //! Command-line argument parsing for goto
 
-use crate::commands::import_export::ImportStrategy;
+use chrono::{DateTime, Duration, Utc};
+
+use crate::commands::import_export::{ExportFormat, ImportStrategy};
+use crate::commands::register::IfExists;
+use crate::commands::list::{GroupBy, ListOptions};
+use crate::commands::stats::{parse_since, parse_window};
+use crate::profile::ProfileOptions;
 
 const VERSION: &str = env!("CARGO_PKG_VERSION");
 
@@ -8,87 +14,328 @@ const VERSION: &str = env!("CARGO_PKG_VERSION");
 #[derive(Debug)]
 pub struct Args {
     pub command: Command,
+    /// `--no-track`: don't record use counts or visit times for this run
+    pub no_track: bool,
+    /// `--db <dir>`: database directory to use instead of GOTO_DB/the default
+    pub db: Option<String>,
+    /// `--yes`/`-y`: don't ask before destructive operations
+    pub yes: bool,
+    /// `-0`/`--print0`: NUL-terminated paths from list, expand and recent
+    pub print0: bool,
+    /// `--no-check`: don't check the target directory before navigating
+    pub no_check: bool,
+    /// `--no-fuzzy`: a name that isn't an alias is an error, with nothing offered
+    pub no_fuzzy: bool,
+    /// `--threshold=<0.0-1.0>`: similarity a fuzzy match needs to be offered
+    pub threshold: Option<f64>,
+    /// `--suggestions=<N>`: similar names offered at most, overriding `max_suggestions`
+    pub suggestions: Option<usize>,
+    /// `--profile`, `--timings-file=` and `--memstats-file=`: time this run
+    pub profile: ProfileOptions,
 }
 
 /// All supported commands
 #[derive(Debug)]
 pub enum Command {
     Help,
-    Version,
+    Version {
+        json: bool,
+    },
     Config,
     List {
-        sort: Option<String>,
-        filter: Option<String>,
+        options: ListOptions,
     },
     ListNames,
     Register {
         name: String,
         path: String,
         tags: Vec<String>,
+        description: Option<String>,
+        /// `--expires=`: when a temporary alias stops working
+        expires: Option<DateTime<Utc>>,
         force: bool,
+        if_exists: IfExists,
+    },
+    RegisterStdin {
+        tags: Vec<String>,
+    },
+    DiscoverWorkspaces {
+        root: String,
+        namespace: Option<String>,
+        tags: Vec<String>,
+        dry_run: bool,
     },
+    Scan {
+        root: String,
+        namespace: Option<String>,
+        dry_run: bool,
+    },
+    Batch,
     Unregister {
         name: String,
     },
+    UnregisterMany {
+        selector: String,
+        filter: Option<String>,
+        dry_run: bool,
+    },
+    UnregisterInteractive,
     Navigate {
         alias: String,
     },
     Expand {
         alias: String,
+        /// `--format=uri`: print a `file://` URI instead of the path
+        uri: bool,
+    },
+    Random {
+        filter: Option<String>,
+    },
+    Which {
+        alias: String,
     },
     Cleanup {
         dry_run: bool,
     },
+    Audit {
+        unused_days: usize,
+        json: bool,
+    },
+    Digest {
+        since: Option<DateTime<Utc>>,
+    },
+    Cdpath {
+        filter: Option<String>,
+    },
+    EnvFile {
+        filter: Option<String>,
+        /// `--format=make`: Makefile assignments instead of shell exports
+        make: bool,
+    },
+    Gc {
+        keep_days: usize,
+        dry_run: bool,
+    },
+    Fsck {
+        fix: bool,
+    },
+    Maintain {
+        interval: Option<Duration>,
+        keep_days: usize,
+    },
     Push {
         alias: String,
     },
     Pop,
+    Swap,
+    Root {
+        register: bool,
+        name: Option<String>,
+    },
     Rename {
         old_name: String,
         new_name: String,
     },
+    Copy {
+        source: String,
+        target: String,
+    },
+    CopyPath {
+        alias: String,
+    },
+    Open {
+        alias: String,
+    },
+    Edit {
+        alias: String,
+    },
+    Repath {
+        alias: String,
+        path: String,
+    },
+    SkipCheck {
+        alias: String,
+        skip: bool,
+    },
+    RewritePaths {
+        old_prefix: String,
+        new_prefix: String,
+        dry_run: bool,
+    },
     Tag {
         alias: String,
         tag: String,
         force: bool,
     },
+    TagMany {
+        selector: String,
+        tag: String,
+        force: bool,
+        filter: Option<String>,
+    },
     Untag {
         alias: String,
         tag: String,
     },
+    SetMeta {
+        alias: String,
+        pairs: Vec<String>,
+    },
     RenameTag {
         old_tag: String,
         new_tag: String,
         dry_run: bool,
         force: bool,
     },
+    RetagAuto {
+        dry_run: bool,
+    },
     ListTags,
     ListTagsRaw,
+    ListTagsOf {
+        alias: String,
+    },
+    Complete {
+        kind: String,
+    },
+    CompleteSubpath {
+        alias: String,
+        partial: String,
+    },
+    ListNamespacesRaw,
     Stats,
     Recent {
         count: Option<usize>,
         navigate_to: Option<usize>,
+        since: Option<DateTime<Utc>>,
+        interactive: bool,
+        /// Only aliases with this tag (or key=value metadata)
+        filter: Option<String>,
     },
     RecentClear,
-    Export,
+    Touch {
+        alias: String,
+    },
+    Show {
+        alias: String,
+    },
+    Tree {
+        alias: String,
+        depth: usize,
+    },
+    ResetStats {
+        alias: Option<String>,
+        last_used: bool,
+    },
+    Export {
+        format: ExportFormat,
+        output: Option<String>,
+        portable: bool,
+    },
     Import {
         file: String,
         strategy: ImportStrategy,
+        format: ExportFormat,
+        sha256: Option<String>,
     },
     Install {
         shell: Option<String>,
         skip_rc: bool,
         dry_run: bool,
     },
+    Setup,
+    Suggest,
+    ContextShow,
+    ContextUse {
+        name: String,
+    },
+    ContextClear,
+    ContextList,
+    SessionSave {
+        name: String,
+        dirs: Vec<String>,
+    },
+    SessionRestore {
+        name: String,
+        tmux: bool,
+    },
+    SessionList,
+    SessionDelete {
+        name: String,
+    },
+    RecordDir {
+        dir: String,
+    },
     Update,
     CheckUpdate,
+    GenerateMan,
     PruneSnooze {
         days: u32,
     },
+    Serve {
+        listen: String,
+    },
+    RestoreBackup {
+        index: Option<usize>,
+    },
 }
 
 /// Parse command-line arguments into a structured Args object
 pub fn parse_args(args: &[String]) -> Result<Args, String> {
+    // Global flags may appear anywhere; strip them before dispatching on args[1]
+    let mut no_track = false;
+    let mut db = None;
+    let mut yes = false;
+    let mut print0 = false;
+    let mut no_check = false;
+    let mut no_fuzzy = false;
+    let mut threshold = None;
+    let mut suggestions = None;
+    let mut profile = ProfileOptions::default();
+    let mut rest: Vec<String> = Vec::with_capacity(args.len());
+    let mut iter = args.iter();
+    while let Some(a) = iter.next() {
+        if a == "--no-track" {
+            no_track = true;
+        } else if a == "--yes" || a == "-y" {
+            yes = true;
+        } else if a == "--print0" || a == "-0" {
+            print0 = true;
+        } else if a == "--no-check" {
+            no_check = true;
+        } else if a == "--no-fuzzy" {
+            no_fuzzy = true;
+        } else if let Some(value) = a.strip_prefix("--threshold=") {
+            threshold = Some(
+                value
+                    .parse::<f64>()
+                    .ok()
+                    .filter(|t| (0.0..=1.0).contains(t))
+                    .ok_or_else(|| format!("Invalid threshold '{}': expected a number from 0.0 to 1.0", value))?,
+            );
+        } else if let Some(value) = a.strip_prefix("--suggestions=") {
+            suggestions = Some(
+                value
+                    .parse::<usize>()
+                    .map_err(|_| format!("Invalid suggestion count '{}': expected a whole number", value))?,
+            );
+        } else if a == "--profile" {
+            profile.report = true;
+        } else if let Some(file) = a.strip_prefix("--timings-file=") {
+            profile.timings_file = Some(file.to_string());
+        } else if let Some(file) = a.strip_prefix("--memstats-file=") {
+            profile.memstats_file = Some(file.to_string());
+        } else if a == "--db" {
+            let dir = iter.next().ok_or("Usage: goto --db <dir> <command>")?;
+            db = Some(dir.clone());
+        } else if let Some(dir) = a.strip_prefix("--db=") {
+            db = Some(dir.to_string());
+        } else {
+            rest.push(a.clone());
+        }
+    }
+    let args = rest.as_slice();
+
     if args.len() < 2 {
         return Err("No arguments provided".to_string());
     }
@@ -97,13 +344,34 @@ pub fn parse_args(args: &[String]) -> Result<Args, String> {
     let command = match arg.as_str() {
         "-h" | "--help" => Command::Help,
 
-        "-v" | "--version" => Command::Version,
+        "-v" | "--version" => Command::Version {
+            json: args.iter().any(|a| a == "--json"),
+        },
 
         "--config" => Command::Config,
 
         "-l" | "--list" => Command::List {
-            sort: find_flag_value(args, "--sort="),
-            filter: find_flag_value(args, "--filter="),
+            options: ListOptions {
+                sort: find_flag_value(args, "--sort="),
+                filter: find_flag_value(args, "--filter="),
+                namespace: find_flag_value(args, "--namespace="),
+                path: find_flag_value(args, "--path="),
+                regex: find_flag_value(args, "--regex="),
+                regex_paths: args.iter().any(|a| a == "--regex-paths"),
+                status: args.iter().any(|a| a == "--status"),
+                long: args.iter().any(|a| a == "--long"),
+                du: args.iter().any(|a| a == "--du"),
+                git: args.iter().any(|a| a == "--git"),
+                project_type: args.iter().any(|a| a == "--type"),
+                broken_only: args.iter().any(|a| a == "--broken-only"),
+                group: find_flag_value(args, "--group=")
+                    .map(|g| GroupBy::from_str(&g))
+                    .transpose()?,
+                reverse: args.iter().any(|a| a == "--reverse"),
+                limit: parse_count_flag(args, "--limit=")?,
+                offset: parse_count_flag(args, "--offset=")?.unwrap_or(0),
+                print0,
+            },
         },
 
         "-s" | "--stats" => Command::Stats,
@@ -112,45 +380,209 @@ pub fn parse_args(args: &[String]) -> Result<Args, String> {
 
         "--tags-raw" => Command::ListTagsRaw,
 
+        "--complete" => Command::Complete {
+            kind: args.get(2).cloned().unwrap_or_else(|| "aliases".to_string()),
+        },
+
+        "--complete-subpath" => match args.get(2) {
+            Some(alias) => Command::CompleteSubpath {
+                alias: alias.clone(),
+                partial: args.get(3).cloned().unwrap_or_default(),
+            },
+            None => return Err("Usage: goto --complete-subpath <alias> [partial]".to_string()),
+        },
+
+        "--tags-of" => match args.get(2) {
+            Some(alias) => Command::ListTagsOf { alias: alias.clone() },
+            None => return Err("Usage: goto --tags-of <alias>".to_string()),
+        },
+
+        "--namespaces-raw" => Command::ListNamespacesRaw,
+
+        "-r" | "--register" if args.iter().any(|a| a == "--stdin") => Command::RegisterStdin {
+            tags: find_flag_value(args, "--tags=")
+                .or_else(|| find_space_separated_flag(args, "-t"))
+                .map(|t| t.split(',').map(String::from).collect())
+                .unwrap_or_default(),
+        },
+
+        "--discover-workspaces" => match args.get(2).filter(|a| !a.starts_with('-')) {
+            Some(root) => Command::DiscoverWorkspaces {
+                root: root.clone(),
+                namespace: find_flag_value(args, "--namespace="),
+                tags: find_flag_value(args, "--tags=")
+                    .or_else(|| find_space_separated_flag(args, "-t"))
+                    .map(|t| t.split(',').map(String::from).collect())
+                    .unwrap_or_default(),
+                dry_run: args.iter().any(|a| a == "--dry-run"),
+            },
+            None => {
+                return Err(
+                    "Usage: goto --discover-workspaces <root> [--namespace=<ns>] [-t tags] [--dry-run]".to_string()
+                )
+            }
+        },
+
+        "--scan" => match args.get(2).filter(|a| !a.starts_with('-')) {
+            Some(root) => Command::Scan {
+                root: root.clone(),
+                namespace: find_flag_value(args, "--namespace="),
+                dry_run: args.iter().any(|a| a == "--dry-run"),
+            },
+            None => return Err("Usage: goto --scan <root> [--namespace=<ns>] [--dry-run]".to_string()),
+        },
+
         "-r" | "--register" => {
             if args.len() < 4 {
-                return Err("Usage: goto -r <alias> <directory> [-t tags] [--force]".to_string());
+                return Err("Usage: goto -r <alias> <directory> [-t tags] [--desc=text] [--force]".to_string());
             }
             let tags = find_flag_value(args, "--tags=")
                 .or_else(|| find_space_separated_flag(args, "-t"))
                 .map(|t| t.split(',').map(String::from).collect::<Vec<_>>())
                 .unwrap_or_default();
+            let description = find_flag_value(args, "--desc=")
+                .map(|d| d.trim().to_string())
+                .filter(|d| !d.is_empty());
+            if description.as_deref().is_some_and(|d| d.contains('\n')) {
+                return Err("Invalid description: must be a single line".to_string());
+            }
+            let expires = find_flag_value(args, "--expires=")
+                .map(|value| {
+                    parse_window(&value)
+                        .filter(|d| *d > Duration::zero())
+                        .map(|d| Utc::now() + d)
+                        .ok_or_else(|| format!("invalid --expires value: {} (use e.g. 12h, 30d or 2w)", value))
+                })
+                .transpose()?;
             let force = args.iter().any(|a| a == "--force" || a == "-f");
             Command::Register {
                 name: args[2].clone(),
                 path: args[3].clone(),
                 tags,
+                description,
+                expires,
                 force,
+                if_exists: if args.iter().any(|a| a == "--if-missing") {
+                    IfExists::Skip
+                } else if args.iter().any(|a| a == "--update") {
+                    IfExists::Update
+                } else if force {
+                    IfExists::Overwrite
+                } else {
+                    IfExists::Fail
+                },
             }
         }
 
         "-u" | "--unregister" => {
-            if args.len() < 3 {
-                return Err("Usage: goto -u <alias>".to_string());
-            }
-            Command::Unregister {
-                name: args[2].clone(),
+            let selector = args[2..].iter().find(|a| !a.starts_with('-'));
+            let dry_run = args.iter().any(|a| a == "--dry-run");
+            let tag = find_flag_value(args, "--tag=");
+            let interactive = args[2..].iter().any(|a| a == "-i" || a == "--interactive");
+            match selector {
+                None if interactive => Command::UnregisterInteractive,
+                _ if tag.is_some() => Command::UnregisterMany {
+                    selector: selector.cloned().unwrap_or_else(|| "*".to_string()),
+                    filter: tag.map(|t| format!("tag:{}", t)),
+                    dry_run,
+                },
+                Some(s) if dry_run || s.contains(',') || s.contains(['*', '?']) => {
+                    Command::UnregisterMany {
+                        selector: s.clone(),
+                        filter: None,
+                        dry_run,
+                    }
+                }
+                Some(s) => Command::Unregister { name: s.clone() },
+                None => {
+                    return Err("Usage: goto -u <alias>|'<glob>'|--tag=<tag>|-i [--dry-run]".to_string())
+                }
             }
         }
 
         "-x" | "--expand" => {
-            if args.len() < 3 {
-                return Err("Usage: goto -x <alias>".to_string());
-            }
-            Command::Expand {
-                alias: args[2].clone(),
+            let uri = match find_flag_value(args, "--format=").as_deref() {
+                None | Some("path") => false,
+                Some("uri") => true,
+                Some(other) => return Err(format!("Unknown format for -x: {} (use path or uri)", other)),
+            };
+            match args[2..].iter().find(|a| !a.starts_with("--format=")) {
+                Some(alias) => Command::Expand { alias: alias.clone(), uri },
+                None => return Err("Usage: goto -x [--format=uri] <alias>".to_string()),
             }
         }
 
+        "--random" => Command::Random {
+            filter: find_flag_value(args, "--filter="),
+        },
+
+        "--copy-path" => match args.get(2) {
+            Some(alias) => Command::CopyPath { alias: alias.clone() },
+            None => return Err("Usage: goto --copy-path <alias>".to_string()),
+        },
+
+        "--open" => match args.get(2) {
+            Some(alias) => Command::Open { alias: alias.clone() },
+            None => return Err("Usage: goto --open <alias>".to_string()),
+        },
+
+        "--edit" => match args.get(2) {
+            Some(alias) => Command::Edit { alias: alias.clone() },
+            None => return Err("Usage: goto --edit <alias>".to_string()),
+        },
+
+        "--which" => match args.get(2) {
+            Some(alias) => Command::Which { alias: alias.clone() },
+            None => return Err("Usage: goto --which <alias>".to_string()),
+        },
+
         "-c" | "--cleanup" => Command::Cleanup {
             dry_run: args.iter().any(|a| a == "--dry-run"),
         },
 
+        "--audit" => Command::Audit {
+            unused_days: parse_count_flag(args, "--unused-days=")?
+                .unwrap_or(crate::commands::audit::DEFAULT_UNUSED_DAYS),
+            json: args.iter().any(|a| a == "--json"),
+        },
+
+        "--digest" => Command::Digest {
+            since: find_flag_value(args, "--since=").map(|s| parse_since(&s)).transpose()?,
+        },
+
+        "--cdpath" => Command::Cdpath {
+            filter: find_flag_value(args, "--filter="),
+        },
+
+        "--env-file" => Command::EnvFile {
+            filter: find_flag_value(args, "--filter="),
+            make: match find_flag_value(args, "--format=").as_deref() {
+                None | Some("sh") => false,
+                Some("make") => true,
+                Some(other) => return Err(format!("Unknown format for --env-file: {} (use sh or make)", other)),
+            },
+        },
+
+        "--gc" => Command::Gc {
+            keep_days: parse_count_flag(args, "--keep-days=")?.unwrap_or(crate::commands::gc::DEFAULT_KEEP_DAYS),
+            dry_run: args.iter().any(|a| a == "--dry-run"),
+        },
+
+        "--fsck" => Command::Fsck {
+            fix: args.iter().any(|a| a == "--fix"),
+        },
+
+        "--maintain" => Command::Maintain {
+            interval: find_flag_value(args, "--interval=")
+                .map(|value| {
+                    parse_window(&value)
+                        .filter(|d| *d > Duration::zero())
+                        .ok_or_else(|| format!("invalid --interval value: {} (use e.g. 30m, 1h or 1d)", value))
+                })
+                .transpose()?,
+            keep_days: parse_count_flag(args, "--keep-days=")?.unwrap_or(crate::commands::gc::DEFAULT_KEEP_DAYS),
+        },
+
         "-p" | "--push" => {
             if args.len() < 3 {
                 return Err("Usage: goto -p <alias>".to_string());
@@ -162,7 +594,32 @@ pub fn parse_args(args: &[String]) -> Result<Args, String> {
 
         "-o" | "--pop" => Command::Pop,
 
-        "-e" | "--export" => Command::Export,
+        "--swap" => Command::Swap,
+
+        "--root" => {
+            let name = find_flag_value(args, "--register=");
+            Command::Root {
+                register: name.is_some() || args[2..].iter().any(|a| a == "--register" || a == "-r"),
+                name,
+            }
+        }
+
+        "-e" | "--export" => {
+            let output = args[2..].iter().find(|a| !a.starts_with('-')).cloned();
+            let format = match find_flag_value(args, "--format=") {
+                Some(f) => ExportFormat::from_str(&f)?,
+                None => output
+                    .as_deref()
+                    .map(ExportFormat::from_path)
+                    .unwrap_or_default(),
+            };
+            let portable = args.iter().any(|a| a == "--portable");
+            Command::Export {
+                format,
+                output,
+                portable,
+            }
+        }
 
         "--rename" => {
             if args.len() < 4 {
@@ -174,15 +631,95 @@ pub fn parse_args(args: &[String]) -> Result<Args, String> {
             }
         }
 
-        "--tag" => {
+        "--copy" => {
+            if args.len() < 4 {
+                // `--copy` duplicates an alias, so the clipboard command is `--copy-path`
+                return Err(
+                    "Usage: goto --copy <alias> <new-alias> (to put a path on the clipboard: goto --copy-path <alias>)"
+                        .to_string(),
+                );
+            }
+            Command::Copy {
+                source: args[2].clone(),
+                target: args[3].clone(),
+            }
+        }
+
+        "--repath" => {
             if args.len() < 4 {
-                return Err("Usage: goto --tag <alias> <tag> [--force]".to_string());
+                return Err("Usage: goto --repath <alias> <new-directory>".to_string());
             }
-            let force = args.iter().any(|a| a == "--force" || a == "-f");
-            Command::Tag {
+            Command::Repath {
                 alias: args[2].clone(),
-                tag: args[3].clone(),
-                force,
+                path: args[3].clone(),
+            }
+        }
+
+        "--skip-check" => {
+            let usage = "Usage: goto --skip-check <alias> [on|off]";
+            if args.len() < 3 {
+                return Err(usage.to_string());
+            }
+            let skip = match args.get(3).map(String::as_str) {
+                None | Some("on") => true,
+                Some("off") => false,
+                Some(_) => return Err(usage.to_string()),
+            };
+            Command::SkipCheck {
+                alias: args[2].clone(),
+                skip,
+            }
+        }
+
+        "--rewrite-paths" => {
+            let positional: Vec<&String> = args[2..].iter().filter(|a| !a.starts_with('-')).collect();
+            if positional.len() < 2 {
+                return Err("Usage: goto --rewrite-paths <old-prefix> <new-prefix> [--dry-run]".to_string());
+            }
+            Command::RewritePaths {
+                old_prefix: positional[0].clone(),
+                new_prefix: positional[1].clone(),
+                dry_run: args.iter().any(|a| a == "--dry-run"),
+            }
+        }
+
+        "--tag" => {
+            let positional: Vec<&String> = args[2..].iter().filter(|a| !a.starts_with('-')).collect();
+            if positional.len() < 2 {
+                return Err(
+                    "Usage: goto --tag <alias>[,<alias>...|'<glob>'] <tag> [--filter=path:<pattern>|<tag>] [--force]"
+                        .to_string(),
+                );
+            }
+            let force = args.iter().any(|a| a == "--force" || a == "-f");
+            let selector = positional[0].clone();
+            let filter = find_flag_value(args, "--filter=");
+            if filter.is_some() || selector.contains(',') || selector.contains(['*', '?']) {
+                Command::TagMany {
+                    selector,
+                    tag: positional[1].clone(),
+                    force,
+                    filter,
+                }
+            } else {
+                Command::Tag {
+                    alias: selector,
+                    tag: positional[1].clone(),
+                    force,
+                }
+            }
+        }
+
+        "--tag-where" => {
+            let tag = args[2..].iter().find(|a| !a.starts_with('-'));
+            match (find_flag_value(args, "--path="), tag) {
+                (Some(path), Some(tag)) => Command::TagMany {
+                    selector: "*".to_string(),
+                    tag: tag.clone(),
+                    force: args.iter().any(|a| a == "--force" || a == "-f"),
+                    filter: Some(format!("path:{}", path)),
+                },
+                _ => return Err("Usage: goto --tag-where --path=<prefix|glob> <tag> [--force]".to_string()),
             }
         }
 
@@ -196,6 +733,20 @@ pub fn parse_args(args: &[String]) -> Result<Args, String> {
             }
         }
 
+        "--set" => {
+            if args.len() < 4 {
+                return Err("Usage: goto --set <alias> <key>=<value> [<key>=<value>...]".to_string());
+            }
+            Command::SetMeta {
+                alias: args[2].clone(),
+                pairs: args[3..].to_vec(),
+            }
+        }
+
+        "--retag-auto" => Command::RetagAuto {
+            dry_run: args.iter().any(|a| a == "--dry-run"),
+        },
+
         "--rename-tag" => {
             if args.len() < 4 {
                 return Err("Usage: goto --rename-tag <old-tag> <new-tag> [--dry-run] [--force]".to_string());
@@ -213,21 +764,51 @@ pub fn parse_args(args: &[String]) -> Result<Args, String> {
         "-T" | "--tags" => Command::ListTags,
 
         "-R" | "--recent" => {
+            let since = find_flag_value(args, "--since=")
+                .map(|s| parse_since(&s))
+                .transpose()?;
+            let interactive = args.iter().any(|a| a == "-i" || a == "--interactive");
+            let filter = find_flag_value(args, "--filter=");
             if args.len() >= 3 {
                 if let Ok(n) = args[2].parse::<usize>() {
-                    if n >= 1 && n <= 20 && args.len() == 3 {
+                    // With -0 the number is always a count: NUL output is for listing
+                    if n >= 1 && n <= 20 && args.len() == 3 && !print0 {
                         return Ok(Args {
                             command: Command::Recent {
                                 count: None,
                                 navigate_to: Some(n),
+                                since,
+                                interactive,
+                                filter,
                             },
+                            no_track,
+                            db,
+                            yes,
+                            print0,
+                            no_check,
+                            no_fuzzy,
+                            threshold,
+                            suggestions,
+                            profile,
                         });
                     } else {
                         return Ok(Args {
                             command: Command::Recent {
                                 count: Some(n),
                                 navigate_to: None,
+                                since,
+                                interactive,
+                                filter,
                             },
+                            no_track,
+                            db,
+                            yes,
+                            print0,
+                            no_check,
+                            no_fuzzy,
+                            threshold,
+                            suggestions,
+                            profile,
                         });
                     }
                 }
@@ -235,23 +816,106 @@ pub fn parse_args(args: &[String]) -> Result<Args, String> {
             Command::Recent {
                 count: Some(10),
                 navigate_to: None,
+                since,
+                interactive,
+                filter,
+            }
+        }
+
+        "--recent-list" => {
+            let count = match args.get(2).filter(|a| !a.starts_with('-')) {
+                Some(n) => Some(n.parse::<usize>().map_err(|_| format!("Invalid count: {}", n))?),
+                None => Some(10),
+            };
+            Command::Recent {
+                count,
+                navigate_to: None,
+                since: find_flag_value(args, "--since=")
+                    .map(|s| parse_since(&s))
+                    .transpose()?,
+                interactive: false,
+                filter: find_flag_value(args, "--filter="),
+            }
+        }
+
+        "--recent-go" => {
+            let n = args
+                .get(2)
+                .ok_or_else(|| "Usage: goto --recent-go <N>".to_string())?;
+            let n: usize = n.parse().map_err(|_| format!("Invalid index: {}", n))?;
+            Command::Recent {
+                count: None,
+                navigate_to: Some(n),
+                since: None,
+                interactive: false,
+                filter: None,
             }
         }
 
+        "--last" => Command::Recent {
+            count: None,
+            navigate_to: Some(1),
+            since: None,
+            interactive: false,
+            filter: None,
+        },
+
         "--recent-clear" => Command::RecentClear,
 
+        "--reset-stats" => Command::ResetStats {
+            alias: args.get(2).filter(|a| !a.starts_with('-')).cloned(),
+            last_used: args.iter().any(|a| a == "--last-used"),
+        },
+
+        "--touch" => {
+            if args.len() < 3 {
+                return Err("Usage: goto --touch <alias>".to_string());
+            }
+            Command::Touch {
+                alias: args[2].clone(),
+            }
+        }
+
+        "--show" => {
+            if args.len() < 3 {
+                return Err("Usage: goto --show <alias>".to_string());
+            }
+            Command::Show {
+                alias: args[2].clone(),
+            }
+        }
+
+        "--tree" => {
+            if args.len() < 3 || args[2].starts_with("--") {
+                return Err("Usage: goto --tree <alias> [--depth=N]".to_string());
+            }
+            Command::Tree {
+                alias: args[2].clone(),
+                depth: parse_count_flag(args, "--depth=")?.unwrap_or(2),
+            }
+        }
+
         "-i" | "--import" => {
             if args.len() < 3 {
                 return Err(
-                    "Usage: goto --import <file> [--strategy=skip|overwrite|rename]".to_string(),
+                    "Usage: goto --import <file|url> [--strategy=skip|overwrite|rename] [--format=toml|json|csv] \
+                     [--sha256=<hex>]"
+                        .to_string(),
                 );
             }
             let strategy_str = find_flag_value(args, "--strategy=").unwrap_or_else(|| "skip".to_string());
             let strategy = ImportStrategy::from_str(&strategy_str)
                 .map_err(|e| e.to_string())?;
+            let format = match find_flag_value(args, "--format=") {
+                Some(f) => ExportFormat::from_str(&f)?,
+                // Ignore a URL's query string when guessing from the extension
+                None => ExportFormat::from_path(args[2].split(['?', '#']).next().unwrap_or_default()),
+            };
             Command::Import {
                 file: args[2].clone(),
                 strategy,
+                format,
+                sha256: find_flag_value(args, "--sha256="),
             }
         }
 
@@ -261,10 +925,68 @@ pub fn parse_args(args: &[String]) -> Result<Args, String> {
             dry_run: args.iter().any(|a| a == "--dry-run"),
         },
 
+        "--setup" => Command::Setup,
+
+        "--suggest" => Command::Suggest,
+
+        "--context" => match args.get(2).map(String::as_str) {
+            None => Command::ContextShow,
+            Some("list") => Command::ContextList,
+            Some("clear") => Command::ContextClear,
+            Some("use") => match args.get(3) {
+                Some(name) => Command::ContextUse { name: name.clone() },
+                None => return Err("Usage: goto --context use <name>".to_string()),
+            },
+            Some(other) => {
+                return Err(format!(
+                    "Unknown context action: {} (use 'use <name>', 'clear' or 'list')",
+                    other
+                ))
+            }
+        },
+
+        "--session" => {
+            let name = args.get(3).filter(|n| !n.starts_with('-')).cloned();
+            match (args.get(2).map(String::as_str), name) {
+                (None | Some("list"), _) => Command::SessionList,
+                (Some("save"), Some(name)) => Command::SessionSave {
+                    name,
+                    dirs: args[4..].iter().filter(|a| !a.starts_with('-')).cloned().collect(),
+                },
+                (Some("restore"), Some(name)) => Command::SessionRestore {
+                    name,
+                    tmux: args.iter().any(|a| a == "--tmux"),
+                },
+                (Some("delete"), Some(name)) => Command::SessionDelete { name },
+                (Some(action @ ("save" | "restore" | "delete")), None) => {
+                    return Err(format!("Usage: goto --session {} <name>", action))
+                }
+                (Some(other), _) => {
+                    return Err(format!(
+                        "Unknown session action: {} (use 'save', 'restore', 'list' or 'delete')",
+                        other
+                    ))
+                }
+            }
+        }
+
+        "--record-dir" => {
+            if args.len() < 3 {
+                return Err("Usage: goto --record-dir <directory>".to_string());
+            }
+            Command::RecordDir {
+                dir: args[2].clone(),
+            }
+        }
+
         "-U" | "--update" => Command::Update,
 
         "--check-update" => Command::CheckUpdate,
 
+        "--batch" => Command::Batch,
+
+        "--generate-man" => Command::GenerateMan,
+
         "--prune-snooze" => {
             if args.len() < 3 {
                 return Err("Usage: goto --prune-snooze <days>".to_string());
@@ -278,18 +1000,58 @@ pub fn parse_args(args: &[String]) -> Result<Args, String> {
             Command::PruneSnooze { days }
         }
 
+        "--serve" => Command::Serve {
+            listen: find_flag_value(args, "--listen=")
+                .unwrap_or_else(|| crate::commands::serve::DEFAULT_LISTEN.to_string()),
+        },
+
+        "--restore-backup" => {
+            let index = match args.get(2) {
+                Some(n) => Some(
+                    n.parse::<usize>()
+                        .ok()
+                        .filter(|&n| n > 0)
+                        .ok_or_else(|| format!("Invalid backup number: {}", n))?,
+                ),
+                None => None,
+            };
+            Command::RestoreBackup { index }
+        }
+
+        // Spelled-out form of `goto <alias>..`
+        "--parent" => match args.get(2) {
+            Some(alias) if !alias.starts_with('-') => Command::Navigate {
+                alias: format!("{}..", alias),
+            },
+            _ => return Err("Usage: goto <alias> --parent".to_string()),
+        },
+
         _ => {
             if arg.starts_with('-') {
                 return Err(format!("Unknown option: {}", arg));
             }
-            // Default action: navigate to alias
-            Command::Navigate {
-                alias: arg.clone(),
-            }
+            // Default action: navigate to alias (or its parent with --parent)
+            let alias = if args[2..].iter().any(|a| a == "--parent") {
+                format!("{}..", arg)
+            } else {
+                arg.clone()
+            };
+            Command::Navigate { alias }
         }
     };
 
-    Ok(Args { command })
+    Ok(Args {
+        command,
+        no_track,
+        db,
+        yes,
+        print0,
+        no_check,
+        no_fuzzy,
+        threshold,
+        suggestions,
+        profile,
+    })
 }
 
 /// Find a flag value with the given prefix (e.g., "--sort=alpha")
@@ -299,6 +1061,16 @@ fn find_flag_value(args: &[String], prefix: &str) -> Option<String> {
         .map(|a| a[prefix.len()..].to_string())
 }
 
+/// Parse a non-negative integer flag value (e.g., "--limit=20")
+fn parse_count_flag(args: &[String], prefix: &str) -> Result<Option<usize>, String> {
+    find_flag_value(args, prefix)
+        .map(|v| {
+            v.parse::<usize>()
+                .map_err(|_| format!("Invalid value for {}: {}", prefix.trim_end_matches('='), v))
+        })
+        .transpose()
+}
+
 /// Find a flag value with space separator (e.g., "-t work,rust")
 fn find_space_separated_flag(args: &[String], flag: &str) -> Option<String> {
     args.iter()
@@ -315,57 +1087,165 @@ pub fn print_usage() {
 
 /// Print the full help text
 pub fn print_help() {
-    print!(
-        r#"goto - Navigate to aliased directories
+    print!("{}", crate::i18n::localize_help(HELP, crate::i18n::lang()));
+}
+
+/// Help text; also the source of the man page (`goto --generate-man`)
+///
+/// Section headings end in ':' and entries are "  <usage>  <description>",
+/// with wrapped descriptions indented to the description column.
+pub const HELP: &str = r#"goto - Navigate to aliased directories
 
 Usage:
   goto <alias>                    Navigate to the directory
+  goto <alias>/<subdir>           Navigate to a directory below the alias
+  goto <alias> --parent           Navigate to the directory containing the alias (or <alias>..)
+  goto %<N>                       Navigate to row N of the last -l/--recent table
+  goto --random [--filter=<tag>]  Navigate to a random alias (optionally with a tag)
   goto -r <alias> <directory>     Register a new alias
   goto -r <alias> <dir> -t tags   Register with tags (comma-separated)
-  goto -r <alias> <dir> --force   Skip confirmation for new tags
+  goto -r <alias> <dir> --force   Overwrite an existing alias, skip tag confirmation
+  goto -r <alias> <dir> --desc=<text>  Register with a one-line description
+  goto -r <alias> <dir> --expires=30d  Register a temporary alias (hidden from navigation once expired)
+  goto -r <alias> <dir> --update  Create, or repoint if the path differs (idempotent)
+  goto -r <alias> <dir> --if-missing  Create only if the alias doesn't exist yet
+  goto -r --stdin [-t tags]       Register each directory read from stdin (derived names)
+  goto --discover-workspaces <root>  Register each go.work/npm/Cargo workspace member as root:member
+  goto --scan <root> [--dry-run]  Merge the aliases of every .goto-aliases file below root
+  goto --batch < script           Run register/tag/unregister/... lines from stdin, saving once
   goto -u <alias>                 Unregister an alias
+  goto -u '<glob>' [--dry-run]    Unregister every matching alias (after confirming)
+  goto -u --tag=<tag>             Unregister every alias with a tag
+  goto -u -i                      Pick aliases to unregister from a list
   goto -l                         List all aliases
   goto -l --sort=<order>          List aliases with sorting
   goto -l --filter=<tag>          List aliases with tag
+  goto -l --group=tag             Show a tree of aliases grouped by tag
+  goto -l --group=path[:<depth>]  Show a tree grouped by shared location
+  goto -l --status                Mark aliases whose directory is missing
+  goto -l --broken-only           List only aliases whose directory is missing
+  goto -l --long                  Add created, last used, uses, tags and description columns
+  goto -l --du [--sort=size]      Add a column with each directory's disk usage
+  goto -l --git                   Add a column with the branch and dirty state of git repos
+  goto -l --type                  Add a column with the project type (rust, go, node, ...)
   goto -x <alias>                 Expand alias to path
+  goto -x --format=uri <alias>    Print the path as a file:// URI
+  goto --copy-path <alias>        Copy the alias's path to the clipboard (not --copy)
+  goto --open <alias>             Open the alias's file or directory with the default application
+  goto --edit <alias>             Edit the alias's file in $VISUAL or $EDITOR
+  goto --which <alias>            Explain how a name resolves (no navigation)
+  goto --show <alias>             Show all details for an alias
+  goto --tree <alias> [--depth=N]  Show the subdirectories below an alias (default depth 2)
   goto -c                         Cleanup invalid aliases
   goto -c --dry-run               List invalid aliases (don't remove)
+  goto --audit [--json]           Score every alias: missing, duplicate, nested, unused, untagged
+  goto --audit --unused-days=N    Count aliases unused for N days as unused (default 90)
+  goto --digest [--since=<when>]  Markdown summary of the last 7 days (or since 2w, a date, ...)
+  goto --gc [--dry-run]           Compact the navigation log and drop surplus backups (for cron)
+  goto --gc --keep-days=N         Keep the last N days of the log uncompacted (default 30)
+  goto --fsck [--fix]             Check every entry and the checksum (and repair trivial problems)
+  goto --maintain [--interval=1h]  Check paths, compact the log, drop backups; write a report file
   goto -p <alias>                 Push current dir, goto alias
   goto -o                         Pop and return to directory
+  goto --swap                     Go to the top of the stack, leaving this dir there
+  goto --root [--register[=<name>]]  Go to the git/hg repository root (and give it an alias)
   goto --rename <old> <new>       Rename an alias
+  goto --copy <alias> <new>       Copy an alias (same path and tags, fresh usage)
+  goto --repath <alias> <dir>     Point alias at a new directory
+  goto --skip-check <alias> [off]  Never check the alias directory before jumping
+  goto --rewrite-paths <old> <new>  Rewrite a path prefix in all aliases (--dry-run)
   goto --tag <alias> <tag>        Add tag to alias
   goto --tag <alias> <tag> -f     Add tag without confirmation
+  goto --tag a,b,c <tag>          Add tag to several aliases
+  goto --tag '*' <tag> --filter=path:~/work  Tag every alias under a path
+  goto --tag-where --path=<glob> <tag>       Same, shorter
   goto --untag <alias> <tag>      Remove tag from alias
+  goto --set <alias> key=value    Set metadata on alias (key= removes it)
   goto --rename-tag <old> <new>   Rename tag across all aliases
   goto --rename-tag old new -f    Rename without confirmation
   goto --rename-tag old new --dry-run  Preview changes only
+  goto --retag-auto [--dry-run]   Apply [[autotag]] rules to existing aliases
   goto -T / --tags                List all tags with counts
   goto -s / --stats               Show usage statistics
   goto -R / --recent              List recently visited directories
   goto -R <N> / --recent <N>      Navigate to Nth most recent
+  goto --recent-list [N]          List N most recent (never navigates)
+  goto --recent-go <N>            Navigate to Nth most recent (never lists)
+  goto --last                     Navigate to the most recently used alias
+  goto -R -i / --recent -i        Pick a recent directory from a menu
+  goto -R --since=<when>          Only show visits since 7d, 12h, 2w or a date
+  goto -R --filter=<tag>          Only show aliases with a tag (or key=value)
   goto --recent-clear             Clear recent history
-  goto -e / --export              Export aliases to TOML (stdout)
-  goto -i / --import <file>       Import aliases from TOML file
+  goto --touch <alias>            Record a use without navigating
+  goto --reset-stats [alias]      Zero use counts (one alias or all)
+  goto --reset-stats --last-used  Also clear last-visited times
+  goto -e / --export [file]       Export aliases to TOML (stdout or file)
+  goto -e --portable              Export with paths under $HOME as ~/...
+  goto -i / --import <file>       Import aliases from file ('-' for stdin, or an http(s) URL)
   goto --config                   Show current configuration
   goto --install                  Install shell integration
+  goto --cdpath [--filter=<tag>]  Print alias parent directories as a CDPATH value
+  goto --env-file [--filter=<tag>] [--format=make]  Print aliases as export GOTO_DIR_NAME="path" lines
+  goto --setup                    Run the first-time setup wizard
+  goto --suggest                  Suggest aliases for often-visited directories
+  goto --context                  Show the active context (namespace)
+  goto --context use <name>       Switch context; bare names resolve in <name>:
+  goto --context clear|list       Clear the context / list available contexts
+  goto --session save <name> [dir|alias...]  Save the stack and these directories as a session
+  goto --session restore <name> [--tmux]  Refill the stack (or open tmux windows) and go back
+  goto --session list|delete <name>  List sessions / forget one
   goto -U / --update              Update goto to latest version
   goto --check-update             Check for available updates
+  goto --generate-man             Print this help as a man page (goto.1)
   goto --prune-snooze <days>      Snooze stale alias notification for N days
+  goto --restore-backup [N]       List database backups or restore backup N
+  goto --serve [--listen=<addr>]  Serve the HTTP API (token from GOTO_API_TOKEN)
+  goto --no-track <alias>         Navigate without recording usage (any command)
+  goto --db <dir> <command>       Use another database directory (like GOTO_DB)
+  goto -y, --yes <command>        Skip confirmation prompts (unregister, cleanup, import)
+  goto -0, --print0 <command>     NUL-separated paths from -l, -x and --recent
+  goto --no-check <alias>         Navigate without checking the directory (slow mounts)
+  goto --no-fuzzy <alias>         Navigate only on an exact name; never offer similar ones
+  goto --threshold=<0-1> <alias>  Similarity a name needs to be offered (fuzzy_threshold)
+  goto --suggestions=<N> <alias>  Offer at most N similar names (default 3)
+  goto --profile <command>        Time config load, database load, fuzzy matching and save
+  goto --timings-file=<file>      Write those timings to a file instead of stderr
+  goto --memstats-file=<file>     Write peak and current memory use to a file (Linux)
   goto -v                         Show version
+  goto -v --json                  Version, commit, build date, rustc and schema as JSON
   goto -h                         Show this help
 
 Sort options (use with -l/--list):
   --sort=alpha                    Sort alphabetically (default)
   --sort=usage                    Sort by use count (most used first)
   --sort=recent                   Sort by last used (most recent first)
+  --sort=created                  Sort by registration time (newest first)
+  --sort=path                     Sort by directory path
+  --reverse                       Invert the sort order
+
+Paging options (use with -l/--list):
+  --limit=<N>                     Show at most N aliases
+  --offset=<N>                    Skip the first N aliases
 
 Filter options (use with -l/--list):
-  --filter=<tag>                  Show only aliases with tag
+  --filter=<tag|key=value>        Show only aliases with tag or metadata
+  --namespace=<ns>                Show only aliases named <ns>:<name>
+  --path=<prefix|glob>            Show only aliases under a path (e.g., '~/code/**')
+  --regex=<pattern>               Show only aliases whose name matches a regex
+  --regex-paths                   Also match --regex against paths
 
 Import strategies (use with -i/--import):
   --strategy=skip                 Skip existing aliases (default)
   --strategy=overwrite            Overwrite existing aliases
   --strategy=rename               Rename conflicting aliases (add suffix)
+  --sha256=<hex>                  Refuse the import unless the file has this checksum
+
+Formats (use with -e/--export and -i/--import):
+  --format=toml                   TOML (default for export)
+  --format=json                   JSON
+  --format=csv                    CSV with a header row
+                                  The format is detected from the file
+                                  extension when --format is omitted
 
 Install options (use with --install):
   --shell=bash|zsh|fish           Shell to configure (auto-detects from $SHELL)
@@ -396,9 +1276,9 @@ Examples:
   goto -o                         Return to saved location
   goto -e > backup.toml           Backup aliases to file
   goto -i backup.toml             Restore aliases from backup
-"#
-    );
-}
+  goto -e --format=csv > a.csv    Export aliases for a spreadsheet
+  ssh host goto-bin -e | goto -i -  Copy aliases from another machine
+"#;
 
 /// Get the version string
 pub fn version() -> &'static str {
@@ -424,7 +1304,10 @@ mod tests {
     fn test_parse_version() {
         let result = parse_args(&args(&["goto", "--version"]));
         assert!(result.is_ok());
-        assert!(matches!(result.unwrap().command, Command::Version));
+        assert!(matches!(result.unwrap().command, Command::Version { json: false }));
+
+        let result = parse_args(&args(&["goto", "--version", "--json"]));
+        assert!(matches!(result.unwrap().command, Command::Version { json: true }));
     }
 
     #[test]
@@ -438,11 +1321,36 @@ mod tests {
         }
     }
 
+    #[test]
+    fn test_parse_navigate_parent() {
+        let result = parse_args(&args(&["goto", "repo", "--parent"])).unwrap();
+        assert!(matches!(result.command, Command::Navigate { ref alias } if alias == "repo.."));
+        let result = parse_args(&args(&["goto", "--parent", "repo"])).unwrap();
+        assert!(matches!(result.command, Command::Navigate { ref alias } if alias == "repo.."));
+        let result = parse_args(&args(&["goto", "repo.."])).unwrap();
+        assert!(matches!(result.command, Command::Navigate { ref alias } if alias == "repo.."));
+        assert!(parse_args(&args(&["goto", "--parent"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_profile() {
+        let result = parse_args(&args(&["goto", "proj", "--profile"])).unwrap();
+        assert!(matches!(result.command, Command::Navigate { ref alias } if alias == "proj"));
+        assert!(result.profile.report);
+
+        let result = parse_args(&args(&["goto", "--timings-file=/tmp/t", "-l", "--memstats-file=/tmp/m"])).unwrap();
+        assert!(matches!(result.command, Command::List { .. }));
+        assert!(!result.profile.report);
+        assert_eq!(result.profile.timings_file.as_deref(), Some("/tmp/t"));
+        assert_eq!(result.profile.memstats_file.as_deref(), Some("/tmp/m"));
+        assert!(!parse_args(&args(&["goto", "proj"])).unwrap().profile.enabled());
+    }
+
     #[test]
     fn test_parse_register() {
         let result = parse_args(&args(&["goto", "-r", "dev", "/path/to/dev"]));
         assert!(result.is_ok());
-        if let Command::Register { name, path, tags, force } = result.unwrap().command {
+        if let Command::Register { name, path, tags, force, .. } = result.unwrap().command {
             assert_eq!(name, "dev");
             assert_eq!(path, "/path/to/dev");
             assert!(tags.is_empty());
@@ -456,7 +1364,7 @@ mod tests {
     fn test_parse_register_with_tags() {
         let result = parse_args(&args(&["goto", "-r", "dev", "/path", "--tags=work,rust"]));
         assert!(result.is_ok());
-        if let Command::Register { name, path, tags, force } = result.unwrap().command {
+        if let Command::Register { name, path, tags, force, .. } = result.unwrap().command {
             assert_eq!(name, "dev");
             assert_eq!(path, "/path");
             assert_eq!(tags, vec!["work", "rust"]);
@@ -467,14 +1375,62 @@ mod tests {
     }
 
     #[test]
-    fn test_parse_register_with_force() {
-        let result = parse_args(&args(&["goto", "-r", "dev", "/path", "--force"]));
-        assert!(result.is_ok());
-        if let Command::Register { name, path, tags, force } = result.unwrap().command {
+    fn test_parse_register_stdin() {
+        let result = parse_args(&args(&["goto", "-r", "--stdin", "--tags=code"])).unwrap();
+        assert!(matches!(result.command, Command::RegisterStdin { ref tags } if tags == &["code"]));
+
+        let result = parse_args(&args(&["goto", "--register", "--stdin"])).unwrap();
+        assert!(matches!(result.command, Command::RegisterStdin { ref tags } if tags.is_empty()));
+    }
+
+    #[test]
+    fn test_parse_discover_workspaces() {
+        let result = parse_args(&args(&["goto", "--discover-workspaces", "~/shop", "-t", "work", "--dry-run"])).unwrap();
+        if let Command::DiscoverWorkspaces { root, namespace, tags, dry_run } = result.command {
+            assert_eq!(root, "~/shop");
+            assert_eq!(namespace, None);
+            assert_eq!(tags, vec!["work"]);
+            assert!(dry_run);
+        } else {
+            panic!("Expected DiscoverWorkspaces command");
+        }
+
+        let result = parse_args(&args(&["goto", "--discover-workspaces", ".", "--namespace=shop"])).unwrap();
+        assert!(matches!(result.command,
+            Command::DiscoverWorkspaces { namespace: Some(ref ns), dry_run: false, .. } if ns == "shop"));
+        assert!(parse_args(&args(&["goto", "--discover-workspaces"])).is_err());
+        assert!(parse_args(&args(&["goto", "--discover-workspaces", "--dry-run"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_scan() {
+        let result = parse_args(&args(&["goto", "--scan", "~/code", "--dry-run"])).unwrap();
+        assert!(matches!(
+            result.command,
+            Command::Scan { ref root, namespace: None, dry_run: true } if root == "~/code"
+        ));
+
+        let result = parse_args(&args(&["goto", "--scan", ".", "--namespace=t"])).unwrap();
+        assert!(matches!(result.command, Command::Scan { namespace: Some(ref ns), dry_run: false, .. } if ns == "t"));
+        assert!(parse_args(&args(&["goto", "--scan"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_batch() {
+        let result = parse_args(&args(&["goto", "--batch"])).unwrap();
+        assert!(matches!(result.command, Command::Batch));
+    }
+
+    #[test]
+    fn test_parse_register_with_force() {
+        let result = parse_args(&args(&["goto", "-r", "dev", "/path", "--force"]));
+        assert!(result.is_ok());
+        if let Command::Register { name, path, tags, force, if_exists, .. } = result.unwrap().command {
             assert_eq!(name, "dev");
             assert_eq!(path, "/path");
             assert!(tags.is_empty());
             assert!(force);
+            assert_eq!(if_exists, IfExists::Overwrite);
         } else {
             panic!("Expected Register command");
         }
@@ -484,21 +1440,67 @@ mod tests {
     fn test_parse_register_with_short_force() {
         let result = parse_args(&args(&["goto", "-r", "dev", "/path", "-f"]));
         assert!(result.is_ok());
-        if let Command::Register { name, path, tags, force } = result.unwrap().command {
+        if let Command::Register { name, path, tags, force, if_exists, .. } = result.unwrap().command {
             assert_eq!(name, "dev");
             assert_eq!(path, "/path");
             assert!(tags.is_empty());
             assert!(force);
+            assert_eq!(if_exists, IfExists::Overwrite);
         } else {
             panic!("Expected Register command");
         }
     }
 
+    #[test]
+    fn test_parse_register_update() {
+        let result = parse_args(&args(&["goto", "-r", "dev", "/path", "--update"])).unwrap();
+        assert!(matches!(
+            result.command,
+            Command::Register { if_exists: IfExists::Update, force: false, .. }
+        ));
+
+        let result = parse_args(&args(&["goto", "-r", "dev", "/path"])).unwrap();
+        assert!(matches!(result.command, Command::Register { if_exists: IfExists::Fail, .. }));
+
+        let result = parse_args(&args(&["goto", "-r", "dev", "/path", "--if-missing"])).unwrap();
+        assert!(matches!(result.command, Command::Register { if_exists: IfExists::Skip, .. }));
+    }
+
+    #[test]
+    fn test_parse_register_with_description() {
+        let result = parse_args(&args(&["goto", "-r", "web", "/path", "--desc=Frontend app", "-t", "work"])).unwrap();
+        if let Command::Register { description, tags, .. } = result.command {
+            assert_eq!(description.as_deref(), Some("Frontend app"));
+            assert_eq!(tags, vec!["work"]);
+        } else {
+            panic!("Expected Register command");
+        }
+
+        let result = parse_args(&args(&["goto", "-r", "web", "/path", "--desc="])).unwrap();
+        assert!(matches!(result.command, Command::Register { description: None, .. }));
+        assert!(parse_args(&args(&["goto", "-r", "web", "/path", "--desc=two\nlines"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_register_with_expiry() {
+        let result = parse_args(&args(&["goto", "-r", "review", "/path", "--expires=30d"])).unwrap();
+        if let Command::Register { expires: Some(at), .. } = result.command {
+            assert_eq!((at - Utc::now()).num_days(), 29);
+        } else {
+            panic!("Expected Register command with an expiry");
+        }
+
+        let result = parse_args(&args(&["goto", "-r", "review", "/path"])).unwrap();
+        assert!(matches!(result.command, Command::Register { expires: None, .. }));
+        assert!(parse_args(&args(&["goto", "-r", "review", "/path", "--expires=0d"])).is_err());
+        assert!(parse_args(&args(&["goto", "-r", "review", "/path", "--expires=soon"])).is_err());
+    }
+
     #[test]
     fn test_parse_register_with_tags_and_force() {
         let result = parse_args(&args(&["goto", "-r", "dev", "/path", "--tags=work", "--force"]));
         assert!(result.is_ok());
-        if let Command::Register { name, path, tags, force } = result.unwrap().command {
+        if let Command::Register { name, path, tags, force, .. } = result.unwrap().command {
             assert_eq!(name, "dev");
             assert_eq!(path, "/path");
             assert_eq!(tags, vec!["work"]);
@@ -534,12 +1536,187 @@ mod tests {
     fn test_parse_list_with_options() {
         let result = parse_args(&args(&["goto", "-l", "--sort=usage", "--filter=work"]));
         assert!(result.is_ok());
-        if let Command::List { sort, filter } = result.unwrap().command {
-            assert_eq!(sort, Some("usage".to_string()));
-            assert_eq!(filter, Some("work".to_string()));
+        if let Command::List { options } = result.unwrap().command {
+            assert_eq!(options.sort, Some("usage".to_string()));
+            assert_eq!(options.filter, Some("work".to_string()));
+            assert!(!options.status);
+        } else {
+            panic!("Expected List command");
+        }
+    }
+
+    #[test]
+    fn test_parse_list_group() {
+        let result = parse_args(&args(&["goto", "-l", "--group=tag"]));
+        if let Command::List { options } = result.unwrap().command {
+            assert_eq!(options.group, Some(GroupBy::Tag));
+        } else {
+            panic!("Expected List command");
+        }
+
+        let result = parse_args(&args(&["goto", "-l", "--group=path"]));
+        if let Command::List { options } = result.unwrap().command {
+            assert_eq!(options.group, Some(GroupBy::Path(crate::commands::list::DEFAULT_GROUP_DEPTH)));
         } else {
             panic!("Expected List command");
         }
+
+        assert!(parse_args(&args(&["goto", "-l", "--group=bogus"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_list_reverse() {
+        let result = parse_args(&args(&["goto", "-l", "--sort=created", "--reverse"]));
+        if let Command::List { options } = result.unwrap().command {
+            assert_eq!(options.sort, Some("created".to_string()));
+            assert!(options.reverse);
+        } else {
+            panic!("Expected List command");
+        }
+    }
+
+    #[test]
+    fn test_parse_list_namespace_filter() {
+        let result = parse_args(&args(&["goto", "-l", "--namespace=work"]));
+        if let Command::List { options } = result.unwrap().command {
+            assert_eq!(options.namespace, Some("work".to_string()));
+        } else {
+            panic!("Expected List command");
+        }
+
+        let result = parse_args(&args(&["goto", "--namespaces-raw"]));
+        assert!(matches!(result.unwrap().command, Command::ListNamespacesRaw));
+    }
+
+    #[test]
+    fn test_parse_list_path_filter() {
+        let result = parse_args(&args(&["goto", "-l", "--path=~/code/**"]));
+        if let Command::List { options } = result.unwrap().command {
+            assert_eq!(options.path, Some("~/code/**".to_string()));
+        } else {
+            panic!("Expected List command");
+        }
+    }
+
+    #[test]
+    fn test_parse_list_regex_filter() {
+        let result = parse_args(&args(&["goto", "-l", "--regex=^client-", "--regex-paths"]));
+        if let Command::List { options } = result.unwrap().command {
+            assert_eq!(options.regex, Some("^client-".to_string()));
+            assert!(options.regex_paths);
+        } else {
+            panic!("Expected List command");
+        }
+    }
+
+    #[test]
+    fn test_parse_list_limit_offset() {
+        let result = parse_args(&args(&["goto", "-l", "--limit=20", "--offset=40"]));
+        if let Command::List { options } = result.unwrap().command {
+            assert_eq!(options.limit, Some(20));
+            assert_eq!(options.offset, 40);
+        } else {
+            panic!("Expected List command");
+        }
+
+        let result = parse_args(&args(&["goto", "-l", "--limit=many"]));
+        assert!(result.unwrap_err().contains("Invalid value for --limit"));
+    }
+
+    #[test]
+    fn test_parse_list_status_flags() {
+        let result = parse_args(&args(&["goto", "-l", "--status", "--broken-only", "--long", "--du", "--git", "--type"]));
+        if let Command::List { options } = result.unwrap().command {
+            assert!(options.du);
+            assert!(options.git);
+            assert!(options.project_type);
+            assert!(options.status);
+            assert!(options.broken_only);
+            assert!(options.long);
+        } else {
+            panic!("Expected List command");
+        }
+    }
+
+    #[test]
+    fn test_parse_no_track() {
+        let result = parse_args(&args(&["goto", "--no-track", "proj"])).unwrap();
+        assert!(result.no_track);
+        assert!(matches!(result.command, Command::Navigate { ref alias } if alias == "proj"));
+
+        let result = parse_args(&args(&["goto", "-p", "proj", "--no-track"])).unwrap();
+        assert!(result.no_track);
+        assert!(matches!(result.command, Command::Push { ref alias } if alias == "proj"));
+
+        assert!(!parse_args(&args(&["goto", "proj"])).unwrap().no_track);
+    }
+
+    #[test]
+    fn test_parse_yes() {
+        let result = parse_args(&args(&["goto", "-u", "proj", "--yes"])).unwrap();
+        assert!(result.yes);
+        assert!(matches!(result.command, Command::Unregister { ref name } if name == "proj"));
+
+        let result = parse_args(&args(&["goto", "-y", "--cleanup"])).unwrap();
+        assert!(result.yes);
+        assert!(matches!(result.command, Command::Cleanup { dry_run: false }));
+
+        assert!(!parse_args(&args(&["goto", "--cleanup"])).unwrap().yes);
+    }
+
+    #[test]
+    fn test_parse_print0() {
+        let result = parse_args(&args(&["goto", "-l", "-0"])).unwrap();
+        assert!(result.print0);
+        assert!(matches!(result.command, Command::List { ref options } if options.print0));
+
+        let result = parse_args(&args(&["goto", "--recent", "5", "-0"])).unwrap();
+        assert!(matches!(result.command, Command::Recent { count: Some(5), navigate_to: None, .. }));
+
+        let result = parse_args(&args(&["goto", "--print0", "-x", "proj"])).unwrap();
+        assert!(result.print0);
+        assert!(matches!(result.command, Command::Expand { ref alias, .. } if alias == "proj"));
+    }
+
+    #[test]
+    fn test_parse_fuzzy_flags() {
+        let result = parse_args(&args(&["goto", "--no-fuzzy", "proj"])).unwrap();
+        assert!(result.no_fuzzy);
+        assert!(matches!(result.command, Command::Navigate { ref alias } if alias == "proj"));
+
+        let result = parse_args(&args(&["goto", "proj", "--threshold=0.85"])).unwrap();
+        assert_eq!(result.threshold, Some(0.85));
+        assert!(!result.no_fuzzy);
+
+        assert!(parse_args(&args(&["goto", "--threshold=2", "proj"])).is_err());
+        assert!(parse_args(&args(&["goto", "--threshold=high", "proj"])).is_err());
+        assert_eq!(parse_args(&args(&["goto", "proj"])).unwrap().threshold, None);
+
+        let result = parse_args(&args(&["goto", "--suggestions=5", "proj"])).unwrap();
+        assert_eq!(result.suggestions, Some(5));
+        assert!(parse_args(&args(&["goto", "--suggestions=-1", "proj"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_no_check() {
+        let result = parse_args(&args(&["goto", "--no-check", "nas"])).unwrap();
+        assert!(result.no_check);
+        assert!(matches!(result.command, Command::Navigate { ref alias } if alias == "nas"));
+        assert!(!parse_args(&args(&["goto", "nas"])).unwrap().no_check);
+    }
+
+    #[test]
+    fn test_parse_db_flag() {
+        let result = parse_args(&args(&["goto", "--db", "/tmp/other", "-l"])).unwrap();
+        assert_eq!(result.db.as_deref(), Some("/tmp/other"));
+        assert!(matches!(result.command, Command::List { .. }));
+
+        let result = parse_args(&args(&["goto", "proj", "--db=/tmp/other"])).unwrap();
+        assert_eq!(result.db.as_deref(), Some("/tmp/other"));
+        assert!(matches!(result.command, Command::Navigate { ref alias } if alias == "proj"));
+
+        assert!(parse_args(&args(&["goto", "proj"])).unwrap().db.is_none());
+        assert!(parse_args(&args(&["goto", "-l", "--db"])).unwrap_err().contains("Usage:"));
     }
 
     #[test]
@@ -575,187 +1752,615 @@ mod tests {
     }
 
     #[test]
-    fn test_parse_push_long() {
-        let result = parse_args(&args(&["goto", "--push", "myalias"]));
+    fn test_parse_push_long() {
+        let result = parse_args(&args(&["goto", "--push", "myalias"]));
+        assert!(result.is_ok());
+        if let Command::Push { alias } = result.unwrap().command {
+            assert_eq!(alias, "myalias");
+        } else {
+            panic!("Expected Push command");
+        }
+    }
+
+    #[test]
+    fn test_parse_push_missing_arg() {
+        let result = parse_args(&args(&["goto", "-p"]));
+        assert!(result.is_err());
+        assert!(result.unwrap_err().contains("Usage:"));
+    }
+
+    #[test]
+    fn test_parse_pop_short() {
+        let result = parse_args(&args(&["goto", "-o"]));
+        assert!(result.is_ok());
+        assert!(matches!(result.unwrap().command, Command::Pop));
+    }
+
+    #[test]
+    fn test_parse_pop_long() {
+        let result = parse_args(&args(&["goto", "--pop"]));
+        assert!(result.is_ok());
+        assert!(matches!(result.unwrap().command, Command::Pop));
+    }
+
+    #[test]
+    fn test_parse_swap() {
+        let result = parse_args(&args(&["goto", "--swap"]));
+        assert!(matches!(result.unwrap().command, Command::Swap));
+    }
+
+    #[test]
+    fn test_parse_root() {
+        let result = parse_args(&args(&["goto", "--root"])).unwrap();
+        assert!(matches!(result.command, Command::Root { register: false, name: None }));
+
+        let result = parse_args(&args(&["goto", "--root", "--register"])).unwrap();
+        assert!(matches!(result.command, Command::Root { register: true, name: None }));
+
+        let result = parse_args(&args(&["goto", "--root", "--register=shop"])).unwrap();
+        assert!(matches!(result.command, Command::Root { register: true, name: Some(ref n) } if n == "shop"));
+    }
+
+    // Tag commands tests
+    #[test]
+    fn test_parse_tag() {
+        let result = parse_args(&args(&["goto", "--tag", "proj", "work"]));
+        assert!(result.is_ok());
+        if let Command::Tag { alias, tag, force } = result.unwrap().command {
+            assert_eq!(alias, "proj");
+            assert_eq!(tag, "work");
+            assert!(!force);
+        } else {
+            panic!("Expected Tag command");
+        }
+    }
+
+    #[test]
+    fn test_parse_tag_missing_args() {
+        let result = parse_args(&args(&["goto", "--tag", "proj"]));
+        assert!(result.is_err());
+        assert!(result.unwrap_err().contains("Usage:"));
+    }
+
+    #[test]
+    fn test_parse_tag_with_force() {
+        let result = parse_args(&args(&["goto", "--tag", "proj", "work", "--force"]));
+        assert!(result.is_ok());
+        if let Command::Tag { alias, tag, force } = result.unwrap().command {
+            assert_eq!(alias, "proj");
+            assert_eq!(tag, "work");
+            assert!(force);
+        } else {
+            panic!("Expected Tag command");
+        }
+    }
+
+    #[test]
+    fn test_parse_tag_with_short_force() {
+        let result = parse_args(&args(&["goto", "--tag", "proj", "work", "-f"]));
+        assert!(result.is_ok());
+        if let Command::Tag { alias, tag, force } = result.unwrap().command {
+            assert_eq!(alias, "proj");
+            assert_eq!(tag, "work");
+            assert!(force);
+        } else {
+            panic!("Expected Tag command");
+        }
+    }
+
+    #[test]
+    fn test_parse_tag_many() {
+        let result = parse_args(&args(&["goto", "--tag", "a,b,c", "work"]));
+        if let Command::TagMany { selector, tag, filter, .. } = result.unwrap().command {
+            assert_eq!(selector, "a,b,c");
+            assert_eq!(tag, "work");
+            assert!(filter.is_none());
+        } else {
+            panic!("Expected TagMany command");
+        }
+
+        let result = parse_args(&args(&["goto", "--tag", "--filter=path:~/work", "*", "work", "-f"]));
+        if let Command::TagMany { selector, tag, force, filter } = result.unwrap().command {
+            assert_eq!(selector, "*");
+            assert_eq!(tag, "work");
+            assert!(force);
+            assert_eq!(filter, Some("path:~/work".to_string()));
+        } else {
+            panic!("Expected TagMany command");
+        }
+    }
+
+    #[test]
+    fn test_parse_tag_where() {
+        let result = parse_args(&args(&["goto", "--tag-where", "--path=~/clients/acme/**", "acme"]));
+        if let Command::TagMany { selector, tag, filter, .. } = result.unwrap().command {
+            assert_eq!(selector, "*");
+            assert_eq!(tag, "acme");
+            assert_eq!(filter, Some("path:~/clients/acme/**".to_string()));
+        } else {
+            panic!("Expected TagMany command");
+        }
+
+        assert!(parse_args(&args(&["goto", "--tag-where", "acme"])).is_err());
+        assert!(parse_args(&args(&["goto", "--tag-where", "--path=/srv"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_untag() {
+        let result = parse_args(&args(&["goto", "--untag", "proj", "work"]));
+        assert!(result.is_ok());
+        if let Command::Untag { alias, tag } = result.unwrap().command {
+            assert_eq!(alias, "proj");
+            assert_eq!(tag, "work");
+        } else {
+            panic!("Expected Untag command");
+        }
+    }
+
+    #[test]
+    fn test_parse_set_meta() {
+        let result = parse_args(&args(&["goto", "--set", "dev", "owner=antti", "ticket=ACME-12"]));
+        if let Command::SetMeta { alias, pairs } = result.unwrap().command {
+            assert_eq!(alias, "dev");
+            assert_eq!(pairs, vec!["owner=antti", "ticket=ACME-12"]);
+        } else {
+            panic!("Expected SetMeta command");
+        }
+
+        assert!(parse_args(&args(&["goto", "--set", "dev"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_untag_missing_args() {
+        let result = parse_args(&args(&["goto", "--untag", "proj"]));
+        assert!(result.is_err());
+        assert!(result.unwrap_err().contains("Usage:"));
+    }
+
+    #[test]
+    fn test_parse_tags() {
+        let result = parse_args(&args(&["goto", "--tags"]));
+        assert!(result.is_ok());
+        assert!(matches!(result.unwrap().command, Command::ListTags));
+    }
+
+    #[test]
+    fn test_parse_tags_raw() {
+        let result = parse_args(&args(&["goto", "--tags-raw"]));
+        assert!(result.is_ok());
+        assert!(matches!(result.unwrap().command, Command::ListTagsRaw));
+    }
+
+    #[test]
+    fn test_parse_complete() {
+        let result = parse_args(&args(&["goto", "--complete", "aliases"])).unwrap();
+        assert!(matches!(result.command, Command::Complete { ref kind } if kind == "aliases"));
+        let result = parse_args(&args(&["goto", "--complete"])).unwrap();
+        assert!(matches!(result.command, Command::Complete { ref kind } if kind == "aliases"));
+    }
+
+    #[test]
+    fn test_parse_complete_subpath() {
+        let result = parse_args(&args(&["goto", "--complete-subpath", "dev", "src/ap"])).unwrap();
+        assert!(matches!(result.command, Command::CompleteSubpath { ref alias, ref partial }
+            if alias == "dev" && partial == "src/ap"));
+        let result = parse_args(&args(&["goto", "--complete-subpath", "dev"])).unwrap();
+        assert!(matches!(result.command, Command::CompleteSubpath { ref partial, .. } if partial.is_empty()));
+        assert!(parse_args(&args(&["goto", "--complete-subpath"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_random() {
+        let result = parse_args(&args(&["goto", "--random"])).unwrap();
+        assert!(matches!(result.command, Command::Random { filter: None }));
+
+        let result = parse_args(&args(&["goto", "--random", "--filter=reading"])).unwrap();
+        assert!(matches!(result.command, Command::Random { filter: Some(ref f) } if f == "reading"));
+    }
+
+    #[test]
+    fn test_parse_which() {
+        let result = parse_args(&args(&["goto", "--which", "api"])).unwrap();
+        assert!(matches!(result.command, Command::Which { ref alias } if alias == "api"));
+        assert!(parse_args(&args(&["goto", "--which"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_copy_path() {
+        let result = parse_args(&args(&["goto", "--copy-path", "api"])).unwrap();
+        assert!(matches!(result.command, Command::CopyPath { ref alias } if alias == "api"));
+        assert!(parse_args(&args(&["goto", "--copy-path"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_open_edit() {
+        let result = parse_args(&args(&["goto", "--open", "notes"])).unwrap();
+        assert!(matches!(result.command, Command::Open { ref alias } if alias == "notes"));
+        let result = parse_args(&args(&["goto", "--edit", "notes"])).unwrap();
+        assert!(matches!(result.command, Command::Edit { ref alias } if alias == "notes"));
+        assert!(parse_args(&args(&["goto", "--open"])).is_err());
+        assert!(parse_args(&args(&["goto", "--edit"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_tags_of() {
+        let result = parse_args(&args(&["goto", "--tags-of", "proj"])).unwrap();
+        assert!(matches!(result.command, Command::ListTagsOf { ref alias } if alias == "proj"));
+        assert!(parse_args(&args(&["goto", "--tags-of"])).is_err());
+    }
+
+    // Stats and recent commands tests
+    #[test]
+    fn test_parse_stats() {
+        let result = parse_args(&args(&["goto", "--stats"]));
+        assert!(result.is_ok());
+        assert!(matches!(result.unwrap().command, Command::Stats));
+    }
+
+    #[test]
+    fn test_parse_recent_default() {
+        let result = parse_args(&args(&["goto", "--recent"]));
+        assert!(result.is_ok());
+        if let Command::Recent { count, navigate_to, .. } = result.unwrap().command {
+            assert_eq!(count, Some(10));
+            assert_eq!(navigate_to, None);
+        } else {
+            panic!("Expected Recent command");
+        }
+    }
+
+    #[test]
+    fn test_parse_recent_with_navigate_number() {
+        let result = parse_args(&args(&["goto", "--recent", "3"]));
+        assert!(result.is_ok());
+        if let Command::Recent { count, navigate_to, .. } = result.unwrap().command {
+            assert_eq!(count, None);
+            assert_eq!(navigate_to, Some(3));
+        } else {
+            panic!("Expected Recent command");
+        }
+    }
+
+    #[test]
+    fn test_parse_recent_with_large_count() {
+        // Numbers > 20 or with extra args should set count instead of navigate_to
+        let result = parse_args(&args(&["goto", "--recent", "50"]));
+        assert!(result.is_ok());
+        if let Command::Recent { count, navigate_to, .. } = result.unwrap().command {
+            assert_eq!(count, Some(50));
+            assert_eq!(navigate_to, None);
+        } else {
+            panic!("Expected Recent command");
+        }
+    }
+
+    #[test]
+    fn test_parse_recent_clear() {
+        let result = parse_args(&args(&["goto", "--recent-clear"]));
+        assert!(result.is_ok());
+        assert!(matches!(result.unwrap().command, Command::RecentClear));
+    }
+
+    #[test]
+    fn test_parse_recent_since() {
+        let result = parse_args(&args(&["goto", "--recent", "--since=7d"]));
+        assert!(result.is_ok());
+        if let Command::Recent { count, since, .. } = result.unwrap().command {
+            assert_eq!(count, Some(10));
+            assert!(since.is_some());
+        } else {
+            panic!("Expected Recent command");
+        }
+    }
+
+    #[test]
+    fn test_parse_recent_filter() {
+        let result = parse_args(&args(&["goto", "--recent", "--filter=work"])).unwrap();
+        if let Command::Recent { count, filter, .. } = result.command {
+            assert_eq!(count, Some(10));
+            assert_eq!(filter.as_deref(), Some("work"));
+        } else {
+            panic!("Expected Recent command");
+        }
+
+        let result = parse_args(&args(&["goto", "-R", "3", "--filter=work"])).unwrap();
+        assert!(matches!(result.command, Command::Recent { count: Some(3), navigate_to: None, .. }));
+    }
+
+    #[test]
+    fn test_parse_recent_interactive() {
+        let result = parse_args(&args(&["goto", "--recent", "-i"]));
+        assert!(result.is_ok());
+        if let Command::Recent { count, navigate_to, interactive, .. } = result.unwrap().command {
+            assert_eq!(count, Some(10));
+            assert_eq!(navigate_to, None);
+            assert!(interactive);
+        } else {
+            panic!("Expected Recent command");
+        }
+    }
+
+    #[test]
+    fn test_parse_recent_list_small_number_lists() {
+        let result = parse_args(&args(&["goto", "--recent-list", "3"]));
+        assert!(result.is_ok());
+        if let Command::Recent { count, navigate_to, .. } = result.unwrap().command {
+            assert_eq!(count, Some(3));
+            assert_eq!(navigate_to, None);
+        } else {
+            panic!("Expected Recent command");
+        }
+    }
+
+    #[test]
+    fn test_parse_recent_list_default() {
+        let result = parse_args(&args(&["goto", "--recent-list"]));
+        if let Command::Recent { count, .. } = result.unwrap().command {
+            assert_eq!(count, Some(10));
+        } else {
+            panic!("Expected Recent command");
+        }
+    }
+
+    #[test]
+    fn test_parse_recent_go_large_number_navigates() {
+        let result = parse_args(&args(&["goto", "--recent-go", "50"]));
+        assert!(result.is_ok());
+        if let Command::Recent { count, navigate_to, .. } = result.unwrap().command {
+            assert_eq!(count, None);
+            assert_eq!(navigate_to, Some(50));
+        } else {
+            panic!("Expected Recent command");
+        }
+    }
+
+    #[test]
+    fn test_parse_last() {
+        let result = parse_args(&args(&["goto", "--last"])).unwrap();
+        assert!(matches!(
+            result.command,
+            Command::Recent { count: None, navigate_to: Some(1), since: None, interactive: false, filter: None }
+        ));
+    }
+
+    #[test]
+    fn test_parse_recent_go_requires_number() {
+        assert!(parse_args(&args(&["goto", "--recent-go"])).is_err());
+        assert!(parse_args(&args(&["goto", "--recent-go", "abc"])).is_err());
+        assert!(parse_args(&args(&["goto", "--recent-list", "abc"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_recent_since_invalid() {
+        let result = parse_args(&args(&["goto", "--recent", "--since=soon"]));
+        assert!(result.is_err());
+        assert!(result.unwrap_err().contains("invalid --since"));
+    }
+
+    // Rename command tests
+    #[test]
+    fn test_parse_rename() {
+        let result = parse_args(&args(&["goto", "--rename", "old", "new"]));
         assert!(result.is_ok());
-        if let Command::Push { alias } = result.unwrap().command {
-            assert_eq!(alias, "myalias");
+        if let Command::Rename { old_name, new_name } = result.unwrap().command {
+            assert_eq!(old_name, "old");
+            assert_eq!(new_name, "new");
         } else {
-            panic!("Expected Push command");
+            panic!("Expected Rename command");
         }
     }
 
     #[test]
-    fn test_parse_push_missing_arg() {
-        let result = parse_args(&args(&["goto", "-p"]));
+    fn test_parse_rename_missing_args() {
+        let result = parse_args(&args(&["goto", "--rename", "old"]));
         assert!(result.is_err());
         assert!(result.unwrap_err().contains("Usage:"));
     }
 
     #[test]
-    fn test_parse_pop_short() {
-        let result = parse_args(&args(&["goto", "-o"]));
-        assert!(result.is_ok());
-        assert!(matches!(result.unwrap().command, Command::Pop));
+    fn test_parse_rewrite_paths() {
+        let argv = args(&["goto", "--rewrite-paths", "/old", "/new", "--dry-run"]);
+        let result = parse_args(&argv).unwrap();
+        if let Command::RewritePaths { old_prefix, new_prefix, dry_run } = result.command {
+            assert_eq!(old_prefix, "/old");
+            assert_eq!(new_prefix, "/new");
+            assert!(dry_run);
+        } else {
+            panic!("Expected RewritePaths command");
+        }
+        assert!(parse_args(&args(&["goto", "--rewrite-paths", "/old"])).is_err());
     }
 
     #[test]
-    fn test_parse_pop_long() {
-        let result = parse_args(&args(&["goto", "--pop"]));
-        assert!(result.is_ok());
-        assert!(matches!(result.unwrap().command, Command::Pop));
+    fn test_parse_copy() {
+        let result = parse_args(&args(&["goto", "--copy", "api", "api-v2"])).unwrap();
+        if let Command::Copy { source, target } = result.command {
+            assert_eq!(source, "api");
+            assert_eq!(target, "api-v2");
+        } else {
+            panic!("Expected Copy command");
+        }
+        let err = parse_args(&args(&["goto", "--copy", "api"])).unwrap_err();
+        assert!(err.contains("--copy-path"), "{}", err);
     }
 
-    // Tag commands tests
     #[test]
-    fn test_parse_tag() {
-        let result = parse_args(&args(&["goto", "--tag", "proj", "work"]));
+    fn test_parse_repath() {
+        let result = parse_args(&args(&["goto", "--repath", "proj", "/new/path"]));
         assert!(result.is_ok());
-        if let Command::Tag { alias, tag, force } = result.unwrap().command {
+        if let Command::Repath { alias, path } = result.unwrap().command {
             assert_eq!(alias, "proj");
-            assert_eq!(tag, "work");
-            assert!(!force);
+            assert_eq!(path, "/new/path");
         } else {
-            panic!("Expected Tag command");
+            panic!("Expected Repath command");
         }
     }
 
     #[test]
-    fn test_parse_tag_missing_args() {
-        let result = parse_args(&args(&["goto", "--tag", "proj"]));
+    fn test_parse_skip_check() {
+        let result = parse_args(&args(&["goto", "--skip-check", "nas"])).unwrap();
+        assert!(matches!(result.command, Command::SkipCheck { ref alias, skip: true } if alias == "nas"));
+
+        let result = parse_args(&args(&["goto", "--skip-check", "nas", "off"])).unwrap();
+        assert!(matches!(result.command, Command::SkipCheck { skip: false, .. }));
+
+        assert!(parse_args(&args(&["goto", "--skip-check", "nas", "maybe"])).is_err());
+        assert!(parse_args(&args(&["goto", "--skip-check"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_repath_missing_args() {
+        let result = parse_args(&args(&["goto", "--repath", "proj"]));
         assert!(result.is_err());
         assert!(result.unwrap_err().contains("Usage:"));
     }
 
     #[test]
-    fn test_parse_tag_with_force() {
-        let result = parse_args(&args(&["goto", "--tag", "proj", "work", "--force"]));
+    fn test_parse_reset_stats_all() {
+        let result = parse_args(&args(&["goto", "--reset-stats"]));
         assert!(result.is_ok());
-        if let Command::Tag { alias, tag, force } = result.unwrap().command {
-            assert_eq!(alias, "proj");
-            assert_eq!(tag, "work");
-            assert!(force);
+        if let Command::ResetStats { alias, last_used } = result.unwrap().command {
+            assert_eq!(alias, None);
+            assert!(!last_used);
         } else {
-            panic!("Expected Tag command");
+            panic!("Expected ResetStats command");
         }
     }
 
     #[test]
-    fn test_parse_tag_with_short_force() {
-        let result = parse_args(&args(&["goto", "--tag", "proj", "work", "-f"]));
+    fn test_parse_reset_stats_alias_with_last_used() {
+        let result = parse_args(&args(&["goto", "--reset-stats", "proj", "--last-used"]));
         assert!(result.is_ok());
-        if let Command::Tag { alias, tag, force } = result.unwrap().command {
-            assert_eq!(alias, "proj");
-            assert_eq!(tag, "work");
-            assert!(force);
+        if let Command::ResetStats { alias, last_used } = result.unwrap().command {
+            assert_eq!(alias, Some("proj".to_string()));
+            assert!(last_used);
         } else {
-            panic!("Expected Tag command");
+            panic!("Expected ResetStats command");
         }
     }
 
     #[test]
-    fn test_parse_untag() {
-        let result = parse_args(&args(&["goto", "--untag", "proj", "work"]));
+    fn test_parse_touch() {
+        let result = parse_args(&args(&["goto", "--touch", "proj"]));
         assert!(result.is_ok());
-        if let Command::Untag { alias, tag } = result.unwrap().command {
+        if let Command::Touch { alias } = result.unwrap().command {
             assert_eq!(alias, "proj");
-            assert_eq!(tag, "work");
         } else {
-            panic!("Expected Untag command");
+            panic!("Expected Touch command");
         }
     }
 
     #[test]
-    fn test_parse_untag_missing_args() {
-        let result = parse_args(&args(&["goto", "--untag", "proj"]));
-        assert!(result.is_err());
-        assert!(result.unwrap_err().contains("Usage:"));
+    fn test_parse_show() {
+        let result = parse_args(&args(&["goto", "--show", "proj"]));
+        if let Command::Show { alias } = result.unwrap().command {
+            assert_eq!(alias, "proj");
+        } else {
+            panic!("Expected Show command");
+        }
+
+        assert!(parse_args(&args(&["goto", "--show"])).is_err());
     }
 
     #[test]
-    fn test_parse_tags() {
-        let result = parse_args(&args(&["goto", "--tags"]));
-        assert!(result.is_ok());
-        assert!(matches!(result.unwrap().command, Command::ListTags));
+    fn test_parse_audit() {
+        let result = parse_args(&args(&["goto", "--audit"]));
+        assert!(matches!(result.unwrap().command, Command::Audit { unused_days: 90, json: false }));
+
+        let result = parse_args(&args(&["goto", "--audit", "--json", "--unused-days=30"]));
+        assert!(matches!(result.unwrap().command, Command::Audit { unused_days: 30, json: true }));
+        assert!(parse_args(&args(&["goto", "--audit", "--unused-days=soon"])).is_err());
     }
 
     #[test]
-    fn test_parse_tags_raw() {
-        let result = parse_args(&args(&["goto", "--tags-raw"]));
-        assert!(result.is_ok());
-        assert!(matches!(result.unwrap().command, Command::ListTagsRaw));
+    fn test_parse_digest() {
+        let result = parse_args(&args(&["goto", "--digest"]));
+        assert!(matches!(result.unwrap().command, Command::Digest { since: None }));
+
+        let result = parse_args(&args(&["goto", "--digest", "--since=2w"]));
+        if let Command::Digest { since: Some(since) } = result.unwrap().command {
+            let days = (Utc::now() - since).num_days();
+            assert_eq!(days, 14);
+        } else {
+            panic!("Expected Digest command");
+        }
+        assert!(parse_args(&args(&["goto", "--digest", "--since=soon"])).is_err());
     }
 
-    // Stats and recent commands tests
     #[test]
-    fn test_parse_stats() {
-        let result = parse_args(&args(&["goto", "--stats"]));
-        assert!(result.is_ok());
-        assert!(matches!(result.unwrap().command, Command::Stats));
+    fn test_parse_cdpath() {
+        let result = parse_args(&args(&["goto", "--cdpath"]));
+        assert!(matches!(result.unwrap().command, Command::Cdpath { filter: None }));
+
+        let result = parse_args(&args(&["goto", "--cdpath", "--filter=work"]));
+        assert!(matches!(result.unwrap().command, Command::Cdpath { filter: Some(ref f) } if f == "work"));
     }
 
     #[test]
-    fn test_parse_recent_default() {
-        let result = parse_args(&args(&["goto", "--recent"]));
-        assert!(result.is_ok());
-        if let Command::Recent { count, navigate_to } = result.unwrap().command {
-            assert_eq!(count, Some(10));
-            assert_eq!(navigate_to, None);
-        } else {
-            panic!("Expected Recent command");
-        }
+    fn test_parse_env_file() {
+        let result = parse_args(&args(&["goto", "--env-file"]));
+        assert!(matches!(result.unwrap().command, Command::EnvFile { filter: None, make: false }));
+
+        let result = parse_args(&args(&["goto", "--env-file", "--format=make", "--filter=work"]));
+        assert!(matches!(result.unwrap().command, Command::EnvFile { filter: Some(ref f), make: true } if f == "work"));
+        assert!(parse_args(&args(&["goto", "--env-file", "--format=json"])).is_err());
     }
 
     #[test]
-    fn test_parse_recent_with_navigate_number() {
-        let result = parse_args(&args(&["goto", "--recent", "3"]));
-        assert!(result.is_ok());
-        if let Command::Recent { count, navigate_to } = result.unwrap().command {
-            assert_eq!(count, None);
-            assert_eq!(navigate_to, Some(3));
-        } else {
-            panic!("Expected Recent command");
-        }
+    fn test_parse_gc() {
+        let result = parse_args(&args(&["goto", "--gc"]));
+        assert!(matches!(result.unwrap().command, Command::Gc { keep_days: 30, dry_run: false }));
+
+        let result = parse_args(&args(&["goto", "--gc", "--keep-days=7", "--dry-run"]));
+        assert!(matches!(result.unwrap().command, Command::Gc { keep_days: 7, dry_run: true }));
+        assert!(parse_args(&args(&["goto", "--gc", "--keep-days=week"])).is_err());
     }
 
     #[test]
-    fn test_parse_recent_with_large_count() {
-        // Numbers > 20 or with extra args should set count instead of navigate_to
-        let result = parse_args(&args(&["goto", "--recent", "50"]));
-        assert!(result.is_ok());
-        if let Command::Recent { count, navigate_to } = result.unwrap().command {
-            assert_eq!(count, Some(50));
-            assert_eq!(navigate_to, None);
-        } else {
-            panic!("Expected Recent command");
-        }
+    fn test_parse_fsck() {
+        let result = parse_args(&args(&["goto", "--fsck"]));
+        assert!(matches!(result.unwrap().command, Command::Fsck { fix: false }));
+        let result = parse_args(&args(&["goto", "--fsck", "--fix"]));
+        assert!(matches!(result.unwrap().command, Command::Fsck { fix: true }));
     }
 
     #[test]
-    fn test_parse_recent_clear() {
-        let result = parse_args(&args(&["goto", "--recent-clear"]));
-        assert!(result.is_ok());
-        assert!(matches!(result.unwrap().command, Command::RecentClear));
+    fn test_parse_maintain() {
+        let result = parse_args(&args(&["goto", "--maintain"]));
+        assert!(matches!(result.unwrap().command, Command::Maintain { interval: None, keep_days: 30 }));
+
+        let result = parse_args(&args(&["goto", "--maintain", "--interval=1h", "--keep-days=7"]));
+        if let Command::Maintain { interval: Some(interval), keep_days } = result.unwrap().command {
+            assert_eq!(interval, Duration::hours(1));
+            assert_eq!(keep_days, 7);
+        } else {
+            panic!("Expected Maintain command");
+        }
+        assert!(parse_args(&args(&["goto", "--maintain", "--interval=0h"])).is_err());
+        assert!(parse_args(&args(&["goto", "--maintain", "--interval=hourly"])).is_err());
     }
 
-    // Rename command tests
     #[test]
-    fn test_parse_rename() {
-        let result = parse_args(&args(&["goto", "--rename", "old", "new"]));
-        assert!(result.is_ok());
-        if let Command::Rename { old_name, new_name } = result.unwrap().command {
-            assert_eq!(old_name, "old");
-            assert_eq!(new_name, "new");
+    fn test_parse_tree() {
+        let result = parse_args(&args(&["goto", "--tree", "proj"]));
+        if let Command::Tree { alias, depth } = result.unwrap().command {
+            assert_eq!(alias, "proj");
+            assert_eq!(depth, 2);
         } else {
-            panic!("Expected Rename command");
+            panic!("Expected Tree command");
         }
+
+        let result = parse_args(&args(&["goto", "--tree", "proj", "--depth=4"]));
+        assert!(matches!(result.unwrap().command, Command::Tree { depth: 4, .. }));
+        assert!(parse_args(&args(&["goto", "--tree"])).is_err());
+        assert!(parse_args(&args(&["goto", "--tree", "--depth=3"])).is_err());
+        assert!(parse_args(&args(&["goto", "--tree", "proj", "--depth=x"])).is_err());
     }
 
     #[test]
-    fn test_parse_rename_missing_args() {
-        let result = parse_args(&args(&["goto", "--rename", "old"]));
+    fn test_parse_touch_missing_alias() {
+        let result = parse_args(&args(&["goto", "--touch"]));
         assert!(result.is_err());
-        assert!(result.unwrap_err().contains("Usage:"));
     }
 
     // Config command tests
@@ -767,6 +2372,67 @@ mod tests {
     }
 
     // Install command tests
+    #[test]
+    fn test_parse_setup() {
+        let result = parse_args(&args(&["goto", "--setup"]));
+        assert!(matches!(result.unwrap().command, Command::Setup));
+    }
+
+    #[test]
+    fn test_parse_context() {
+        let result = parse_args(&args(&["goto", "--context"]));
+        assert!(matches!(result.unwrap().command, Command::ContextShow));
+
+        let result = parse_args(&args(&["goto", "--context", "use", "work"]));
+        assert!(matches!(result.unwrap().command, Command::ContextUse { ref name } if name == "work"));
+
+        let result = parse_args(&args(&["goto", "--context", "clear"]));
+        assert!(matches!(result.unwrap().command, Command::ContextClear));
+
+        let result = parse_args(&args(&["goto", "--context", "list"]));
+        assert!(matches!(result.unwrap().command, Command::ContextList));
+
+        assert!(parse_args(&args(&["goto", "--context", "use"])).is_err());
+        assert!(parse_args(&args(&["goto", "--context", "bogus"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_session() {
+        let result = parse_args(&args(&["goto", "--session", "save", "work", "api", "~/notes"]));
+        if let Command::SessionSave { name, dirs } = result.unwrap().command {
+            assert_eq!(name, "work");
+            assert_eq!(dirs, vec!["api", "~/notes"]);
+        } else {
+            panic!("Expected SessionSave command");
+        }
+
+        let result = parse_args(&args(&["goto", "--session", "restore", "work", "--tmux"]));
+        assert!(matches!(result.unwrap().command, Command::SessionRestore { ref name, tmux: true } if name == "work"));
+
+        let result = parse_args(&args(&["goto", "--session"]));
+        assert!(matches!(result.unwrap().command, Command::SessionList));
+        let result = parse_args(&args(&["goto", "--session", "delete", "work"]));
+        assert!(matches!(result.unwrap().command, Command::SessionDelete { ref name } if name == "work"));
+
+        assert!(parse_args(&args(&["goto", "--session", "restore"])).is_err());
+        assert!(parse_args(&args(&["goto", "--session", "resume", "work"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_suggest_and_record_dir() {
+        let result = parse_args(&args(&["goto", "--suggest"]));
+        assert!(matches!(result.unwrap().command, Command::Suggest));
+
+        let result = parse_args(&args(&["goto", "--record-dir", "/srv/app"]));
+        if let Command::RecordDir { dir } = result.unwrap().command {
+            assert_eq!(dir, "/srv/app");
+        } else {
+            panic!("Expected RecordDir command");
+        }
+
+        assert!(parse_args(&args(&["goto", "--record-dir"])).is_err());
+    }
+
     #[test]
     fn test_parse_install_default() {
         let result = parse_args(&args(&["goto", "--install"]));
@@ -837,7 +2503,7 @@ mod tests {
     fn test_parse_import() {
         let result = parse_args(&args(&["goto", "--import", "backup.toml"]));
         assert!(result.is_ok());
-        if let Command::Import { file, strategy } = result.unwrap().command {
+        if let Command::Import { file, strategy, .. } = result.unwrap().command {
             assert_eq!(file, "backup.toml");
             assert!(matches!(strategy, ImportStrategy::Skip));
         } else {
@@ -849,7 +2515,7 @@ mod tests {
     fn test_parse_import_with_strategy_overwrite() {
         let result = parse_args(&args(&["goto", "--import", "backup.toml", "--strategy=overwrite"]));
         assert!(result.is_ok());
-        if let Command::Import { file, strategy } = result.unwrap().command {
+        if let Command::Import { file, strategy, .. } = result.unwrap().command {
             assert_eq!(file, "backup.toml");
             assert!(matches!(strategy, ImportStrategy::Overwrite));
         } else {
@@ -861,7 +2527,7 @@ mod tests {
     fn test_parse_import_with_strategy_rename() {
         let result = parse_args(&args(&["goto", "--import", "backup.toml", "--strategy=rename"]));
         assert!(result.is_ok());
-        if let Command::Import { file, strategy } = result.unwrap().command {
+        if let Command::Import { file, strategy, .. } = result.unwrap().command {
             assert_eq!(file, "backup.toml");
             assert!(matches!(strategy, ImportStrategy::Rename));
         } else {
@@ -869,6 +2535,24 @@ mod tests {
         }
     }
 
+    #[test]
+    fn test_parse_import_url() {
+        let result = parse_args(&args(&[
+            "goto",
+            "--import",
+            "https://example.com/team.json?raw=1",
+            "--sha256=abc123",
+        ]))
+        .unwrap();
+        if let Command::Import { file, format, sha256, .. } = result.command {
+            assert_eq!(file, "https://example.com/team.json?raw=1");
+            assert_eq!(format, ExportFormat::Json);
+            assert_eq!(sha256.as_deref(), Some("abc123"));
+        } else {
+            panic!("Expected Import command");
+        }
+    }
+
     #[test]
     fn test_parse_import_missing_file() {
         let result = parse_args(&args(&["goto", "--import"]));
@@ -876,6 +2560,105 @@ mod tests {
         assert!(result.unwrap_err().contains("Usage:"));
     }
 
+    #[test]
+    fn test_parse_export_with_format() {
+        let result = parse_args(&args(&["goto", "--export", "--format=json"]));
+        assert!(result.is_ok());
+        if let Command::Export { format, .. } = result.unwrap().command {
+            assert_eq!(format, ExportFormat::Json);
+        } else {
+            panic!("Expected Export command");
+        }
+    }
+
+    #[test]
+    fn test_parse_export_to_file() {
+        let result = parse_args(&args(&["goto", "--export", "backup.csv"]));
+        assert!(result.is_ok());
+        if let Command::Export { format, output, .. } = result.unwrap().command {
+            assert_eq!(format, ExportFormat::Csv);
+            assert_eq!(output, Some("backup.csv".to_string()));
+        } else {
+            panic!("Expected Export command");
+        }
+    }
+
+    #[test]
+    fn test_parse_export_file_after_flags() {
+        let result = parse_args(&args(&["goto", "--export", "--format=json", "out.json"]));
+        if let Command::Export { format, output, .. } = result.unwrap().command {
+            assert_eq!(format, ExportFormat::Json);
+            assert_eq!(output, Some("out.json".to_string()));
+        } else {
+            panic!("Expected Export command");
+        }
+    }
+
+    #[test]
+    fn test_parse_export_portable() {
+        let result = parse_args(&args(&["goto", "--export", "--portable"]));
+        assert!(result.is_ok());
+        if let Command::Export { output, portable, .. } = result.unwrap().command {
+            assert_eq!(output, None);
+            assert!(portable);
+        } else {
+            panic!("Expected Export command");
+        }
+    }
+
+    #[test]
+    fn test_parse_export_to_stdout() {
+        let result = parse_args(&args(&["goto", "-e", "--format=json"]));
+        assert!(result.is_ok());
+        if let Command::Export { format, output, .. } = result.unwrap().command {
+            assert_eq!(format, ExportFormat::Json);
+            assert_eq!(output, None);
+        } else {
+            panic!("Expected Export command");
+        }
+    }
+
+    #[test]
+    fn test_parse_import_stdin() {
+        let result = parse_args(&args(&["goto", "--import", "-"]));
+        assert!(result.is_ok());
+        if let Command::Import { file, format, .. } = result.unwrap().command {
+            assert_eq!(file, "-");
+            assert_eq!(format, ExportFormat::Toml);
+        } else {
+            panic!("Expected Import command");
+        }
+    }
+
+    #[test]
+    fn test_parse_export_invalid_format() {
+        let result = parse_args(&args(&["goto", "--export", "--format=yaml"]));
+        assert!(result.is_err());
+        assert!(result.unwrap_err().contains("invalid format"));
+    }
+
+    #[test]
+    fn test_parse_import_format_from_extension() {
+        let result = parse_args(&args(&["goto", "--import", "backup.csv"]));
+        assert!(result.is_ok());
+        if let Command::Import { format, .. } = result.unwrap().command {
+            assert_eq!(format, ExportFormat::Csv);
+        } else {
+            panic!("Expected Import command");
+        }
+    }
+
+    #[test]
+    fn test_parse_import_format_flag_overrides_extension() {
+        let result = parse_args(&args(&["goto", "--import", "backup.csv", "--format=json"]));
+        assert!(result.is_ok());
+        if let Command::Import { format, .. } = result.unwrap().command {
+            assert_eq!(format, ExportFormat::Json);
+        } else {
+            panic!("Expected Import command");
+        }
+    }
+
     // Unregister and expand tests
     #[test]
     fn test_parse_unregister_short() {
@@ -899,6 +2682,33 @@ mod tests {
         }
     }
 
+    #[test]
+    fn test_parse_unregister_glob() {
+        let result = parse_args(&args(&["goto", "-u", "client-*", "--dry-run"]));
+        if let Command::UnregisterMany { selector, filter, dry_run } = result.unwrap().command {
+            assert_eq!(selector, "client-*");
+            assert_eq!(filter, None);
+            assert!(dry_run);
+        } else {
+            panic!("Expected UnregisterMany command");
+        }
+
+        let result = parse_args(&args(&["goto", "-u", "a,b"]));
+        assert!(matches!(result.unwrap().command, Command::UnregisterMany { dry_run: false, .. }));
+
+        let result = parse_args(&args(&["goto", "-u", "--tag=archived"]));
+        if let Command::UnregisterMany { selector, filter, .. } = result.unwrap().command {
+            assert_eq!(selector, "*");
+            assert_eq!(filter.as_deref(), Some("tag:archived"));
+        } else {
+            panic!("Expected UnregisterMany command");
+        }
+        assert!(parse_args(&args(&["goto", "-u"])).is_err());
+
+        let result = parse_args(&args(&["goto", "-u", "-i"]));
+        assert!(matches!(result.unwrap().command, Command::UnregisterInteractive));
+    }
+
     #[test]
     fn test_parse_unregister_missing_arg() {
         let result = parse_args(&args(&["goto", "-u"]));
@@ -910,7 +2720,7 @@ mod tests {
     fn test_parse_expand_short() {
         let result = parse_args(&args(&["goto", "-x", "proj"]));
         assert!(result.is_ok());
-        if let Command::Expand { alias } = result.unwrap().command {
+        if let Command::Expand { alias, .. } = result.unwrap().command {
             assert_eq!(alias, "proj");
         } else {
             panic!("Expected Expand command");
@@ -921,13 +2731,25 @@ mod tests {
     fn test_parse_expand_long() {
         let result = parse_args(&args(&["goto", "--expand", "proj"]));
         assert!(result.is_ok());
-        if let Command::Expand { alias } = result.unwrap().command {
+        if let Command::Expand { alias, .. } = result.unwrap().command {
             assert_eq!(alias, "proj");
         } else {
             panic!("Expected Expand command");
         }
     }
 
+    #[test]
+    fn test_parse_expand_uri() {
+        let result = parse_args(&args(&["goto", "-x", "--format=uri", "proj"])).unwrap();
+        assert!(matches!(result.command, Command::Expand { ref alias, uri: true } if alias == "proj"));
+
+        let result = parse_args(&args(&["goto", "-x", "proj", "--format=path"])).unwrap();
+        assert!(matches!(result.command, Command::Expand { ref alias, uri: false } if alias == "proj"));
+
+        assert!(parse_args(&args(&["goto", "-x", "--format=json", "proj"])).is_err());
+        assert!(parse_args(&args(&["goto", "-x", "--format=uri"])).is_err());
+    }
+
     #[test]
     fn test_parse_expand_missing_arg() {
         let result = parse_args(&args(&["goto", "-x"]));
@@ -940,7 +2762,7 @@ mod tests {
     fn test_parse_export() {
         let result = parse_args(&args(&["goto", "--export"]));
         assert!(result.is_ok());
-        assert!(matches!(result.unwrap().command, Command::Export));
+        assert!(matches!(result.unwrap().command, Command::Export { .. }));
     }
 
     // List names test
@@ -980,6 +2802,12 @@ mod tests {
         assert!(matches!(result.unwrap().command, Command::CheckUpdate));
     }
 
+    #[test]
+    fn test_parse_generate_man() {
+        let result = parse_args(&args(&["goto", "--generate-man"]));
+        assert!(matches!(result.unwrap().command, Command::GenerateMan));
+    }
+
     // Short flag tests
     #[test]
     fn test_parse_stats_short() {
@@ -999,7 +2827,7 @@ mod tests {
     fn test_parse_recent_short() {
         let result = parse_args(&args(&["goto", "-R"]));
         assert!(result.is_ok());
-        if let Command::Recent { count, navigate_to } = result.unwrap().command {
+        if let Command::Recent { count, navigate_to, .. } = result.unwrap().command {
             assert_eq!(count, Some(10));
             assert_eq!(navigate_to, None);
         } else {
@@ -1011,7 +2839,7 @@ mod tests {
     fn test_parse_recent_short_with_number() {
         let result = parse_args(&args(&["goto", "-R", "5"]));
         assert!(result.is_ok());
-        if let Command::Recent { count, navigate_to } = result.unwrap().command {
+        if let Command::Recent { count, navigate_to, .. } = result.unwrap().command {
             assert_eq!(count, None);
             assert_eq!(navigate_to, Some(5));
         } else {
@@ -1023,14 +2851,14 @@ mod tests {
     fn test_parse_export_short() {
         let result = parse_args(&args(&["goto", "-e"]));
         assert!(result.is_ok());
-        assert!(matches!(result.unwrap().command, Command::Export));
+        assert!(matches!(result.unwrap().command, Command::Export { .. }));
     }
 
     #[test]
     fn test_parse_import_short() {
         let result = parse_args(&args(&["goto", "-i", "backup.toml"]));
         assert!(result.is_ok());
-        if let Command::Import { file, strategy } = result.unwrap().command {
+        if let Command::Import { file, strategy, .. } = result.unwrap().command {
             assert_eq!(file, "backup.toml");
             assert!(matches!(strategy, ImportStrategy::Skip));
         } else {
@@ -1042,7 +2870,7 @@ mod tests {
     fn test_parse_import_short_with_strategy() {
         let result = parse_args(&args(&["goto", "-i", "backup.toml", "--strategy=overwrite"]));
         assert!(result.is_ok());
-        if let Command::Import { file, strategy } = result.unwrap().command {
+        if let Command::Import { file, strategy, .. } = result.unwrap().command {
             assert_eq!(file, "backup.toml");
             assert!(matches!(strategy, ImportStrategy::Overwrite));
         } else {
@@ -1061,7 +2889,7 @@ mod tests {
     fn test_parse_register_with_short_tags() {
         let result = parse_args(&args(&["goto", "-r", "dev", "/path", "-t", "work,rust"]));
         assert!(result.is_ok());
-        if let Command::Register { name, path, tags, force } = result.unwrap().command {
+        if let Command::Register { name, path, tags, force, .. } = result.unwrap().command {
             assert_eq!(name, "dev");
             assert_eq!(path, "/path");
             assert_eq!(tags, vec!["work", "rust"]);
@@ -1075,7 +2903,7 @@ mod tests {
     fn test_parse_register_with_short_tags_and_force() {
         let result = parse_args(&args(&["goto", "-r", "dev", "/path", "-t", "work", "-f"]));
         assert!(result.is_ok());
-        if let Command::Register { name, path, tags, force } = result.unwrap().command {
+        if let Command::Register { name, path, tags, force, .. } = result.unwrap().command {
             assert_eq!(name, "dev");
             assert_eq!(path, "/path");
             assert_eq!(tags, vec!["work"]);
@@ -1085,6 +2913,16 @@ mod tests {
         }
     }
 
+    #[test]
+    fn test_parse_retag_auto() {
+        let result = parse_args(&args(&["goto", "--retag-auto", "--dry-run"]));
+        if let Command::RetagAuto { dry_run } = result.unwrap().command {
+            assert!(dry_run);
+        } else {
+            panic!("Expected RetagAuto command");
+        }
+    }
+
     // RenameTag command tests
     #[test]
     fn test_parse_rename_tag() {
@@ -1175,6 +3013,27 @@ mod tests {
         }
     }
 
+    #[test]
+    fn test_parse_serve() {
+        let result = parse_args(&args(&["goto", "--serve"])).unwrap();
+        assert!(matches!(result.command, Command::Serve { ref listen } if listen == "127.0.0.1:7777"));
+
+        let result = parse_args(&args(&["goto", "--serve", "--listen=0.0.0.0:9000"])).unwrap();
+        assert!(matches!(result.command, Command::Serve { ref listen } if listen == "0.0.0.0:9000"));
+    }
+
+    #[test]
+    fn test_parse_restore_backup() {
+        let result = parse_args(&args(&["goto", "--restore-backup"]));
+        assert!(matches!(result.unwrap().command, Command::RestoreBackup { index: None }));
+
+        let result = parse_args(&args(&["goto", "--restore-backup", "2"]));
+        assert!(matches!(result.unwrap().command, Command::RestoreBackup { index: Some(2) }));
+
+        assert!(parse_args(&args(&["goto", "--restore-backup", "0"])).is_err());
+        assert!(parse_args(&args(&["goto", "--restore-backup", "x"])).is_err());
+    }
+
     #[test]
     fn test_parse_prune_snooze_missing_days() {
         let result = parse_args(&args(&["goto", "--prune-snooze"]));
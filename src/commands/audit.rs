@@ -0,0 +1,300 @@
+//! Audit command: a scored health report over all aliases
+//!
+//! Each alias starts at 100 and loses points for every problem found: a
+//! missing directory, an expiry that has passed, a path shared with another
+//! alias, a path nested under another alias, no use in a long time, or no tags.
+
+use chrono::{DateTime, Duration, Utc};
+use serde::Serialize;
+use std::collections::HashMap;
+use std::path::Path;
+
+use crate::alias::Alias;
+use crate::commands::register::format_expiry;
+use crate::config::Config;
+use crate::database::Database;
+use crate::table::{create_table, TableStyle};
+
+/// Days without use before an alias counts as unused, unless `--unused-days=` says otherwise
+pub const DEFAULT_UNUSED_DAYS: usize = 90;
+
+/// The checks run on every alias
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "lowercase")]
+pub enum Check {
+    /// The directory doesn't exist
+    Missing,
+    /// A temporary alias past its expiry
+    Expired,
+    /// Another alias points to the same directory
+    Duplicate,
+    /// The directory is inside another alias's directory
+    Nested,
+    /// Not used for the configured number of days
+    Unused,
+    /// No tags
+    Untagged,
+}
+
+impl Check {
+    /// Points taken off the alias's score
+    pub fn penalty(self) -> u32 {
+        match self {
+            Check::Missing => 50,
+            Check::Expired => 50,
+            Check::Duplicate => 20,
+            Check::Unused => 15,
+            Check::Nested => 10,
+            Check::Untagged => 5,
+        }
+    }
+}
+
+/// One problem found with an alias
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct Issue {
+    pub check: Check,
+    pub detail: String,
+}
+
+/// The audit result for one alias
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct AliasAudit {
+    pub name: String,
+    pub path: String,
+    pub score: u32,
+    pub issues: Vec<Issue>,
+}
+
+/// The whole report: per-alias results (worst first) and their average score
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct AuditReport {
+    pub score: u32,
+    pub aliases: Vec<AliasAudit>,
+}
+
+/// Print the audit report as a table, or as JSON with `json`
+pub fn audit(
+    db: &Database,
+    config: &Config,
+    unused_days: usize,
+    json: bool,
+) -> Result<(), Box<dyn std::error::Error>> {
+    let aliases: Vec<&Alias> = db.all().filter(|a| !db.is_system(&a.name)).collect();
+    let report = build_report(&aliases, unused_days, Utc::now());
+
+    if json {
+        println!("{}", serde_json::to_string_pretty(&report)?);
+        return Ok(());
+    }
+
+    if report.aliases.is_empty() {
+        eprintln!("No aliases registered");
+        return Ok(());
+    }
+
+    let flagged: Vec<&AliasAudit> = report.aliases.iter().filter(|a| !a.issues.is_empty()).collect();
+    println!(
+        "Audit score: {}/100 ({} aliases, {} with issues)",
+        report.score,
+        report.aliases.len(),
+        flagged.len()
+    );
+    if flagged.is_empty() {
+        return Ok(());
+    }
+
+    let style = TableStyle::from(config.user.display.table_style.as_str());
+    let mut table = create_table(style);
+    table.set_header(vec!["Name", "Score", "Issues"]);
+    for entry in flagged {
+        let issues: Vec<&str> = entry.issues.iter().map(|i| i.detail.as_str()).collect();
+        table.add_row(vec![entry.name.clone(), entry.score.to_string(), issues.join("; ")]);
+    }
+    println!("{table}");
+    Ok(())
+}
+
+/// Run every check on `aliases` as of `now`
+pub fn build_report(aliases: &[&Alias], unused_days: usize, now: DateTime<Utc>) -> AuditReport {
+    let paths: Vec<String> = aliases.iter().map(|a| a.resolved_path()).collect();
+    let mut by_path: HashMap<&str, Vec<&str>> = HashMap::new();
+    for (alias, path) in aliases.iter().zip(&paths) {
+        by_path.entry(path.as_str()).or_default().push(alias.name.as_str());
+    }
+    let unused_after = Duration::days(unused_days as i64);
+
+    let mut results: Vec<AliasAudit> = aliases
+        .iter()
+        .zip(&paths)
+        .map(|(alias, path)| {
+            let mut issues = Vec::new();
+
+            if !alias.skip_check && !Path::new(path).exists() {
+                issues.push(Issue { check: Check::Missing, detail: "directory does not exist".to_string() });
+            }
+            if let Some(at) = alias.expires_at.filter(|_| alias.is_expired(now)) {
+                issues.push(Issue {
+                    check: Check::Expired,
+                    detail: format!("expired on {}; remove with 'goto -c'", format_expiry(at)),
+                });
+            }
+
+            let mut twins: Vec<&str> =
+                by_path[path.as_str()].iter().copied().filter(|n| *n != alias.name).collect();
+            if !twins.is_empty() {
+                twins.sort_unstable();
+                issues.push(Issue {
+                    check: Check::Duplicate,
+                    detail: format!("same path as {}", twins.join(", ")),
+                });
+            }
+
+            // The closest enclosing alias is the one with the longest path
+            let parent = aliases
+                .iter()
+                .zip(&paths)
+                .filter(|(_, other)| *other != path && Path::new(path).starts_with(other.as_str()))
+                .max_by(|(a, a_path), (b, b_path)| {
+                    a_path.len().cmp(&b_path.len()).then_with(|| b.name.cmp(&a.name))
+                });
+            if let Some((other, other_path)) = parent {
+                issues.push(Issue {
+                    check: Check::Nested,
+                    detail: format!("inside '{}' ({})", other.name, other_path),
+                });
+            }
+
+            match alias.last_used {
+                Some(used) if now - used >= unused_after => issues.push(Issue {
+                    check: Check::Unused,
+                    detail: format!("not used for {} days", (now - used).num_days()),
+                }),
+                None if now - alias.created_at >= unused_after => issues.push(Issue {
+                    check: Check::Unused,
+                    detail: format!("never used in {} days", (now - alias.created_at).num_days()),
+                }),
+                _ => {}
+            }
+
+            if alias.tags.is_empty() {
+                issues.push(Issue { check: Check::Untagged, detail: "no tags".to_string() });
+            }
+
+            let penalty: u32 = issues.iter().map(|i| i.check.penalty()).sum();
+            AliasAudit {
+                name: alias.name.clone(),
+                path: path.clone(),
+                score: 100u32.saturating_sub(penalty),
+                issues,
+            }
+        })
+        .collect();
+
+    results.sort_by(|a, b| a.score.cmp(&b.score).then_with(|| a.name.cmp(&b.name)));
+    let score = if results.is_empty() {
+        100
+    } else {
+        let total: u32 = results.iter().map(|a| a.score).sum();
+        (total as f64 / results.len() as f64).round() as u32
+    };
+    AuditReport { score, aliases: results }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    fn alias(name: &str, path: &str, tags: &[&str], days_since_use: Option<i64>, now: DateTime<Utc>) -> Alias {
+        let mut alias = Alias::new(name, path).unwrap();
+        alias.tags = tags.iter().map(|t| t.to_string()).collect();
+        alias.created_at = now - Duration::days(365);
+        alias.last_used = days_since_use.map(|d| now - Duration::days(d));
+        alias
+    }
+
+    fn checks(report: &AuditReport, name: &str) -> Vec<Check> {
+        let entry = report.aliases.iter().find(|a| a.name == name).unwrap();
+        entry.issues.iter().map(|i| i.check).collect()
+    }
+
+    #[test]
+    fn test_build_report() {
+        let dir = tempdir().unwrap();
+        let root = dir.path().to_string_lossy().to_string();
+        let sub = dir.path().join("sub");
+        std::fs::create_dir(&sub).unwrap();
+        let sub = sub.to_string_lossy().to_string();
+        let now = Utc::now();
+
+        let aliases = [
+            alias("root", &root, &["work"], Some(1), now),
+            alias("sub", &sub, &["work"], Some(1), now),
+            alias("sub2", &sub, &["work"], Some(1), now),
+            alias("gone", "/nonexistent/path/12345", &[], None, now),
+            alias("old", &root, &["work"], Some(200), now),
+        ];
+        let refs: Vec<&Alias> = aliases.iter().collect();
+        let report = build_report(&refs, 90, now);
+
+        assert_eq!(checks(&report, "sub"), vec![Check::Duplicate, Check::Nested]);
+        assert_eq!(checks(&report, "root"), vec![Check::Duplicate]);
+        assert_eq!(checks(&report, "gone"), vec![Check::Missing, Check::Unused, Check::Untagged]);
+        assert_eq!(checks(&report, "old"), vec![Check::Duplicate, Check::Unused]);
+
+        // Worst first
+        assert_eq!(report.aliases[0].name, "gone");
+        assert_eq!(report.aliases[0].score, 30);
+        let sub = report.aliases.iter().find(|a| a.name == "sub").unwrap();
+        assert_eq!(sub.score, 70);
+        assert!(sub.issues[0].detail.contains("sub2"));
+        // Of two aliases for the same enclosing directory, the first by name is named
+        assert!(sub.issues[1].detail.contains("'old'"));
+    }
+
+    #[test]
+    fn test_unused_threshold_and_clean_score() {
+        let dir = tempdir().unwrap();
+        let path = dir.path().to_string_lossy().to_string();
+        let now = Utc::now();
+
+        let mut fresh = alias("fresh", &path, &["x"], None, now);
+        fresh.created_at = now - Duration::days(3);
+        let refs = [&fresh];
+        let report = build_report(&refs, 90, now);
+        assert!(report.aliases[0].issues.is_empty());
+        assert_eq!(report.score, 100);
+
+        let stale = alias("stale", &path, &["x"], Some(10), now);
+        assert_eq!(checks(&build_report(&[&stale], 7, now), "stale"), vec![Check::Unused]);
+        assert!(checks(&build_report(&[&stale], 30, now), "stale").is_empty());
+        assert_eq!(build_report(&[], 90, now).score, 100);
+    }
+
+    #[test]
+    fn test_expired() {
+        let dir = tempdir().unwrap();
+        let path = dir.path().to_string_lossy().to_string();
+        let now = Utc::now();
+
+        let mut review = alias("review", &path, &["x"], Some(1), now);
+        review.expires_at = Some(now - Duration::days(2));
+        let mut spike = alias("spike", &path, &["x"], Some(1), now);
+        spike.expires_at = Some(now + Duration::days(2));
+        let report = build_report(&[&review, &spike], 90, now);
+        assert_eq!(checks(&report, "review"), vec![Check::Expired, Check::Duplicate]);
+        assert_eq!(checks(&report, "spike"), vec![Check::Duplicate]);
+        assert!(report.aliases[0].issues[0].detail.starts_with("expired on "));
+    }
+
+    #[test]
+    fn test_report_json_shape() {
+        let now = Utc::now();
+        let gone = alias("gone", "/nonexistent/path/12345", &["x"], Some(1), now);
+        let json = serde_json::to_value(build_report(&[&gone], 90, now)).unwrap();
+        assert_eq!(json["score"], 50);
+        assert_eq!(json["aliases"][0]["name"], "gone");
+        assert_eq!(json["aliases"][0]["issues"][0]["check"], "missing");
+    }
+}
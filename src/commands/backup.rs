@@ -0,0 +1,168 @@
+//! Backup commands: list and restore the rotating database backups
+
+use std::error::Error;
+use std::fs;
+use std::io::{self, IsTerminal};
+use std::path::PathBuf;
+
+use chrono::{DateTime, Local};
+
+use crate::alias::AliasError;
+use crate::config::Config;
+use crate::database::{backup_path, count_entries};
+use crate::prompt_selection;
+
+/// Upper bound when scanning for backups, independent of the configured count
+const MAX_SCAN: usize = 100;
+
+/// One backup file next to the database
+#[derive(Debug)]
+pub struct BackupInfo {
+    /// 1 is the newest backup
+    pub index: usize,
+    pub path: PathBuf,
+    pub modified: Option<DateTime<Local>>,
+    /// Number of aliases, or why the file could not be read
+    pub entries: Result<usize, String>,
+}
+
+impl BackupInfo {
+    /// One-line description used in listings
+    pub fn describe(&self) -> String {
+        let modified = self
+            .modified
+            .map(|t| t.format("%Y-%m-%d %H:%M").to_string())
+            .unwrap_or_else(|| "unknown time".to_string());
+        let entries = match &self.entries {
+            Ok(1) => "1 alias".to_string(),
+            Ok(n) => format!("{} aliases", n),
+            Err(e) => format!("unreadable: {}", e),
+        };
+        format!("{}  {}  {}", self.path.display(), modified, entries)
+    }
+}
+
+/// Backups of the alias database, newest first
+pub fn list_backups(config: &Config) -> Vec<BackupInfo> {
+    (1..=MAX_SCAN)
+        .map(|n| (n, backup_path(&config.aliases_path, n)))
+        .take_while(|(_, path)| path.exists())
+        .map(|(index, path)| BackupInfo {
+            index,
+            modified: fs::metadata(&path)
+                .and_then(|m| m.modified())
+                .ok()
+                .map(DateTime::<Local>::from),
+            entries: count_entries(&path).map_err(|e| e.to_string()),
+            path,
+        })
+        .collect()
+}
+
+/// Restore backup `index`, or list the backups and pick one interactively
+///
+/// The file being replaced is kept as `aliases.toml.before-restore`.
+pub fn restore_backup(config: &Config, index: Option<usize>) -> Result<(), Box<dyn Error>> {
+    let backups = list_backups(config);
+    if backups.is_empty() {
+        return Err(format!("no backups found next to {}", config.aliases_path.display()).into());
+    }
+
+    let index = match index {
+        Some(index) => index,
+        None if io::stdin().is_terminal() => {
+            eprintln!("Available backups (newest first):");
+            let labels: Vec<String> = backups.iter().map(BackupInfo::describe).collect();
+            let label_refs: Vec<&str> = labels.iter().map(String::as_str).collect();
+            match prompt_selection(&label_refs, None)? {
+                Some(i) => backups[i].index,
+                None => return Err(AliasError::Cancelled("restore".to_string()).into()),
+            }
+        }
+        None => {
+            for backup in &backups {
+                println!("{}  {}", backup.index, backup.describe());
+            }
+            println!("Run 'goto --restore-backup <N>' to restore one.");
+            return Ok(());
+        }
+    };
+
+    let backup = backups
+        .iter()
+        .find(|b| b.index == index)
+        .ok_or_else(|| AliasError::Missing(format!("backup {}", index)))?;
+    let entries = backup
+        .entries
+        .as_ref()
+        .map_err(|e| format!("backup {} is unreadable: {}", index, e))?;
+
+    if config.aliases_path.exists() {
+        let mut kept = config.aliases_path.clone().into_os_string();
+        kept.push(".before-restore");
+        fs::copy(&config.aliases_path, PathBuf::from(&kept))?;
+        eprintln!("Previous database kept as {}", PathBuf::from(kept).display());
+    }
+    fs::copy(&backup.path, &config.aliases_path)?;
+    println!(
+        "Restored backup {} ({} alias{})",
+        index,
+        entries,
+        if *entries == 1 { "" } else { "es" }
+    );
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    fn test_config(dir: &std::path::Path) -> Config {
+        Config {
+            database_path: dir.to_path_buf(),
+            stack_path: dir.join("goto_stack"),
+            history_path: dir.join("dir_history"),
+            context_path: dir.join("context"),
+            listing_path: dir.join("last_listing"),
+            config_path: dir.join("config.toml"),
+            aliases_path: dir.join("aliases.toml"),
+            user: Default::default(),
+        }
+    }
+
+    #[test]
+    fn test_list_backups() {
+        let dir = tempdir().unwrap();
+        let config = test_config(dir.path());
+        let one_alias = "[[aliases]]\nname = \"a\"\npath = \"/a\"\ncreated_at = \"2024-01-01T00:00:00Z\"\n";
+        fs::write(backup_path(&config.aliases_path, 1), one_alias).unwrap();
+        fs::write(backup_path(&config.aliases_path, 2), "not [valid toml").unwrap();
+        fs::write(backup_path(&config.aliases_path, 4), "").unwrap();
+
+        let backups = list_backups(&config);
+        assert_eq!(backups.len(), 2);
+        assert_eq!(backups[0].entries, Ok(1));
+        assert!(backups[1].entries.is_err());
+        assert!(backups[0].describe().contains("1 alias"));
+    }
+
+    #[test]
+    fn test_restore_backup() {
+        let dir = tempdir().unwrap();
+        let config = test_config(dir.path());
+        fs::write(&config.aliases_path, "corrupt [").unwrap();
+        fs::write(backup_path(&config.aliases_path, 1), "aliases = []\n").unwrap();
+        fs::write(backup_path(&config.aliases_path, 2), "corrupt too [").unwrap();
+
+        assert!(restore_backup(&config, Some(2)).is_err());
+        assert!(restore_backup(&config, Some(3)).is_err());
+
+        restore_backup(&config, Some(1)).unwrap();
+        assert_eq!(fs::read_to_string(&config.aliases_path).unwrap(), "aliases = []\n");
+        assert_eq!(
+            fs::read_to_string(dir.path().join("aliases.toml.before-restore")).unwrap(),
+            "corrupt ["
+        );
+    }
+}
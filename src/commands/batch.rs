@@ -0,0 +1,188 @@
+//! Batch command: run many alias changes from stdin with one load and save
+//!
+//! One command per line; blank lines and lines starting with '#' are skipped.
+//! Arguments are separated by whitespace and may be quoted with '' or "".
+//!
+//! ```text
+//! register web ~/code/web work,frontend
+//! tag web team
+//! unregister old-api
+//! ```
+
+use std::error::Error;
+use std::io::BufRead;
+
+use crate::commands::register::{self, RegisterOptions};
+use crate::commands::tags;
+use crate::config::Config;
+use crate::database::Database;
+
+/// Usage of every batch command, shown when a line doesn't parse
+const USAGE: &str = "register <alias> <dir> [tags], unregister <alias>, rename <old> <new>, \
+                     copy <alias> <new>, repath <alias> <dir>, tag <alias> <tag>, untag <alias> <tag>, \
+                     set <alias> key=value...";
+
+/// Split a line into words, honouring single and double quotes
+fn split_words(line: &str) -> Result<Vec<String>, String> {
+    let mut words = Vec::new();
+    let mut word = String::new();
+    let mut in_word = false;
+    let mut quote: Option<char> = None;
+
+    for c in line.chars() {
+        match (quote, c) {
+            (Some(q), c) if c == q => quote = None,
+            (Some(_), c) => word.push(c),
+            (None, '\'' | '"') => {
+                quote = Some(c);
+                in_word = true;
+            }
+            (None, c) if c.is_whitespace() => {
+                if in_word {
+                    words.push(std::mem::take(&mut word));
+                    in_word = false;
+                }
+            }
+            (None, c) => {
+                word.push(c);
+                in_word = true;
+            }
+        }
+    }
+    if quote.is_some() {
+        return Err("unterminated quote".to_string());
+    }
+    if in_word {
+        words.push(word);
+    }
+    Ok(words)
+}
+
+/// Run one batch command
+fn run_line(db: &mut Database, config: &Config, words: &[String]) -> Result<(), Box<dyn Error>> {
+    let args: Vec<&str> = words.iter().map(String::as_str).collect();
+    match args.as_slice() {
+        ["register", name, path] => {
+            let options = RegisterOptions { force: true, rules: &config.user.autotag, ..Default::default() };
+            register::register_with_rules(db, name, path, &options)
+        }
+        ["register", name, path, tags] => {
+            let tags: Vec<String> = tags.split(',').map(str::to_string).collect();
+            let options =
+                RegisterOptions { tags: &tags, force: true, rules: &config.user.autotag, ..Default::default() };
+            register::register_with_rules(db, name, path, &options)
+        }
+        ["unregister", name] => register::unregister(db, name, true),
+        ["rename", old, new] => register::rename(db, old, new),
+        ["copy", src, dst] => register::copy(db, src, dst),
+        ["repath", name, path] => register::repath(db, name, path),
+        ["tag", name, tag] => tags::tag(db, name, tag, true),
+        ["untag", name, tag] => tags::untag(db, name, tag),
+        ["set", name, pairs @ ..] if !pairs.is_empty() => {
+            let pairs: Vec<String> = pairs.iter().map(|p| p.to_string()).collect();
+            tags::set_meta(db, name, &pairs)
+        }
+        _ => Err(format!("unknown batch command '{}' (expected {})", words.join(" "), USAGE).into()),
+    }
+}
+
+/// Run the commands read from `input`, saving once at the end
+///
+/// Stops at the first failing line, which is named in the error; nothing is
+/// saved then, so a batch applies completely or not at all. Returns the number
+/// of commands run.
+pub fn run_batch(db: &mut Database, config: &Config, input: impl BufRead) -> Result<usize, Box<dyn Error>> {
+    db.begin_batch();
+    let mut count = 0;
+
+    for (i, line) in input.lines().enumerate() {
+        let line = line?;
+        let line = line.trim();
+        if line.is_empty() || line.starts_with('#') {
+            continue;
+        }
+        let words = split_words(line).map_err(|e| format!("line {}: {}", i + 1, e))?;
+        run_line(db, config, &words).map_err(|e| format!("line {}: {}", i + 1, e))?;
+        count += 1;
+    }
+
+    db.commit_batch()?;
+    Ok(count)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::fs;
+    use tempfile::tempdir;
+
+    fn test_config(dir: &std::path::Path) -> Config {
+        Config {
+            database_path: dir.to_path_buf(),
+            stack_path: dir.join("goto_stack"),
+            history_path: dir.join("dir_history"),
+            context_path: dir.join("context"),
+            listing_path: dir.join("last_listing"),
+            config_path: dir.join("config.toml"),
+            aliases_path: dir.join("aliases.toml"),
+            user: Default::default(),
+        }
+    }
+
+    #[test]
+    fn test_split_words() {
+        assert_eq!(split_words("tag  web work").unwrap(), vec!["tag", "web", "work"]);
+        assert_eq!(
+            split_words("register docs '/tmp/my docs' \"a,b\"").unwrap(),
+            vec!["register", "docs", "/tmp/my docs", "a,b"]
+        );
+        assert_eq!(split_words("set web note=''").unwrap(), vec!["set", "web", "note="]);
+        assert!(split_words("register x '/tmp").is_err());
+    }
+
+    #[test]
+    fn test_run_batch() {
+        let dir = tempdir().unwrap();
+        let config = test_config(dir.path());
+        let target = dir.path().join("web");
+        fs::create_dir(&target).unwrap();
+        let script = format!(
+            "# provisioning\nregister web {0} work\nregister old {0}\n\ntag web team\nunregister old\nset web owner=ops\n",
+            target.display()
+        );
+
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        assert_eq!(run_batch(&mut db, &config, script.as_bytes()).unwrap(), 5);
+        drop(db);
+
+        let db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        let web = db.get("web").unwrap();
+        assert_eq!(web.tags, vec!["team", "work"]);
+        assert_eq!(web.meta.get("owner").map(String::as_str), Some("ops"));
+        assert!(!db.contains("old"));
+    }
+
+    #[test]
+    fn test_run_batch_failure_saves_nothing() {
+        let dir = tempdir().unwrap();
+        let config = test_config(dir.path());
+        let script = format!("register web {}\nunregister missing\n", dir.path().display());
+
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        let err = run_batch(&mut db, &config, script.as_bytes()).unwrap_err().to_string();
+        assert!(err.starts_with("line 2: "), "{}", err);
+        assert!(err.contains("not found"));
+        drop(db);
+
+        assert!(!config.aliases_path.exists());
+    }
+
+    #[test]
+    fn test_run_batch_unknown_command() {
+        let dir = tempdir().unwrap();
+        let config = test_config(dir.path());
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        let err = run_batch(&mut db, &config, "frobnicate web\n".as_bytes()).unwrap_err();
+        assert!(err.to_string().contains("unknown batch command 'frobnicate web'"));
+    }
+}
@@ -0,0 +1,84 @@
+//! Cdpath command: a CDPATH value built from the aliases
+//!
+//! With the parent directories of the aliases on CDPATH, plain `cd proj`
+//! finds `~/src/proj` from anywhere, as long as the directory is named like
+//! the alias. The list starts with `.` so relative `cd` keeps working first.
+
+use std::error::Error;
+use std::path::Path;
+
+use crate::alias::{Alias, AliasError};
+use crate::database::Database;
+
+/// Print the CDPATH for the aliases matching `filter` (a tag or `key=value`), or all of them
+pub fn cdpath(db: &Database, filter: Option<&str>) -> Result<(), Box<dyn Error>> {
+    let aliases: Vec<&Alias> = db
+        .all()
+        .filter(|a| !db.is_system(&a.name))
+        .filter(|a| filter.is_none_or(|f| a.matches_filter(f)))
+        .collect();
+    if let (Some(f), true) = (filter, aliases.is_empty()) {
+        return Err(AliasError::NoneTagged(f.to_string()).into());
+    }
+    println!("{}", build_cdpath(&aliases));
+    Ok(())
+}
+
+/// Join the parents of the aliases' directories, most used first and without duplicates
+///
+/// Missing directories are left out, and so are paths containing `:`, which
+/// can't be written in CDPATH.
+pub fn build_cdpath(aliases: &[&Alias]) -> String {
+    let mut sorted = aliases.to_vec();
+    sorted.sort_by(|a, b| b.use_count.cmp(&a.use_count).then_with(|| a.name.cmp(&b.name)));
+
+    let mut parents = vec![".".to_string()];
+    for alias in sorted {
+        let path = alias.resolved_path();
+        if !Path::new(&path).is_dir() {
+            continue;
+        }
+        let Some(parent) = Path::new(&path).parent() else {
+            continue;
+        };
+        let parent = parent.to_string_lossy().to_string();
+        if parent.contains(':') {
+            eprintln!("Skipping {}: ':' can't be used in CDPATH", parent);
+            continue;
+        }
+        if !parents.contains(&parent) {
+            parents.push(parent);
+        }
+    }
+    parents.join(":")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::fs;
+    use tempfile::tempdir;
+
+    #[test]
+    fn test_build_cdpath() {
+        let dir = tempdir().unwrap();
+        let src = dir.path().join("src");
+        let docs = dir.path().join("docs");
+        for sub in ["api", "web"] {
+            fs::create_dir_all(src.join(sub)).unwrap();
+        }
+        fs::create_dir_all(docs.join("notes")).unwrap();
+        let path = |p: &Path| p.to_string_lossy().to_string();
+
+        let api = Alias::new("api", &path(&src.join("api"))).unwrap();
+        let web = Alias::new("web", &path(&src.join("web"))).unwrap();
+        let mut notes = Alias::new("notes", &path(&docs.join("notes"))).unwrap();
+        notes.use_count = 3;
+        let gone = Alias::new("gone", "/nonexistent/path/12345").unwrap();
+        let root = Alias::new("root", "/").unwrap();
+
+        let cdpath = build_cdpath(&[&api, &web, &notes, &gone, &root]);
+        assert_eq!(cdpath, format!(".:{}:{}", path(&docs), path(&src)));
+        assert_eq!(build_cdpath(&[]), ".");
+    }
+}
@@ -1,29 +1,48 @@
 //! Cleanup commands
 
-use std::path::Path;
+use chrono::{DateTime, Utc};
 
+use crate::alias::AliasError;
+use crate::commands::register::format_expiry;
 use crate::config::Config;
 use crate::database::Database;
+use crate::pathcheck::check_target;
 use crate::table::{create_table, TableStyle};
-
-/// Remove aliases with invalid (non-existent) paths
-/// If dry_run is true, only lists invalid aliases without removing them
-pub fn cleanup(db: &mut Database, config: &Config, dry_run: bool) -> Result<(), Box<dyn std::error::Error>> {
-    let invalid: Vec<String> = db
-        .all()
-        .filter(|a| !Path::new(&a.path).exists())
-        .map(|a| a.name.clone())
-        .collect();
-
-    if invalid.is_empty() {
-        println!("All aliases point to valid paths.");
-        return Ok(());
+use crate::{confirm, needs_confirmation};
+
+/// Remove aliases with invalid (non-existent) paths, and expired temporary aliases
+/// If dry_run is true, only lists invalid aliases without removing them.
+/// On a terminal the list is confirmed first unless `yes` is set.
+/// Directories that exist but can't be entered are reported, never removed.
+pub fn cleanup(
+    db: &mut Database,
+    config: &Config,
+    dry_run: bool,
+    yes: bool,
+) -> Result<(), Box<dyn std::error::Error>> {
+    let (invalid, denied) = find_invalid(db);
+    let expired: Vec<String> = find_expired(db, Utc::now()).into_iter().filter(|n| !invalid.contains(n)).collect();
+
+    if !denied.is_empty() {
+        eprintln!(
+            "Keeping {} alias{} that can't be entered (permission denied): {}",
+            denied.len(),
+            if denied.len() == 1 { "" } else { "es" },
+            denied.join(", ")
+        );
     }
 
-    if dry_run {
-        println!("Would remove {} aliases with invalid paths (dry-run):", invalid.len());
-    } else {
-        println!("Removing {} aliases with invalid paths:", invalid.len());
+    if invalid.is_empty() && expired.is_empty() {
+        // Nothing to remove still counts as a cleanup for the reminder
+        if !dry_run {
+            let _ = crate::commands::prune::reset_cache(config);
+        }
+        if denied.is_empty() {
+            println!("All aliases point to valid paths.");
+        } else {
+            println!("No aliases with missing directories.");
+        }
+        return Ok(());
     }
 
     let style = TableStyle::from(config.user.display.table_style.as_str());
@@ -34,27 +53,83 @@ pub fn cleanup(db: &mut Database, config: &Config, dry_run: bool) -> Result<(),
         if let Some(alias) = db.get(name) {
             table.add_row(vec![
                 name.clone(),
-                alias.path.clone(),
+                alias.resolved_path(),
                 "Path does not exist".to_string(),
             ]);
         }
-        if !dry_run {
-            db.remove(name);
+    }
+    for name in &expired {
+        if let Some(alias) = db.get(name) {
+            let at = alias.expires_at.map(format_expiry).unwrap_or_default();
+            table.add_row(vec![name.clone(), alias.resolved_path(), format!("Expired {}", at)]);
         }
     }
 
-    println!("{}", table);
+    let count = invalid.len() + expired.len();
+    let what = match (invalid.is_empty(), expired.is_empty()) {
+        (false, true) => "aliases with invalid paths",
+        (true, false) => "expired aliases",
+        _ => "aliases with invalid paths or expired",
+    };
+    if dry_run {
+        println!("Would remove {} {} (dry-run):", count, what);
+        println!("{}", table);
+        return Ok(());
+    }
 
-    if !dry_run {
-        db.save()?;
-        // Reset prune cache since stale aliases are now cleaned
-        let _ = crate::commands::prune::reset_cache(config);
-        println!("Cleanup complete.");
+    // The shell wrapper captures stdout, so the list shown before the prompt goes to stderr
+    if needs_confirmation(yes) {
+        eprintln!("Removing {} {}:", count, what);
+        eprintln!("{}", table);
+        if !confirm(&format!("Remove {} aliases?", count), false)? {
+            return Err(AliasError::Cancelled("Cleanup".to_string()).into());
+        }
+    } else {
+        println!("Removing {} {}:", count, what);
+        println!("{}", table);
     }
 
+    for name in invalid.iter().chain(&expired) {
+        db.remove(name);
+    }
+    db.save()?;
+    // Reset prune cache since stale aliases are now cleaned
+    let _ = crate::commands::prune::reset_cache(config);
+    println!("Cleanup complete.");
+
     Ok(())
 }
 
+/// Names of user aliases that have expired as of `now`, sorted
+pub fn find_expired(db: &Database, now: DateTime<Utc>) -> Vec<String> {
+    let mut expired: Vec<String> = db
+        .all()
+        .filter(|a| !db.is_system(&a.name) && a.is_expired(now))
+        .map(|a| a.name.clone())
+        .collect();
+    expired.sort();
+    expired
+}
+
+/// Names of user aliases whose directory (or file) is missing, and of those that can't be entered, sorted
+pub fn find_invalid(db: &Database) -> (Vec<String>, Vec<String>) {
+    let mut invalid: Vec<String> = Vec::new();
+    let mut denied: Vec<String> = Vec::new();
+    // skip_check aliases may live on mounts that only appear on access; leave them alone
+    for alias in db.all().filter(|a| !db.is_system(&a.name) && !a.skip_check) {
+        match check_target(&alias.resolved_path(), alias.kind) {
+            Err(AliasError::DirectoryNotFound(_) | AliasError::FileNotFound(_)) => {
+                invalid.push(alias.name.clone())
+            }
+            Err(AliasError::PermissionDenied(_)) => denied.push(alias.name.clone()),
+            _ => {}
+        }
+    }
+    invalid.sort();
+    denied.sort();
+    (invalid, denied)
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -76,7 +151,7 @@ mod tests {
 
         db.insert(Alias::new("valid", temp_dir.path().to_str().unwrap()).unwrap());
 
-        let result = cleanup(&mut db, &config, false);
+        let result = cleanup(&mut db, &config, false, true);
         assert!(result.is_ok());
         assert!(db.contains("valid"));
     }
@@ -90,12 +165,30 @@ mod tests {
         db.insert(Alias::new("valid", temp_dir.path().to_str().unwrap()).unwrap());
         db.insert(Alias::new("invalid", "/nonexistent/path/12345").unwrap());
 
-        let result = cleanup(&mut db, &config, false);
+        let result = cleanup(&mut db, &config, false, true);
         assert!(result.is_ok());
         assert!(db.contains("valid"));
         assert!(!db.contains("invalid"));
     }
 
+    #[test]
+    fn test_find_invalid_file_bookmarks() {
+        let (mut db, _file) = create_test_db();
+        let temp_dir = TempDir::new().unwrap();
+        let file = temp_dir.path().join("todo.md");
+        std::fs::write(&file, "").unwrap();
+
+        for (name, path) in [("notes", file.clone()), ("gone", temp_dir.path().join("gone.md"))] {
+            let mut alias = Alias::new(name, path.to_str().unwrap()).unwrap();
+            alias.kind = crate::alias::AliasKind::File;
+            db.insert(alias);
+        }
+
+        let (invalid, denied) = find_invalid(&db);
+        assert_eq!(invalid, vec!["gone"]);
+        assert!(denied.is_empty());
+    }
+
     #[test]
     fn test_cleanup_dry_run_preserves_invalid() {
         let (mut db, _file) = create_test_db();
@@ -105,18 +198,43 @@ mod tests {
         db.insert(Alias::new("valid", temp_dir.path().to_str().unwrap()).unwrap());
         db.insert(Alias::new("invalid", "/nonexistent/path/12345").unwrap());
 
-        let result = cleanup(&mut db, &config, true);
+        let result = cleanup(&mut db, &config, true, true);
         assert!(result.is_ok());
         // Both should still exist after dry-run
         assert!(db.contains("valid"));
         assert!(db.contains("invalid"));
     }
 
+    #[test]
+    fn test_cleanup_removes_expired() {
+        let (mut db, _file) = create_test_db();
+        let config = Config::load().unwrap();
+        let temp_dir = TempDir::new().unwrap();
+        let path = temp_dir.path().to_str().unwrap();
+        let now = Utc::now();
+
+        let mut expired = Alias::new("review", path).unwrap();
+        expired.expires_at = Some(now - chrono::Duration::hours(1));
+        let mut pending = Alias::new("spike", path).unwrap();
+        pending.expires_at = Some(now + chrono::Duration::days(1));
+        db.insert(expired);
+        db.insert(pending);
+        db.insert(Alias::new("valid", path).unwrap());
+        assert_eq!(find_expired(&db, now), vec!["review"]);
+
+        cleanup(&mut db, &config, true, true).unwrap();
+        assert!(db.contains("review"));
+        cleanup(&mut db, &config, false, true).unwrap();
+        assert!(!db.contains("review"));
+        assert!(db.contains("spike"));
+        assert!(db.contains("valid"));
+    }
+
     #[test]
     fn test_cleanup_empty() {
         let (mut db, _file) = create_test_db();
         let config = Config::load().unwrap();
-        let result = cleanup(&mut db, &config, false);
+        let result = cleanup(&mut db, &config, false, true);
         assert!(result.is_ok());
     }
 }
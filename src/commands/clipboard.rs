@@ -0,0 +1,100 @@
+//! Clipboard command: put an alias's path on the system clipboard
+
+use std::io::{ErrorKind, Write};
+use std::process::{Command, Stdio};
+
+use crate::alias::AliasError;
+use crate::database::Database;
+
+/// Copy an alias's path to the clipboard without navigating or recording usage
+pub fn copy_path(db: &Database, alias: &str) -> Result<(), Box<dyn std::error::Error>> {
+    let name = db.resolve_name(alias);
+    let entry = db.get(&name).ok_or_else(|| AliasError::NotFound(alias.to_string()))?;
+    let path = entry.resolved_path();
+
+    let tool = to_clipboard(&path)?;
+    println!("Copied {} to the clipboard ({})", path, tool);
+    Ok(())
+}
+
+/// Clipboard tools worth trying here, best first
+///
+/// `$GOTO_CLIPBOARD` (run with `sh -c`) overrides the detection.
+fn candidates(os: &str, wayland: bool, x11: bool, wsl: bool) -> Vec<Vec<&'static str>> {
+    let mut tools: Vec<Vec<&str>> = Vec::new();
+    match os {
+        "macos" => tools.push(vec!["pbcopy"]),
+        "windows" => tools.push(vec!["clip.exe"]),
+        _ => {
+            if wayland {
+                tools.push(vec!["wl-copy"]);
+            }
+            if x11 {
+                tools.push(vec!["xclip", "-selection", "clipboard"]);
+                tools.push(vec!["xsel", "--clipboard", "--input"]);
+            }
+            if wsl {
+                tools.push(vec!["clip.exe"]);
+            }
+        }
+    }
+    tools
+}
+
+/// Write `text` to the first clipboard tool that runs, returning its name
+fn to_clipboard(text: &str) -> Result<String, Box<dyn std::error::Error>> {
+    let tools: Vec<(String, Vec<String>)> = match std::env::var("GOTO_CLIPBOARD").ok().filter(|c| !c.is_empty()) {
+        Some(cmd) => vec![("GOTO_CLIPBOARD".to_string(), vec!["sh".to_string(), "-c".to_string(), cmd])],
+        None => {
+            let set = |var: &str| std::env::var_os(var).is_some_and(|v| !v.is_empty());
+            let wsl = std::fs::read_to_string("/proc/version").is_ok_and(|v| v.to_lowercase().contains("microsoft"));
+            candidates(std::env::consts::OS, set("WAYLAND_DISPLAY"), set("DISPLAY"), wsl)
+                .into_iter()
+                .map(|tool| (tool[0].to_string(), tool.into_iter().map(String::from).collect()))
+                .collect()
+        }
+    };
+
+    for (name, argv) in &tools {
+        let mut child = match Command::new(&argv[0])
+            .args(&argv[1..])
+            .stdin(Stdio::piped())
+            .stdout(Stdio::null())
+            .spawn()
+        {
+            Ok(child) => child,
+            Err(e) if e.kind() == ErrorKind::NotFound => continue,
+            Err(e) => return Err(e.into()),
+        };
+        if let Some(mut stdin) = child.stdin.take() {
+            stdin.write_all(text.as_bytes())?;
+        }
+        let status = child.wait()?;
+        if !status.success() {
+            return Err(format!("{} failed ({})", name, status).into());
+        }
+        return Ok(name.clone());
+    }
+    Err("no clipboard tool found (install wl-copy, xclip or xsel, or set GOTO_CLIPBOARD)".into())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_candidates() {
+        assert_eq!(candidates("macos", false, true, false), vec![vec!["pbcopy"]]);
+        assert_eq!(candidates("windows", false, false, false), vec![vec!["clip.exe"]]);
+        assert_eq!(
+            candidates("linux", true, true, false),
+            vec![
+                vec!["wl-copy"],
+                vec!["xclip", "-selection", "clipboard"],
+                vec!["xsel", "--clipboard", "--input"],
+            ]
+        );
+        assert_eq!(candidates("linux", false, false, true), vec![vec!["clip.exe"]]);
+        assert!(candidates("linux", false, false, false).is_empty());
+    }
+}
@@ -0,0 +1,156 @@
+//! Context commands: show, use, clear and list the active context
+//!
+//! A context is a namespace that bare alias names resolve in, so switching
+//! from `work` to `oss` makes `goto api` jump to `oss:api` instead of `work:api`.
+
+use std::error::Error;
+use std::fs;
+
+use crate::config::{env_context, valid_context_name, Config};
+use crate::database::Database;
+
+/// Print the active context and where it comes from
+pub fn show_context(config: &Config) -> Result<(), Box<dyn Error>> {
+    let from_env = env_context().is_some();
+    if let Ok(env) = std::env::var("GOTO_CONTEXT") {
+        if !from_env && !env.trim().is_empty() {
+            eprintln!("Note: ignoring GOTO_CONTEXT={}: not a valid context name", env);
+        }
+    }
+    match config.active_context() {
+        Some(context) if from_env => println!("{} (from GOTO_CONTEXT)", context),
+        Some(context) => println!("{}", context),
+        None => println!("No active context"),
+    }
+    Ok(())
+}
+
+/// Persist `name` as the active context
+pub fn use_context(config: &Config, db: &Database, name: &str) -> Result<(), Box<dyn Error>> {
+    if !valid_context_name(name) {
+        return Err(format!(
+            "invalid context '{}': use a namespace name (letters, digits, '-', '_', '.')",
+            name
+        )
+        .into());
+    }
+
+    config.ensure_dirs()?;
+    fs::write(&config.context_path, format!("{}\n", name))?;
+    println!("Switched to context '{}'", name);
+
+    if !db.namespaces().iter().any(|ns| ns == name) {
+        eprintln!("Note: no aliases in namespace '{}' yet (register with 'goto -r {}:<name> <dir>')", name, name);
+    }
+    warn_if_env_overrides(name);
+    Ok(())
+}
+
+/// Forget the persisted context
+pub fn clear_context(config: &Config) -> Result<(), Box<dyn Error>> {
+    if config.context_path.exists() {
+        fs::remove_file(&config.context_path)?;
+    }
+    println!("Cleared active context");
+    warn_if_env_overrides("");
+    Ok(())
+}
+
+/// List the namespaces available as contexts, marking the active one
+pub fn list_contexts(config: &Config, db: &Database) -> Result<(), Box<dyn Error>> {
+    let active = config.active_context();
+    let mut contexts = db.namespaces();
+    if let Some(active) = &active {
+        if !contexts.contains(active) {
+            contexts.push(active.clone());
+            contexts.sort();
+        }
+    }
+
+    if contexts.is_empty() {
+        println!("No contexts (namespaced aliases like work:api define them)");
+        return Ok(());
+    }
+    for context in contexts {
+        let marker = if active.as_ref() == Some(&context) { "*" } else { " " };
+        println!("{} {}", marker, context);
+    }
+    Ok(())
+}
+
+/// The persisted context only applies when GOTO_CONTEXT is unset
+fn warn_if_env_overrides(name: &str) {
+    if let Some(env) = env_context() {
+        if env != name {
+            eprintln!("Note: GOTO_CONTEXT={} is set and takes precedence in this shell", env);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::alias::Alias;
+    use tempfile::tempdir;
+
+    fn test_config(dir: &std::path::Path) -> Config {
+        Config {
+            database_path: dir.to_path_buf(),
+            stack_path: dir.join("goto_stack"),
+            history_path: dir.join("dir_history"),
+            context_path: dir.join("context"),
+            listing_path: dir.join("last_listing"),
+            config_path: dir.join("config.toml"),
+            aliases_path: dir.join("aliases.toml"),
+            user: Default::default(),
+        }
+    }
+
+    #[test]
+    fn test_use_and_clear_context() {
+        let dir = tempdir().unwrap();
+        let config = test_config(dir.path());
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        db.insert(Alias::new("work:api", "/srv/work/api").unwrap());
+
+        assert_eq!(config.saved_context(), None);
+        use_context(&config, &db, "work").unwrap();
+        assert_eq!(config.saved_context(), Some("work".to_string()));
+
+        clear_context(&config).unwrap();
+        assert_eq!(config.saved_context(), None);
+    }
+
+    #[test]
+    fn test_use_context_rejects_invalid_names() {
+        let dir = tempdir().unwrap();
+        let config = test_config(dir.path());
+        let db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+
+        assert!(use_context(&config, &db, "work:api").is_err());
+        assert!(use_context(&config, &db, "-work").is_err());
+        assert!(!config.context_path.exists());
+    }
+
+    #[test]
+    fn test_valid_context_name() {
+        assert!(valid_context_name("work"));
+        assert!(valid_context_name("client-a.v2"));
+        assert!(!valid_context_name("work:api"));
+        assert!(!valid_context_name(""));
+        assert!(!valid_context_name("my work"));
+    }
+
+    #[test]
+    fn test_saved_context_drives_namespace() {
+        let dir = tempdir().unwrap();
+        let mut config = test_config(dir.path());
+        config.user.general.default_namespace = "oss".to_string();
+        fs::write(&config.context_path, "work\n").unwrap();
+
+        assert_eq!(config.saved_context(), Some("work".to_string()));
+        if std::env::var("GOTO_CONTEXT").is_err() {
+            assert_eq!(config.effective_namespace(), "work");
+        }
+    }
+}
@@ -0,0 +1,199 @@
+//! Digest command: a Markdown summary of the past week (or any period)
+//!
+//! Navigation counts come from the event log next to the database, so they
+//! only cover jumps made since that log was introduced.
+
+use chrono::{DateTime, Duration, Utc};
+use std::collections::HashMap;
+use std::fmt::Write;
+use std::path::Path;
+
+use crate::alias::Alias;
+use crate::config::Config;
+use crate::database::Database;
+use crate::events::{self, Event};
+
+/// How many of the most used aliases the digest lists
+const TOP_ALIASES: usize = 5;
+
+/// What happened between `since` and `until`
+#[derive(Debug, Clone, PartialEq)]
+pub struct Digest {
+    pub since: DateTime<Utc>,
+    pub until: DateTime<Utc>,
+    /// Navigations in the period
+    pub navigations: u64,
+    /// Number of different aliases navigated to
+    pub aliases_used: usize,
+    /// Most navigated aliases with their counts, most first
+    pub top: Vec<(String, u64)>,
+    /// Aliases registered in the period, with their paths
+    pub registered: Vec<(String, String)>,
+    /// Aliases whose directory is missing, with their paths
+    pub broken: Vec<(String, String)>,
+    /// Aliases registered before the period that were never used
+    pub never_used: Vec<(String, DateTime<Utc>)>,
+}
+
+/// Print the digest for the period starting at `since` (default: the last 7 days)
+pub fn digest(
+    db: &Database,
+    config: &Config,
+    since: Option<DateTime<Utc>>,
+) -> Result<(), Box<dyn std::error::Error>> {
+    let now = Utc::now();
+    let since = since.unwrap_or(now - Duration::days(7));
+    let events = events::load(&config.database_path.join(events::FILE_NAME))?;
+    let aliases: Vec<&Alias> = db.all().filter(|a| !db.is_system(&a.name)).collect();
+
+    print!("{}", format_digest(&build_digest(&aliases, &events, since, now)));
+    Ok(())
+}
+
+/// Gather the digest for `since..until` from the aliases and the event log
+pub fn build_digest(aliases: &[&Alias], events: &[Event], since: DateTime<Utc>, until: DateTime<Utc>) -> Digest {
+    let mut counts: HashMap<&str, u64> = HashMap::new();
+    for event in events.iter().filter(|e| e.at >= since && e.at <= until) {
+        *counts.entry(event.alias.as_str()).or_default() += event.count;
+    }
+    let mut top: Vec<(String, u64)> = counts.iter().map(|(name, n)| (name.to_string(), *n)).collect();
+    top.sort_by(|a, b| b.1.cmp(&a.1).then_with(|| a.0.cmp(&b.0)));
+    let navigations = top.iter().map(|(_, n)| n).sum();
+    let aliases_used = top.len();
+    top.truncate(TOP_ALIASES);
+
+    let mut sorted: Vec<&Alias> = aliases.to_vec();
+    sorted.sort_by(|a, b| a.name.cmp(&b.name));
+
+    let registered = sorted
+        .iter()
+        .filter(|a| a.created_at >= since && a.created_at <= until)
+        .map(|a| (a.name.clone(), a.resolved_path()))
+        .collect();
+    let broken = sorted
+        .iter()
+        .filter(|a| !a.skip_check && !Path::new(&a.resolved_path()).exists())
+        .map(|a| (a.name.clone(), a.resolved_path()))
+        .collect();
+    let never_used = sorted
+        .iter()
+        .filter(|a| a.use_count == 0 && a.last_used.is_none() && a.created_at < since)
+        .map(|a| (a.name.clone(), a.created_at))
+        .collect();
+
+    Digest { since, until, navigations, aliases_used, top, registered, broken, never_used }
+}
+
+/// Render a digest as Markdown, ready to append to a notes file
+pub fn format_digest(digest: &Digest) -> String {
+    let mut out = String::new();
+    writeln!(
+        out,
+        "## goto digest: {} to {}\n",
+        digest.since.format("%Y-%m-%d"),
+        digest.until.format("%Y-%m-%d")
+    )
+    .unwrap();
+    writeln!(
+        out,
+        "Navigations: {} (to {} alias{})",
+        digest.navigations,
+        digest.aliases_used,
+        if digest.aliases_used == 1 { "" } else { "es" }
+    )
+    .unwrap();
+
+    writeln!(out, "\n### Top aliases\n").unwrap();
+    if digest.top.is_empty() {
+        writeln!(out, "- none").unwrap();
+    }
+    for (i, (name, count)) in digest.top.iter().enumerate() {
+        writeln!(out, "{}. {} ({})", i + 1, name, count).unwrap();
+    }
+
+    writeln!(out, "\n### Newly registered\n").unwrap();
+    write_paths(&mut out, &digest.registered);
+
+    writeln!(out, "\n### Broken paths\n").unwrap();
+    write_paths(&mut out, &digest.broken);
+
+    writeln!(out, "\n### Never used\n").unwrap();
+    if digest.never_used.is_empty() {
+        writeln!(out, "- none").unwrap();
+    }
+    for (name, created) in &digest.never_used {
+        writeln!(out, "- {} (registered {})", name, created.format("%Y-%m-%d")).unwrap();
+    }
+    out
+}
+
+/// "- name -> path" lines, or "- none"
+fn write_paths(out: &mut String, entries: &[(String, String)]) {
+    if entries.is_empty() {
+        writeln!(out, "- none").unwrap();
+    }
+    for (name, path) in entries {
+        writeln!(out, "- {} -> {}", name, path).unwrap();
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    fn event(alias: &str, at: DateTime<Utc>) -> Event {
+        Event::new(at, alias)
+    }
+
+    #[test]
+    fn test_build_and_format_digest() {
+        let dir = tempdir().unwrap();
+        let path = dir.path().to_string_lossy().to_string();
+        let now = Utc::now();
+        let since = now - Duration::days(7);
+
+        let mut proj = Alias::new("proj", &path).unwrap();
+        proj.created_at = now - Duration::days(30);
+        proj.use_count = 4;
+        let mut new = Alias::new("new", &path).unwrap();
+        new.created_at = now - Duration::days(1);
+        let mut idle = Alias::new("idle", &path).unwrap();
+        idle.created_at = now - Duration::days(60);
+        let mut gone = Alias::new("gone", "/nonexistent/path/12345").unwrap();
+        gone.created_at = now - Duration::days(60);
+        gone.use_count = 1;
+
+        let events = [
+            event("proj", now - Duration::days(1)),
+            event("proj", now - Duration::days(2)),
+            event("gone", now - Duration::days(3)),
+            event("proj", now - Duration::days(10)),
+        ];
+        let digest = build_digest(&[&proj, &new, &idle, &gone], &events, since, now);
+
+        assert_eq!(digest.navigations, 3);
+        assert_eq!(digest.aliases_used, 2);
+        assert_eq!(digest.top, vec![("proj".to_string(), 2), ("gone".to_string(), 1)]);
+        assert_eq!(digest.registered, vec![("new".to_string(), path.clone())]);
+        assert_eq!(digest.broken, vec![("gone".to_string(), "/nonexistent/path/12345".to_string())]);
+        assert_eq!(digest.never_used.len(), 1);
+        assert_eq!(digest.never_used[0].0, "idle");
+
+        let text = format_digest(&digest);
+        assert!(text.starts_with("## goto digest: "));
+        assert!(text.contains("Navigations: 3 (to 2 aliases)"));
+        assert!(text.contains("1. proj (2)\n2. gone (1)"));
+        assert!(text.contains(&format!("- new -> {}", path)));
+        assert!(text.contains("- idle (registered "));
+    }
+
+    #[test]
+    fn test_empty_digest() {
+        let now = Utc::now();
+        let digest = build_digest(&[], &[], now - Duration::days(7), now);
+        let text = format_digest(&digest);
+        assert!(text.contains("Navigations: 0 (to 0 aliases)"));
+        assert_eq!(text.matches("- none").count(), 4);
+    }
+}
@@ -0,0 +1,197 @@
+//! Disk usage of aliased directories for `goto -l --du`
+//!
+//! Walking a large tree is slow, so sizes are cached next to the database and
+//! only recomputed once they are older than a day.
+
+use chrono::{DateTime, Duration, Utc};
+use serde::{Deserialize, Serialize};
+use std::collections::{BTreeMap, HashMap};
+use std::fs::{self, File};
+use std::io::BufReader;
+use std::path::{Path, PathBuf};
+
+use crate::config::Config;
+
+/// How long a computed size is reused before walking the directory again
+const CACHE_HOURS: i64 = 24;
+
+/// A size computed at some point in the past
+#[derive(Debug, Clone, Serialize, Deserialize)]
+struct CachedSize {
+    bytes: u64,
+    computed_at: DateTime<Utc>,
+}
+
+/// Get the path to the size cache file
+fn cache_path(config: &Config) -> PathBuf {
+    config.database_path.join("du_cache.json")
+}
+
+/// Load the size cache, keyed by directory path
+fn load_cache(config: &Config) -> BTreeMap<String, CachedSize> {
+    File::open(cache_path(config))
+        .ok()
+        .and_then(|file| serde_json::from_reader(BufReader::new(file)).ok())
+        .unwrap_or_default()
+}
+
+/// Save the size cache to disk
+fn save_cache(config: &Config, cache: &BTreeMap<String, CachedSize>) -> Result<(), Box<dyn std::error::Error>> {
+    config.ensure_dirs()?;
+    let file = File::create(cache_path(config))?;
+    serde_json::to_writer_pretty(file, cache)?;
+    Ok(())
+}
+
+/// Sizes of the given directories, using cached values younger than a day
+///
+/// Directories that no longer exist map to `None` and are dropped from the cache.
+pub fn sizes(config: &Config, paths: &[String]) -> HashMap<String, Option<u64>> {
+    let mut cache = load_cache(config);
+    let mut changed = false;
+    let now = Utc::now();
+
+    let mut result = HashMap::new();
+    for path in paths {
+        if result.contains_key(path) {
+            continue;
+        }
+        if !Path::new(path).is_dir() {
+            changed |= cache.remove(path).is_some();
+            result.insert(path.clone(), None);
+            continue;
+        }
+        let bytes = match cache.get(path) {
+            Some(entry) if now - entry.computed_at < Duration::hours(CACHE_HOURS) => entry.bytes,
+            _ => {
+                let bytes = dir_size(Path::new(path));
+                cache.insert(path.clone(), CachedSize { bytes, computed_at: now });
+                changed = true;
+                bytes
+            }
+        };
+        result.insert(path.clone(), Some(bytes));
+    }
+
+    if changed {
+        if let Err(e) = save_cache(config, &cache) {
+            eprintln!("Warning: could not save size cache: {}", e);
+        }
+    }
+    result
+}
+
+/// Total disk usage of everything below `root`
+///
+/// Symlinks are not followed and unreadable entries are skipped, like `du`.
+pub fn dir_size(root: &Path) -> u64 {
+    let mut total = 0;
+    let mut pending = vec![root.to_path_buf()];
+    while let Some(dir) = pending.pop() {
+        let Ok(entries) = fs::read_dir(&dir) else {
+            continue;
+        };
+        for entry in entries.flatten() {
+            let Ok(meta) = entry.path().symlink_metadata() else {
+                continue;
+            };
+            if meta.is_dir() {
+                pending.push(entry.path());
+            } else {
+                total += disk_bytes(&meta);
+            }
+        }
+    }
+    total
+}
+
+/// Bytes a file occupies on disk (its allocated blocks where the OS reports them)
+#[cfg(unix)]
+fn disk_bytes(meta: &fs::Metadata) -> u64 {
+    use std::os::unix::fs::MetadataExt;
+    meta.blocks() * 512
+}
+
+#[cfg(not(unix))]
+fn disk_bytes(meta: &fs::Metadata) -> u64 {
+    meta.len()
+}
+
+/// Human-readable size with binary units, e.g. "512 B" or "1.5 GiB"
+pub fn format_size(bytes: u64) -> String {
+    const UNITS: [&str; 5] = ["KiB", "MiB", "GiB", "TiB", "PiB"];
+    if bytes < 1024 {
+        return format!("{} B", bytes);
+    }
+    let mut value = bytes as f64 / 1024.0;
+    let mut unit = 0;
+    while value >= 1024.0 && unit + 1 < UNITS.len() {
+        value /= 1024.0;
+        unit += 1;
+    }
+    format!("{:.1} {}", value, UNITS[unit])
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    fn test_config(dir: &Path) -> Config {
+        Config {
+            database_path: dir.to_path_buf(),
+            stack_path: dir.join("goto_stack"),
+            history_path: dir.join("dir_history"),
+            context_path: dir.join("context"),
+            listing_path: dir.join("last_listing"),
+            config_path: dir.join("config.toml"),
+            aliases_path: dir.join("aliases.toml"),
+            user: Default::default(),
+        }
+    }
+
+    #[test]
+    fn test_format_size() {
+        assert_eq!(format_size(0), "0 B");
+        assert_eq!(format_size(1023), "1023 B");
+        assert_eq!(format_size(1536), "1.5 KiB");
+        assert_eq!(format_size(5 * 1024 * 1024), "5.0 MiB");
+        assert_eq!(format_size(3 * 1024 * 1024 * 1024 + 1024 * 1024 * 512), "3.5 GiB");
+    }
+
+    #[test]
+    fn test_dir_size_counts_nested_files() {
+        let dir = tempdir().unwrap();
+        fs::create_dir_all(dir.path().join("a/b")).unwrap();
+        fs::write(dir.path().join("top"), vec![0u8; 10_000]).unwrap();
+        fs::write(dir.path().join("a/b/deep"), vec![0u8; 20_000]).unwrap();
+
+        let size = dir_size(dir.path());
+        assert!(size >= 30_000, "got {}", size);
+        assert_eq!(dir_size(&dir.path().join("missing")), 0);
+    }
+
+    #[test]
+    fn test_sizes_are_cached() {
+        let dir = tempdir().unwrap();
+        let config = test_config(&dir.path().join("db"));
+        let project = dir.path().join("project");
+        fs::create_dir(&project).unwrap();
+        fs::write(project.join("file"), vec![0u8; 10_000]).unwrap();
+        let project = project.to_string_lossy().to_string();
+        let missing = dir.path().join("gone").to_string_lossy().to_string();
+
+        let first = sizes(&config, &[project.clone(), missing.clone()]);
+        assert!(first[&project].unwrap() >= 10_000);
+        assert_eq!(first[&missing], None);
+
+        // Growing the directory doesn't show until the cached size expires
+        fs::write(Path::new(&project).join("more"), vec![0u8; 100_000]).unwrap();
+        assert_eq!(sizes(&config, &[project.clone()])[&project], first[&project]);
+
+        let mut cache = load_cache(&config);
+        cache.get_mut(&project).unwrap().computed_at = Utc::now() - Duration::hours(CACHE_HOURS + 1);
+        save_cache(&config, &cache).unwrap();
+        assert!(sizes(&config, &[project.clone()])[&project].unwrap() >= 110_000);
+    }
+}
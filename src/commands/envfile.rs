@@ -0,0 +1,118 @@
+//! Env-file command: aliases as environment variables for scripts and Makefiles
+//!
+//! Each alias becomes a variable named after it in upper case behind
+//! [`PREFIX`], with anything that can't appear in a variable name replaced by
+//! `_` (`work:api-v2` becomes `GOTO_DIR_WORK_API_V2`). The prefix keeps aliases
+//! like `home` or `path` from replacing `HOME` or `PATH`, or goto's own
+//! `GOTO_PATH`. The shell format is meant for `source` or `eval`; the Makefile
+//! format for `include`.
+
+use std::error::Error;
+use std::fmt::Write;
+
+use crate::alias::{Alias, AliasError};
+use crate::database::Database;
+
+/// Print a variable line for every alias matching `filter` (a tag or `key=value`), or all of them
+pub fn env_file(db: &Database, filter: Option<&str>, make: bool) -> Result<(), Box<dyn Error>> {
+    let aliases: Vec<&Alias> = db
+        .all()
+        .filter(|a| !db.is_system(&a.name))
+        .filter(|a| filter.is_none_or(|f| a.matches_filter(f)))
+        .collect();
+    if let (Some(f), true) = (filter, aliases.is_empty()) {
+        return Err(AliasError::NoneTagged(f.to_string()).into());
+    }
+    print!("{}", format_env(&aliases, make));
+    Ok(())
+}
+
+/// Start of every variable name, so no alias can set a variable the shell or goto relies on
+pub const PREFIX: &str = "GOTO_DIR_";
+
+/// The variable name for an alias
+pub fn var_name(alias: &str) -> String {
+    let name: String = alias
+        .chars()
+        .map(|c| if c.is_ascii_alphanumeric() { c.to_ascii_uppercase() } else { '_' })
+        .collect();
+    format!("{}{}", PREFIX, name)
+}
+
+/// `export NAME="path"` lines (or `export NAME := path` for make), sorted by name
+///
+/// When two aliases map to the same variable (`my-app` and `my_app`, or
+/// `café` and `caf!`) the first one by name wins and the other is reported
+/// on stderr.
+pub fn format_env(aliases: &[&Alias], make: bool) -> String {
+    let mut sorted = aliases.to_vec();
+    sorted.sort_by(|a, b| a.name.cmp(&b.name));
+
+    let mut out = String::new();
+    let mut seen: Vec<(String, &str)> = Vec::new();
+    for alias in sorted {
+        let var = var_name(&alias.name);
+        if let Some((_, first)) = seen.iter().find(|(v, _)| *v == var) {
+            eprintln!("Skipping {}: {} is already set from {}", alias.name, var, first);
+            continue;
+        }
+        let path = alias.resolved_path();
+        if make {
+            writeln!(out, "export {} := {}", var, path.replace('$', "$$")).unwrap();
+        } else {
+            writeln!(out, "export {}=\"{}\"", var, shell_escape(&path)).unwrap();
+        }
+        seen.push((var, &alias.name));
+    }
+    out
+}
+
+/// Escape the characters that stay special inside double quotes
+fn shell_escape(value: &str) -> String {
+    let mut escaped = String::with_capacity(value.len());
+    for c in value.chars() {
+        if matches!(c, '"' | '\\' | '$' | '`') {
+            escaped.push('\\');
+        }
+        escaped.push(c);
+    }
+    escaped
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_var_name() {
+        assert_eq!(var_name("dev"), "GOTO_DIR_DEV");
+        assert_eq!(var_name("work:api-v2"), "GOTO_DIR_WORK_API_V2");
+        assert_eq!(var_name("2024"), "GOTO_DIR_2024");
+        assert_eq!(var_name("café"), "GOTO_DIR_CAF_");
+        // Never the shell's own variables
+        assert_eq!(var_name("path"), "GOTO_DIR_PATH");
+        assert_eq!(var_name("home"), "GOTO_DIR_HOME");
+    }
+
+    #[test]
+    fn test_format_env() {
+        let dev = Alias::new("dev", "/home/me/dev").unwrap();
+        let odd = Alias::new("odd", "/tmp/a \"b\" $c").unwrap();
+        let clash = Alias::new("my-app", "/srv/one").unwrap();
+        let other = Alias::new("my_app", "/srv/two").unwrap();
+        let accented = Alias::new("café", "/srv/three").unwrap();
+        let plain = Alias::new("caf_", "/srv/four").unwrap();
+
+        let shell = format_env(&[&odd, &dev, &other, &clash, &accented, &plain], false);
+        assert_eq!(
+            shell,
+            "export GOTO_DIR_CAF_=\"/srv/four\"\n\
+             export GOTO_DIR_DEV=\"/home/me/dev\"\n\
+             export GOTO_DIR_MY_APP=\"/srv/one\"\n\
+             export GOTO_DIR_ODD=\"/tmp/a \\\"b\\\" \\$c\"\n"
+        );
+
+        let make = format_env(&[&odd, &dev], true);
+        assert_eq!(make, "export GOTO_DIR_DEV := /home/me/dev\nexport GOTO_DIR_ODD := /tmp/a \"b\" $$c\n");
+    }
+}
@@ -0,0 +1,295 @@
+//! Fsck command: check the database file entry by entry and against its checksum
+//!
+//! Works on the raw file rather than the loaded database, so duplicate names
+//! (which loading silently collapses) and files that no longer load at all are
+//! reported too. `--fix` repairs what can be repaired without guessing.
+
+use chrono::{DateTime, Duration, Utc};
+use std::collections::HashMap;
+use std::error::Error;
+
+use crate::alias::{normalize_name, validate_alias, validate_tag, Alias};
+use crate::config::Config;
+use crate::database::{self, Database};
+
+/// Timestamps this far ahead of the clock still count as sane
+const CLOCK_SLACK_MINUTES: i64 = 5;
+
+/// One problem found in the database file
+#[derive(Debug, Clone, PartialEq)]
+pub struct Problem {
+    /// The entry concerned, or None for the file as a whole
+    pub alias: Option<String>,
+    pub message: String,
+    /// Whether `--fix` repairs it
+    pub fixable: bool,
+}
+
+/// Problems found in the entries and the entries with every fixable one repaired
+#[derive(Debug, Default)]
+pub struct FsckResult {
+    pub problems: Vec<Problem>,
+    pub repaired: Vec<Alias>,
+    /// Names with several entries for different directories; saving would keep only one
+    pub conflicts: Vec<String>,
+}
+
+fn problem(alias: &str, message: String, fixable: bool) -> Problem {
+    Problem { alias: Some(alias.to_string()), message, fixable }
+}
+
+/// Check the database file and print what's wrong; with `fix`, repair what can be
+pub fn fsck(config: &Config, fix: bool) -> Result<(), Box<dyn Error>> {
+    let path = &config.aliases_path;
+    if !path.exists() {
+        println!("No database at {}", path.display());
+        return Ok(());
+    }
+    let (entries, stored) = database::read_entries(path).map_err(|e| {
+        format!("cannot read {}: {} (try 'goto --restore-backup')", path.display(), e)
+    })?;
+
+    let mut result = check(&entries, Utc::now());
+    match stored {
+        None => result.problems.push(Problem {
+            alias: None,
+            message: "no checksum (saved by an older goto)".to_string(),
+            fixable: true,
+        }),
+        Some(sum) if sum != database::checksum(&entries)? => result.problems.push(Problem {
+            alias: None,
+            message: "checksum mismatch: edited outside goto or damaged".to_string(),
+            fixable: true,
+        }),
+        Some(_) => {}
+    }
+
+    if result.problems.is_empty() {
+        println!("{}: {} aliases, checksum OK", path.display(), entries.len());
+        return Ok(());
+    }
+
+    for problem in &result.problems {
+        let status = match (problem.fixable, fix) {
+            (true, true) => " [fixed]",
+            (true, false) => " [fixable]",
+            (false, _) => "",
+        };
+        let subject = problem.alias.as_deref().unwrap_or("database");
+        println!("{}: {}{}", subject, problem.message, status);
+    }
+
+    let fixable = result.problems.iter().filter(|p| p.fixable).count();
+    if fix && !result.conflicts.is_empty() {
+        return Err(format!(
+            "not fixing anything while {} {} entries for different directories; edit {} by hand first",
+            result.conflicts.join(", "),
+            if result.conflicts.len() == 1 { "has" } else { "have" },
+            path.display()
+        )
+        .into());
+    }
+    if fix && fixable > 0 {
+        let mut db = Database::load(config)?;
+        db.replace_aliases(result.repaired);
+        db.save()?;
+    }
+
+    let left = if fix { result.problems.len() - fixable } else { result.problems.len() };
+    if left == 0 {
+        println!("Fixed {} problem{}", fixable, if fixable == 1 { "" } else { "s" });
+        return Ok(());
+    }
+    let hint = if !fix && fixable > 0 {
+        format!(", {} fixable with 'goto --fsck --fix'", fixable)
+    } else {
+        String::new()
+    };
+    Err(format!("database check found {} problem{}{}", left, if left == 1 { "" } else { "s" }, hint).into())
+}
+
+/// Check every entry as of `now`
+pub fn check(entries: &[Alias], now: DateTime<Utc>) -> FsckResult {
+    let mut result = FsckResult::default();
+    let latest = now + Duration::minutes(CLOCK_SLACK_MINUTES);
+
+    let names: Vec<&str> = entries.iter().map(|a| a.name.as_str()).collect();
+    for entry in entries {
+        let mut alias = entry.clone();
+        let name = entry.name.as_str();
+
+        if validate_alias(name).is_err() {
+            let normalized = normalize_name(name.trim());
+            let fixable = validate_alias(&normalized).is_ok() && !names.contains(&normalized.as_str());
+            result.problems.push(problem(name, "invalid name".to_string(), fixable));
+            if fixable {
+                alias.name = normalized;
+            }
+        }
+
+        if entry.path.trim().is_empty() {
+            result.problems.push(problem(name, "empty path".to_string(), false));
+        }
+
+        if entry.created_at > latest {
+            result.problems.push(problem(name, format!("created in the future ({})", entry.created_at), true));
+            alias.created_at = now;
+        }
+        match entry.last_used {
+            Some(used) if used > latest => {
+                result.problems.push(problem(name, format!("last used in the future ({})", used), true));
+                alias.last_used = Some(now);
+            }
+            Some(used) if used < entry.created_at => {
+                result.problems.push(problem(name, "last used before it was created".to_string(), true));
+            }
+            _ => {}
+        }
+        if let Some(used) = alias.last_used.filter(|used| *used < alias.created_at) {
+            alias.created_at = used;
+        }
+
+        let mut tags = Vec::new();
+        for tag in &entry.tags {
+            let trimmed = tag.trim();
+            if validate_tag(tag).is_err() {
+                let fixable = validate_tag(trimmed).is_ok();
+                result.problems.push(problem(name, format!("invalid tag '{}'", tag), fixable));
+                if !fixable {
+                    tags.push(tag.clone());
+                    continue;
+                }
+            }
+            if tags.iter().any(|t| t == trimmed) {
+                result.problems.push(problem(name, format!("duplicate tag '{}'", trimmed), true));
+            } else {
+                tags.push(trimmed.to_string());
+            }
+        }
+        alias.tags = tags;
+
+        result.repaired.push(alias);
+    }
+
+    // Same name more than once: loading keeps only one of them
+    let mut by_name: HashMap<&str, Vec<usize>> = HashMap::new();
+    for (i, entry) in entries.iter().enumerate() {
+        by_name.entry(entry.name.as_str()).or_default().push(i);
+    }
+    let mut dropped = vec![false; entries.len()];
+    let mut duplicates: Vec<(&str, Vec<usize>)> = by_name.into_iter().filter(|(_, ix)| ix.len() > 1).collect();
+    duplicates.sort();
+    for (name, indexes) in duplicates {
+        // Copies of one directory can be merged into the most used; different directories need a decision
+        let same_path = indexes.iter().all(|&i| entries[i].path == entries[indexes[0]].path);
+        let message = format!("{} entries with this name", indexes.len());
+        result.problems.push(problem(name, message, same_path));
+        if same_path {
+            let keep = *indexes.iter().max_by_key(|&&i| entries[i].use_count).unwrap();
+            for &i in &indexes {
+                dropped[i] = i != keep;
+            }
+        } else {
+            result.conflicts.push(name.to_string());
+        }
+    }
+    let mut index = 0;
+    result.repaired.retain(|_| {
+        index += 1;
+        !dropped[index - 1]
+    });
+    result
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn entry(name: &str, path: &str) -> Alias {
+        let mut alias = Alias::new("x", "/").unwrap();
+        alias.name = name.to_string();
+        alias.path = path.to_string();
+        alias.created_at = Utc::now() - Duration::days(10);
+        alias
+    }
+
+    fn messages(result: &FsckResult) -> Vec<(String, bool)> {
+        result.problems.iter().map(|p| (p.message.clone(), p.fixable)).collect()
+    }
+
+    #[test]
+    fn test_clean_entries() {
+        let result = check(&[entry("proj", "/tmp"), entry("work:api", "/srv")], Utc::now());
+        assert!(result.problems.is_empty());
+        assert_eq!(result.repaired.len(), 2);
+    }
+
+    #[test]
+    fn test_names_and_paths() {
+        let entries = [entry(" proj", "/tmp"), entry("bad name", "/tmp"), entry("empty", "")];
+        let result = check(&entries, Utc::now());
+        assert_eq!(
+            messages(&result),
+            vec![
+                ("invalid name".to_string(), true),
+                ("invalid name".to_string(), false),
+                ("empty path".to_string(), false),
+            ]
+        );
+        assert_eq!(result.repaired[0].name, "proj");
+        assert_eq!(result.repaired[1].name, "bad name");
+    }
+
+    #[test]
+    fn test_timestamps() {
+        let now = Utc::now();
+        let mut future = entry("future", "/tmp");
+        future.created_at = now + Duration::days(400);
+        future.last_used = Some(now + Duration::days(400));
+        let mut backwards = entry("backwards", "/tmp");
+        backwards.last_used = Some(backwards.created_at - Duration::days(3));
+        let mut skewed = entry("skewed", "/tmp");
+        skewed.last_used = Some(now + Duration::minutes(1));
+
+        let result = check(&[future, backwards.clone(), skewed], now);
+        assert_eq!(result.problems.len(), 3);
+        assert!(result.problems.iter().all(|p| p.fixable));
+        assert_eq!(result.repaired[0].created_at, now);
+        assert_eq!(result.repaired[0].last_used, Some(now));
+        assert_eq!(result.repaired[1].created_at, backwards.last_used.unwrap());
+    }
+
+    #[test]
+    fn test_tags() {
+        let mut alias = entry("proj", "/tmp");
+        alias.tags = vec!["work".to_string(), " rust".to_string(), "work".to_string(), "no good".to_string()];
+        let result = check(&[alias], Utc::now());
+        assert_eq!(
+            messages(&result),
+            vec![
+                ("invalid tag ' rust'".to_string(), true),
+                ("duplicate tag 'work'".to_string(), true),
+                ("invalid tag 'no good'".to_string(), false),
+            ]
+        );
+        assert_eq!(result.repaired[0].tags, vec!["work", "rust", "no good"]);
+    }
+
+    #[test]
+    fn test_duplicate_names() {
+        let mut used = entry("proj", "/tmp");
+        used.use_count = 7;
+        let entries = [entry("proj", "/tmp"), used, entry("docs", "/a"), entry("docs", "/b")];
+        let result = check(&entries, Utc::now());
+        assert_eq!(
+            messages(&result),
+            vec![("2 entries with this name".to_string(), false), ("2 entries with this name".to_string(), true)]
+        );
+        assert_eq!(result.problems[0].alias.as_deref(), Some("docs"));
+
+        // The most used copy is kept; both conflicting entries stay
+        let kept: Vec<(&str, u64)> = result.repaired.iter().map(|a| (a.name.as_str(), a.use_count)).collect();
+        assert_eq!(kept, vec![("proj", 7), ("docs", 0), ("docs", 0)]);
+        assert_eq!(result.conflicts, vec!["docs"]);
+    }
+}
@@ -0,0 +1,174 @@
+//! Git branch and working-tree state of aliased repositories for `goto -l --git`
+//!
+//! Every repository is asked at once on its own thread and the listing waits
+//! at most [`TIMEOUT`] for all of them, so one slow repository (or network
+//! mount) can't hold up the table.
+
+use std::collections::HashMap;
+use std::path::Path;
+use std::process::Command;
+use std::sync::mpsc;
+use std::thread;
+use std::time::{Duration, Instant};
+
+/// How long the whole listing waits for `git status` across all repositories
+pub const TIMEOUT: Duration = Duration::from_secs(3);
+
+/// What `git status` said about a repository
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum GitStatus {
+    /// On this branch with nothing to commit
+    Clean(String),
+    /// On this branch with modified, staged or untracked files
+    Dirty(String),
+    /// `git status` did not answer in time
+    TimedOut,
+    /// `git` is missing or refused to run here
+    Failed,
+}
+
+impl std::fmt::Display for GitStatus {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self {
+            GitStatus::Clean(branch) => write!(f, "{}", branch),
+            GitStatus::Dirty(branch) => write!(f, "{} (dirty)", branch),
+            GitStatus::TimedOut => write!(f, "timeout"),
+            GitStatus::Failed => write!(f, "?"),
+        }
+    }
+}
+
+/// Whether `path` is the top of a git working tree (`.git` may be a file in worktrees)
+pub fn is_repo(path: &str) -> bool {
+    Path::new(path).join(".git").exists()
+}
+
+/// Status of each path that is a git repository, waiting at most `timeout` in total
+///
+/// Paths that aren't repositories are left out; one whose `.git` check hasn't
+/// finished by the deadline (a hung mount) counts as timed out. Threads still
+/// running at the deadline are left behind; their `git` ends on its own.
+pub fn statuses(paths: &[String], timeout: Duration) -> HashMap<String, GitStatus> {
+    let (tx, rx) = mpsc::channel();
+    let mut pending = 0;
+    let mut result = HashMap::new();
+    for path in paths {
+        if result.contains_key(path) {
+            continue;
+        }
+        result.insert(path.clone(), GitStatus::TimedOut);
+        pending += 1;
+
+        let tx = tx.clone();
+        let path = path.clone();
+        thread::spawn(move || {
+            // Even looking for `.git` can block on a dead mount, so it runs here, under the deadline
+            let status = is_repo(&path).then(|| query(&path));
+            let _ = tx.send((path, status));
+        });
+    }
+
+    let deadline = Instant::now() + timeout;
+    while pending > 0 {
+        let left = deadline.saturating_duration_since(Instant::now());
+        let Ok((path, status)) = rx.recv_timeout(left) else {
+            break;
+        };
+        match status {
+            Some(status) => result.insert(path, status),
+            None => result.remove(&path),
+        };
+        pending -= 1;
+    }
+    result
+}
+
+/// Run `git status` in `path`
+fn query(path: &str) -> GitStatus {
+    let output = Command::new("git")
+        .args(["-C", path, "status", "--porcelain=v1", "--branch"])
+        // A read-only look shouldn't take the index lock from a running git
+        .env("GIT_OPTIONAL_LOCKS", "0")
+        .output();
+    match output {
+        Ok(out) if out.status.success() => parse_status(&String::from_utf8_lossy(&out.stdout)),
+        _ => GitStatus::Failed,
+    }
+}
+
+/// Read the branch and dirtiness out of `git status --porcelain=v1 --branch`
+pub fn parse_status(output: &str) -> GitStatus {
+    let mut lines = output.lines();
+    let Some(header) = lines.next().and_then(|l| l.strip_prefix("## ")) else {
+        return GitStatus::Failed;
+    };
+    let branch = if let Some(unborn) = header.strip_prefix("No commits yet on ") {
+        unborn.to_string()
+    } else if header.starts_with("HEAD (no branch)") {
+        "(detached)".to_string()
+    } else {
+        // "main...origin/main [ahead 1]"
+        let end = header.find("...").or_else(|| header.find(' ')).unwrap_or(header.len());
+        header[..end].to_string()
+    };
+
+    if lines.any(|l| !l.is_empty()) {
+        GitStatus::Dirty(branch)
+    } else {
+        GitStatus::Clean(branch)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::fs;
+    use tempfile::tempdir;
+
+    #[test]
+    fn test_parse_status() {
+        assert_eq!(parse_status("## main\n"), GitStatus::Clean("main".to_string()));
+        assert_eq!(
+            parse_status("## main...origin/main [ahead 2]\n"),
+            GitStatus::Clean("main".to_string())
+        );
+        assert_eq!(
+            parse_status("## feature/x...origin/feature/x\n M src/lib.rs\n?? notes.txt\n"),
+            GitStatus::Dirty("feature/x".to_string())
+        );
+        assert_eq!(
+            parse_status("## No commits yet on trunk\n?? a\n"),
+            GitStatus::Dirty("trunk".to_string())
+        );
+        assert_eq!(parse_status("## HEAD (no branch)\n"), GitStatus::Clean("(detached)".to_string()));
+        assert_eq!(parse_status(""), GitStatus::Failed);
+    }
+
+    #[test]
+    fn test_display() {
+        assert_eq!(GitStatus::Clean("main".to_string()).to_string(), "main");
+        assert_eq!(GitStatus::Dirty("dev".to_string()).to_string(), "dev (dirty)");
+        assert_eq!(GitStatus::TimedOut.to_string(), "timeout");
+    }
+
+    #[test]
+    fn test_statuses_skip_non_repos() {
+        let dir = tempdir().unwrap();
+        let plain = dir.path().join("plain");
+        let worktree = dir.path().join("worktree");
+        fs::create_dir(&plain).unwrap();
+        fs::create_dir(&worktree).unwrap();
+        // A worktree's .git is a file
+        fs::write(worktree.join(".git"), "gitdir: /nonexistent/.git/worktrees/x").unwrap();
+
+        let plain = plain.to_string_lossy().to_string();
+        let worktree = worktree.to_string_lossy().to_string();
+        assert!(!is_repo(&plain));
+        assert!(is_repo(&worktree));
+
+        let result = statuses(&[plain.clone(), worktree.clone()], TIMEOUT);
+        assert!(!result.contains_key(&plain));
+        // The gitdir doesn't exist, so git (if installed) refuses to run there
+        assert!(matches!(result[&worktree], GitStatus::Failed | GitStatus::TimedOut));
+    }
+}
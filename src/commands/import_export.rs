@@ -1,24 +1,135 @@
 //! Import and export commands
 
-use std::collections::HashMap;
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+use sha2::{Digest, Sha256};
+use std::collections::{BTreeMap, HashMap};
 use std::fs;
+use std::io::{self, Read};
 use std::path::Path;
 
-use crate::alias::{validate_alias, Alias};
+use crate::alias::{normalize_name, validate_alias, Alias, AliasKind};
+use crate::config::{collapse_home, expand_path};
 use crate::database::Database;
 
+/// Column order used for CSV export
+const CSV_COLUMNS: [&str; 7] = ["name", "path", "tags", "use_count", "last_used", "created_at", "description"];
+
+/// Serialization format for export and import
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Default)]
+pub enum ExportFormat {
+    #[default]
+    Toml,
+    Json,
+    Csv,
+}
+
+impl ExportFormat {
+    pub fn from_str(s: &str) -> Result<Self, String> {
+        match s.to_lowercase().as_str() {
+            "toml" => Ok(ExportFormat::Toml),
+            "json" => Ok(ExportFormat::Json),
+            "csv" => Ok(ExportFormat::Csv),
+            _ => Err(format!(
+                "invalid format: {} (must be toml, json, or csv)",
+                s
+            )),
+        }
+    }
+
+    /// Guess the format from a file extension, defaulting to TOML
+    pub fn from_path(path: &str) -> Self {
+        match Path::new(path)
+            .extension()
+            .and_then(|e| e.to_str())
+            .map(|e| e.to_lowercase())
+            .as_deref()
+        {
+            Some("json") => ExportFormat::Json,
+            Some("csv") => ExportFormat::Csv,
+            _ => ExportFormat::Toml,
+        }
+    }
+}
+
+/// File layout shared by the TOML and JSON formats
+#[derive(Debug, Serialize, Deserialize)]
+struct AliasFile {
+    #[serde(default)]
+    aliases: Vec<Alias>,
+}
+
 /// Export aliases as TOML to stdout
 pub fn export(db: &Database) -> Result<(), Box<dyn std::error::Error>> {
+    export_with_format(db, ExportFormat::Toml, false)
+}
+
+/// Export aliases to stdout in the given format
+///
+/// With `portable`, paths under $HOME are written as `~/...`.
+pub fn export_with_format(
+    db: &Database,
+    format: ExportFormat,
+    portable: bool,
+) -> Result<(), Box<dyn std::error::Error>> {
     if db.is_empty() {
         eprintln!("No aliases to export");
         return Ok(());
     }
 
-    let toml = db.export_toml()?;
-    print!("{}", toml);
+    print!("{}", export_to_string(db, format, portable)?);
     Ok(())
 }
 
+/// Export aliases to a file in the given format
+pub fn export_to_file(
+    db: &Database,
+    file_path: &str,
+    format: ExportFormat,
+    portable: bool,
+) -> Result<(), Box<dyn std::error::Error>> {
+    if db.is_empty() {
+        eprintln!("No aliases to export");
+        return Ok(());
+    }
+
+    fs::write(file_path, export_to_string(db, format, portable)?)?;
+    println!(
+        "Exported {} alias{} to {}",
+        db.len(),
+        if db.len() == 1 { "" } else { "es" },
+        file_path
+    );
+    Ok(())
+}
+
+/// Serialize all aliases (sorted by name) in the given format
+pub fn export_to_string(
+    db: &Database,
+    format: ExportFormat,
+    portable: bool,
+) -> Result<String, Box<dyn std::error::Error>> {
+    // System aliases belong to the machine, not the user database
+    let mut aliases: Vec<Alias> = db.all().filter(|a| !db.is_system(&a.name)).cloned().collect();
+    aliases.sort_by(|a, b| a.name.cmp(&b.name));
+
+    if portable {
+        for alias in &mut aliases {
+            alias.path = collapse_home(&alias.path);
+        }
+    }
+
+    match format {
+        ExportFormat::Toml => Ok(toml::to_string_pretty(&AliasFile { aliases })?),
+        ExportFormat::Json => {
+            let mut json = serde_json::to_string_pretty(&AliasFile { aliases })?;
+            json.push('\n');
+            Ok(json)
+        }
+        ExportFormat::Csv => Ok(aliases_to_csv(&aliases)),
+    }
+}
+
 /// Import result statistics
 #[derive(Debug, Default)]
 pub struct ImportResult {
@@ -57,26 +168,101 @@ pub fn import(
     file_path: &str,
     strategy: ImportStrategy,
 ) -> Result<ImportResult, Box<dyn std::error::Error>> {
-    let content = fs::read_to_string(file_path)?;
-    let result = import_from_content(db, &content, strategy)?;
+    import_with_format(db, file_path, strategy, ExportFormat::Toml, None)
+}
+
+/// Import aliases from a file in the given format with the specified strategy
+///
+/// A `file_path` of "-" reads the content from stdin, and an http(s) URL is
+/// downloaded. With `sha256`, the content must match that hex digest before
+/// anything is imported.
+pub fn import_with_format(
+    db: &mut Database,
+    file_path: &str,
+    strategy: ImportStrategy,
+    format: ExportFormat,
+    sha256: Option<&str>,
+) -> Result<ImportResult, Box<dyn std::error::Error>> {
+    let raw = if file_path == "-" {
+        let mut raw = Vec::new();
+        io::stdin().read_to_end(&mut raw)?;
+        raw
+    } else if is_url(file_path) {
+        if file_path.starts_with("http://") && sha256.is_none() {
+            eprintln!("Warning: fetching over plain HTTP without --sha256; the file could be tampered with");
+        }
+        fetch_url(file_path)?
+    } else {
+        fs::read(file_path)?
+    };
+    // Check the bytes as served, before any decoding can change them
+    if let Some(expected) = sha256 {
+        verify_sha256(&raw, expected)?;
+    }
+    let content = String::from_utf8(raw).map_err(|_| format!("{} is not valid UTF-8", file_path))?;
+    let result = import_from_content_with_format(db, &content, strategy, format)?;
     db.save()?;
     Ok(result)
 }
 
+/// Whether an import source is a URL rather than a file
+pub fn is_url(source: &str) -> bool {
+    source.starts_with("https://") || source.starts_with("http://")
+}
+
+/// Download an alias file
+fn fetch_url(url: &str) -> Result<Vec<u8>, Box<dyn std::error::Error>> {
+    let client = reqwest::blocking::Client::builder()
+        .user_agent(format!("goto/{}", env!("CARGO_PKG_VERSION")))
+        .timeout(std::time::Duration::from_secs(30))
+        .build()?;
+
+    let response = client
+        .get(url)
+        .send()
+        .map_err(|e| format!("failed to fetch {}: {}", url, e))?;
+    if !response.status().is_success() {
+        return Err(format!("failed to fetch {}: server returned status {}", url, response.status()).into());
+    }
+    Ok(response.bytes()?.to_vec())
+}
+
+/// Check content against a hex SHA-256 digest (as `sha256sum` prints it)
+fn verify_sha256(content: &[u8], expected: &str) -> Result<(), String> {
+    let actual: String = Sha256::digest(content)
+        .iter()
+        .map(|b| format!("{:02x}", b))
+        .collect();
+    if actual.eq_ignore_ascii_case(expected.trim()) {
+        Ok(())
+    } else {
+        Err(format!("checksum mismatch: expected {}, got {}", expected.trim(), actual))
+    }
+}
+
 /// Import aliases from TOML content string with the specified strategy
 pub fn import_from_content(
     db: &mut Database,
     content: &str,
     strategy: ImportStrategy,
 ) -> Result<ImportResult, Box<dyn std::error::Error>> {
-    // Parse TOML content to get aliases
-    #[derive(serde::Deserialize)]
-    struct ImportFile {
-        #[serde(default)]
-        aliases: Vec<Alias>,
-    }
+    import_from_content_with_format(db, content, strategy, ExportFormat::Toml)
+}
 
-    let import_data: ImportFile = toml::from_str(content)?;
+/// Import aliases from content in the given format with the specified strategy
+pub fn import_from_content_with_format(
+    db: &mut Database,
+    content: &str,
+    strategy: ImportStrategy,
+    format: ExportFormat,
+) -> Result<ImportResult, Box<dyn std::error::Error>> {
+    let import_data = match format {
+        ExportFormat::Toml => toml::from_str::<AliasFile>(content)?,
+        ExportFormat::Json => serde_json::from_str::<AliasFile>(content)?,
+        ExportFormat::Csv => AliasFile {
+            aliases: aliases_from_csv(content)?,
+        },
+    };
 
     if import_data.aliases.is_empty() {
         return Err("no aliases found in import file".into());
@@ -87,8 +273,14 @@ pub fn import_from_content(
 
     let mut result = ImportResult::default();
 
-    for import_alias in import_data.aliases {
+    for mut import_alias in import_data.aliases {
+        // Home-relative paths from portable exports resolve against this machine
+        if import_alias.path.starts_with('~') {
+            import_alias.path = expand_path(&import_alias.path)?.to_string_lossy().to_string();
+        }
+
         // Validate alias name
+        import_alias.name = normalize_name(&import_alias.name);
         if let Err(e) = validate_alias(&import_alias.name) {
             result.warnings.push(format!(
                 "skipping invalid alias name '{}': {}",
@@ -98,6 +290,12 @@ pub fn import_from_content(
             continue;
         }
 
+        if let Err(e) = db.check_alias_allowed(&import_alias) {
+            result.warnings.push(format!("skipping alias '{}': {}", import_alias.name, e));
+            result.skipped += 1;
+            continue;
+        }
+
         // Check if path exists (warn but don't skip)
         if !Path::new(&import_alias.path).exists() {
             result.warnings.push(format!(
@@ -136,6 +334,161 @@ pub fn import_from_content(
     Ok(result)
 }
 
+/// Render aliases as CSV with a header row
+fn aliases_to_csv(aliases: &[Alias]) -> String {
+    let mut out = CSV_COLUMNS.join(",");
+    out.push('\n');
+
+    for alias in aliases {
+        let fields = [
+            alias.name.clone(),
+            alias.path.clone(),
+            alias.tags.join(";"),
+            alias.use_count.to_string(),
+            alias.last_used.map(|t| t.to_rfc3339()).unwrap_or_default(),
+            alias.created_at.to_rfc3339(),
+            alias.description.clone().unwrap_or_default(),
+        ];
+        let escaped: Vec<String> = fields.iter().map(|f| csv_escape(f)).collect();
+        out.push_str(&escaped.join(","));
+        out.push('\n');
+    }
+
+    out
+}
+
+/// Parse CSV content into aliases, matching columns by header name
+fn aliases_from_csv(content: &str) -> Result<Vec<Alias>, Box<dyn std::error::Error>> {
+    let mut records = parse_csv(content)?.into_iter();
+
+    let header = match records.next() {
+        Some(header) => header,
+        None => return Ok(Vec::new()),
+    };
+    let column = |name: &str| header.iter().position(|h| h.trim().eq_ignore_ascii_case(name));
+
+    let name_col = column("name").ok_or("invalid CSV: missing 'name' column")?;
+    let path_col = column("path").ok_or("invalid CSV: missing 'path' column")?;
+    let tags_col = column("tags");
+    let use_count_col = column("use_count");
+    let last_used_col = column("last_used");
+    let created_at_col = column("created_at");
+    let description_col = column("description");
+
+    let mut aliases = Vec::new();
+    for (i, record) in records.enumerate() {
+        let line = i + 2;
+        let field = |col: Option<usize>| {
+            col.and_then(|c| record.get(c))
+                .map(|v| v.trim())
+                .filter(|v| !v.is_empty())
+        };
+
+        let tags = field(tags_col)
+            .map(|t| {
+                t.split(';')
+                    .map(|tag| tag.trim().to_string())
+                    .filter(|tag| !tag.is_empty())
+                    .collect()
+            })
+            .unwrap_or_default();
+
+        let use_count = match field(use_count_col) {
+            Some(v) => v
+                .parse()
+                .map_err(|_| format!("invalid CSV: bad use_count '{}' on line {}", v, line))?,
+            None => 0,
+        };
+
+        let parse_time = |v: &str| {
+            DateTime::parse_from_rfc3339(v)
+                .map(|t| t.with_timezone(&Utc))
+                .map_err(|_| format!("invalid CSV: bad timestamp '{}' on line {}", v, line))
+        };
+
+        let last_used = field(last_used_col).map(parse_time).transpose()?;
+        let created_at = field(created_at_col)
+            .map(parse_time)
+            .transpose()?
+            .unwrap_or_else(Utc::now);
+
+        aliases.push(Alias {
+            name: field(Some(name_col)).unwrap_or_default().to_string(),
+            path: field(Some(path_col)).unwrap_or_default().to_string(),
+            kind: AliasKind::Dir,
+            tags,
+            description: field(description_col).map(str::to_string),
+            use_count,
+            last_used,
+            created_at,
+            paths: BTreeMap::new(),
+            meta: BTreeMap::new(),
+            skip_check: false,
+            project_type: None,
+            expires_at: None,
+        });
+    }
+
+    Ok(aliases)
+}
+
+/// Quote a CSV field if it contains separators, quotes, or newlines
+fn csv_escape(field: &str) -> String {
+    if field.contains(',') || field.contains('"') || field.contains('\n') || field.contains('\r') {
+        format!("\"{}\"", field.replace('"', "\"\""))
+    } else {
+        field.to_string()
+    }
+}
+
+/// Split CSV content into records, honoring quoted fields (RFC 4180)
+fn parse_csv(content: &str) -> Result<Vec<Vec<String>>, String> {
+    let mut records = Vec::new();
+    let mut record = Vec::new();
+    let mut field = String::new();
+    let mut in_quotes = false;
+    let mut chars = content.chars().peekable();
+
+    while let Some(c) = chars.next() {
+        if in_quotes {
+            match c {
+                '"' if chars.peek() == Some(&'"') => {
+                    chars.next();
+                    field.push('"');
+                }
+                '"' => in_quotes = false,
+                _ => field.push(c),
+            }
+            continue;
+        }
+
+        match c {
+            '"' => in_quotes = true,
+            ',' => record.push(std::mem::take(&mut field)),
+            '\r' => {}
+            '\n' => {
+                record.push(std::mem::take(&mut field));
+                if !(record.len() == 1 && record[0].is_empty()) {
+                    records.push(std::mem::take(&mut record));
+                }
+                record.clear();
+            }
+            _ => field.push(c),
+        }
+    }
+
+    if in_quotes {
+        return Err("invalid CSV: unterminated quoted field".to_string());
+    }
+
+    if !field.is_empty() || !record.is_empty() {
+        record.push(field);
+        records.push(record);
+    }
+
+    Ok(records)
+}
+
 /// Generate a unique alias name by appending a numeric suffix
 fn find_unique_name(base_name: &str, existing_names: &HashMap<String, bool>) -> String {
     let mut suffix = 2;
@@ -188,6 +541,41 @@ mod tests {
         assert!(result.is_ok());
     }
 
+    #[test]
+    fn test_portable_export_round_trip() {
+        let home = dirs::home_dir().unwrap();
+        let project = home.join("goto-portable-test").to_string_lossy().to_string();
+
+        let (mut db, _dir) = create_test_db();
+        db.insert(Alias::new("proj", &project).unwrap());
+        db.insert(Alias::new("abs", "/opt/elsewhere").unwrap());
+
+        let exported = export_to_string(&db, ExportFormat::Toml, true).unwrap();
+        assert!(exported.contains("path = \"~/goto-portable-test\""));
+        assert!(exported.contains("path = \"/opt/elsewhere\""));
+
+        let (mut db2, _dir2) = create_test_db();
+        import_from_content(&mut db2, &exported, ImportStrategy::Skip).unwrap();
+        assert_eq!(db2.get("proj").unwrap().path, project);
+        assert_eq!(db2.get("abs").unwrap().path, "/opt/elsewhere");
+    }
+
+    #[test]
+    fn test_export_to_file() {
+        let (mut db, dir) = create_test_db();
+        db.insert(Alias::new("proj", "/tmp").unwrap());
+        let out = dir.path().join("out.json");
+
+        export_to_file(&db, out.to_str().unwrap(), ExportFormat::Json, false).unwrap();
+
+        let content = fs::read_to_string(&out).unwrap();
+        let (mut db2, _dir2) = create_test_db();
+        let result =
+            import_from_content_with_format(&mut db2, &content, ImportStrategy::Skip, ExportFormat::Json).unwrap();
+        assert_eq!(result.imported, 1);
+        assert!(db2.contains("proj"));
+    }
+
     #[test]
     fn test_import_strategy_from_str() {
         assert_eq!(ImportStrategy::from_str("skip").unwrap(), ImportStrategy::Skip);
@@ -225,6 +613,35 @@ created_at = "2024-01-01T00:00:00Z"
         assert!(alias.has_tag("work"));
     }
 
+    #[test]
+    fn test_import_checks_host_paths_against_register_rules() {
+        let (mut db, _dir) = create_test_db();
+        db.set_register_rules(crate::config::RegisterConfig {
+            allow: Vec::new(),
+            deny: vec!["/proc".to_string()],
+        });
+        let content = r#"[[aliases]]
+name = "sneaky"
+path = "/tmp"
+created_at = "2024-01-01T00:00:00Z"
+
+[aliases.paths]
+default = "/proc"
+
+[[aliases]]
+name = "fine"
+path = "/tmp"
+created_at = "2024-01-01T00:00:00Z"
+"#;
+
+        let result =
+            import_from_content_with_format(&mut db, content, ImportStrategy::Skip, ExportFormat::Toml).unwrap();
+        assert_eq!((result.imported, result.skipped), (1, 1));
+        assert!(result.warnings[0].contains("path not allowed"), "{:?}", result.warnings);
+        assert!(!db.contains("sneaky"));
+        assert!(db.contains("fine"));
+    }
+
     #[test]
     fn test_import_skip_existing() {
         let (mut db, _dir) = create_test_db_with_alias();
@@ -422,6 +839,40 @@ created_at = "2024-01-01T00:00:00Z"
         assert!(result.is_err());
     }
 
+    #[test]
+    fn test_verify_sha256() {
+        let digest = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824";
+        assert!(verify_sha256(b"hello", digest).is_ok());
+        assert!(verify_sha256(b"hello", &digest.to_uppercase()).is_ok());
+        let err = verify_sha256(b"hello!", digest).unwrap_err();
+        assert!(err.contains("checksum mismatch"));
+    }
+
+    #[test]
+    fn test_import_checks_sha256_of_raw_bytes() {
+        let (mut db, _dir) = create_test_db();
+
+        // Latin-1 bytes: the digest matches, so the failure is the decoding
+        let mut import_file = NamedTempFile::new().unwrap();
+        import_file.write_all(b"[[aliases]]\nname = \"caf\xe9\"\n").unwrap();
+        let raw = fs::read(import_file.path()).unwrap();
+        let digest: String = Sha256::digest(&raw).iter().map(|b| format!("{:02x}", b)).collect();
+        let path = import_file.path().to_str().unwrap();
+
+        let err = import_with_format(&mut db, path, ImportStrategy::Skip, ExportFormat::Toml, Some(&digest))
+            .unwrap_err()
+            .to_string();
+        assert!(err.contains("not valid UTF-8"), "{}", err);
+    }
+
+    #[test]
+    fn test_is_url() {
+        assert!(is_url("https://example.com/aliases.toml"));
+        assert!(is_url("http://example.com/aliases.toml"));
+        assert!(!is_url("aliases.toml"));
+        assert!(!is_url("-"));
+    }
+
     #[test]
     fn test_find_unique_name() {
         let mut existing: HashMap<String, bool> = HashMap::new();
@@ -461,4 +912,110 @@ created_at = "2024-06-15T10:30:00Z"
         assert!(alias.has_tag("work"));
         assert!(alias.has_tag("important"));
     }
+
+    #[test]
+    fn test_export_format_from_str() {
+        assert_eq!(ExportFormat::from_str("toml").unwrap(), ExportFormat::Toml);
+        assert_eq!(ExportFormat::from_str("JSON").unwrap(), ExportFormat::Json);
+        assert_eq!(ExportFormat::from_str("csv").unwrap(), ExportFormat::Csv);
+        assert!(ExportFormat::from_str("yaml").is_err());
+    }
+
+    #[test]
+    fn test_export_format_from_path() {
+        assert_eq!(ExportFormat::from_path("backup.json"), ExportFormat::Json);
+        assert_eq!(ExportFormat::from_path("backup.CSV"), ExportFormat::Csv);
+        assert_eq!(ExportFormat::from_path("backup.toml"), ExportFormat::Toml);
+        assert_eq!(ExportFormat::from_path("backup"), ExportFormat::Toml);
+    }
+
+    #[test]
+    fn test_json_round_trip() {
+        let (mut db, _dir) = create_test_db();
+        let mut alias = Alias::new("proj", "/tmp").unwrap();
+        alias.add_tag("work");
+        alias.use_count = 7;
+        alias.record_use();
+        db.insert(alias);
+
+        let json = export_to_string(&db, ExportFormat::Json, false).unwrap();
+        assert!(json.contains("\"aliases\""));
+
+        let (mut db2, _dir2) = create_test_db();
+        let result =
+            import_from_content_with_format(&mut db2, &json, ImportStrategy::Skip, ExportFormat::Json).unwrap();
+        assert_eq!(result.imported, 1);
+
+        let imported = db2.get("proj").unwrap();
+        assert_eq!(imported.use_count, 8);
+        assert!(imported.has_tag("work"));
+        assert!(imported.last_used.is_some());
+    }
+
+    #[test]
+    fn test_csv_round_trip() {
+        let (mut db, _dir) = create_test_db();
+        let mut alias = Alias::new("proj", "/tmp/with, comma").unwrap();
+        alias.add_tag("work");
+        alias.add_tag("rust");
+        alias.use_count = 3;
+        alias.description = Some("Main app, v2".to_string());
+        db.insert(alias);
+        db.insert(Alias::new("other", "/tmp/\"quoted\"").unwrap());
+
+        let csv = export_to_string(&db, ExportFormat::Csv, false).unwrap();
+        assert!(csv.starts_with("name,path,tags,use_count,last_used,created_at,description\n"));
+        assert!(csv.contains("\"/tmp/with, comma\""));
+
+        let (mut db2, _dir2) = create_test_db();
+        let result =
+            import_from_content_with_format(&mut db2, &csv, ImportStrategy::Skip, ExportFormat::Csv).unwrap();
+        assert_eq!(result.imported, 2);
+
+        let proj = db2.get("proj").unwrap();
+        assert_eq!(proj.path, "/tmp/with, comma");
+        assert_eq!(proj.tags, vec!["rust", "work"]);
+        assert_eq!(proj.use_count, 3);
+        assert!(proj.last_used.is_none());
+        assert_eq!(proj.description.as_deref(), Some("Main app, v2"));
+        assert_eq!(db2.get("other").unwrap().path, "/tmp/\"quoted\"");
+        assert!(db2.get("other").unwrap().description.is_none());
+    }
+
+    #[test]
+    fn test_csv_import_minimal_columns() {
+        let (mut db, _dir) = create_test_db_with_alias();
+        let csv = "path,name\n/tmp/new,new\n/tmp/other,test\n";
+
+        let result =
+            import_from_content_with_format(&mut db, csv, ImportStrategy::Rename, ExportFormat::Csv).unwrap();
+        assert_eq!(result.imported, 1);
+        assert_eq!(result.renamed, 1);
+        assert_eq!(db.get("new").unwrap().use_count, 0);
+        assert_eq!(db.get("test_2").unwrap().path, "/tmp/other");
+    }
+
+    #[test]
+    fn test_csv_import_missing_name_column() {
+        let (mut db, _dir) = create_test_db();
+        let result =
+            import_from_content_with_format(&mut db, "path\n/tmp\n", ImportStrategy::Skip, ExportFormat::Csv);
+        assert!(result.unwrap_err().to_string().contains("missing 'name' column"));
+    }
+
+    #[test]
+    fn test_csv_import_bad_use_count() {
+        let (mut db, _dir) = create_test_db();
+        let csv = "name,path,use_count\nproj,/tmp,lots\n";
+        let result = import_from_content_with_format(&mut db, csv, ImportStrategy::Skip, ExportFormat::Csv);
+        assert!(result.unwrap_err().to_string().contains("bad use_count"));
+    }
+
+    #[test]
+    fn test_parse_csv_quoted_newline() {
+        let records = parse_csv("a,b\n\"line1\nline2\",x\n").unwrap();
+        assert_eq!(records.len(), 2);
+        assert_eq!(records[1][0], "line1\nline2");
+        assert!(parse_csv("a,\"open\n").is_err());
+    }
 }
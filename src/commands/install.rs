@@ -3,6 +3,7 @@
 use std::env;
 use std::error::Error;
 use std::fs;
+use std::io::{self, Write};
 use std::path::PathBuf;
 
 /// Shell wrapper script for bash (embedded)
@@ -104,45 +105,50 @@ impl InstallOptions {
 
 /// Install shell integration (wrapper script + rc file modification)
 pub fn install(options: &InstallOptions) -> Result<(), Box<dyn Error>> {
+    install_to(options, &mut io::stdout())
+}
+
+/// Install shell integration, writing progress to `out`
+pub fn install_to(options: &InstallOptions, out: &mut dyn Write) -> Result<(), Box<dyn Error>> {
     let home = env::var("HOME")?;
     let config_dir = PathBuf::from(&home).join(".config").join("goto");
     let wrapper_path = config_dir.join(options.shell.wrapper_filename());
     let rc_file = options.shell.rc_file();
     let source_line = format!("source {}", wrapper_path.display());
 
-    println!("Installing goto shell integration for {:?}...", options.shell);
-    println!();
+    writeln!(out, "Installing goto shell integration for {:?}...", options.shell)?;
+    writeln!(out)?;
 
     // Step 1: Create config directory and copy shell wrapper
-    println!("[1/2] Installing shell wrapper to {}", wrapper_path.display());
+    writeln!(out, "[1/2] Installing shell wrapper to {}", wrapper_path.display())?;
     if options.dry_run {
-        println!("  Would create: {}", config_dir.display());
-        println!("  Would write: {}", wrapper_path.display());
+        writeln!(out, "  Would create: {}", config_dir.display())?;
+        writeln!(out, "  Would write: {}", wrapper_path.display())?;
     } else {
         fs::create_dir_all(&config_dir)?;
         fs::write(&wrapper_path, options.shell.wrapper_content())?;
-        println!("  Installed");
+        writeln!(out, "  Installed")?;
     }
 
     // Step 2: Update shell config (unless skipped)
     if options.skip_rc {
-        println!("[2/2] Skipping rc file modification (--skip-rc)");
-        println!("  Add this line to your shell config manually:");
-        println!("  {}", source_line);
+        writeln!(out, "[2/2] Skipping rc file modification (--skip-rc)")?;
+        writeln!(out, "  Add this line to your shell config manually:")?;
+        writeln!(out, "  {}", source_line)?;
     } else {
-        println!("[2/2] Updating {}", rc_file.display());
+        writeln!(out, "[2/2] Updating {}", rc_file.display())?;
         let rc_content = fs::read_to_string(&rc_file).unwrap_or_default();
         let already_present = rc_content.contains(&source_line);
 
         if options.dry_run {
             if already_present {
-                println!("  Source line already present, would skip");
+                writeln!(out, "  Source line already present, would skip")?;
             } else {
-                println!("  Would append: {}", source_line);
+                writeln!(out, "  Would append: {}", source_line)?;
             }
         } else {
             if already_present {
-                println!("  Source line already present, skipping");
+                writeln!(out, "  Source line already present, skipping")?;
             } else {
                 // Create parent directory if needed (for fish)
                 if let Some(parent) = rc_file.parent() {
@@ -153,17 +159,17 @@ pub fn install(options: &InstallOptions) -> Result<(), Box<dyn Error>> {
                 content.push_str(&source_line);
                 content.push('\n');
                 fs::write(&rc_file, content)?;
-                println!("  Added source line");
+                writeln!(out, "  Added source line")?;
             }
         }
     }
 
-    println!();
+    writeln!(out)?;
     if options.dry_run {
-        println!("Dry run complete. No changes were made.");
+        writeln!(out, "Dry run complete. No changes were made.")?;
     } else {
-        println!("Installation complete!");
-        println!("Restart your shell or run: source {}", rc_file.display());
+        writeln!(out, "Installation complete!")?;
+        writeln!(out, "Restart your shell or run: source {}", rc_file.display())?;
     }
 
     Ok(())
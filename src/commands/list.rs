@@ -1,8 +1,20 @@
-//! List commands: list, list_with_options, list_names
+//! List commands: list, list_with_options, list_aliases, list_names
 
-use crate::config::Config;
+use chrono::Utc;
+use std::collections::{BTreeMap, HashMap};
+use std::path::{Path, PathBuf};
+
+use regex::Regex;
+
+use crate::alias::{Alias, AliasError};
+use crate::commands::{du, gitstatus, projecttype};
+use crate::config::{collapse_home, path_matcher, Config};
 use crate::database::Database;
+use crate::deadline::Deadline;
+use crate::listing;
+use crate::pathcheck::PathCheck;
 use crate::table::{TableStyle, create_table};
+use crate::print_record;
 
 /// Sort order for listing aliases
 #[derive(Debug, Clone, Copy, PartialEq, Eq, Default)]
@@ -14,6 +26,12 @@ pub enum SortOrder {
     Usage,
     /// Sort by last used time (most recent first)
     Recent,
+    /// Sort by creation time (newest first)
+    Created,
+    /// Sort alphabetically by path
+    Path,
+    /// Sort by disk usage (largest first)
+    Size,
 }
 
 impl From<&str> for SortOrder {
@@ -21,6 +39,9 @@ impl From<&str> for SortOrder {
         match s.to_lowercase().as_str() {
             "usage" => SortOrder::Usage,
             "recent" => SortOrder::Recent,
+            "created" => SortOrder::Created,
+            "path" => SortOrder::Path,
+            "size" => SortOrder::Size,
             _ => SortOrder::Alpha,
         }
     }
@@ -32,69 +53,341 @@ impl std::fmt::Display for SortOrder {
             SortOrder::Alpha => write!(f, "alpha"),
             SortOrder::Usage => write!(f, "usage"),
             SortOrder::Recent => write!(f, "recent"),
+            SortOrder::Created => write!(f, "created"),
+            SortOrder::Path => write!(f, "path"),
+            SortOrder::Size => write!(f, "size"),
+        }
+    }
+}
+
+/// How to group aliases in a tree view
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum GroupBy {
+    /// Group under each tag, with an "untagged" bucket
+    Tag,
+    /// Group aliases whose paths share their first N levels, under the
+    /// deepest directory the group has in common
+    Path(usize),
+}
+
+/// How many directory levels aliases must share to be grouped by path
+/// (`~/work/shop` and `~/work/blog` share `~/work`)
+pub const DEFAULT_GROUP_DEPTH: usize = 2;
+
+impl GroupBy {
+    /// Parse a group name from a string: `tag`, `path`, or `path:<depth>`
+    pub fn from_str(s: &str) -> Result<Self, String> {
+        let lower = s.to_lowercase();
+        let (name, depth) = match lower.split_once(':') {
+            Some((name, depth)) => match depth.parse::<usize>() {
+                Ok(d) if d > 0 && name != "tag" && name != "tags" => (name, d),
+                _ => return Err(format!("invalid group: {} (depth must be a positive number)", s)),
+            },
+            None => (lower.as_str(), DEFAULT_GROUP_DEPTH),
+        };
+        match name {
+            "tag" | "tags" => Ok(GroupBy::Tag),
+            "path" | "dir" => Ok(GroupBy::Path(depth)),
+            _ => Err(format!("invalid group: {} (must be tag or path)", s)),
         }
     }
 }
 
+/// Options for listing aliases
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct ListOptions {
+    /// Sort order (falls back to the configured default)
+    pub sort: Option<String>,
+    /// Only show aliases with this tag
+    pub filter: Option<String>,
+    /// Only show aliases in this namespace
+    pub namespace: Option<String>,
+    /// Only show aliases whose path is under this prefix or matches this glob
+    pub path: Option<String>,
+    /// Only show aliases whose name matches this regular expression
+    pub regex: Option<String>,
+    /// Also match `regex` against the alias path
+    pub regex_paths: bool,
+    /// Add a column marking aliases whose directory no longer exists
+    pub status: bool,
+    /// Show timestamps, use count and tags regardless of display config
+    pub long: bool,
+    /// Add a column with the disk usage of each directory (`--du`)
+    pub du: bool,
+    /// Add a column with the branch and clean/dirty state of git repositories (`--git`)
+    pub git: bool,
+    /// Add a column with the detected project type (`--type`; also part of `long`)
+    pub project_type: bool,
+    /// Only show aliases whose directory no longer exists (implies `status`)
+    pub broken_only: bool,
+    /// Render a tree grouped by this key instead of a table
+    pub group: Option<GroupBy>,
+    /// Invert the sort order
+    pub reverse: bool,
+    /// Show at most this many aliases
+    pub limit: Option<usize>,
+    /// Skip this many aliases (after sorting)
+    pub offset: usize,
+    /// Print only the paths, NUL-terminated (`-0`/`--print0`)
+    pub print0: bool,
+}
+
+/// List namespaces, one per line (for shell completion)
+pub fn list_namespaces_raw(db: &Database) -> Result<(), Box<dyn std::error::Error>> {
+    for namespace in db.namespaces() {
+        println!("{}", namespace);
+    }
+    Ok(())
+}
+
 /// List all aliases with optional sorting and filtering
 pub fn list_with_options(
     db: &Database,
     config: &Config,
     sort_order: Option<&str>,
     filter_tag: Option<&str>,
+) -> Result<(), Box<dyn std::error::Error>> {
+    let options = ListOptions {
+        sort: sort_order.map(String::from),
+        filter: filter_tag.map(String::from),
+        ..Default::default()
+    };
+    list_aliases(db, config, &options)
+}
+
+/// List aliases according to `options`
+pub fn list_aliases(
+    db: &Database,
+    config: &Config,
+    options: &ListOptions,
 ) -> Result<(), Box<dyn std::error::Error>> {
     let mut aliases: Vec<_> = db.all().cloned().collect();
 
-    // Filter by tag if specified
-    if let Some(tag) = filter_tag {
-        let tag_lower = tag.to_lowercase();
-        aliases.retain(|a| a.tags.iter().any(|t| t.to_lowercase() == tag_lower));
+    // Filter by tag (or key=value metadata) if specified
+    if let Some(filter) = &options.filter {
+        aliases.retain(|a| a.matches_filter(filter));
+    }
+
+    if let Some(namespace) = &options.namespace {
+        aliases.retain(|a| a.namespace() == Some(namespace.as_str()));
+    }
+
+    if let Some(pattern) = &options.path {
+        let matcher = path_matcher(pattern)?;
+        aliases.retain(|a| matcher.is_match(&a.resolved_path()));
+    }
+
+    if let Some(pattern) = &options.regex {
+        let re = Regex::new(pattern)
+            .map_err(|e| format!("invalid --regex pattern '{}': {}", pattern, e))?;
+        aliases.retain(|a| {
+            re.is_match(&a.name) || (options.regex_paths && re.is_match(&a.resolved_path()))
+        });
+    }
+
+    // Only looked at when shown: a dead mount must not hold up a plain listing
+    let show_status = options.status || options.broken_only;
+    let statuses = if show_status {
+        target_statuses(db, &aliases, &Deadline::from_ms(config.user.general.check_timeout_ms))
+    } else {
+        HashMap::new()
+    };
+    if options.broken_only {
+        aliases.retain(|a| statuses.get(&a.name) != Some(&TargetStatus::Present));
     }
 
     if aliases.is_empty() {
-        if filter_tag.is_some() {
-            eprintln!("No aliases with tag '{}'", filter_tag.unwrap());
+        if let Some(filter) = &options.filter {
+            if filter.contains('=') {
+                eprintln!("No aliases with metadata '{}'", filter);
+            } else {
+                eprintln!("No aliases with tag '{}'", filter);
+            }
+        } else if let Some(namespace) = &options.namespace {
+            eprintln!("No aliases in namespace '{}'", namespace);
+        } else if let Some(pattern) = &options.path {
+            eprintln!("No aliases matching path '{}'", pattern);
+        } else if let Some(pattern) = &options.regex {
+            eprintln!("No aliases matching '{}'", pattern);
+        } else if options.broken_only {
+            eprintln!("All aliases point to existing directories");
         } else {
             eprintln!("No aliases registered");
         }
+        forget_listing(config);
         return Ok(());
     }
 
     // Determine sort order from argument or config default
-    let order = sort_order
+    let order = options
+        .sort
+        .as_deref()
         .map(SortOrder::from)
         .unwrap_or_else(|| SortOrder::from(config.user.general.default_sort.as_str()));
 
+    // Sizes are needed both for the column and for sorting by them
+    let show_size = options.du || order == SortOrder::Size;
+    let sizes = if show_size {
+        let paths: Vec<String> = aliases.iter().map(|a| a.resolved_path()).collect();
+        du::sizes(config, &paths)
+    } else {
+        Default::default()
+    };
+
     // Sort entries
     match order {
         SortOrder::Usage => aliases.sort_by(|a, b| b.use_count.cmp(&a.use_count)),
         SortOrder::Recent => aliases.sort_by(|a, b| b.last_used.cmp(&a.last_used)),
+        SortOrder::Created => aliases.sort_by(|a, b| b.created_at.cmp(&a.created_at)),
+        SortOrder::Path => aliases.sort_by_cached_key(|a| a.resolved_path()),
         SortOrder::Alpha => aliases.sort_by(|a, b| a.name.cmp(&b.name)),
+        SortOrder::Size => aliases.sort_by_cached_key(|a| {
+            std::cmp::Reverse(sizes.get(&a.resolved_path()).copied().flatten())
+        }),
+    }
+    if options.reverse {
+        aliases.reverse();
+    }
+
+    // Paginate after sorting
+    let total = aliases.len();
+    let aliases: Vec<_> = aliases
+        .into_iter()
+        .skip(options.offset)
+        .take(options.limit.unwrap_or(usize::MAX))
+        .collect();
+
+    if aliases.is_empty() {
+        eprintln!("No aliases at offset {} ({} total)", options.offset, total);
+        forget_listing(config);
+        return Ok(());
+    }
+    if aliases.len() < total {
+        eprintln!(
+            "Showing {}-{} of {} aliases",
+            options.offset + 1,
+            options.offset + aliases.len(),
+            total
+        );
+    }
+
+    if options.print0 {
+        for alias in &aliases {
+            print_record(&alias.resolved_path(), true);
+        }
+        remember_listing(config, &aliases, options.offset);
+        return Ok(());
+    }
+
+    if let Some(context) = config.active_context() {
+        println!("Context: {}", context);
     }
 
     // Build table with configured style
     let style = TableStyle::from(config.user.display.table_style.as_str());
+    let statuses = show_status.then_some(&statuses);
+
+    if let Some(group) = options.group {
+        let refs: Vec<&Alias> = aliases.iter().collect();
+        print!("{}", render_tree(&group_aliases(&refs, group), style, statuses));
+        // Tree rows aren't numbered (and an alias can appear under several tags)
+        forget_listing(config);
+        return Ok(());
+    }
+
+    let git = if options.git {
+        let paths: Vec<String> = aliases.iter().map(|a| a.resolved_path()).collect();
+        gitstatus::statuses(&paths, gitstatus::TIMEOUT)
+    } else {
+        Default::default()
+    };
+
+    // Aliases from before types were stored (or read-only ones) are looked up
+    let show_type = options.project_type || options.long;
+    let types = if show_type {
+        let paths: Vec<String> = aliases
+            .iter()
+            .filter(|a| a.project_type.is_none())
+            .map(|a| a.directory())
+            .collect();
+        projecttype::types(config, &paths)
+    } else {
+        Default::default()
+    };
+
     let mut table = create_table(style);
 
-    // Build header dynamically based on config
-    let mut header = vec!["Name", "Path"];
-    if config.user.display.show_stats {
+    // Build header dynamically based on config; "#" is the N for `goto %N`
+    let mut header = vec!["#", "Name", "Path"];
+    let show_stats = options.long || config.user.display.show_stats;
+    let show_tags = options.long || config.user.display.show_tags;
+    if show_status {
+        header.push("Status");
+    }
+    if show_size {
+        header.push("Size");
+    }
+    if options.git {
+        header.push("Git");
+    }
+    if show_type {
+        header.push("Type");
+    }
+    if options.long {
+        header.push("Created");
+        header.push("Last used");
+    }
+    if show_stats {
         header.push("Uses");
     }
-    if config.user.display.show_tags {
+    if show_tags {
         header.push("Tags");
     }
+    if options.long {
+        header.push("Description");
+    }
     table.set_header(header);
 
     // Add rows for each alias
-    for alias in &aliases {
-        let mut row: Vec<String> = vec![alias.name.clone(), alias.path.clone()];
+    for (i, alias) in aliases.iter().enumerate() {
+        let path = alias.resolved_path();
+        let mut row: Vec<String> = vec![(options.offset + i + 1).to_string(), alias.name.clone(), path.clone()];
+
+        if let Some(statuses) = statuses {
+            row.push(status_marker(statuses[&alias.name], style).to_string());
+        }
+
+        if show_size {
+            row.push(match sizes.get(&path).copied().flatten() {
+                Some(bytes) => du::format_size(bytes),
+                None => "-".to_string(),
+            });
+        }
+
+        if options.git {
+            row.push(git.get(&path).map(|s| s.to_string()).unwrap_or_else(|| "-".to_string()));
+        }
+
+        if show_type {
+            let kind = alias.project_type.clone().or_else(|| types.get(&alias.directory()).cloned().flatten());
+            row.push(kind.unwrap_or_else(|| "-".to_string()));
+        }
+
+        if options.long {
+            row.push(alias.created_at.format("%Y-%m-%d %H:%M").to_string());
+            row.push(
+                alias
+                    .last_used
+                    .map(|t| t.format("%Y-%m-%d %H:%M").to_string())
+                    .unwrap_or_else(|| "never".to_string()),
+            );
+        }
 
-        if config.user.display.show_stats {
+        if show_stats {
             row.push(alias.use_count.to_string());
         }
 
-        if config.user.display.show_tags {
+        if show_tags {
             let tags_str = if alias.tags.is_empty() {
                 "-".to_string()
             } else {
@@ -103,14 +396,173 @@ pub fn list_with_options(
             row.push(tags_str);
         }
 
+        if options.long {
+            row.push(alias.description.clone().unwrap_or_else(|| "-".to_string()));
+        }
+
         table.add_row(row);
     }
 
     println!("{table}");
+    remember_listing(config, &aliases, options.offset);
 
     Ok(())
 }
 
+/// Remember the rows just printed for `goto %N`, numbered from `offset + 1`
+///
+/// Failing to is not worth failing the listing.
+fn remember_listing(config: &Config, aliases: &[Alias], offset: usize) {
+    let rows: Vec<(usize, &str)> =
+        aliases.iter().enumerate().map(|(i, a)| (offset + i + 1, a.name.as_str())).collect();
+    if let Err(e) = listing::save(&config.listing_path, &rows) {
+        eprintln!("Warning: could not save listing for %N shortcuts: {}", e);
+    }
+}
+
+/// Drop the saved listing, so `%N` can't resolve against rows no longer on screen
+fn forget_listing(config: &Config) {
+    if let Err(e) = listing::clear(&config.listing_path) {
+        eprintln!("Warning: could not clear listing for %N shortcuts: {}", e);
+    }
+}
+
+/// A named group of aliases, each with the path text to display
+type Group<'a> = (String, Vec<(&'a Alias, String)>);
+
+/// Split aliases into named groups, keeping their order within each group
+fn group_aliases<'a>(aliases: &[&'a Alias], group: GroupBy) -> Vec<Group<'a>> {
+    match group {
+        GroupBy::Tag => {
+            let mut groups: BTreeMap<String, Vec<(&Alias, String)>> = BTreeMap::new();
+            let mut untagged = Vec::new();
+            for alias in aliases {
+                let path = alias.resolved_path();
+                if alias.tags.is_empty() {
+                    untagged.push((*alias, path.clone()));
+                }
+                for tag in &alias.tags {
+                    groups.entry(tag.clone()).or_default().push((*alias, path.clone()));
+                }
+            }
+
+            let mut result: Vec<_> = groups.into_iter().collect();
+            if !untagged.is_empty() {
+                result.push(("untagged".to_string(), untagged));
+            }
+            result
+        }
+        GroupBy::Path(depth) => {
+            // Bucket by the first `depth` levels of each alias's path...
+            let mut buckets: BTreeMap<PathBuf, Vec<(&Alias, PathBuf)>> = BTreeMap::new();
+            for alias in aliases {
+                let path = PathBuf::from(collapse_home(&alias.resolved_path()));
+                let key = path.components().take(depth + usize::from(path.has_root())).collect();
+                buckets.entry(key).or_default().push((*alias, path));
+            }
+
+            // ...then name each bucket after the deepest directory containing all of them
+            buckets
+                .into_values()
+                .map(|entries| {
+                    let root = entries
+                        .iter()
+                        .map(|(_, path)| path.parent().unwrap_or(path).to_path_buf())
+                        .reduce(|a, b| common_ancestor(&a, &b))
+                        .unwrap_or_default();
+                    let shown = entries
+                        .into_iter()
+                        .map(|(alias, path)| {
+                            let rest = path.strip_prefix(&root).unwrap_or(&path).to_string_lossy().to_string();
+                            (alias, if rest.is_empty() { ".".to_string() } else { rest })
+                        })
+                        .collect();
+                    (root.to_string_lossy().to_string(), shown)
+                })
+                .collect()
+        }
+    }
+}
+
+/// The deepest directory containing both paths
+fn common_ancestor(a: &Path, b: &Path) -> PathBuf {
+    a.components().zip(b.components()).take_while(|(x, y)| x == y).map(|(x, _)| x).collect()
+}
+
+/// Render groups as a tree with aligned alias names
+fn render_tree(groups: &[Group], style: TableStyle, statuses: Option<&HashMap<String, TargetStatus>>) -> String {
+    let (branch, last) = match style {
+        TableStyle::Unicode => ("├── ", "└── "),
+        _ => ("|-- ", "`-- "),
+    };
+    let width = groups
+        .iter()
+        .flat_map(|(_, entries)| entries.iter().map(|(a, _)| a.name.chars().count()))
+        .max()
+        .unwrap_or(0);
+
+    let mut out = String::new();
+    for (name, entries) in groups {
+        out.push_str(&format!("{} ({})\n", name, entries.len()));
+        for (i, (alias, shown)) in entries.iter().enumerate() {
+            let prefix = if i + 1 == entries.len() { last } else { branch };
+            out.push_str(&format!("{}{:<width$}  {}", prefix, alias.name, shown, width = width));
+            if let Some(&status) = statuses.and_then(|s| s.get(&alias.name)) {
+                if status != TargetStatus::Present {
+                    out.push_str(&format!("  {}", status_marker(status, style)));
+                }
+            }
+            out.push('\n');
+        }
+    }
+    out
+}
+
+/// What the status column knows about an alias's target
+#[derive(Debug, Clone, Copy, PartialEq)]
+enum TargetStatus {
+    Present,
+    Missing,
+    /// The check timed out (or was skipped with `--no-check`)
+    Unknown,
+}
+
+/// The status of every alias's target, keyed by alias name
+///
+/// Targets are checked as navigation checks them, and all the checks share
+/// `deadline` (the `check_timeout_ms` budget): once a dead mount has used it
+/// up, the rest are reported as unknown rather than waited for.
+fn target_statuses(db: &Database, aliases: &[Alias], deadline: &Deadline) -> HashMap<String, TargetStatus> {
+    let check = db.path_check();
+    aliases
+        .iter()
+        .map(|alias| {
+            if check == PathCheck::Skip {
+                return (alias.name.clone(), TargetStatus::Unknown);
+            }
+            let status = match check.run_within(&alias.resolved_path(), alias.kind, deadline) {
+                Err(AliasError::DirectoryNotFound(_) | AliasError::FileNotFound(_)) => TargetStatus::Missing,
+                Err(AliasError::CheckTimedOut(..) | AliasError::CheckCancelled(_)) => TargetStatus::Unknown,
+                // Anything else is there, even if it can't be entered
+                _ => TargetStatus::Present,
+            };
+            (alias.name.clone(), status)
+        })
+        .collect()
+}
+
+/// Marker for the status column; plain words outside the unicode style
+fn status_marker(status: TargetStatus, style: TableStyle) -> &'static str {
+    match (status, style) {
+        (TargetStatus::Present, TableStyle::Unicode) => "✓",
+        (TargetStatus::Missing, TableStyle::Unicode) => "✗ missing",
+        (TargetStatus::Unknown, TableStyle::Unicode) => "? unknown",
+        (TargetStatus::Present, _) => "ok",
+        (TargetStatus::Missing, _) => "missing",
+        (TargetStatus::Unknown, _) => "unknown",
+    }
+}
+
 /// List all aliases with default options (uses config for display settings)
 pub fn list(db: &Database, config: &Config) -> Result<(), Box<dyn std::error::Error>> {
     list_with_options(db, config, None, None)
@@ -118,7 +570,9 @@ pub fn list(db: &Database, config: &Config) -> Result<(), Box<dyn std::error::Er
 
 /// List only alias names (one per line, for shell completion)
 pub fn list_names(db: &Database) -> Result<(), Box<dyn std::error::Error>> {
-    let mut names: Vec<_> = db.names().collect();
+    // Feeds the fzf picker and completion, where expired aliases are no use
+    let now = Utc::now();
+    let mut names: Vec<_> = db.all().filter(|a| !a.is_expired(now)).map(|a| a.name.as_str()).collect();
     names.sort();
 
     for name in names {
@@ -128,10 +582,28 @@ pub fn list_names(db: &Database) -> Result<(), Box<dyn std::error::Error>> {
     Ok(())
 }
 
+/// Completion candidates with descriptions, one `value<TAB>description` per line
+///
+/// Only `aliases` (name and target path) is supported for now.
+pub fn list_completions(db: &Database, kind: &str) -> Result<(), Box<dyn std::error::Error>> {
+    if kind != "aliases" {
+        return Err(format!("unknown completion kind '{}' (expected: aliases)", kind).into());
+    }
+
+    // Expired aliases can't be jumped to, so don't offer them
+    let now = Utc::now();
+    let mut aliases: Vec<_> = db.all().filter(|a| !a.is_expired(now)).collect();
+    aliases.sort_by(|a, b| a.name.cmp(&b.name));
+    for alias in aliases {
+        println!("{}\t{}", alias.name, alias.resolved_path());
+    }
+
+    Ok(())
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
-    use crate::alias::Alias;
     use tempfile::tempdir;
 
     fn create_test_db_and_config() -> (Database, Config, tempfile::TempDir) {
@@ -150,6 +622,9 @@ mod tests {
         assert_eq!(SortOrder::from("USAGE"), SortOrder::Usage);
         assert_eq!(SortOrder::from("recent"), SortOrder::Recent);
         assert_eq!(SortOrder::from("RECENT"), SortOrder::Recent);
+        assert_eq!(SortOrder::from("created"), SortOrder::Created);
+        assert_eq!(SortOrder::from("PATH"), SortOrder::Path);
+        assert_eq!(SortOrder::from("size"), SortOrder::Size);
         assert_eq!(SortOrder::from("invalid"), SortOrder::Alpha); // default
     }
 
@@ -158,6 +633,9 @@ mod tests {
         assert_eq!(format!("{}", SortOrder::Alpha), "alpha");
         assert_eq!(format!("{}", SortOrder::Usage), "usage");
         assert_eq!(format!("{}", SortOrder::Recent), "recent");
+        assert_eq!(format!("{}", SortOrder::Created), "created");
+        assert_eq!(format!("{}", SortOrder::Path), "path");
+        assert_eq!(format!("{}", SortOrder::Size), "size");
     }
 
     #[test]
@@ -186,6 +664,15 @@ mod tests {
         assert!(result.is_ok());
     }
 
+    #[test]
+    fn test_list_completions() {
+        let (mut db, _config, _dir) = create_test_db_and_config();
+        db.insert(Alias::new("alpha", "/tmp/a").unwrap());
+
+        assert!(list_completions(&db, "aliases").is_ok());
+        assert!(list_completions(&db, "paths").is_err());
+    }
+
     #[test]
     fn test_list_with_sort_usage() {
         let (mut db, config, _dir) = create_test_db_and_config();
@@ -224,6 +711,248 @@ mod tests {
         assert!(result.is_ok());
     }
 
+    #[test]
+    fn test_status_marker() {
+        assert_eq!(status_marker(TargetStatus::Present, TableStyle::Unicode), "✓");
+        assert_eq!(status_marker(TargetStatus::Missing, TableStyle::Unicode), "✗ missing");
+        assert_eq!(status_marker(TargetStatus::Present, TableStyle::Ascii), "ok");
+        assert_eq!(status_marker(TargetStatus::Missing, TableStyle::Minimal), "missing");
+        assert_eq!(status_marker(TargetStatus::Unknown, TableStyle::Ascii), "unknown");
+    }
+
+    #[test]
+    fn test_target_statuses() {
+        let (mut db, _config, dir) = create_test_db_and_config();
+        db.insert(Alias::new("present", dir.path().to_str().unwrap()).unwrap());
+        db.insert(Alias::new("gone", "/nonexistent/path/12345").unwrap());
+        let aliases: Vec<Alias> = db.all().cloned().collect();
+
+        let statuses = target_statuses(&db, &aliases, &Deadline::from_ms(5000));
+        assert_eq!(statuses["present"], TargetStatus::Present);
+        assert_eq!(statuses["gone"], TargetStatus::Missing);
+
+        // Checks given up on (or skipped) are unknown, not missing
+        let (deadline, cancel) = Deadline::default().with_cancel();
+        cancel.cancel();
+        let statuses = target_statuses(&db, &aliases, &deadline);
+        assert!(statuses.values().all(|s| *s == TargetStatus::Unknown));
+        db.set_path_check(PathCheck::Skip);
+        let statuses = target_statuses(&db, &aliases, &Deadline::default());
+        assert_eq!(statuses["gone"], TargetStatus::Unknown);
+    }
+
+    #[test]
+    fn test_list_broken_only() {
+        let (mut db, config, dir) = create_test_db_and_config();
+        db.insert(Alias::new("present", dir.path().to_str().unwrap()).unwrap());
+        db.insert(Alias::new("gone", "/nonexistent/path/12345").unwrap());
+
+        let options = ListOptions {
+            broken_only: true,
+            ..Default::default()
+        };
+        let result = list_aliases(&db, &config, &options);
+        assert!(result.is_ok());
+    }
+
+    #[test]
+    fn test_list_long() {
+        let (mut db, mut config, dir) = create_test_db_and_config();
+        config.user.display.show_stats = false;
+        config.user.display.show_tags = false;
+        let mut alias = Alias::new("proj", dir.path().to_str().unwrap()).unwrap();
+        alias.add_tag("work");
+        alias.record_use();
+        db.insert(alias);
+
+        let options = ListOptions {
+            long: true,
+            ..Default::default()
+        };
+        let result = list_aliases(&db, &config, &options);
+        assert!(result.is_ok());
+    }
+
+    #[test]
+    fn test_group_by_from_str() {
+        assert_eq!(GroupBy::from_str("tag"), Ok(GroupBy::Tag));
+        assert_eq!(GroupBy::from_str("TAGS"), Ok(GroupBy::Tag));
+        assert_eq!(GroupBy::from_str("path"), Ok(GroupBy::Path(DEFAULT_GROUP_DEPTH)));
+        assert_eq!(GroupBy::from_str("path:3"), Ok(GroupBy::Path(3)));
+        assert!(GroupBy::from_str("path:0").is_err());
+        assert!(GroupBy::from_str("tag:2").is_err());
+        assert!(GroupBy::from_str("color").is_err());
+    }
+
+    #[test]
+    fn test_group_by_tag_tree() {
+        let mut api = Alias::new("api", "/srv/api").unwrap();
+        api.add_tag("work");
+        api.add_tag("rust");
+        let mut web = Alias::new("web", "/srv/web").unwrap();
+        web.add_tag("work");
+        let misc = Alias::new("misc", "/srv/misc").unwrap();
+
+        let aliases = vec![&api, &misc, &web];
+        let groups = group_aliases(&aliases, GroupBy::Tag);
+        let names: Vec<&str> = groups.iter().map(|(g, _)| g.as_str()).collect();
+        assert_eq!(names, vec!["rust", "work", "untagged"]);
+
+        let tree = render_tree(&groups, TableStyle::Ascii, None);
+        assert_eq!(
+            tree,
+            "rust (1)\n\
+             `-- api   /srv/api\n\
+             work (2)\n\
+             |-- api   /srv/api\n\
+             `-- web   /srv/web\n\
+             untagged (1)\n\
+             `-- misc  /srv/misc\n"
+        );
+    }
+
+    #[test]
+    fn test_group_by_path_tree() {
+        let api = Alias::new("api", "/srv/code/api").unwrap();
+        let api2 = Alias::new("api2", "/srv/code/api").unwrap();
+        let web = Alias::new("web", "/srv/code/web").unwrap();
+        let logs = Alias::new("logs", "/var/log").unwrap();
+
+        let aliases = vec![&api, &api2, &logs, &web];
+        let groups = group_aliases(&aliases, GroupBy::Path(DEFAULT_GROUP_DEPTH));
+        let tree = render_tree(&groups, TableStyle::Unicode, None);
+        assert_eq!(
+            tree,
+            "/srv/code (3)\n\
+             ├── api   api\n\
+             ├── api2  api\n\
+             └── web   web\n\
+             /var (1)\n\
+             └── logs  log\n"
+        );
+    }
+
+    #[test]
+    fn test_group_by_path_shared_location() {
+        let api = Alias::new("api", "/srv/shop/services/api").unwrap();
+        let front = Alias::new("front", "/srv/shop/web/frontend").unwrap();
+        let shop = Alias::new("shop", "/srv/shop").unwrap();
+        let blog = Alias::new("blog", "/home/me/blog").unwrap();
+        let aliases = vec![&api, &front, &shop, &blog];
+
+        let groups = group_aliases(&aliases, GroupBy::Path(2));
+        let names: Vec<&str> = groups.iter().map(|(g, _)| g.as_str()).collect();
+        assert_eq!(names, vec!["/home/me", "/srv"]);
+        let shown: Vec<&str> = groups[1].1.iter().map(|(_, s)| s.as_str()).collect();
+        assert_eq!(shown, vec!["shop/services/api", "shop/web/frontend", "shop"]);
+
+        // Without the project root alias the group starts at the project
+        let groups = group_aliases(&[&api, &front], GroupBy::Path(2));
+        assert_eq!(groups[0].0, "/srv/shop");
+        let shown: Vec<&str> = groups[0].1.iter().map(|(_, s)| s.as_str()).collect();
+        assert_eq!(shown, vec!["services/api", "web/frontend"]);
+    }
+
+    #[test]
+    fn test_list_with_path_filter() {
+        let (mut db, config, _dir) = create_test_db_and_config();
+        db.insert(Alias::new("a", "/srv/web").unwrap());
+        db.insert(Alias::new("b", "/opt/tools").unwrap());
+
+        let options = ListOptions {
+            path: Some("/srv/**".to_string()),
+            ..Default::default()
+        };
+        assert!(list_aliases(&db, &config, &options).is_ok());
+    }
+
+    #[test]
+    fn test_list_with_namespace_filter() {
+        let (mut db, config, _dir) = create_test_db_and_config();
+        db.insert(Alias::new("work:api", "/srv/a").unwrap());
+        db.insert(Alias::new("api", "/srv/b").unwrap());
+
+        let options = ListOptions {
+            namespace: Some("work".to_string()),
+            ..Default::default()
+        };
+        assert!(list_aliases(&db, &config, &options).is_ok());
+    }
+
+    #[test]
+    fn test_list_with_regex_filter() {
+        let (mut db, config, _dir) = create_test_db_and_config();
+        db.insert(Alias::new("client-a", "/srv/a").unwrap());
+        db.insert(Alias::new("internal", "/srv/client-b").unwrap());
+
+        let options = ListOptions {
+            regex: Some("^client-".to_string()),
+            regex_paths: true,
+            ..Default::default()
+        };
+        assert!(list_aliases(&db, &config, &options).is_ok());
+    }
+
+    #[test]
+    fn test_list_with_invalid_regex() {
+        let (db, config, _dir) = create_test_db_and_config();
+        let options = ListOptions {
+            regex: Some("(".to_string()),
+            ..Default::default()
+        };
+        let err = list_aliases(&db, &config, &options).unwrap_err();
+        assert!(err.to_string().contains("invalid --regex pattern"));
+    }
+
+    #[test]
+    fn test_list_with_limit_and_offset() {
+        let (mut db, config, _dir) = create_test_db_and_config();
+        for name in ["a", "b", "c"] {
+            db.insert(Alias::new(name, "/tmp").unwrap());
+        }
+
+        let options = ListOptions {
+            limit: Some(1),
+            offset: 1,
+            ..Default::default()
+        };
+        assert!(list_aliases(&db, &config, &options).is_ok());
+        // Rows keep their position in the whole listing
+        assert_eq!(listing::expand_shortcut(&config.listing_path, "%2").unwrap(), "b");
+        assert!(listing::expand_shortcut(&config.listing_path, "%1").is_err());
+
+        // Offset past the end prints a message, not an error
+        let options = ListOptions {
+            offset: 10,
+            ..Default::default()
+        };
+        assert!(list_aliases(&db, &config, &options).is_ok());
+        assert!(listing::expand_shortcut(&config.listing_path, "%2").is_err());
+    }
+
+    #[test]
+    fn test_listing_saved_or_cleared_on_every_path() {
+        let (mut db, config, _dir) = create_test_db_and_config();
+        for name in ["a", "b"] {
+            db.insert(Alias::new(name, "/tmp").unwrap());
+        }
+        let shortcut = |n: &str| listing::expand_shortcut(&config.listing_path, n);
+
+        let print0 = ListOptions { print0: true, reverse: true, ..Default::default() };
+        list_aliases(&db, &config, &print0).unwrap();
+        assert_eq!(shortcut("%1").unwrap(), "b");
+
+        let tree = ListOptions { group: Some(GroupBy::Tag), ..Default::default() };
+        list_aliases(&db, &config, &tree).unwrap();
+        assert!(shortcut("%1").is_err());
+
+        list_aliases(&db, &config, &ListOptions::default()).unwrap();
+        assert_eq!(shortcut("%1").unwrap(), "a");
+        let none = ListOptions { filter: Some("missing".to_string()), ..Default::default() };
+        list_aliases(&db, &config, &none).unwrap();
+        assert!(shortcut("%1").is_err());
+    }
+
     #[test]
     fn test_list_filter_by_nonexistent_tag() {
         let (mut db, config, _dir) = create_test_db_and_config();
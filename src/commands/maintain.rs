@@ -0,0 +1,194 @@
+//! Maintain command: unattended housekeeping that writes a report file
+//!
+//! Each run lists aliases with missing directories (like `goto -c --dry-run`,
+//! nothing is removed) and rotates the database backups: when the database
+//! changed since the newest backup, the backups shift up one and it becomes
+//! the new `.1`. Then it does what `goto --gc` does: compacts the navigation
+//! log and drops surplus backups. The result replaces
+//! [`REPORT_FILE`] next to the database; nothing is printed unless a run fails.
+//! Without an interval it runs once, for a systemd timer or cron.
+
+use chrono::{DateTime, Duration, Utc};
+use std::error::Error;
+use std::fmt::Write;
+use std::fs;
+use std::thread;
+
+use crate::commands::cleanup::find_invalid;
+use crate::commands::du::format_size;
+use crate::commands::gc::{self, GcReport};
+use crate::config::Config;
+use crate::database::{rotate_backups_if_changed, Database};
+use crate::events;
+
+/// File name of the report, next to the database
+pub const REPORT_FILE: &str = "maintenance_report";
+
+/// What one maintenance run found and did
+#[derive(Debug)]
+pub struct MaintenanceReport {
+    pub at: DateTime<Utc>,
+    /// Aliases whose directory is missing, with their paths
+    pub missing: Vec<(String, String)>,
+    /// Aliases whose directory can't be entered
+    pub denied: Vec<String>,
+    /// Whether a new backup was taken (the database changed since the last one)
+    pub rotated: bool,
+    pub gc: GcReport,
+}
+
+/// Run maintenance once, or every `interval` until killed
+///
+/// In the loop a failed run is reported on stderr and retried next time; a
+/// single run returns the error so a systemd unit shows as failed.
+pub fn maintain(config: &Config, interval: Option<Duration>, keep_days: usize) -> Result<(), Box<dyn Error>> {
+    let Some(interval) = interval else {
+        return run(config, Utc::now(), keep_days).map(|_| ());
+    };
+    let pause = interval.to_std()?;
+    loop {
+        if let Err(e) = run(config, Utc::now(), keep_days) {
+            eprintln!("goto --maintain: {}", e);
+        }
+        thread::sleep(pause);
+    }
+}
+
+/// One maintenance run at `now`, writing the report file
+pub fn run(config: &Config, now: DateTime<Utc>, keep_days: usize) -> Result<MaintenanceReport, Box<dyn Error>> {
+    // Reloaded every run so the check sees aliases added since the last one
+    let db = Database::load(config)?;
+    let (invalid, denied) = find_invalid(&db);
+    let missing = invalid
+        .into_iter()
+        .map(|name| {
+            let path = db.get(&name).map(|a| a.resolved_path()).unwrap_or_default();
+            (name, path)
+        })
+        .collect();
+    drop(db);
+
+    let rotated = rotate_backups_if_changed(&config.aliases_path, config.user.storage.backups)?;
+    let gc = gc::collect(config, now - Duration::days(keep_days as i64), false)?;
+    let report = MaintenanceReport { at: now, missing, denied, rotated, gc };
+
+    let path = config.database_path.join(REPORT_FILE);
+    let tmp = config.database_path.join(format!("{}.tmp", REPORT_FILE));
+    fs::write(&tmp, format_report(&report))?;
+    fs::rename(&tmp, &path)?;
+    Ok(report)
+}
+
+/// Render a report as Markdown
+pub fn format_report(report: &MaintenanceReport) -> String {
+    let mut out = String::new();
+    writeln!(out, "## goto maintenance: {}\n", report.at.format("%Y-%m-%d %H:%M:%S UTC")).unwrap();
+
+    writeln!(out, "### Missing directories (not removed; run 'goto -c')\n").unwrap();
+    if report.missing.is_empty() {
+        writeln!(out, "- none").unwrap();
+    }
+    for (name, path) in &report.missing {
+        writeln!(out, "- {} -> {}", name, path).unwrap();
+    }
+    if !report.denied.is_empty() {
+        writeln!(out, "\nCan't be entered (permission denied): {}", report.denied.join(", ")).unwrap();
+    }
+
+    writeln!(out, "\n### Housekeeping\n").unwrap();
+    let (before, after) = report.gc.log_lines;
+    writeln!(out, "- {}: {} -> {} lines", events::FILE_NAME, before, after).unwrap();
+    if report.rotated {
+        writeln!(out, "- Backups rotated: the database was saved as backup 1").unwrap();
+    } else {
+        writeln!(out, "- Backups rotated: no, unchanged since the newest backup").unwrap();
+    }
+    writeln!(out, "- Backups removed: {}", report.gc.removed_backups.len()).unwrap();
+    writeln!(out, "- Reclaimed: {}", format_size(report.gc.reclaimed())).unwrap();
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::alias::Alias;
+    use crate::database::backup_path;
+    use std::path::Path;
+    use tempfile::tempdir;
+
+    fn test_config(dir: &Path) -> Config {
+        Config {
+            database_path: dir.to_path_buf(),
+            stack_path: dir.join("goto_stack"),
+            history_path: dir.join("dir_history"),
+            context_path: dir.join("context"),
+            listing_path: dir.join("last_listing"),
+            config_path: dir.join("config.toml"),
+            aliases_path: dir.join("aliases.toml"),
+            user: Default::default(),
+        }
+    }
+
+    #[test]
+    fn test_run_writes_report() {
+        let dir = tempdir().unwrap();
+        let mut config = test_config(dir.path());
+        config.user.storage.backups = 1;
+        let path = dir.path().to_string_lossy().to_string();
+
+        let mut db = Database::load(&config).unwrap();
+        db.insert(Alias::new("here", &path).unwrap());
+        db.insert(Alias::new("gone", "/nonexistent/path/12345").unwrap());
+        db.save().unwrap();
+        drop(db);
+        fs::write(backup_path(&config.aliases_path, 2), "").unwrap();
+
+        let report = run(&config, Utc::now(), gc::DEFAULT_KEEP_DAYS).unwrap();
+        assert_eq!(report.missing, vec![("gone".to_string(), "/nonexistent/path/12345".to_string())]);
+        assert!(report.rotated);
+        assert_eq!(
+            fs::read(backup_path(&config.aliases_path, 1)).unwrap(),
+            fs::read(&config.aliases_path).unwrap()
+        );
+        assert_eq!(report.gc.removed_backups.len(), 1);
+        // Nothing is removed from the database
+        assert!(Database::load(&config).unwrap().contains("gone"));
+
+        let text = fs::read_to_string(dir.path().join(REPORT_FILE)).unwrap();
+        assert!(text.starts_with("## goto maintenance: "));
+        assert!(text.contains("- gone -> /nonexistent/path/12345"));
+        assert!(text.contains("- Backups removed: 1"));
+
+        // Nothing changed since, so the next run keeps the backups as they are
+        assert!(!run(&config, Utc::now(), gc::DEFAULT_KEEP_DAYS).unwrap().rotated);
+    }
+
+    #[test]
+    fn test_run_shifts_backups() {
+        let dir = tempdir().unwrap();
+        let mut config = test_config(dir.path());
+        config.user.storage.backups = 3;
+        fs::write(&config.aliases_path, "# newest\n").unwrap();
+        fs::write(backup_path(&config.aliases_path, 1), "# older\n").unwrap();
+        fs::write(backup_path(&config.aliases_path, 2), "# oldest\n").unwrap();
+
+        assert!(run(&config, Utc::now(), gc::DEFAULT_KEEP_DAYS).unwrap().rotated);
+        let backup = |n| fs::read_to_string(backup_path(&config.aliases_path, n)).unwrap();
+        assert_eq!((backup(1), backup(2), backup(3)), ("# newest\n".into(), "# older\n".into(), "# oldest\n".into()));
+    }
+
+    #[test]
+    fn test_format_empty_report() {
+        let report = MaintenanceReport {
+            at: Utc::now(),
+            missing: Vec::new(),
+            denied: Vec::new(),
+            rotated: false,
+            gc: GcReport::default(),
+        };
+        let text = format_report(&report);
+        assert!(text.contains("### Missing directories (not removed; run 'goto -c')\n\n- none"));
+        assert!(!text.contains("permission denied"));
+        assert!(text.contains("- Reclaimed: 0 B"));
+    }
+}
@@ -0,0 +1,163 @@
+//! Man page command: render goto(1) in roff from the help text
+
+use crate::cli::HELP;
+use crate::commands::update::BuildInfo;
+
+/// Sections that only exist in the man page
+const EXIT_STATUS: &[(&str, &str)] = &[
+    ("0", "Success"),
+    ("1", "Alias not found, stack empty, or cancelled"),
+    ("2", "Directory no longer exists"),
+    ("3", "Invalid alias or tag format"),
+    ("4", "Alias already exists"),
+    ("5", "System or I/O error"),
+    ("6", "Permission denied (the directory exists but can't be entered)"),
+    ("7", "Path not allowed by [register] in config.toml"),
+];
+
+const FILES: &[(&str, &str)] = &[
+    ("~/.config/goto/aliases.toml", "Alias database"),
+    ("~/.config/goto/config.toml", "User settings"),
+    ("~/.config/goto/goto_stack", "Directory stack"),
+];
+
+/// Print the man page to stdout (`goto --generate-man > goto.1`)
+pub fn generate_man() {
+    let info = BuildInfo::current();
+    let date = info.build_date.get(..10).unwrap_or(&info.build_date);
+    print!("{}", render(HELP, info.version, date));
+}
+
+/// Escape text for roff: backslashes, and a leading control character
+fn escape(text: &str) -> String {
+    let text = text.replace('\\', "\\e");
+    if text.starts_with(['.', '\'']) {
+        format!("\\&{}", text)
+    } else {
+        text
+    }
+}
+
+/// Format a usage term: bold, with `<placeholders>` in italics and literal dashes
+fn term(text: &str) -> String {
+    let mut out = String::from("\\fB");
+    for c in escape(text).chars() {
+        match c {
+            '<' => out.push_str("\\fI"),
+            '>' => out.push_str("\\fB"),
+            '-' => out.push_str("\\-"),
+            c => out.push(c),
+        }
+    }
+    out.push_str("\\fR");
+    out
+}
+
+/// Split an entry line into its usage and description at the first run of two spaces
+fn split_entry(line: &str) -> (&str, &str) {
+    match line.find("  ") {
+        Some(i) => (&line[..i], line[i..].trim_start()),
+        None => (line, ""),
+    }
+}
+
+/// Render help text in the format of [`HELP`] as a roff man page
+pub fn render(help: &str, version: &str, date: &str) -> String {
+    let mut lines = help.lines();
+    let title = lines.next().unwrap_or_default();
+    let (name, summary) = title.split_once(" - ").unwrap_or((title, ""));
+
+    let mut out = format!(".TH GOTO 1 \"{}\" \"goto {}\" \"User Commands\"\n", date, version);
+    out.push_str(&format!(".SH NAME\n{} \\- {}\n", name, escape(summary)));
+    out.push_str(".SH SYNOPSIS\n.B goto\n.I alias\n.br\n.B goto\n.I option\n[\\fIarguments\\fR]\n");
+
+    let mut in_options = false;
+    for line in lines {
+        if line.trim().is_empty() {
+            continue;
+        }
+        if !line.starts_with(' ') {
+            let heading = line.trim_end_matches(':');
+            if heading == "Usage" {
+                out.push_str(".SH COMMANDS\n");
+            } else if heading.contains("(use with") {
+                if !in_options {
+                    out.push_str(".SH OPTIONS\n");
+                    in_options = true;
+                }
+                out.push_str(&format!(".SS {}\n", escape(heading)));
+            } else {
+                // Upper-case the title but not a note like "(edit ~/.config/...)"
+                let (title, note) = heading.split_at(heading.find(" (").unwrap_or(heading.len()));
+                out.push_str(&format!(".SH {}{}\n", escape(&title.to_uppercase()), escape(note)));
+            }
+            continue;
+        }
+
+        let entry = line.trim_start();
+        if let Some(item) = entry.strip_prefix("- ") {
+            out.push_str(&format!(".IP \\(bu 2\n{}\n", escape(item)));
+        } else if line.len() - entry.len() > 4 {
+            // A description wrapped onto its own line
+            out.push_str(&format!("{}\n", escape(entry)));
+        } else {
+            let (usage, description) = split_entry(entry);
+            out.push_str(&format!(".TP\n{}\n", term(usage)));
+            if !description.is_empty() {
+                out.push_str(&format!("{}\n", escape(description)));
+            }
+        }
+    }
+
+    out.push_str(".SH EXIT STATUS\n");
+    for (code, meaning) in EXIT_STATUS {
+        out.push_str(&format!(".TP\n.B {}\n{}\n", code, escape(meaning)));
+    }
+    out.push_str(".SH FILES\n");
+    for (path, what) in FILES {
+        out.push_str(&format!(".TP\n.I {}\n{}\n", path, what));
+    }
+    out.push_str(".SH ENVIRONMENT\n.TP\n.B GOTO_DB\nDirectory holding the database and config instead of ~/.config/goto\n");
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_render() {
+        let help = "goto - Navigate to aliased directories\n\nUsage:\n  goto <alias>    Navigate there\n\
+                    \nSort options (use with -l/--list):\n  --sort=alpha    Alphabetical\n\
+                    \x20                 and more\n\nTag rules:\n  - No spaces in tags\n\
+                    \nExamples:\n  goto -r dev ~/dev    Register 'dev' alias\n";
+        let page = render(help, "1.2.3", "2026-01-02");
+
+        assert!(page.starts_with(".TH GOTO 1 \"2026-01-02\" \"goto 1.2.3\""));
+        assert!(page.contains(".SH NAME\ngoto \\- Navigate to aliased directories\n"));
+        assert!(page.contains(".SH COMMANDS\n.TP\n\\fBgoto \\fIalias\\fB\\fR\nNavigate there\n"));
+        assert!(page.contains(".SH OPTIONS\n.SS Sort options (use with -l/--list)\n"));
+        assert!(page.contains("\\fB\\-\\-sort=alpha\\fR\nAlphabetical\nand more\n"));
+        assert!(page.contains(".SH TAG RULES\n.IP \\(bu 2\nNo spaces in tags\n"));
+        assert!(page.contains("Register 'dev' alias\n"));
+        let page = render("goto - x\nConfiguration (edit ~/.config):\n", "1", "d");
+        assert!(page.contains(".SH CONFIGURATION (edit ~/.config)\n"));
+        assert!(page.contains(".SH EXIT STATUS\n"));
+    }
+
+    #[test]
+    fn test_escape() {
+        assert_eq!(escape("'quoted'"), "\\&'quoted'");
+        assert_eq!(escape(".hidden"), "\\&.hidden");
+        assert_eq!(escape("a\\b"), "a\\eb");
+        assert_eq!(escape("plain"), "plain");
+    }
+
+    #[test]
+    fn test_render_help() {
+        let page = render(HELP, "0.0.0", "2026-01-01");
+        // Every entry of the real help text becomes a tagged paragraph
+        assert!(page.contains("\\fBgoto \\-l\\fR\nList all aliases\n"));
+        assert!(!page.lines().any(|l| l.starts_with('\'')));
+    }
+}
@@ -1,18 +1,43 @@
 //! Command implementations for the goto CLI
 
+pub mod audit;
+pub mod backup;
+pub mod batch;
+pub mod cdpath;
 pub mod cleanup;
+pub mod clipboard;
 pub mod config;
+pub mod context;
+pub mod digest;
+pub mod du;
+pub mod envfile;
+pub mod fsck;
+pub mod gc;
+pub mod gitstatus;
 pub mod import_export;
 pub mod install;
 pub mod list;
+pub mod maintain;
+pub mod man;
 pub mod navigate;
+pub mod open;
+pub mod projecttype;
 pub mod prune;
 pub mod register;
+pub mod scan;
+pub mod select;
+pub mod serve;
+pub mod session;
+pub mod setup;
+pub mod show;
 pub mod stack;
 pub mod stats;
+pub mod suggest;
 pub mod tags;
+pub mod tree;
 pub mod update;
+pub mod workspace;
 
 // Re-export commonly used types
-pub use import_export::{ImportResult, ImportStrategy};
+pub use import_export::{ExportFormat, ImportResult, ImportStrategy};
 pub use list::SortOrder;
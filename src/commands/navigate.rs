@@ -1,54 +1,101 @@
-//! Navigation commands: navigate, expand, completions
-
-use std::path::Path;
-
-use crate::alias::AliasError;
+//! Navigation commands: navigate, expand, which, root, completions
+
+use chrono::Utc;
+use std::collections::hash_map::RandomState;
+use std::hash::{BuildHasher, Hasher};
+use std::io::{self, IsTerminal};
+use std::path::{Path, PathBuf};
+
+use crate::alias::{Alias, AliasError, AliasKind};
+use crate::commands::register::{format_expiry, validate_and_normalize_tags};
+use crate::commands::setup::propose_name;
+use crate::config::{autotags_for, AutoTagRule};
 use crate::database::Database;
+use crate::deadline::Deadline;
 use crate::fuzzy;
-use crate::prompt_selection;
+use crate::hooks::run_pre_navigate;
+use crate::{confirm, print_record, prompt_selection};
+
+/// Candidates scoring below this (out of 1000) are never offered
+const FUZZY_MIN_SCORE: i32 = 300;
+
+/// Fuzzy candidates for a name that doesn't exist, best first; none with `--no-fuzzy`
+///
+/// The best one must also reach the database's fuzzy threshold for the "Did you
+/// mean" prompt.
+fn fuzzy_candidates(db: &Database, alias: &str) -> Vec<(String, i32)> {
+    if !db.fuzzy().enabled {
+        return Vec::new();
+    }
+    let now = Utc::now();
+    let names = db.all().filter(|a| !a.is_expired(now)).map(|a| a.name.as_str());
+    fuzzy::find_matches(alias, names)
+        .into_iter()
+        .take(db.fuzzy().max_suggestions)
+        .filter(|(_, score)| *score >= FUZZY_MIN_SCORE)
+        .map(|(name, score)| (name.to_string(), score))
+        .collect()
+}
 
 /// Navigate to an aliased directory
 /// Prints the path for the shell function to cd to
 ///
 /// Returns the path on success, which should be printed to stdout for the shell to cd to.
+///
+/// `alias/sub/dir` jumps to a directory below the alias's path; alias names
+/// can't contain '/', so the part before the first one is always the alias.
+/// `alias..` jumps to the directory containing it, unless an alias is really
+/// named like that.
 pub fn navigate(db: &mut Database, alias: &str) -> Result<(), Box<dyn std::error::Error>> {
-    if let Some(entry) = db.get(alias) {
-        // Verify directory exists
-        let path = Path::new(&entry.path);
-        if !path.exists() {
-            return Err(AliasError::DirectoryNotFound(entry.path.clone()).into());
-        }
-        if !path.is_dir() {
-            return Err(format!("not a directory: {}", entry.path).into());
-        }
+    navigate_within(db, alias, &Deadline::default())
+}
 
-        // Get the path before mutable borrow
-        let path_str = entry.path.clone();
+/// Like [`navigate`], giving up checking the target at `deadline` or when it is cancelled
+pub fn navigate_within(db: &mut Database, alias: &str, deadline: &Deadline) -> Result<(), Box<dyn std::error::Error>> {
+    let parent = alias.strip_suffix("..").filter(|name| !name.is_empty() && !name.contains('/'));
+    if let Some(name) = parent.filter(|_| !db.contains(&db.resolve_name(alias))) {
+        let name = db.resolve_name(name);
+        if !db.contains(&name) {
+            return Err(AliasError::NotFound(name).into());
+        }
+        return jump_to(db, &name, Some(".."), deadline);
+    }
 
-        // Record usage
-        db.record_usage(alias)?;
+    if let Some((name, subpath)) = alias.split_once('/') {
+        let name = db.resolve_name(name);
+        if !db.contains(&name) {
+            return Err(AliasError::NotFound(name).into());
+        }
+        return jump_to(db, &name, Some(subpath), deadline);
+    }
 
-        // Print path for shell to cd to
-        println!("{}", path_str);
-        db.save()?;
-        Ok(())
+    let alias = &db.resolve_name(alias);
+    if db.contains(alias) {
+        jump(db, alias, deadline)
     } else {
-        // Try fuzzy matching - get top 3 matches with minimum score
-        // Clone names to avoid borrow conflicts with db
-        let matches: Vec<(String, i32)> = fuzzy::find_matches(alias, db.names())
-            .into_iter()
-            .take(3)
-            .filter(|(_, score)| *score >= 300) // Filter low-confidence matches
-            .map(|(name, score)| (name.to_string(), score))
-            .collect();
+        // Try fuzzy matching; names are cloned to avoid borrow conflicts with db
+        let matches = fuzzy_candidates(db, alias);
 
         if matches.is_empty() {
-            return Err(format!("alias '{}' not found", alias).into());
+            return Err(AliasError::NotFound(alias.to_string()).into());
         }
 
-        // Check if best match has minimum confidence (>= 0.7 similarity = 700 score)
-        if matches[0].1 < 700 {
-            return Err(format!("alias '{}' not found", alias).into());
+        // Check if best match has minimum confidence
+        if matches[0].1 < db.fuzzy().min_score() {
+            return Err(AliasError::NotFound(alias.to_string()).into());
+        }
+
+        if let Some(name) = db.fuzzy().accepted(&matches) {
+            eprintln!("Alias '{}' not found, assuming you meant '{}'", alias, name);
+            return jump(db, name, deadline);
+        }
+
+        // confirm() says yes by itself off a terminal, so only ask on one
+        if let Some(name) = db.fuzzy().to_confirm(&matches).filter(|_| io::stdin().is_terminal()) {
+            if confirm(&format!("Alias '{}' not found. Did you mean '{}'?", alias, name), true)? {
+                return jump(db, name, deadline);
+            }
+            return Err(AliasError::Cancelled("Navigation".to_string()).into());
         }
 
         eprintln!("Alias '{}' not found. Did you mean:", alias);
@@ -58,39 +105,273 @@ pub fn navigate(db: &mut Database, alias: &str) -> Result<(), Box<dyn std::error
 
         match prompt_selection(&names, Some(&scores))? {
             Some(idx) => {
-                let selected = &matches[idx].0;
                 // Navigate to selected alias
-                if let Some(entry) = db.get(selected) {
-                    let path = Path::new(&entry.path);
-                    if !path.exists() {
-                        return Err(AliasError::DirectoryNotFound(entry.path.clone()).into());
-                    }
-                    if !path.is_dir() {
-                        return Err(format!("not a directory: {}", entry.path).into());
-                    }
-                    let path_str = entry.path.clone();
-                    db.record_usage(selected)?;
-                    println!("{}", path_str);
-                    db.save()?;
-                    Ok(())
-                } else {
-                    Err(format!("alias '{}' not found", selected).into())
-                }
+                jump(db, &matches[idx].0, deadline)
             }
-            None => Err("Navigation cancelled".into()),
+            None => Err(AliasError::Cancelled("Navigation".to_string()).into()),
         }
     }
 }
 
+/// Navigate to a random alias, optionally only among those matching a `--filter=` value
+pub fn random(db: &mut Database, filter: Option<&str>) -> Result<(), Box<dyn std::error::Error>> {
+    let now = Utc::now();
+    let mut names: Vec<String> = db
+        .all()
+        .filter(|a| filter.is_none_or(|f| a.matches_filter(f)) && !a.is_expired(now))
+        .map(|a| a.name.clone())
+        .collect();
+    if names.is_empty() {
+        return Err(match filter {
+            Some(f) => AliasError::NoneTagged(f.to_string()).into(),
+            None => "no aliases registered yet".into(),
+        });
+    }
+    names.sort();
+
+    // std's hasher keys are seeded randomly per process; plenty for picking a directory
+    let seed = RandomState::new().build_hasher().finish();
+    let name = &names[(seed % names.len() as u64) as usize];
+    jump(db, name, &Deadline::default())
+}
+
+/// Check the target, run the pre-navigate hooks, record usage and print the path
+fn jump(db: &mut Database, name: &str, deadline: &Deadline) -> Result<(), Box<dyn std::error::Error>> {
+    jump_to(db, name, None, deadline)
+}
+
+/// Like [`jump`], but to `subpath` below the alias's directory when given
+///
+/// A `subpath` of `..` goes to the directory containing the alias's target
+/// instead, which for a file bookmark is the directory holding the file.
+fn jump_to(
+    db: &mut Database,
+    name: &str,
+    subpath: Option<&str>,
+    deadline: &Deadline,
+) -> Result<(), Box<dyn std::error::Error>> {
+    let entry = db.get(name).ok_or_else(|| AliasError::NotFound(name.to_string()))?;
+    if let Some(at) = entry.expires_at.filter(|_| entry.is_expired(Utc::now())) {
+        return Err(AliasError::Expired(name.to_string(), format_expiry(at)).into());
+    }
+    // Resolve host-specific path before mutable borrow; a file bookmark goes to its directory
+    let mut path_str = entry.directory();
+
+    // Verify the directory (or file) exists and can be entered
+    db.check_target_within(entry, deadline)?;
+    if let Some("..") = subpath.map(|s| s.trim_matches('/')) {
+        let target = entry.resolved_path();
+        path_str = Path::new(&target)
+            .parent()
+            .ok_or_else(|| format!("alias '{}' points to {}, which has no parent", name, target))?
+            .to_string_lossy()
+            .to_string();
+    } else if let Some(sub) = subpath.map(|s| s.trim_matches('/')).filter(|s| !s.is_empty()) {
+        if entry.kind == AliasKind::File {
+            return Err(format!("alias '{}' is a file bookmark; it has no subdirectories", name).into());
+        }
+        path_str = Path::new(&path_str).join(sub).to_string_lossy().to_string();
+        // An alias on a slow mount skips the check for everything below it too
+        if !entry.skip_check {
+            db.path_check().run_within(&path_str, AliasKind::Dir, deadline)?;
+        }
+    }
+    run_pre_navigate(db.hooks(), name, &path_str)?;
+
+    // Record usage
+    db.record_usage(name)?;
+
+    // Print path for shell to cd to
+    println!("{}", path_str);
+    db.save()?;
+    Ok(())
+}
+
 /// Expand an alias to its path without navigating (no side effects)
 /// This is for scripts that need the raw path without recording usage.
-pub fn expand(db: &Database, alias: &str) -> Result<(), Box<dyn std::error::Error>> {
-    if let Some(entry) = db.get(alias) {
-        println!("{}", entry.path);
-        Ok(())
-    } else {
-        Err(format!("alias '{}' not found", alias).into())
+pub fn expand(db: &Database, alias: &str, print0: bool) -> Result<(), Box<dyn std::error::Error>> {
+    print_record(&expanded_path(db, alias)?, print0);
+    Ok(())
+}
+
+/// Like [`expand`], printing the path as a `file://` URI
+pub fn expand_uri(db: &Database, alias: &str, print0: bool) -> Result<(), Box<dyn std::error::Error>> {
+    print_record(&file_uri(&expanded_path(db, alias)?), print0);
+    Ok(())
+}
+
+/// The path `expand` prints for `alias`
+fn expanded_path(db: &Database, alias: &str) -> Result<String, Box<dyn std::error::Error>> {
+    db.get(&db.resolve_name(alias))
+        .map(|entry| entry.resolved_path())
+        .ok_or_else(|| AliasError::NotFound(alias.to_string()).into())
+}
+
+/// A `file://` URI for an absolute path, percent-encoding every byte of the
+/// UTF-8 form except unreserved characters and '/'
+///
+/// Windows paths (`C:\x`) become `file:///C:/x`.
+pub fn file_uri(path: &str) -> String {
+    let bytes = path.as_bytes();
+    let drive = bytes.len() >= 2 && bytes[0].is_ascii_alphabetic() && bytes[1] == b':';
+    let path = if drive { path.replace('\\', "/") } else { path.to_string() };
+    let mut uri = String::from("file://");
+    if !path.starts_with('/') {
+        uri.push('/');
+    }
+    for (i, byte) in path.bytes().enumerate() {
+        if byte.is_ascii_alphanumeric() || b"-._~/".contains(&byte) || (drive && i == 1) {
+            uri.push(byte as char);
+        } else {
+            uri.push_str(&format!("%{:02X}", byte));
+        }
+    }
+    uri
+}
+
+/// How a name typed on the command line resolves, step by step
+#[derive(Debug, Default, PartialEq)]
+pub struct Resolution {
+    /// The name is an alias as typed
+    pub exact: bool,
+    /// The default namespace (or context) tried, and whether `ns:name` exists
+    pub namespace: Option<(String, bool)>,
+    /// Fuzzy candidates with scores (0-1000), only looked at without a direct match
+    pub candidates: Vec<(String, i32)>,
+    /// The alias a jump would use; a fuzzy match still asks first
+    pub resolved: Option<String>,
+}
+
+/// Work out how `alias` would resolve, the same way `navigate` does
+pub fn resolve(db: &Database, alias: &str) -> Resolution {
+    let mut resolution = Resolution {
+        exact: db.contains(alias),
+        ..Default::default()
+    };
+    if resolution.exact {
+        resolution.resolved = Some(alias.to_string());
+        return resolution;
+    }
+
+    if let Some(ns) = db.default_namespace().filter(|_| !alias.contains(':')) {
+        let qualified = format!("{}:{}", ns, alias);
+        let found = db.contains(&qualified);
+        resolution.namespace = Some((ns.to_string(), found));
+        if found {
+            resolution.resolved = Some(qualified);
+            return resolution;
+        }
     }
+
+    resolution.candidates = fuzzy_candidates(db, alias);
+    resolution.resolved = resolution
+        .candidates
+        .first()
+        .filter(|(_, score)| *score >= db.fuzzy().min_score())
+        .map(|(name, _)| name.clone());
+    resolution
+}
+
+/// Explain how `alias` would resolve without navigating or recording usage
+pub fn which(db: &Database, alias: &str) -> Result<(), Box<dyn std::error::Error>> {
+    let resolution = resolve(db, alias);
+
+    println!("Exact match:     {}", if resolution.exact { "yes" } else { "no" });
+    match &resolution.namespace {
+        Some((ns, true)) => println!("Namespace:       {}:{}", ns, alias),
+        Some((ns, false)) => println!("Namespace:       no {}:{}", ns, alias),
+        None if !resolution.exact => println!("Namespace:       (no default namespace or context)"),
+        None => {}
+    }
+    if !resolution.exact && !resolution.namespace.as_ref().is_some_and(|(_, found)| *found) {
+        if !db.fuzzy().enabled {
+            println!("Fuzzy matches:   (disabled)");
+        } else if resolution.candidates.is_empty() {
+            println!("Fuzzy matches:   none");
+        } else {
+            println!("Fuzzy matches:");
+            for (name, score) in &resolution.candidates {
+                println!("  {:<16} {:.2}", name, *score as f64 / 1000.0);
+            }
+            if resolution.resolved.is_none() {
+                println!(
+                    "  (best score below {:.2}: nothing would be offered)",
+                    db.fuzzy().threshold
+                );
+            } else if let Some(name) = db.fuzzy().accepted(&resolution.candidates) {
+                println!("  (a jump would go straight to {})", name);
+            } else if let Some(name) = db.fuzzy().to_confirm(&resolution.candidates) {
+                println!("  (a jump would ask whether you meant {})", name);
+            } else {
+                println!("  (a jump would ask which one to use)");
+            }
+        }
+    }
+
+    let Some(name) = resolution.resolved else {
+        return Err(AliasError::NotFound(alias.to_string()).into());
+    };
+    let entry = db.get(&name).ok_or_else(|| AliasError::NotFound(name.clone()))?;
+    let path = entry.resolved_path();
+    let problem = match db.check_target(entry) {
+        Ok(()) if entry.skip_check => " (not checked)".to_string(),
+        Ok(()) => String::new(),
+        Err(e) => format!(" ({})", e),
+    };
+    println!("Resolves to:     {} -> {}{}", name, path, problem);
+    Ok(())
+}
+
+/// Entries that mark the top of a working copy
+const VCS_MARKERS: &[&str] = &[".git", ".hg"];
+
+/// The nearest directory at or above `start` holding a `.git` or `.hg`
+///
+/// `.git` may be a file, as in worktrees and submodules.
+pub fn find_vcs_root(start: &Path) -> Option<PathBuf> {
+    start
+        .ancestors()
+        .find(|dir| VCS_MARKERS.iter().any(|m| dir.join(m).exists()))
+        .map(Path::to_path_buf)
+}
+
+/// Print the repository root above the current directory for the shell to cd to
+///
+/// With `register`, the root also gets an alias (`name`, or one derived from
+/// the directory name) unless it has one already. Messages go to stderr, since
+/// stdout is the path.
+pub fn root(
+    db: &mut Database,
+    register: bool,
+    name: Option<&str>,
+    rules: &[AutoTagRule],
+) -> Result<(), Box<dyn std::error::Error>> {
+    let cwd = std::env::current_dir()?;
+    let root = find_vcs_root(&cwd)
+        .ok_or_else(|| AliasError::NoRepository(cwd.display().to_string()))?;
+    let path = root.to_string_lossy().to_string();
+
+    if register {
+        let existing = db.all().find(|a| a.path == path).map(|a| a.name.clone());
+        if let Some(existing) = existing {
+            eprintln!("'{}' already registered -> {}", existing, path);
+        } else {
+            let name = match name {
+                Some(name) => name.to_string(),
+                None => propose_name(db, &path).ok_or_else(|| format!("no usable alias name for {}", path))?,
+            };
+            if db.contains(&name) {
+                return Err(AliasError::AlreadyExists(name).into());
+            }
+            let tags = validate_and_normalize_tags(&autotags_for(rules, &path)?)?;
+            db.add_with_tags(Alias::new(&name, &path)?, tags)?;
+            db.save()?;
+            eprintln!("Registered '{}' -> {}", name, path);
+        }
+    }
+
+    println!("{}", path);
+    Ok(())
 }
 
 /// Generate completions for shell tab completion
@@ -116,6 +397,9 @@ pub fn completions(db: &Database, query: &str) -> Result<(), Box<dyn std::error:
 mod tests {
     use super::*;
     use crate::alias::Alias;
+    use crate::config::{Config, UserConfig};
+    use crate::fuzzy::FuzzyPolicy;
+    use crate::hooks::HooksConfig;
     use tempfile::{tempdir, NamedTempFile};
 
     fn create_test_db() -> (Database, NamedTempFile) {
@@ -133,17 +417,39 @@ mod tests {
     fn test_expand() {
         let (db, _file) = create_test_db();
         // Just verify it doesn't panic and returns Ok
-        let result = expand(&db, "projects");
+        let result = expand(&db, "projects", false);
         assert!(result.is_ok());
     }
 
     #[test]
     fn test_expand_not_found() {
         let (db, _file) = create_test_db();
-        let result = expand(&db, "nonexistent");
+        let result = expand(&db, "nonexistent", false);
         assert!(result.is_err());
     }
 
+    #[test]
+    fn test_resolve() {
+        let (mut db, _file) = create_test_db();
+        db.insert(Alias::new("work:api", "/srv/work/api").unwrap());
+
+        let exact = resolve(&db, "projects");
+        assert!(exact.exact);
+        assert_eq!(exact.resolved.as_deref(), Some("projects"));
+
+        assert_eq!(resolve(&db, "api").resolved, None);
+        db.set_default_namespace("work");
+        let namespaced = resolve(&db, "api");
+        assert_eq!(namespaced.namespace, Some(("work".to_string(), true)));
+        assert_eq!(namespaced.resolved.as_deref(), Some("work:api"));
+
+        let fuzzy = resolve(&db, "projcts");
+        assert_eq!(fuzzy.candidates[0].0, "projects");
+        assert_eq!(fuzzy.resolved.as_deref(), Some("projects"));
+
+        assert!(which(&db, "zzzzzz").is_err());
+    }
+
     #[test]
     fn test_completions() {
         let (db, _file) = create_test_db();
@@ -171,6 +477,187 @@ mod tests {
         assert!(alias.last_used.is_some());
     }
 
+    #[test]
+    fn test_navigate_subpath() {
+        let (mut db, _dir) = create_test_db();
+        let target = tempdir().unwrap();
+        std::fs::create_dir_all(target.path().join("src/bin")).unwrap();
+        db.insert(Alias::new("proj", target.path().to_str().unwrap()).unwrap());
+
+        assert!(navigate(&mut db, "proj/src/bin").is_ok());
+        assert!(navigate(&mut db, "proj/src/").is_ok());
+        assert_eq!(db.get("proj").unwrap().use_count, 2);
+
+        let err = navigate(&mut db, "proj/missing").unwrap_err().to_string();
+        assert!(err.contains("directory does not exist"), "{}", err);
+        let err = navigate(&mut db, "nope/src").unwrap_err().to_string();
+        assert!(err.contains("alias 'nope' not found"), "{}", err);
+        assert_eq!(db.get("proj").unwrap().use_count, 2);
+    }
+
+    #[test]
+    fn test_navigate_parent() {
+        let (mut db, _dir) = create_test_db();
+        let workspace = tempdir().unwrap();
+        let repo = workspace.path().join("repo");
+        std::fs::create_dir_all(&repo).unwrap();
+        db.insert(Alias::new("repo", repo.to_str().unwrap()).unwrap());
+        db.insert(Alias::new("root", "/").unwrap());
+        // A name that really ends in ".." wins over the parent syntax
+        db.insert(Alias::new("v1..", "/tmp").unwrap());
+
+        assert!(navigate(&mut db, "repo..").is_ok());
+        assert!(navigate(&mut db, "repo/..").is_ok());
+        assert_eq!(db.get("repo").unwrap().use_count, 2);
+        assert!(navigate(&mut db, "v1..").is_ok());
+        assert_eq!(db.get("v1..").unwrap().use_count, 1);
+
+        let err = navigate(&mut db, "nope..").unwrap_err().to_string();
+        assert_eq!(err, "alias 'nope' not found");
+        let err = navigate(&mut db, "root..").unwrap_err().to_string();
+        assert!(err.contains("which has no parent"), "{}", err);
+    }
+
+    #[test]
+    fn test_navigate_parent_of_file_bookmark() {
+        let (mut db, _dir) = create_test_db();
+        let workspace = tempdir().unwrap();
+        let notes = workspace.path().join("docs/notes.md");
+        std::fs::create_dir_all(notes.parent().unwrap()).unwrap();
+        std::fs::write(&notes, "").unwrap();
+        let mut alias = Alias::new("notes", notes.to_str().unwrap()).unwrap();
+        alias.kind = AliasKind::File;
+        db.insert(alias);
+
+        // The hook only lets the jump through to the file's own directory
+        let docs = workspace.path().join("docs");
+        let mut hooks = HooksConfig::default();
+        hooks
+            .alias
+            .insert("notes".to_string(), format!("test \"$GOTO_PATH\" = '{}'", docs.display()));
+        db.set_hooks(hooks);
+        assert!(navigate(&mut db, "notes..").is_ok());
+        assert_eq!(db.get("notes").unwrap().use_count, 1);
+    }
+
+    #[test]
+    fn test_navigate_within_cancelled_deadline() {
+        let (mut db, _dir) = create_test_db();
+        let target = tempdir().unwrap();
+        db.insert(Alias::new("work", target.path().to_str().unwrap()).unwrap());
+
+        let (deadline, cancel) = Deadline::default().with_cancel();
+        cancel.cancel();
+        assert!(matches!(
+            navigate_within(&mut db, "work", &deadline).unwrap_err().downcast_ref(),
+            Some(AliasError::CheckCancelled(_))
+        ));
+        assert!(navigate_within(&mut db, "work/sub", &deadline).is_err());
+        assert_eq!(db.get("work").unwrap().use_count, 0);
+        assert!(navigate_within(&mut db, "work", &Deadline::from_ms(5000)).is_ok());
+    }
+
+    #[test]
+    fn test_navigate_expired() {
+        let dir = tempdir().unwrap();
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        db.insert(Alias::new("tmp", "/tmp").unwrap());
+        let mut review = Alias::new("review", "/tmp").unwrap();
+        review.expires_at = Some(Utc::now() - chrono::Duration::minutes(1));
+        db.insert(review);
+
+        let err = navigate(&mut db, "review").unwrap_err().to_string();
+        assert!(err.starts_with("alias 'review' expired on "), "{}", err);
+        assert!(navigate(&mut db, "review/sub").is_err());
+        assert_eq!(db.get("review").unwrap().use_count, 0);
+        // Not offered as a fuzzy match either
+        assert!(fuzzy_candidates(&db, "reviw").is_empty());
+        for _ in 0..5 {
+            random(&mut db, None).unwrap();
+        }
+        assert_eq!(db.get("review").unwrap().use_count, 0);
+    }
+
+    #[test]
+    fn test_find_vcs_root() {
+        let dir = tempdir().unwrap();
+        let nested = dir.path().join("repo/src/deep");
+        std::fs::create_dir_all(&nested).unwrap();
+        assert_eq!(find_vcs_root(&nested), None);
+
+        std::fs::create_dir(dir.path().join("repo/.hg")).unwrap();
+        assert_eq!(find_vcs_root(&nested), Some(dir.path().join("repo")));
+
+        // A worktree or submodule has a .git file
+        std::fs::write(dir.path().join("repo/src/.git"), "gitdir: ../.git/modules/src").unwrap();
+        assert_eq!(find_vcs_root(&nested), Some(dir.path().join("repo/src")));
+        assert_eq!(find_vcs_root(&dir.path().join("repo")), Some(dir.path().join("repo")));
+    }
+
+    #[test]
+    fn test_navigate_default_namespace() {
+        let (mut db, _dir) = create_test_db();
+        db.insert(Alias::new("work:tmp", "/tmp").unwrap());
+        db.set_default_namespace("work");
+
+        assert!(navigate(&mut db, "tmp").is_ok());
+        assert_eq!(db.get("work:tmp").unwrap().use_count, 1);
+    }
+
+    #[test]
+    fn test_random() {
+        let dir = tempdir().unwrap();
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        assert!(random(&mut db, None).is_err());
+
+        db.insert(Alias::new("tmp", "/tmp").unwrap());
+        let mut reading = Alias::new("reading", "/tmp").unwrap();
+        reading.add_tag("reading");
+        db.insert(reading);
+
+        for _ in 0..5 {
+            random(&mut db, Some("reading")).unwrap();
+        }
+        assert_eq!(db.get("reading").unwrap().use_count, 5);
+        assert_eq!(db.get("tmp").unwrap().use_count, 0);
+        assert!(random(&mut db, Some("missing-tag")).unwrap_err().to_string().contains("not found"));
+        assert!(random(&mut db, None).is_ok());
+    }
+
+    #[test]
+    fn test_navigate_pre_hook_veto() {
+        let (mut db, _dir) = create_test_db();
+        db.insert(Alias::new("tmp", "/tmp").unwrap());
+        db.insert(Alias::new("prod", "/tmp").unwrap());
+        let mut hooks = HooksConfig::default();
+        hooks.alias.insert("prod".to_string(), "exit 1".to_string());
+        db.set_hooks(hooks);
+
+        assert!(navigate(&mut db, "tmp").is_ok());
+        let err = navigate(&mut db, "prod").unwrap_err().to_string();
+        assert!(err.contains("cancelled by pre-navigate hook"));
+        assert_eq!(db.get("prod").unwrap().use_count, 0);
+    }
+
+    #[test]
+    fn test_navigate_uses_default_host_path() {
+        let dir = tempdir().unwrap();
+        let db_path = dir.path().join("aliases");
+        let mut db = Database::load_from_path(&db_path).unwrap();
+
+        // The plain path is missing, but the "default" host path exists
+        let target_dir = tempdir().unwrap();
+        let mut alias = Alias::new("dev", "/nonexistent/directory/path").unwrap();
+        alias
+            .paths
+            .insert("default".to_string(), target_dir.path().to_str().unwrap().to_string());
+        db.insert(alias);
+
+        let result = navigate(&mut db, "dev");
+        assert!(result.is_ok());
+        assert_eq!(db.get("dev").unwrap().use_count, 1);
+    }
+
     #[test]
     fn test_navigate_directory_not_found() {
         let dir = tempdir().unwrap();
@@ -200,6 +687,28 @@ mod tests {
         assert!(result.unwrap_err().to_string().contains("not a directory"));
     }
 
+    #[test]
+    fn test_navigate_file_bookmark() {
+        let dir = tempdir().unwrap();
+        let db_path = dir.path().join("aliases");
+        let mut db = Database::load_from_path(&db_path).unwrap();
+
+        let file = dir.path().join("todo.md");
+        std::fs::write(&file, "").unwrap();
+        let mut notes = Alias::new("notes", file.to_str().unwrap()).unwrap();
+        notes.kind = AliasKind::File;
+        db.insert(notes);
+
+        navigate(&mut db, "notes").unwrap();
+        assert_eq!(db.get("notes").unwrap().use_count, 1);
+        let err = navigate(&mut db, "notes/sub").unwrap_err();
+        assert!(err.to_string().contains("is a file bookmark"));
+
+        std::fs::remove_file(&file).unwrap();
+        let err = navigate(&mut db, "notes").unwrap_err();
+        assert!(err.to_string().contains("file does not exist"));
+    }
+
     #[test]
     fn test_navigate_fuzzy_suggestions() {
         let dir = tempdir().unwrap();
@@ -340,6 +849,103 @@ mod tests {
         assert!(err.contains("not found"), "Expected 'not found' error, got: {}", err);
     }
 
+    #[test]
+    fn test_navigate_fuzzy_policy() {
+        let dir = tempdir().unwrap();
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        let target = tempdir().unwrap();
+        db.insert(Alias::new("myproject", target.path().to_str().unwrap()).unwrap());
+
+        // "myprojet" scores 0.89: a threshold above that offers nothing
+        db.set_fuzzy(FuzzyPolicy { threshold: 0.95, ..Default::default() });
+        let err = navigate(&mut db, "myprojet").unwrap_err().to_string();
+        assert_eq!(err, "alias 'myprojet' not found");
+        assert_eq!(resolve(&db, "myprojet").resolved, None);
+
+        db.set_fuzzy(FuzzyPolicy { enabled: false, ..Default::default() });
+        let err = navigate(&mut db, "myprojet").unwrap_err().to_string();
+        assert_eq!(err, "alias 'myprojet' not found");
+        assert!(resolve(&db, "myprojet").candidates.is_empty());
+
+        // Exact names still work
+        assert!(navigate(&mut db, "myproject").is_ok());
+    }
+
+    #[test]
+    fn test_configured_fuzzy_threshold() {
+        let dir = tempdir().unwrap();
+        let mut config = Config {
+            database_path: dir.path().to_path_buf(),
+            stack_path: dir.path().join("goto_stack"),
+            history_path: dir.path().join("dir_history"),
+            context_path: dir.path().join("context"),
+            listing_path: dir.path().join("last_listing"),
+            config_path: dir.path().join("config.toml"),
+            aliases_path: dir.path().join("aliases"),
+            user: UserConfig::default(),
+        };
+        let mut db = Database::load(&config).unwrap();
+        db.insert(Alias::new("myproject", "/tmp").unwrap());
+        db.save().unwrap();
+
+        // "myprojet" scores 0.89
+        config.user.general.fuzzy_threshold = 0.95;
+        let db = Database::load(&config).unwrap();
+        assert_eq!(db.fuzzy().threshold, 0.95);
+        assert_eq!(resolve(&db, "myprojet").resolved, None);
+
+        config.user.general.fuzzy_threshold = 0.8;
+        let db = Database::load(&config).unwrap();
+        assert_eq!(resolve(&db, "myprojet").resolved.as_deref(), Some("myproject"));
+    }
+
+    #[test]
+    fn test_navigate_auto_accepts_single_confident_match() {
+        let dir = tempdir().unwrap();
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        let target = tempdir().unwrap();
+        db.insert(Alias::new("myproject", target.path().to_str().unwrap()).unwrap());
+        db.insert(Alias::new("project1", target.path().to_str().unwrap()).unwrap());
+        db.insert(Alias::new("project2", target.path().to_str().unwrap()).unwrap());
+        db.set_fuzzy(FuzzyPolicy { auto_accept: Some(0.85), ..Default::default() });
+
+        // "myprojet" scores 0.89 against myproject only
+        navigate(&mut db, "myprojet").unwrap();
+        assert_eq!(db.get("myproject").unwrap().use_count, 1);
+
+        // Two equally close names: still asks (and declines without a terminal)
+        let err = navigate(&mut db, "project").unwrap_err().to_string();
+        assert!(err.contains("cancelled"), "{}", err);
+    }
+
+    #[test]
+    fn test_fuzzy_candidates_respects_max_suggestions() {
+        let dir = tempdir().unwrap();
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        for name in ["project1", "project2", "project3", "project4", "project5"] {
+            db.insert(Alias::new(name, dir.path().to_str().unwrap()).unwrap());
+        }
+        assert_eq!(fuzzy_candidates(&db, "project").len(), 3);
+
+        db.set_fuzzy(FuzzyPolicy { max_suggestions: 5, ..Default::default() });
+        assert_eq!(fuzzy_candidates(&db, "project").len(), 5);
+
+        // None to offer: a plain "not found", no prompt
+        db.set_fuzzy(FuzzyPolicy { max_suggestions: 0, ..Default::default() });
+        assert!(resolve(&db, "project").resolved.is_none());
+        let err = navigate(&mut db, "project").unwrap_err().to_string();
+        assert_eq!(err, "alias 'project' not found");
+    }
+
+    #[test]
+    fn test_file_uri() {
+        assert_eq!(file_uri("/home/me/dev"), "file:///home/me/dev");
+        assert_eq!(file_uri("/home/me/My Projects/50%"), "file:///home/me/My%20Projects/50%25");
+        assert_eq!(file_uri("/srv/projëkt#1?x"), "file:///srv/proj%C3%ABkt%231%3Fx");
+        assert_eq!(file_uri("C:\\Users\\me"), "file:///C:/Users/me");
+        assert_eq!(file_uri("/odd\\name"), "file:///odd%5Cname");
+    }
+
     #[test]
     fn test_completions_empty_query() {
         // completions with empty query returns all sorted
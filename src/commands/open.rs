@@ -0,0 +1,122 @@
+//! Open and edit commands: act on what an alias points to instead of cd-ing there
+//!
+//! Mostly for file bookmarks (`goto -r notes ~/notes/todo.md`), but a directory
+//! alias works too: `--open` shows it in the file manager and `--edit` hands
+//! the directory to the editor.
+
+use std::io::ErrorKind;
+use std::process::Command;
+
+use crate::alias::AliasError;
+use crate::database::Database;
+
+/// Open an alias's file or directory with the desktop's default application
+///
+/// `$GOTO_OPENER` overrides the platform opener. Usage is not recorded.
+pub fn open(db: &Database, alias: &str) -> Result<(), Box<dyn std::error::Error>> {
+    let path = target(db, alias)?;
+    let opener = std::env::var("GOTO_OPENER")
+        .ok()
+        .filter(|o| !o.is_empty())
+        .unwrap_or_else(|| opener(std::env::consts::OS).to_string());
+    run(&opener, &path, false)
+}
+
+/// Edit an alias's file (or directory) in `$VISUAL`, `$EDITOR` or `vi`
+///
+/// The editor gets the terminal, so the shell wrappers pass this command
+/// straight through. Usage is not recorded.
+pub fn edit(db: &Database, alias: &str) -> Result<(), Box<dyn std::error::Error>> {
+    let path = target(db, alias)?;
+    let editor = editor(std::env::var("VISUAL").ok(), std::env::var("EDITOR").ok());
+    run(&editor, &path, true)
+}
+
+/// The checked path of an alias
+fn target(db: &Database, alias: &str) -> Result<String, Box<dyn std::error::Error>> {
+    let name = db.resolve_name(alias);
+    let entry = db.get(&name).ok_or_else(|| AliasError::NotFound(alias.to_string()))?;
+    db.check_target(entry)?;
+    Ok(entry.resolved_path())
+}
+
+/// The command that opens a path with its default application
+fn opener(os: &str) -> &'static str {
+    match os {
+        "macos" => "open",
+        "windows" => "explorer",
+        _ => "xdg-open",
+    }
+}
+
+/// The editor to use: `$VISUAL`, then `$EDITOR`, then `vi`
+fn editor(visual: Option<String>, editor: Option<String>) -> String {
+    visual
+        .filter(|v| !v.trim().is_empty())
+        .or(editor.filter(|e| !e.trim().is_empty()))
+        .unwrap_or_else(|| "vi".to_string())
+}
+
+/// Run `command` (split on whitespace, so `code --wait` works) on `path`
+///
+/// With `wait`, goto waits for it to exit and fails if it does.
+fn run(command: &str, path: &str, wait: bool) -> Result<(), Box<dyn std::error::Error>> {
+    let mut words = command.split_whitespace();
+    let program = words.next().ok_or("no command to open with")?;
+    let mut cmd = Command::new(program);
+    cmd.args(words).arg(path);
+
+    let result = if wait { cmd.status().map(Some) } else { cmd.spawn().map(|_| None) };
+    match result {
+        Ok(Some(status)) if !status.success() => Err(format!("{} failed ({})", program, status).into()),
+        Ok(_) => Ok(()),
+        Err(e) if e.kind() == ErrorKind::NotFound => Err(format!("{}: command not found", program).into()),
+        Err(e) => Err(e.into()),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::alias::{Alias, AliasKind};
+    use tempfile::tempdir;
+
+    #[test]
+    fn test_opener() {
+        assert_eq!(opener("macos"), "open");
+        assert_eq!(opener("windows"), "explorer");
+        assert_eq!(opener("linux"), "xdg-open");
+    }
+
+    #[test]
+    fn test_editor() {
+        let s = |v: &str| Some(v.to_string());
+        assert_eq!(editor(s("code --wait"), s("nano")), "code --wait");
+        assert_eq!(editor(s(" "), s("nano")), "nano");
+        assert_eq!(editor(None, None), "vi");
+    }
+
+    #[test]
+    fn test_target() {
+        let dir = tempdir().unwrap();
+        let file = dir.path().join("todo.md");
+        std::fs::write(&file, "").unwrap();
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        let mut notes = Alias::new("notes", file.to_str().unwrap()).unwrap();
+        notes.kind = AliasKind::File;
+        db.insert(notes);
+        db.insert(Alias::new("gone", "/nonexistent/path/12345").unwrap());
+
+        assert_eq!(target(&db, "notes").unwrap(), file.to_string_lossy());
+        assert!(target(&db, "gone").unwrap_err().to_string().contains("does not exist"));
+        assert!(target(&db, "missing").unwrap_err().to_string().contains("not found"));
+    }
+
+    #[test]
+    fn test_run() {
+        assert!(run("true", "/tmp", true).is_ok());
+        assert!(run("false", "/tmp", true).unwrap_err().to_string().contains("failed"));
+        let err = run("goto-no-such-editor --wait", "/tmp", true).unwrap_err();
+        assert_eq!(err.to_string(), "goto-no-such-editor: command not found");
+    }
+}
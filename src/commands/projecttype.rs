@@ -0,0 +1,151 @@
+//! Project types of aliased directories for `goto -l --type`
+//!
+//! Aliases registered before types were stored have no `project_type`, so the
+//! listing looks them up. Listing is read-only: results (including "no known
+//! project") are cached next to the database rather than written into it, and
+//! the lookups get no more time than `general.check_timeout_ms`.
+
+use chrono::{DateTime, Duration, Utc};
+use serde::{Deserialize, Serialize};
+use std::collections::{BTreeMap, HashMap};
+use std::fs::File;
+use std::io::BufReader;
+use std::path::PathBuf;
+use std::sync::mpsc;
+use std::thread;
+
+use crate::config::Config;
+use crate::deadline::Deadline;
+use crate::project;
+
+/// How long a looked-up type is reused before checking the directory again
+const CACHE_HOURS: i64 = 24;
+
+/// A type looked up at some point in the past; `None` when no marker was found
+#[derive(Debug, Clone, Serialize, Deserialize)]
+struct CachedType {
+    kind: Option<String>,
+    checked_at: DateTime<Utc>,
+}
+
+/// Get the path to the type cache file
+fn cache_path(config: &Config) -> PathBuf {
+    config.database_path.join("project_cache.json")
+}
+
+/// Load the type cache, keyed by directory path
+fn load_cache(config: &Config) -> BTreeMap<String, CachedType> {
+    File::open(cache_path(config))
+        .ok()
+        .and_then(|file| serde_json::from_reader(BufReader::new(file)).ok())
+        .unwrap_or_default()
+}
+
+/// Save the type cache to disk
+fn save_cache(config: &Config, cache: &BTreeMap<String, CachedType>) -> Result<(), Box<dyn std::error::Error>> {
+    config.ensure_dirs()?;
+    let file = File::create(cache_path(config))?;
+    serde_json::to_writer_pretty(file, cache)?;
+    Ok(())
+}
+
+/// Project types of the given directories, using cached results younger than a day
+///
+/// Directories not looked at before the check timeout are left out, and are
+/// tried again next time.
+pub fn types(config: &Config, paths: &[String]) -> HashMap<String, Option<String>> {
+    let mut cache = load_cache(config);
+    let now = Utc::now();
+
+    let mut result = HashMap::new();
+    let mut pending = Vec::new();
+    for path in paths {
+        if result.contains_key(path) || pending.contains(path) {
+            continue;
+        }
+        match cache.get(path) {
+            Some(entry) if now - entry.checked_at < Duration::hours(CACHE_HOURS) => {
+                result.insert(path.clone(), entry.kind.clone());
+            }
+            _ => pending.push(path.clone()),
+        }
+    }
+    if pending.is_empty() {
+        return result;
+    }
+
+    // One helper walks the directories; an unresponsive mount holds up only it
+    let (tx, rx) = mpsc::channel();
+    thread::spawn(move || {
+        for path in pending {
+            let kind = project::detect(&path);
+            if tx.send((path, kind)).is_err() {
+                break;
+            }
+        }
+    });
+    let deadline = Deadline::from_ms(config.user.general.check_timeout_ms);
+    loop {
+        let received = match deadline.remaining() {
+            Some(left) => rx.recv_timeout(left).ok(),
+            None => rx.recv().ok(),
+        };
+        let Some((path, kind)) = received else {
+            break;
+        };
+        cache.insert(path.clone(), CachedType { kind: kind.clone(), checked_at: now });
+        result.insert(path, kind);
+    }
+
+    if let Err(e) = save_cache(config, &cache) {
+        eprintln!("Warning: could not save project type cache: {}", e);
+    }
+    result
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::fs;
+    use std::path::Path;
+    use tempfile::tempdir;
+
+    fn test_config(dir: &Path) -> Config {
+        Config {
+            database_path: dir.to_path_buf(),
+            stack_path: dir.join("goto_stack"),
+            history_path: dir.join("dir_history"),
+            context_path: dir.join("context"),
+            listing_path: dir.join("last_listing"),
+            config_path: dir.join("config.toml"),
+            aliases_path: dir.join("aliases.toml"),
+            user: Default::default(),
+        }
+    }
+
+    #[test]
+    fn test_types_cache_negative_results() {
+        let dir = tempdir().unwrap();
+        let config = test_config(&dir.path().join("db"));
+        let crate_dir = dir.path().join("crate");
+        let plain = dir.path().join("plain");
+        fs::create_dir(&crate_dir).unwrap();
+        fs::create_dir(&plain).unwrap();
+        fs::write(crate_dir.join("Cargo.toml"), "").unwrap();
+        let crate_dir = crate_dir.to_string_lossy().to_string();
+        let plain = plain.to_string_lossy().to_string();
+
+        let first = types(&config, &[crate_dir.clone(), plain.clone()]);
+        assert_eq!(first[&crate_dir].as_deref(), Some("rust"));
+        assert_eq!(first[&plain], None);
+
+        // "Not a project" is remembered too, until it expires
+        fs::write(Path::new(&plain).join("go.mod"), "").unwrap();
+        assert_eq!(types(&config, &[plain.clone()])[&plain], None);
+
+        let mut cache = load_cache(&config);
+        cache.get_mut(&plain).unwrap().checked_at = Utc::now() - Duration::hours(CACHE_HOURS + 1);
+        save_cache(&config, &cache).unwrap();
+        assert_eq!(types(&config, &[plain.clone()])[&plain].as_deref(), Some("go"));
+    }
+}
@@ -22,6 +22,9 @@ pub struct PruneCache {
     pub stale_count: usize,
     /// If set, notifications are snoozed until this time
     pub snoozed_until: Option<DateTime<Utc>>,
+    /// Last time `goto --cleanup` ran (not a dry run)
+    #[serde(default)]
+    pub last_cleanup: Option<DateTime<Utc>>,
 }
 
 impl Default for PruneCache {
@@ -30,6 +33,7 @@ impl Default for PruneCache {
             last_check: DateTime::from_timestamp(0, 0).unwrap(),
             stale_count: 0,
             snoozed_until: None,
+            last_cleanup: None,
         }
     }
 }
@@ -67,7 +71,7 @@ fn save_cache(config: &Config, cache: &PruneCache) -> Result<(), Box<dyn Error>>
 /// Count aliases pointing to non-existent directories
 pub fn count_stale_aliases(db: &Database) -> usize {
     db.all()
-        .filter(|a| !Path::new(&a.path).exists())
+        .filter(|a| !db.is_system(&a.name) && !a.skip_check && !Path::new(&a.resolved_path()).exists())
         .count()
 }
 
@@ -130,6 +134,82 @@ pub fn notify_if_stale_aliases(config: &Config, db: &Database) {
     }
 }
 
+/// Print maintenance nudges after a successful jump to `name` (stderr, never fatal)
+pub fn warn_on_navigate(config: &Config, db: &Database, name: &str) {
+    for warning in navigation_warnings(config, db, name, Utc::now()) {
+        eprintln!("Note: {}", warning);
+    }
+}
+
+/// Maintenance nudges for a jump to `name`, per `[prune] warn_on_navigate` and `cleanup_reminder_days`
+///
+/// Snoozing prune notifications silences these too.
+pub fn navigation_warnings(config: &Config, db: &Database, name: &str, now: DateTime<Utc>) -> Vec<String> {
+    let settings = &config.user.prune;
+    if !settings.warn_on_navigate && settings.cleanup_reminder_days == 0 {
+        return Vec::new();
+    }
+    let cache = load_cache(config);
+    if cache.snoozed_until.is_some_and(|until| now < until) {
+        return Vec::new();
+    }
+
+    let mut warnings = Vec::new();
+    if settings.warn_on_navigate {
+        let broken = broken_siblings(db, name);
+        if !broken.is_empty() {
+            warnings.push(format!(
+                "{} alias{} next to '{}' point{} to missing directories ({}). Run 'goto --cleanup' to review.",
+                broken.len(),
+                if broken.len() == 1 { "" } else { "es" },
+                name,
+                if broken.len() == 1 { "s" } else { "" },
+                broken.join(", ")
+            ));
+        }
+    }
+
+    if settings.cleanup_reminder_days > 0 {
+        // Never cleaned: count from when the oldest alias was registered
+        let since = cache
+            .last_cleanup
+            .or_else(|| db.all().filter(|a| !db.is_system(&a.name)).map(|a| a.created_at).min());
+        if let Some(since) = since {
+            let days = (now - since).num_days();
+            if days >= settings.cleanup_reminder_days as i64 {
+                warnings.push(format!(
+                    "aliases haven't been cleaned up in {} days. Run 'goto --cleanup' to review.",
+                    days
+                ));
+            }
+        }
+    }
+    warnings
+}
+
+/// Names of other aliases in the same parent directory as `name` whose directory is missing
+fn broken_siblings(db: &Database, name: &str) -> Vec<String> {
+    let Some(alias) = db.get(name) else {
+        return Vec::new();
+    };
+    let path = alias.resolved_path();
+    let Some(parent) = Path::new(&path).parent() else {
+        return Vec::new();
+    };
+
+    let mut broken: Vec<String> = db
+        .all()
+        .filter(|a| a.name != alias.name && !db.is_system(&a.name) && !a.skip_check)
+        .filter(|a| {
+            let other = a.resolved_path();
+            Path::new(&other).parent() == Some(parent) && !Path::new(&other).exists()
+        })
+        .map(|a| a.name.clone())
+        .collect();
+    broken.sort();
+    broken
+}
+
 /// Snooze prune notifications for the specified number of days
 pub fn snooze_notifications(config: &Config, days: u32) -> Result<(), Box<dyn Error>> {
     let mut cache = load_cache(config);
@@ -141,10 +221,12 @@ pub fn snooze_notifications(config: &Config, days: u32) -> Result<(), Box<dyn Er
 
 /// Reset the prune cache (called after cleanup)
 ///
-/// Clears the stale count so notification doesn't appear until next check.
+/// Clears the stale count so notification doesn't appear until next check,
+/// and remembers when the cleanup ran for `cleanup_reminder_days`.
 pub fn reset_cache(config: &Config) -> Result<(), Box<dyn Error>> {
     let mut cache = load_cache(config);
     cache.stale_count = 0;
+    cache.last_cleanup = Some(Utc::now());
     save_cache(config, &cache)?;
     Ok(())
 }
@@ -162,6 +244,9 @@ mod tests {
         Config {
             database_path: temp_dir.to_path_buf(),
             stack_path: temp_dir.join("goto_stack"),
+            history_path: temp_dir.join("dir_history"),
+            context_path: temp_dir.join("context"),
+            listing_path: temp_dir.join("last_listing"),
             config_path: temp_dir.join("config.toml"),
             aliases_path: temp_dir.join("aliases.toml"),
             user: UserConfig::default(),
@@ -183,6 +268,7 @@ mod tests {
             last_check: Utc::now(),
             stale_count: 5,
             snoozed_until: Some(Utc::now() + Duration::days(7)),
+            last_cleanup: None,
         };
 
         let json = serde_json::to_string(&cache).unwrap();
@@ -225,6 +311,7 @@ mod tests {
             last_check: Utc::now(),
             stale_count: 3,
             snoozed_until: None,
+            last_cleanup: None,
         };
 
         save_cache(&config, &cache).unwrap();
@@ -276,6 +363,7 @@ mod tests {
             last_check: Utc::now(),
             stale_count: 5,
             snoozed_until: None,
+            last_cleanup: None,
         };
         save_cache(&config, &cache).unwrap();
 
@@ -336,6 +424,7 @@ mod tests {
             last_check: Utc::now(),
             stale_count: 5,
             snoozed_until: Some(Utc::now() + Duration::days(1)),
+            last_cleanup: None,
         };
         save_cache(&config, &cache).unwrap();
 
@@ -346,4 +435,51 @@ mod tests {
         notify_if_stale_aliases(&config, &db);
         // Test passes if no panic - we can't easily test stderr output
     }
+
+    #[test]
+    fn test_navigation_warnings_broken_siblings() {
+        let temp_dir = TempDir::new().unwrap();
+        let mut config = test_config(temp_dir.path());
+        let code = temp_dir.path().join("code");
+        fs::create_dir_all(code.join("api")).unwrap();
+
+        let mut db = crate::database::Database::load_from_path(&temp_dir.path().join("aliases.toml")).unwrap();
+        db.insert(Alias::new("api", code.join("api").to_str().unwrap()).unwrap());
+        db.insert(Alias::new("web", code.join("web").to_str().unwrap()).unwrap());
+        db.insert(Alias::new("old", code.join("old").to_str().unwrap()).unwrap());
+        db.insert(Alias::new("far", "/nonexistent/elsewhere").unwrap());
+
+        let now = Utc::now();
+        assert!(navigation_warnings(&config, &db, "api", now).is_empty(), "off by default");
+
+        config.user.prune.warn_on_navigate = true;
+        let warnings = navigation_warnings(&config, &db, "api", now);
+        assert_eq!(warnings.len(), 1);
+        assert!(warnings[0].starts_with("2 aliases next to 'api' point to missing directories (old, web)"));
+
+        snooze_notifications(&config, 1).unwrap();
+        assert!(navigation_warnings(&config, &db, "api", now).is_empty());
+    }
+
+    #[test]
+    fn test_navigation_warnings_cleanup_reminder() {
+        let temp_dir = TempDir::new().unwrap();
+        let mut config = test_config(temp_dir.path());
+        config.user.prune.cleanup_reminder_days = 30;
+
+        let mut db = crate::database::Database::load_from_path(&temp_dir.path().join("aliases.toml")).unwrap();
+        let mut alias = Alias::new("proj", temp_dir.path().to_str().unwrap()).unwrap();
+        alias.created_at = Utc::now() - Duration::days(45);
+        db.insert(alias);
+
+        // Never cleaned: counted from the oldest alias
+        let warnings = navigation_warnings(&config, &db, "proj", Utc::now());
+        assert_eq!(warnings.len(), 1);
+        assert!(warnings[0].contains("in 45 days"));
+
+        reset_cache(&config).unwrap();
+        assert!(navigation_warnings(&config, &db, "proj", Utc::now()).is_empty());
+        let later = Utc::now() + Duration::days(31);
+        assert!(navigation_warnings(&config, &db, "proj", later)[0].contains("in 31 days"));
+    }
 }
@@ -1,11 +1,32 @@
 //! Registration commands: register, unregister, rename
 
-use std::collections::HashSet;
-
-use crate::alias::{validate_alias, validate_tag, Alias, AliasError};
-use crate::config::expand_path;
-use crate::confirm;
+use chrono::{DateTime, Utc};
+use std::collections::{BTreeMap, HashSet};
+use std::io::{self, BufRead, IsTerminal};
+
+use crate::alias::{normalize_name, validate_alias, validate_tag, Alias, AliasError, AliasKind};
+use crate::commands::import_export::ImportResult;
+use crate::commands::select::select_aliases;
+use crate::commands::setup::{base_name, propose_name};
+use crate::config::{autotags_for, expand_path, AutoTagRule};
+use crate::commands::stats::format_time_ago;
 use crate::database::Database;
+use crate::project;
+use crate::{confirm, needs_confirmation, prompt_multi_selection};
+
+/// What registering a name that is already taken should do
+#[derive(Debug, Clone, Copy, PartialEq, Default)]
+pub enum IfExists {
+    /// Fail with "already exists"
+    #[default]
+    Fail,
+    /// Point the alias at the new path, keeping its tags and usage history
+    Overwrite,
+    /// Like `Overwrite`, but leave the alias alone when nothing would change
+    Update,
+    /// Leave an existing alias alone, whatever it points to
+    Skip,
+}
 
 /// Register a new alias for a directory
 pub fn register(db: &mut Database, name: &str, path: &str) -> Result<(), Box<dyn std::error::Error>> {
@@ -28,11 +49,50 @@ pub fn register_with_tags(
     tags: &[String],
     force: bool,
 ) -> Result<(), Box<dyn std::error::Error>> {
+    register_with_rules(db, name, path, &RegisterOptions { tags, force, ..Default::default() })
+}
+
+/// Everything about a registration beyond the name and path
+#[derive(Debug, Clone, Copy, Default)]
+pub struct RegisterOptions<'a> {
+    /// Tags to add to the alias
+    pub tags: &'a [String],
+    /// One-line description of the directory
+    pub description: Option<&'a str>,
+    /// Makes the alias temporary
+    pub expires: Option<DateTime<Utc>>,
+    /// Skip confirmation for new tags
+    pub force: bool,
+    /// Auto-tag rules whose tags are added when the path matches
+    pub rules: &'a [AutoTagRule],
+    /// What happens when the name is already registered
+    pub if_exists: IfExists,
+}
+
+/// Register a new alias, also adding the tags of every matching auto-tag rule
+///
+/// Tags from rules are configured up front, so they never ask for confirmation.
+pub fn register_with_rules(
+    db: &mut Database,
+    name: &str,
+    path: &str,
+    options: &RegisterOptions,
+) -> Result<(), Box<dyn std::error::Error>> {
+    let RegisterOptions { tags, description, expires, force, rules, if_exists } = *options;
     // Validate alias name
+    let name = &normalize_name(name);
     validate_alias(name)?;
 
+    // Checked before the path so provisioning scripts pass even if the directory is gone
+    if if_exists == IfExists::Skip {
+        if let Some(existing) = db.get(name) {
+            println!("'{}' already registered -> {}", name, existing.path);
+            return Ok(());
+        }
+    }
+
     // Validate and normalize tags
-    let normalized_tags = validate_and_normalize_tags(tags)?;
+    let mut normalized_tags = validate_and_normalize_tags(tags)?;
 
     // Check for new tags that need confirmation
     if !normalized_tags.is_empty() && !force {
@@ -45,54 +105,220 @@ pub fn register_with_tags(
                 if !existing_tags.contains_key(tag) {
                     let message = format!("Tag '{}' doesn't exist. Create it?", tag);
                     if !confirm(&message, false)? {
-                        return Err("Tag creation cancelled".into());
+                        return Err(AliasError::Cancelled("Tag creation".to_string()).into());
                     }
                 }
             }
         }
     }
 
-    // Expand and validate directory
-    let expanded_path = expand_path(path)?;
-    let path_str = expanded_path.to_string_lossy().to_string();
+    // Expand and validate the directory, or the file for a file bookmark
+    let (path_str, kind) = resolve_target(path)?;
+    db.check_path_allowed(&path_str)?;
 
-    // Check directory exists
-    if !expanded_path.exists() {
-        return Err(AliasError::DirectoryNotFound(path_str).into());
+    for tag in validate_and_normalize_tags(&autotags_for(rules, &path_str)?)? {
+        if !normalized_tags.contains(&tag) {
+            normalized_tags.push(tag);
+        }
     }
-    if !expanded_path.is_dir() {
-        return Err(format!("not a directory: {}", path_str).into());
+
+    if let Some(existing) = db.get(name) {
+        match if_exists {
+            IfExists::Fail | IfExists::Skip => {}
+            IfExists::Update
+                if existing.path == path_str
+                    && existing.kind == kind
+                    && normalized_tags.iter().all(|t| existing.tags.contains(t))
+                    && description.is_none_or(|d| existing.description.as_deref() == Some(d))
+                    && expires.is_none()
+                    && existing.expires_at.is_none() =>
+            {
+                println!("'{}' already points to {}", name, path_str);
+                return Ok(());
+            }
+            IfExists::Overwrite | IfExists::Update => {
+                return overwrite_path(db, name, &path_str, kind, &normalized_tags, description, expires);
+            }
+        }
     }
 
     // Add alias with tags
     let alias = Alias {
         name: name.to_string(),
         path: path_str.clone(),
+        kind,
         tags: Vec::new(),
+        description: description.map(str::to_string),
         use_count: 0,
         last_used: None,
         created_at: chrono::Utc::now(),
+        paths: BTreeMap::new(),
+        meta: BTreeMap::new(),
+        skip_check: false,
+        project_type: project::detect(&kind.directory(&path_str)),
+        expires_at: expires,
     };
 
     db.add_with_tags(alias, normalized_tags.clone())?;
     db.save()?;
 
+    let mut message = format!("Registered '{}' -> {}", name, path_str);
     if !normalized_tags.is_empty() {
-        println!(
-            "Registered '{}' -> {} [{}]",
-            name,
-            path_str,
-            normalized_tags.join(", ")
-        );
-    } else {
-        println!("Registered '{}' -> {}", name, path_str);
+        message.push_str(&format!(" [{}]", normalized_tags.join(", ")));
+    }
+    if let Some(description) = description {
+        message.push_str(&format!(" - {}", description));
+    }
+    if kind == AliasKind::File {
+        message.push_str(" (file)");
+    }
+    if let Some(at) = expires {
+        message.push_str(&format!(" (expires {})", format_expiry(at)));
     }
+    println!("{}", message);
 
     Ok(())
 }
 
+/// Point an existing alias at `path`, adding `tags` to the ones it already has
+///
+/// A `description` replaces the old one; without one the old one is kept.
+/// The expiry is always replaced, so re-registering without `--expires=` makes
+/// a temporary alias permanent.
+fn overwrite_path(
+    db: &mut Database,
+    name: &str,
+    path: &str,
+    kind: AliasKind,
+    tags: &[String],
+    description: Option<&str>,
+    expires: Option<DateTime<Utc>>,
+) -> Result<(), Box<dyn std::error::Error>> {
+    db.check_writable(name)?;
+
+    let mut all_tags = db.get(name).map(|a| a.tags.clone()).unwrap_or_default();
+    for tag in tags {
+        if !all_tags.contains(tag) {
+            all_tags.push(tag.clone());
+        }
+    }
+    if let Some(alias) = db.get_mut(name) {
+        alias.path = path.to_string();
+        alias.kind = kind;
+        alias.project_type = project::detect(&kind.directory(path));
+        if let Some(description) = description {
+            alias.description = Some(description.to_string());
+        }
+        alias.expires_at = expires;
+    }
+    db.set_tags(name, all_tags)?;
+    db.save()?;
+
+    match expires {
+        Some(at) => println!("Updated '{}' -> {} (expires {})", name, path, format_expiry(at)),
+        None => println!("Updated '{}' -> {}", name, path),
+    }
+    Ok(())
+}
+
+/// An expiry time as shown to the user, like `--show` prints timestamps
+pub fn format_expiry(at: DateTime<Utc>) -> String {
+    at.format("%Y-%m-%d %H:%M").to_string()
+}
+
+/// Register one directory per line (as `fd -t d` or `find` print them) under derived names
+///
+/// Names come from the last path component like `--setup` proposes them, with a
+/// numeric suffix when taken. Directories that already have an alias, don't exist
+/// or yield no usable name are skipped with a warning. Everything is saved once.
+pub fn register_from_lines(
+    db: &mut Database,
+    input: impl BufRead,
+    tags: &[String],
+    rules: &[AutoTagRule],
+) -> Result<ImportResult, Box<dyn std::error::Error>> {
+    let tags = validate_and_normalize_tags(tags)?;
+    let mut result = ImportResult::default();
+
+    for line in input.lines() {
+        let line = line?;
+        let line = line.trim();
+        if line.is_empty() {
+            continue;
+        }
+
+        let path = match resolve_directory(line) {
+            Ok(path) => path,
+            Err(e) => {
+                result.warnings.push(format!("skipping {}: {}", line, e));
+                result.skipped += 1;
+                continue;
+            }
+        };
+        if let Some(existing) = db.all().find(|a| a.path == path) {
+            result.warnings.push(format!("skipping {}: already registered as '{}'", path, existing.name));
+            result.skipped += 1;
+            continue;
+        }
+        if let Err(e) = db.check_path_allowed(&path) {
+            result.warnings.push(format!("skipping {}: {}", path, e));
+            result.skipped += 1;
+            continue;
+        }
+        let Some(name) = propose_name(db, &path) else {
+            result.warnings.push(format!("skipping {}: no usable alias name", path));
+            result.skipped += 1;
+            continue;
+        };
+
+        let mut alias_tags = tags.clone();
+        for tag in validate_and_normalize_tags(&autotags_for(rules, &path)?)? {
+            if !alias_tags.contains(&tag) {
+                alias_tags.push(tag);
+            }
+        }
+        match base_name(&path) {
+            Some(base) if base != name => {
+                result.warnings.push(format!("'{}' is taken; registering {} as '{}'", base, path, name));
+                result.renamed += 1;
+            }
+            _ => result.imported += 1,
+        }
+        db.add_with_tags(Alias::new(&name, &path)?, alias_tags)?;
+    }
+
+    db.save()?;
+    Ok(result)
+}
+
+/// Expand a path and check that it is an existing directory
+pub(crate) fn resolve_directory(path: &str) -> Result<String, Box<dyn std::error::Error>> {
+    let expanded_path = expand_path(path)?;
+    let path_str = expanded_path.to_string_lossy().to_string();
+
+    if !expanded_path.exists() {
+        return Err(AliasError::DirectoryNotFound(path_str).into());
+    }
+    if !expanded_path.is_dir() {
+        return Err(format!("not a directory: {}", path_str).into());
+    }
+
+    Ok(path_str)
+}
+
+/// Like [`resolve_directory`], but an existing file is accepted too, as a file bookmark
+pub(crate) fn resolve_target(path: &str) -> Result<(String, AliasKind), Box<dyn std::error::Error>> {
+    let expanded_path = expand_path(path)?;
+    let path_str = expanded_path.to_string_lossy().to_string();
+
+    if expanded_path.is_file() {
+        return Ok((path_str, AliasKind::File));
+    }
+    Ok((resolve_directory(path)?, AliasKind::Dir))
+}
+
 /// Validate tags and convert to lowercase, removing duplicates
-fn validate_and_normalize_tags(tags: &[String]) -> Result<Vec<String>, AliasError> {
+pub(crate) fn validate_and_normalize_tags(tags: &[String]) -> Result<Vec<String>, AliasError> {
     let mut normalized = Vec::new();
     let mut seen = HashSet::new();
 
@@ -111,15 +337,126 @@ fn validate_and_normalize_tags(tags: &[String]) -> Result<Vec<String>, AliasErro
     Ok(normalized)
 }
 
-/// Unregister (remove) an alias
-pub fn unregister(db: &mut Database, name: &str) -> Result<(), Box<dyn std::error::Error>> {
-    if db.remove(name).is_some() {
-        db.save()?;
-        println!("Unregistered '{}'", name);
-        Ok(())
-    } else {
-        Err(AliasError::NotFound(name.to_string()).into())
+/// Unregister (remove) an alias, asking first on a terminal unless `yes`
+pub fn unregister(db: &mut Database, name: &str, yes: bool) -> Result<(), Box<dyn std::error::Error>> {
+    let name = &db.resolve_name(name);
+    db.check_writable(name)?;
+
+    let path = db
+        .get(name)
+        .map(|a| a.resolved_path())
+        .ok_or_else(|| AliasError::NotFound(name.to_string()))?;
+    if needs_confirmation(yes) && !confirm(&format!("Unregister '{}' -> {}?", name, path), false)? {
+        return Err(AliasError::Cancelled("Unregister".to_string()).into());
+    }
+
+    db.remove(name);
+    db.save()?;
+    println!("Unregistered '{}'", name);
+    Ok(())
+}
+
+/// Unregister every alias matched by `selector` (names or globs, see [`select_aliases`])
+pub fn unregister_matching(
+    db: &mut Database,
+    selector: &str,
+    filter: Option<&str>,
+    dry_run: bool,
+    yes: bool,
+) -> Result<(), Box<dyn std::error::Error>> {
+    let names = select_aliases(db, selector, filter)?;
+    match (names.is_empty(), filter) {
+        (true, Some(filter)) => {
+            Err(AliasError::NoneMatching(selector.to_string(), filter.to_string()).into())
+        }
+        (true, None) => Err(AliasError::NotFound(selector.to_string()).into()),
+        (false, _) => unregister_all(db, &names, dry_run, yes),
+    }
+}
+
+/// Pick aliases to unregister from a numbered list, then confirm and remove them
+pub fn unregister_interactive(db: &mut Database, yes: bool) -> Result<(), Box<dyn std::error::Error>> {
+    if !io::stdin().is_terminal() {
+        return Err("interactive unregister requires a terminal".into());
+    }
+
+    let mut aliases: Vec<&Alias> = db.all().filter(|a| !db.is_system(&a.name)).collect();
+    if aliases.is_empty() {
+        eprintln!("No aliases registered");
+        return Ok(());
+    }
+    aliases.sort_by(|a, b| a.name.cmp(&b.name));
+
+    let width = aliases.iter().map(|a| a.name.chars().count()).max().unwrap_or(0);
+    let labels: Vec<String> = aliases
+        .iter()
+        .map(|a| {
+            format!(
+                "{:<width$}  {}{}  (last used: {})",
+                a.name,
+                a.resolved_path(),
+                project::label(a.project_type.as_deref()),
+                format_time_ago(a.last_used),
+                width = width
+            )
+        })
+        .collect();
+    let label_refs: Vec<&str> = labels.iter().map(String::as_str).collect();
+
+    eprintln!("Select aliases to unregister:");
+    let names: Vec<String> = match prompt_multi_selection(&label_refs)? {
+        Some(selected) if !selected.is_empty() => {
+            selected.iter().map(|&i| aliases[i].name.clone()).collect()
+        }
+        _ => return Err(AliasError::Cancelled("Unregister".to_string()).into()),
+    };
+    unregister_all(db, &names, false, yes)
+}
+
+/// Remove several aliases, listing them and asking for confirmation on a terminal
+///
+/// With `dry_run` the list is printed and nothing is removed; `yes` skips the prompt.
+pub fn unregister_all(
+    db: &mut Database,
+    names: &[String],
+    dry_run: bool,
+    yes: bool,
+) -> Result<(), Box<dyn std::error::Error>> {
+    let count = format!("{} alias{}", names.len(), if names.len() == 1 { "" } else { "es" });
+    let lines: Vec<String> = names
+        .iter()
+        .filter_map(|n| db.get(n).map(|a| format!("  {} -> {}", n, a.resolved_path())))
+        .collect();
+
+    if dry_run {
+        println!("Would unregister {}:", count);
+        for line in &lines {
+            println!("{}", line);
+        }
+        return Ok(());
+    }
+
+    // The shell wrapper captures stdout, so the list shown before the prompt goes to stderr
+    if needs_confirmation(yes) {
+        eprintln!("About to unregister {}:", count);
+        for line in &lines {
+            eprintln!("{}", line);
+        }
+        if !confirm(&format!("Unregister {}?", count), false)? {
+            return Err(AliasError::Cancelled("Unregister".to_string()).into());
+        }
+    }
+
+    for name in names {
+        db.check_writable(name)?;
+    }
+    for name in names {
+        if db.remove(name).is_some() {
+            println!("Unregistered '{}'", name);
+        }
     }
+    db.save()?;
+    Ok(())
 }
 
 /// Rename an alias while preserving all metadata
@@ -129,8 +466,10 @@ pub fn rename(
     new_name: &str,
 ) -> Result<(), Box<dyn std::error::Error>> {
     // Validate new alias name
+    let new_name = &normalize_name(new_name);
     validate_alias(new_name)?;
 
+    let old_name = &db.resolve_name(old_name);
     db.rename_alias(old_name, new_name)?;
     db.save()?;
 
@@ -138,9 +477,165 @@ pub fn rename(
     Ok(())
 }
 
+/// Duplicate an alias under a new name
+///
+/// The copy has the same path, tags, description and metadata but starts with fresh usage counters.
+pub fn copy(db: &mut Database, src: &str, dst: &str) -> Result<(), Box<dyn std::error::Error>> {
+    let dst = &normalize_name(dst);
+    validate_alias(dst)?;
+
+    let src = &db.resolve_name(src);
+    let source = db.get(src).ok_or_else(|| AliasError::NotFound(src.to_string()))?;
+    let alias = Alias {
+        name: dst.to_string(),
+        path: source.path.clone(),
+        kind: source.kind,
+        tags: Vec::new(),
+        description: source.description.clone(),
+        use_count: 0,
+        last_used: None,
+        created_at: chrono::Utc::now(),
+        paths: source.paths.clone(),
+        meta: source.meta.clone(),
+        skip_check: source.skip_check,
+        project_type: source.project_type.clone(),
+        expires_at: source.expires_at,
+    };
+    let tags = source.tags.clone();
+    db.add_with_tags(alias, tags)?;
+    db.save()?;
+
+    println!("Copied alias '{}' to '{}'", src, dst);
+    Ok(())
+}
+
+/// Point an existing alias at a new directory, preserving tags and usage
+pub fn repath(db: &mut Database, name: &str, path: &str) -> Result<(), Box<dyn std::error::Error>> {
+    let name = &db.resolve_name(name);
+    db.check_writable(name)?;
+    if !db.contains(name) {
+        return Err(AliasError::NotFound(name.to_string()).into());
+    }
+
+    let (path_str, kind) = resolve_target(path)?;
+    db.check_path_allowed(&path_str)?;
+
+    if let Some(alias) = db.get_mut(name) {
+        alias.path = path_str.clone();
+        alias.kind = kind;
+        alias.project_type = project::detect(&kind.directory(&path_str));
+    }
+    db.save()?;
+
+    println!("Updated '{}' -> {}", name, path_str);
+    Ok(())
+}
+
+/// Turn the pre-navigation directory check off for an alias (or back on with `skip = false`)
+///
+/// Meant for autofs and other mount-on-access paths, where a stat is slow or mounts as a side effect.
+pub fn skip_check(db: &mut Database, name: &str, skip: bool) -> Result<(), Box<dyn std::error::Error>> {
+    let name = db.resolve_name(name);
+    db.set_skip_check(&name, skip)?;
+    db.save()?;
+
+    if skip {
+        println!("'{}' will no longer be checked before navigating", name);
+    } else {
+        println!("'{}' will be checked before navigating again", name);
+    }
+    Ok(())
+}
+
+/// Replace the `old` prefix of every alias path (host-specific ones included) with `new`
+///
+/// Prefixes match whole path components, so `/srv/app` does not touch `/srv/app2`.
+/// All changes are saved at once; with `dry_run` they are only listed.
+pub fn rewrite_paths(
+    db: &mut Database,
+    old: &str,
+    new: &str,
+    dry_run: bool,
+) -> Result<(), Box<dyn std::error::Error>> {
+    let old = trim_trailing_slash(old);
+    let new = trim_trailing_slash(new);
+    if old.is_empty() || new.is_empty() {
+        return Err("Usage: goto --rewrite-paths <old-prefix> <new-prefix> [--dry-run]".into());
+    }
+
+    // Work out every change first so a dry run never touches the database
+    let mut changes: Vec<(String, String, String, BTreeMap<String, String>)> = Vec::new();
+    for alias in db.all().filter(|a| !db.is_system(&a.name)) {
+        let path = replace_prefix(&alias.path, old, new);
+        let mut paths = alias.paths.clone();
+        let mut host_changed = false;
+        for p in paths.values_mut() {
+            if let Some(rewritten) = replace_prefix(p, old, new) {
+                *p = rewritten;
+                host_changed = true;
+            }
+        }
+        if path.is_some() || host_changed {
+            let path = path.unwrap_or_else(|| alias.path.clone());
+            changes.push((alias.name.clone(), alias.path.clone(), path, paths));
+        }
+    }
+    changes.sort();
+    for (_, _, after, paths) in &changes {
+        db.check_path_allowed(after)?;
+        paths.values().try_for_each(|p| db.check_path_allowed(p))?;
+    }
+
+    for (name, before, after, paths) in &changes {
+        println!(
+            "{} '{}': {} -> {}",
+            if dry_run { "Would rewrite" } else { "Rewrote" },
+            name,
+            before,
+            after
+        );
+        if !dry_run {
+            if let Some(alias) = db.get_mut(name) {
+                alias.path = after.clone();
+                alias.paths = paths.clone();
+                alias.project_type = project::detect(&alias.resolved_path());
+            }
+        }
+    }
+
+    if !dry_run && !changes.is_empty() {
+        db.save()?;
+    }
+    println!(
+        "{} {} alias{}",
+        if dry_run { "Would rewrite" } else { "Rewrote" },
+        changes.len(),
+        if changes.len() == 1 { "" } else { "es" }
+    );
+    Ok(())
+}
+
+/// `path` with `old` replaced by `new`, if `old` is a whole-component prefix of it
+fn replace_prefix(path: &str, old: &str, new: &str) -> Option<String> {
+    let rest = path.strip_prefix(old)?;
+    if rest.is_empty() || rest.starts_with('/') {
+        Some(format!("{}{}", new, rest))
+    } else {
+        None
+    }
+}
+
+fn trim_trailing_slash(path: &str) -> &str {
+    match path.trim_end_matches('/') {
+        "" if path.starts_with('/') => "/",
+        trimmed => trimmed,
+    }
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
+    use crate::config::RegisterConfig;
     use tempfile::{NamedTempFile, TempDir};
 
     fn create_test_db() -> (Database, NamedTempFile) {
@@ -160,6 +655,42 @@ mod tests {
         assert!(db.contains("test"));
     }
 
+    #[test]
+    fn test_register_detects_project_type() {
+        let (mut db, _file) = create_test_db();
+        let project = TempDir::new().unwrap();
+        std::fs::write(project.path().join("go.mod"), "module example\n").unwrap();
+        let plain = TempDir::new().unwrap();
+
+        register(&mut db, "svc", &project.path().to_string_lossy()).unwrap();
+        assert_eq!(db.get("svc").unwrap().project_type.as_deref(), Some("go"));
+
+        repath(&mut db, "svc", &plain.path().to_string_lossy()).unwrap();
+        assert_eq!(db.get("svc").unwrap().project_type, None);
+    }
+
+    #[test]
+    fn test_register_with_rules_adds_autotags() {
+        let (mut db, _file) = create_test_db();
+        let temp_dir = TempDir::new().unwrap();
+        let path = temp_dir.path().to_string_lossy().to_string();
+        let rules = vec![
+            AutoTagRule {
+                pattern: format!("{}/**", path),
+                tags: vec!["Work".to_string()],
+            },
+            AutoTagRule {
+                pattern: "/nonexistent/**".to_string(),
+                tags: vec!["other".to_string()],
+            },
+        ];
+
+        let tags = vec!["work".to_string()];
+        let options = RegisterOptions { tags: &tags, force: true, rules: &rules, ..Default::default() };
+        register_with_rules(&mut db, "test", &path, &options).unwrap();
+        assert_eq!(db.get("test").unwrap().tags, vec!["work"]);
+    }
+
     #[test]
     fn test_register_duplicate() {
         let (mut db, _file) = create_test_db();
@@ -171,6 +702,190 @@ mod tests {
         assert!(result.is_err());
     }
 
+    #[test]
+    fn test_register_update_is_idempotent() {
+        let (mut db, _file) = create_test_db();
+        let old_dir = TempDir::new().unwrap();
+        let new_dir = TempDir::new().unwrap();
+        let old_path = old_dir.path().to_string_lossy().to_string();
+        let new_path = new_dir.path().to_string_lossy().to_string();
+
+        let options = RegisterOptions { force: true, if_exists: IfExists::Update, ..Default::default() };
+        register_with_rules(&mut db, "test", &old_path, &options).unwrap();
+        assert_eq!(db.get("test").unwrap().path, old_path);
+
+        register_with_rules(&mut db, "test", &old_path, &options).unwrap();
+        assert_eq!(db.get("test").unwrap().path, old_path);
+
+        register_with_rules(&mut db, "test", &new_path, &options).unwrap();
+        assert_eq!(db.get("test").unwrap().path, new_path);
+    }
+
+    #[test]
+    fn test_register_with_description() {
+        let (mut db, _file) = create_test_db();
+        let temp_dir = TempDir::new().unwrap();
+        let path = temp_dir.path().to_string_lossy().to_string();
+
+        let options = RegisterOptions { description: Some("Frontend app"), force: true, ..Default::default() };
+        register_with_rules(&mut db, "web", &path, &options).unwrap();
+        assert_eq!(db.get("web").unwrap().description.as_deref(), Some("Frontend app"));
+
+        // --update replaces a changed description, and keeps it when none is given
+        let options = RegisterOptions {
+            description: Some("Storefront"),
+            force: true,
+            if_exists: IfExists::Update,
+            ..Default::default()
+        };
+        register_with_rules(&mut db, "web", &path, &options).unwrap();
+        assert_eq!(db.get("web").unwrap().description.as_deref(), Some("Storefront"));
+        let options = RegisterOptions { force: true, if_exists: IfExists::Overwrite, ..Default::default() };
+        register_with_rules(&mut db, "web", &path, &options).unwrap();
+        assert_eq!(db.get("web").unwrap().description.as_deref(), Some("Storefront"));
+    }
+
+    #[test]
+    fn test_register_with_expiry() {
+        let (mut db, _file) = create_test_db();
+        let temp_dir = TempDir::new().unwrap();
+        let path = temp_dir.path().to_string_lossy().to_string();
+        let at = Utc::now() + chrono::Duration::days(30);
+
+        let options = RegisterOptions { expires: Some(at), force: true, ..Default::default() };
+        register_with_rules(&mut db, "review", &path, &options).unwrap();
+        assert_eq!(db.get("review").unwrap().expires_at, Some(at));
+
+        // --update with an expiry always refreshes it; re-registering without one makes the alias permanent
+        let later = at + chrono::Duration::days(1);
+        let options = RegisterOptions {
+            expires: Some(later),
+            force: true,
+            if_exists: IfExists::Update,
+            ..Default::default()
+        };
+        register_with_rules(&mut db, "review", &path, &options).unwrap();
+        assert_eq!(db.get("review").unwrap().expires_at, Some(later));
+        let options = RegisterOptions { force: true, if_exists: IfExists::Update, ..Default::default() };
+        register_with_rules(&mut db, "review", &path, &options).unwrap();
+        assert_eq!(db.get("review").unwrap().expires_at, None);
+    }
+
+    #[test]
+    fn test_copy() {
+        let (mut db, _file) = create_test_db();
+        let temp_dir = TempDir::new().unwrap();
+        let path = temp_dir.path().to_string_lossy().to_string();
+
+        register_with_tags(&mut db, "api", &path, &["work".to_string()], true).unwrap();
+        db.record_usage("api").unwrap();
+
+        copy(&mut db, "api", "api-v2").unwrap();
+        let copied = db.get("api-v2").unwrap();
+        assert_eq!(copied.path, path);
+        assert_eq!(copied.tags, vec!["work"]);
+        assert_eq!(copied.use_count, 0);
+        assert!(copied.last_used.is_none());
+        assert_eq!(db.get("api").unwrap().use_count, 1);
+
+        assert!(copy(&mut db, "api", "api-v2").is_err());
+        assert!(copy(&mut db, "missing", "other").is_err());
+    }
+
+    #[test]
+    fn test_copy_resolves_source_name() {
+        let (mut db, _file) = create_test_db();
+        db.insert(Alias::new("work:api", "/srv/api").unwrap());
+        db.insert(Alias::new("projëkt", "/srv/projekt").unwrap());
+        db.set_default_namespace("work");
+
+        copy(&mut db, "api", "work:api-v2").unwrap();
+        assert_eq!(db.get("work:api-v2").unwrap().path, "/srv/api");
+        copy(&mut db, "proje\u{0308}kt", "projekt2").unwrap();
+        assert_eq!(db.get("projekt2").unwrap().path, "/srv/projekt");
+    }
+
+    #[test]
+    fn test_rewrite_paths() {
+        let (mut db, _file) = create_test_db();
+        db.insert(Alias::new("app", "/srv/old/app").unwrap());
+        db.insert(Alias::new("root", "/srv/old").unwrap());
+        db.insert(Alias::new("other", "/srv/older/x").unwrap());
+        let mut hosts = Alias::new("hosts", "/opt/hosts").unwrap();
+        hosts.paths.insert("laptop".to_string(), "/srv/old/hosts".to_string());
+        db.insert(hosts);
+
+        rewrite_paths(&mut db, "/srv/old/", "/srv/new", true).unwrap();
+        assert_eq!(db.get("app").unwrap().path, "/srv/old/app");
+
+        rewrite_paths(&mut db, "/srv/old/", "/srv/new", false).unwrap();
+        assert_eq!(db.get("app").unwrap().path, "/srv/new/app");
+        assert_eq!(db.get("root").unwrap().path, "/srv/new");
+        assert_eq!(db.get("other").unwrap().path, "/srv/older/x");
+        let hosts = db.get("hosts").unwrap();
+        assert_eq!(hosts.path, "/opt/hosts");
+        assert_eq!(hosts.paths["laptop"], "/srv/new/hosts");
+    }
+
+    #[test]
+    fn test_rewrite_paths_checks_host_paths() {
+        let (mut db, _file) = create_test_db();
+        let mut hosts = Alias::new("hosts", "/opt/hosts").unwrap();
+        hosts.paths.insert("laptop".to_string(), "/srv/old/hosts".to_string());
+        db.insert(hosts);
+        db.set_register_rules(RegisterConfig {
+            allow: Vec::new(),
+            deny: vec!["/proc".to_string()],
+        });
+
+        let err = rewrite_paths(&mut db, "/srv/old", "/proc", false).unwrap_err();
+        assert!(err.to_string().starts_with("path not allowed:"), "{}", err);
+        assert_eq!(db.get("hosts").unwrap().paths["laptop"], "/srv/old/hosts");
+    }
+
+    #[test]
+    fn test_register_if_missing() {
+        let (mut db, _file) = create_test_db();
+        let temp_dir = TempDir::new().unwrap();
+        let path = temp_dir.path().to_string_lossy().to_string();
+
+        let options = RegisterOptions { force: true, if_exists: IfExists::Skip, ..Default::default() };
+        register_with_rules(&mut db, "test", &path, &options).unwrap();
+        assert_eq!(db.get("test").unwrap().path, path);
+
+        let gone = "/nonexistent/path/12345";
+        let options = RegisterOptions { force: true, if_exists: IfExists::Skip, ..Default::default() };
+        register_with_rules(&mut db, "test", gone, &options).unwrap();
+        assert_eq!(db.get("test").unwrap().path, path);
+    }
+
+    #[test]
+    fn test_register_overwrite_keeps_history() {
+        let (mut db, _file) = create_test_db();
+        let old_dir = TempDir::new().unwrap();
+        let new_dir = TempDir::new().unwrap();
+        let old_path = old_dir.path().to_string_lossy().to_string();
+        let new_path = new_dir.path().to_string_lossy().to_string();
+
+        register_with_tags(&mut db, "test", &old_path, &["work".to_string()], true).unwrap();
+        db.record_usage("test").unwrap();
+        let created_at = db.get("test").unwrap().created_at;
+
+        let tags = vec!["rust".to_string()];
+        let options = RegisterOptions {
+            tags: &tags,
+            force: true,
+            if_exists: IfExists::Overwrite,
+            ..Default::default()
+        };
+        register_with_rules(&mut db, "test", &new_path, &options).unwrap();
+        let alias = db.get("test").unwrap();
+        assert_eq!(alias.path, new_path);
+        assert_eq!(alias.tags, vec!["rust", "work"]);
+        assert_eq!(alias.use_count, 1);
+        assert_eq!(alias.created_at, created_at);
+    }
+
     #[test]
     fn test_register_nonexistent_path() {
         let (mut db, _file) = create_test_db();
@@ -265,17 +980,23 @@ mod tests {
     }
 
     #[test]
-    fn test_register_not_a_directory() {
+    fn test_register_file() {
         let (mut db, _file) = create_test_db();
         let temp_file = NamedTempFile::new().unwrap();
         let path = temp_file.path().to_string_lossy().to_string();
 
-        let result = register(&mut db, "test", &path);
-        assert!(result.is_err());
-        assert!(result
-            .unwrap_err()
-            .to_string()
-            .contains("not a directory"));
+        register(&mut db, "test", &path).unwrap();
+        let alias = db.get("test").unwrap();
+        assert_eq!(alias.kind, AliasKind::File);
+        assert_eq!(alias.path, path);
+        assert_eq!(alias.directory(), temp_file.path().parent().unwrap().to_string_lossy());
+
+        // Pointing it at a directory makes it a directory alias again
+        let options = RegisterOptions { force: true, if_exists: IfExists::Overwrite, ..Default::default() };
+        register_with_rules(&mut db, "test", "/tmp", &options).unwrap();
+        assert_eq!(db.get("test").unwrap().kind, AliasKind::Dir);
+        repath(&mut db, "test", &path).unwrap();
+        assert_eq!(db.get("test").unwrap().kind, AliasKind::File);
     }
 
     #[test]
@@ -283,15 +1004,68 @@ mod tests {
         let (mut db, _file) = create_test_db();
         db.insert(Alias::new("test", "/tmp").unwrap());
 
-        let result = unregister(&mut db, "test");
+        let result = unregister(&mut db, "test", true);
         assert!(result.is_ok());
         assert!(!db.contains("test"));
     }
 
+    #[test]
+    fn test_unregister_resolves_name() {
+        let (mut db, _file) = create_test_db();
+        db.insert(Alias::new("work:api", "/tmp").unwrap());
+        db.insert(Alias::new("projëkt", "/tmp").unwrap());
+        db.set_default_namespace("work");
+
+        unregister(&mut db, "api", true).unwrap();
+        assert!(!db.contains("work:api"));
+        unregister(&mut db, "proje\u{0308}kt", true).unwrap();
+        assert!(!db.contains("projëkt"));
+    }
+
+    #[test]
+    fn test_unregister_matching_glob() {
+        let (mut db, _file) = create_test_db();
+        db.insert(Alias::new("client-a", "/tmp").unwrap());
+        db.insert(Alias::new("client-b", "/tmp").unwrap());
+        db.insert(Alias::new("keep", "/tmp").unwrap());
+
+        unregister_matching(&mut db, "client-*", None, false, true).unwrap();
+        assert!(!db.contains("client-a"));
+        assert!(!db.contains("client-b"));
+        assert!(db.contains("keep"));
+
+        assert!(unregister_matching(&mut db, "client-*", None, false, true).is_err());
+    }
+
+    #[test]
+    fn test_unregister_matching_tag() {
+        let (mut db, _file) = create_test_db();
+        let mut done = Alias::new("done", "/tmp").unwrap();
+        done.add_tag("archived");
+        db.insert(done);
+        db.insert(Alias::new("active", "/tmp").unwrap());
+
+        unregister_matching(&mut db, "*", Some("tag:archived"), false, true).unwrap();
+        assert!(!db.contains("done"));
+        assert!(db.contains("active"));
+
+        let err = unregister_matching(&mut db, "*", Some("tag:archived"), false, true).unwrap_err();
+        assert!(err.to_string().contains("not found"));
+    }
+
+    #[test]
+    fn test_unregister_matching_dry_run() {
+        let (mut db, _file) = create_test_db();
+        db.insert(Alias::new("client-a", "/tmp").unwrap());
+
+        unregister_matching(&mut db, "client-*", None, true, true).unwrap();
+        assert!(db.contains("client-a"));
+    }
+
     #[test]
     fn test_unregister_not_found() {
         let (mut db, _file) = create_test_db();
-        let result = unregister(&mut db, "nonexistent");
+        let result = unregister(&mut db, "nonexistent", true);
         assert!(result.is_err());
     }
 
@@ -313,6 +1087,21 @@ mod tests {
         assert_eq!(renamed.use_count, 1);
     }
 
+    #[test]
+    fn test_rename_resolves_name() {
+        let (mut db, _file) = create_test_db();
+        db.insert(Alias::new("work:api", "/tmp").unwrap());
+        db.insert(Alias::new("projëkt", "/tmp").unwrap());
+        db.set_default_namespace("work");
+
+        rename(&mut db, "api", "work:backend").unwrap();
+        assert!(db.contains("work:backend"));
+        assert!(!db.contains("work:api"));
+        rename(&mut db, "proje\u{0308}kt", "project").unwrap();
+        assert!(db.contains("project"));
+        assert!(!db.contains("projëkt"));
+    }
+
     #[test]
     fn test_rename_not_found() {
         let (mut db, _file) = create_test_db();
@@ -344,6 +1133,131 @@ mod tests {
         assert!(result.is_err());
     }
 
+    #[test]
+    fn test_repath_preserves_metadata() {
+        let (mut db, _file) = create_test_db();
+        let mut alias = Alias::new("proj", "/old/location").unwrap();
+        alias.add_tag("work");
+        alias.record_use();
+        let created_at = alias.created_at;
+        db.insert(alias);
+
+        let temp_dir = TempDir::new().unwrap();
+        let new_path = temp_dir.path().canonicalize().unwrap();
+
+        let result = repath(&mut db, "proj", new_path.to_str().unwrap());
+        assert!(result.is_ok());
+
+        let alias = db.get("proj").unwrap();
+        assert_eq!(alias.path, new_path.to_string_lossy());
+        assert!(alias.has_tag("work"));
+        assert_eq!(alias.use_count, 1);
+        assert_eq!(alias.created_at, created_at);
+    }
+
+    #[test]
+    fn test_repath_validates_directory() {
+        let (mut db, _file) = create_test_db();
+        db.insert(Alias::new("proj", "/tmp").unwrap());
+
+        let result = repath(&mut db, "proj", "/nonexistent/path/12345");
+        assert!(result.unwrap_err().to_string().contains("directory does not exist"));
+        assert_eq!(db.get("proj").unwrap().path, "/tmp");
+    }
+
+    #[test]
+    fn test_skip_check() {
+        let (mut db, _file) = create_test_db();
+        db.insert(Alias::new("nas", "/nonexistent/path/12345").unwrap());
+
+        skip_check(&mut db, "nas", true).unwrap();
+        assert!(db.get("nas").unwrap().skip_check);
+        assert!(db.check_target(db.get("nas").unwrap()).is_ok());
+
+        skip_check(&mut db, "nas", false).unwrap();
+        assert!(db.check_target(db.get("nas").unwrap()).is_err());
+
+        assert!(skip_check(&mut db, "missing", true).unwrap_err().to_string().contains("not found"));
+    }
+
+    #[test]
+    fn test_register_from_lines() {
+        let (mut db, _file) = create_test_db();
+        let root = TempDir::new().unwrap();
+        let root_path = root.path().canonicalize().unwrap();
+        for dir in ["api", "web", "other/api"] {
+            std::fs::create_dir_all(root_path.join(dir)).unwrap();
+        }
+        let web = root_path.join("web").to_string_lossy().to_string();
+        db.insert(Alias::new("site", &web).unwrap());
+
+        let input = format!(
+            "{0}/api\n\n{0}/web\n{0}/other/api\n/nonexistent/path/12345\n",
+            root_path.display()
+        );
+        let result = register_from_lines(&mut db, input.as_bytes(), &["code".to_string()], &[]).unwrap();
+
+        assert_eq!((result.imported, result.renamed, result.skipped), (1, 1, 2));
+        assert_eq!(result.warnings.len(), 3);
+        assert!(db.get("api").unwrap().has_tag("code"));
+        assert!(db.get("api-2").unwrap().path.ends_with("other/api"));
+        assert!(!db.contains("web"));
+    }
+
+    #[test]
+    fn test_register_respects_register_rules() {
+        let (mut db, _file) = create_test_db();
+        let root = TempDir::new().unwrap();
+        let root_path = root.path().canonicalize().unwrap();
+        for dir in ["work/api", "scratch"] {
+            std::fs::create_dir_all(root_path.join(dir)).unwrap();
+        }
+        let work = root_path.join("work/api").to_string_lossy().to_string();
+        let scratch = root_path.join("scratch").to_string_lossy().to_string();
+        db.set_register_rules(RegisterConfig {
+            allow: Vec::new(),
+            deny: vec![scratch.clone()],
+        });
+
+        let options = RegisterOptions { force: true, ..Default::default() };
+        let err = register_with_rules(&mut db, "tmp", &scratch, &options).unwrap_err();
+        assert!(err.to_string().starts_with("path not allowed:"), "{}", err);
+        assert!(!db.contains("tmp"));
+
+        register_with_rules(&mut db, "api", &work, &RegisterOptions { force: true, ..Default::default() }).unwrap();
+        assert!(repath(&mut db, "api", &scratch).is_err());
+        assert_eq!(db.get("api").unwrap().path, work);
+
+        let input = format!("{}\n", scratch);
+        let result = register_from_lines(&mut db, input.as_bytes(), &[], &[]).unwrap();
+        assert_eq!(result.skipped, 1);
+        assert!(result.warnings[0].contains("path not allowed"));
+    }
+
+    #[test]
+    fn test_repath_resolves_name() {
+        let (mut db, _file) = create_test_db();
+        db.insert(Alias::new("work:api", "/old").unwrap());
+        db.insert(Alias::new("projëkt", "/old").unwrap());
+        db.set_default_namespace("work");
+        let temp_dir = TempDir::new().unwrap();
+        let new_path = temp_dir.path().canonicalize().unwrap();
+        let new_path = new_path.to_str().unwrap();
+
+        repath(&mut db, "api", new_path).unwrap();
+        assert_eq!(db.get("work:api").unwrap().path, new_path);
+        assert!(!db.contains("api"));
+        repath(&mut db, "proje\u{0308}kt", new_path).unwrap();
+        assert_eq!(db.get("projëkt").unwrap().path, new_path);
+    }
+
+    #[test]
+    fn test_repath_not_found() {
+        let (mut db, _file) = create_test_db();
+        let result = repath(&mut db, "nonexistent", "/tmp");
+        assert!(result.unwrap_err().to_string().contains("not found"));
+    }
+
     #[test]
     fn test_validate_and_normalize_tags() {
         // Valid tags
@@ -0,0 +1,305 @@
+//! Scan command: merge the aliases declared in `.goto-aliases` files below a directory
+//!
+//! A project (or a project template) ships its own shortcuts in a
+//! [`FILE_NAME`] file, TOML with one entry per alias and paths relative to the
+//! file:
+//!
+//! ```toml
+//! api = "services/api"
+//! docs = { path = "docs", tags = ["docs"], description = "Team handbook" }
+//! ```
+//!
+//! Merged aliases remember the file in their `source` metadata. Scanning again
+//! updates them; aliases registered any other way are never touched.
+
+use serde::Deserialize;
+use std::collections::{BTreeMap, HashMap};
+use std::error::Error;
+use std::fs;
+use std::path::{Path, PathBuf};
+
+use crate::alias::{validate_alias, Alias};
+use crate::commands::register::{resolve_directory, validate_and_normalize_tags};
+use crate::commands::workspace::collect_descendants;
+use crate::database::Database;
+
+/// Name of the alias files looked for
+pub const FILE_NAME: &str = ".goto-aliases";
+
+/// Metadata key recording which file an alias came from
+pub const SOURCE_KEY: &str = "source";
+
+/// One entry of an alias file: just a path, or a table
+#[derive(Debug, Clone, PartialEq, Deserialize)]
+#[serde(untagged)]
+enum Entry {
+    Path(String),
+    Full {
+        #[serde(default = "here")]
+        path: String,
+        #[serde(default)]
+        tags: Vec<String>,
+        description: Option<String>,
+    },
+}
+
+fn here() -> String {
+    ".".to_string()
+}
+
+/// An alias declared in an alias file, with its path resolved
+#[derive(Debug, Clone, PartialEq)]
+pub struct Declared {
+    pub name: String,
+    pub path: String,
+    pub tags: Vec<String>,
+    pub description: Option<String>,
+}
+
+/// What a scan found and did (or, in a dry run, would do)
+#[derive(Debug, Default)]
+pub struct ScanResult {
+    pub files: usize,
+    pub added: usize,
+    pub updated: usize,
+    pub skipped: usize,
+    pub warnings: Vec<String>,
+}
+
+/// Every alias file below `root`, sorted by path
+///
+/// Hidden directories, `node_modules`, `target` and symlinked directories are
+/// not descended into.
+pub fn find_files(root: &Path) -> Vec<PathBuf> {
+    let mut dirs = Vec::new();
+    collect_descendants(root, &mut dirs);
+    dirs.into_iter().map(|d| d.join(FILE_NAME)).filter(|f| f.is_file()).collect()
+}
+
+/// Parse an alias file in directory `dir`
+///
+/// Entries whose directory is missing or whose tags are invalid come back as
+/// warnings rather than failing the whole file.
+pub fn parse_file(content: &str, dir: &Path) -> Result<(Vec<Declared>, Vec<String>), String> {
+    let entries: BTreeMap<String, Entry> = toml::from_str(content).map_err(|e| e.to_string())?;
+    let mut declared = Vec::new();
+    let mut warnings = Vec::new();
+
+    for (name, entry) in entries {
+        let (path, tags, description) = match entry {
+            Entry::Path(path) => (path, Vec::new(), None),
+            Entry::Full { path, tags, description } => (path, tags, description),
+        };
+        let resolved = if path.starts_with(['~', '/', '$']) {
+            resolve_directory(&path)
+        } else {
+            resolve_directory(&dir.join(&path).to_string_lossy())
+        };
+        let resolved = match resolved {
+            Ok(p) => p,
+            Err(e) => {
+                warnings.push(format!("skipping '{}': {}", name, e));
+                continue;
+            }
+        };
+        let tags = match validate_and_normalize_tags(&tags) {
+            Ok(t) => t,
+            Err(e) => {
+                warnings.push(format!("skipping '{}': {}", name, e));
+                continue;
+            }
+        };
+        let description = description.map(|d| d.trim().to_string()).filter(|d| !d.is_empty());
+        declared.push(Declared { name, path: resolved, tags, description });
+    }
+    Ok((declared, warnings))
+}
+
+/// Merge the aliases of every alias file below `root`, optionally as `<namespace>:<name>`
+///
+/// A name already registered from elsewhere is skipped with a warning; with
+/// two files declaring the same name, the first by path wins.
+pub fn scan(
+    db: &mut Database,
+    root: &str,
+    namespace: Option<&str>,
+    dry_run: bool,
+) -> Result<ScanResult, Box<dyn Error>> {
+    let root = resolve_directory(root)?;
+    let mut result = ScanResult::default();
+    // Names merged in this run, with the file they came from
+    let mut claimed: HashMap<String, String> = HashMap::new();
+
+    for file in find_files(Path::new(&root)) {
+        result.files += 1;
+        let source = file.to_string_lossy().to_string();
+        let content = fs::read_to_string(&file)?;
+        let dir = file.parent().unwrap_or(Path::new("/"));
+        let (declared, warnings) = match parse_file(&content, dir) {
+            Ok(parsed) => parsed,
+            Err(e) => {
+                result.warnings.push(format!("skipping {}: {}", source, e));
+                continue;
+            }
+        };
+        result.warnings.extend(warnings.into_iter().map(|w| format!("{}: {}", source, w)));
+
+        for entry in declared {
+            let name = match namespace {
+                Some(ns) => format!("{}:{}", ns, entry.name),
+                None => entry.name.clone(),
+            };
+            let skip = |reason: String| format!("{}: skipping '{}': {}", source, name, reason);
+            if let Err(e) = validate_alias(&name) {
+                result.warnings.push(skip(e.to_string()));
+                result.skipped += 1;
+                continue;
+            }
+            if let Some(first) = claimed.get(&name) {
+                result.warnings.push(skip(format!("already declared in {}", first)));
+                result.skipped += 1;
+                continue;
+            }
+            if let Err(e) = db.check_path_allowed(&entry.path) {
+                result.warnings.push(skip(e.to_string()));
+                result.skipped += 1;
+                continue;
+            }
+
+            match db.get(&name) {
+                Some(existing) if existing.meta.get(SOURCE_KEY) != Some(&source) => {
+                    result.warnings.push(skip(format!("already registered -> {}", existing.path)));
+                    result.skipped += 1;
+                    continue;
+                }
+                Some(existing) => {
+                    let unchanged = existing.path == entry.path
+                        && existing.tags == sorted(&entry.tags)
+                        && existing.description == entry.description;
+                    if !unchanged {
+                        result.updated += 1;
+                        if dry_run {
+                            println!("Would update '{}' -> {}", name, entry.path);
+                        } else {
+                            let alias = db.get_mut(&name).expect("alias exists");
+                            alias.path = entry.path.clone();
+                            alias.tags = sorted(&entry.tags);
+                            alias.description = entry.description.clone();
+                        }
+                    }
+                }
+                None => {
+                    result.added += 1;
+                    if dry_run {
+                        println!("Would register '{}' -> {} [{}]", name, entry.path, entry.tags.join(", "));
+                    } else {
+                        let mut alias = Alias::new(&name, &entry.path)?;
+                        alias.description = entry.description.clone();
+                        alias.meta.insert(SOURCE_KEY.to_string(), source.clone());
+                        db.add_with_tags(alias, entry.tags.clone())?;
+                    }
+                }
+            }
+            claimed.insert(name, source.clone());
+        }
+    }
+
+    if !dry_run {
+        db.save()?;
+    }
+    Ok(result)
+}
+
+fn sorted(tags: &[String]) -> Vec<String> {
+    let mut tags = tags.to_vec();
+    tags.sort();
+    tags
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    fn touch(root: &Path, file: &str, content: &str) {
+        let path = root.join(file);
+        fs::create_dir_all(path.parent().unwrap()).unwrap();
+        fs::write(path, content).unwrap();
+    }
+
+    #[test]
+    fn test_parse_file() {
+        let dir = tempdir().unwrap();
+        fs::create_dir_all(dir.path().join("services/api")).unwrap();
+        let root = fs::canonicalize(dir.path()).unwrap();
+        let content = r#"
+api = "services/api"
+home = { tags = ["Work", "work"], description = " Project root " }
+gone = "missing"
+bad = { path = ".", tags = ["no good"] }
+"#;
+        let (declared, warnings) = parse_file(content, &root).unwrap();
+        let names: Vec<&str> = declared.iter().map(|d| d.name.as_str()).collect();
+        assert_eq!(names, vec!["api", "home"]);
+        assert_eq!(declared[0].path, root.join("services/api").to_string_lossy());
+        assert_eq!(declared[1].path, root.to_string_lossy());
+        assert_eq!(declared[1].tags, vec!["work"]);
+        assert_eq!(declared[1].description.as_deref(), Some("Project root"));
+        assert_eq!(warnings.len(), 2);
+        assert!(warnings[0].starts_with("skipping 'bad'"));
+
+        assert!(parse_file("api = [", &root).is_err());
+    }
+
+    #[test]
+    fn test_scan() {
+        let dir = tempdir().unwrap();
+        let root = fs::canonicalize(dir.path()).unwrap();
+        touch(&root, "shop/.goto-aliases", "api = \"api\"\nweb = { path = \"web\", tags = [\"frontend\"] }\n");
+        touch(&root, "shop/api/.keep", "");
+        touch(&root, "shop/web/.keep", "");
+        touch(&root, "tools/.goto-aliases", "api = \".\"\nmine = \".\"\n");
+        touch(&root, "node_modules/dep/.goto-aliases", "dep = \".\"\n");
+        let root_str = root.to_string_lossy().to_string();
+
+        let mut db = Database::load_from_path(&root.join("aliases")).unwrap();
+        db.insert(Alias::new("mine", &root_str).unwrap());
+
+        let result = scan(&mut db, &root_str, None, true).unwrap();
+        assert_eq!((result.files, result.added, result.skipped), (2, 2, 2));
+        assert!(!db.contains("api"));
+
+        let result = scan(&mut db, &root_str, None, false).unwrap();
+        assert_eq!((result.added, result.updated, result.skipped), (2, 0, 2));
+        let source = root.join("shop/.goto-aliases").to_string_lossy().to_string();
+        let web = db.get("web").unwrap();
+        assert_eq!(web.tags, vec!["frontend"]);
+        assert_eq!(web.meta.get(SOURCE_KEY), Some(&source));
+        assert_eq!(db.get("api").unwrap().path, root.join("shop/api").to_string_lossy());
+        assert_eq!(db.get("mine").unwrap().path, root_str);
+        assert!(!db.contains("dep"));
+
+        // Scanning again updates what the file changed and nothing else
+        touch(&root, "shop/.goto-aliases", "api = \"web\"\nweb = { path = \"web\", tags = [\"frontend\"] }\n");
+        let result = scan(&mut db, &root_str, None, false).unwrap();
+        assert_eq!((result.added, result.updated), (0, 1));
+        assert_eq!(db.get("api").unwrap().path, root.join("shop/web").to_string_lossy());
+
+        let result = scan(&mut db, &root_str, Some("t"), false).unwrap();
+        assert_eq!(result.added, 3);
+        assert!(db.contains("t:mine"));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn test_find_files_symlink_loop() {
+        let dir = tempdir().unwrap();
+        let root = fs::canonicalize(dir.path()).unwrap();
+        touch(&root, "shop/.goto-aliases", "api = \".\"\n");
+        std::os::unix::fs::symlink(&root, root.join("shop/loop")).unwrap();
+        std::os::unix::fs::symlink(root.join("shop"), root.join("shop-link")).unwrap();
+
+        // Ends, and finds the file once rather than through every link
+        assert_eq!(find_files(&root), vec![root.join("shop/.goto-aliases")]);
+    }
+}
@@ -0,0 +1,125 @@
+//! Alias selection shared by batch commands (name lists, name globs, filters)
+
+use regex::Regex;
+
+use crate::alias::{Alias, AliasError};
+use crate::config::path_matcher;
+use crate::database::Database;
+
+/// Which aliases a batch command should touch
+///
+/// `names` is a comma-separated list of alias names or name globs (`*`, `?`);
+/// `filter` narrows the result further, either `path:<prefix|glob>`, metadata
+/// (`key=value`) or a tag (`tag:<tag>` or just `<tag>`). Read-only system aliases are never selected
+/// by a glob; naming one explicitly is an error.
+pub fn select_aliases(
+    db: &Database,
+    names: &str,
+    filter: Option<&str>,
+) -> Result<Vec<String>, Box<dyn std::error::Error>> {
+    let mut selected: Vec<String> = Vec::new();
+
+    for part in names.split(',').map(str::trim).filter(|p| !p.is_empty()) {
+        if is_glob(part) {
+            let re = name_glob(part)?;
+            let mut matched: Vec<String> = db
+                .names()
+                .filter(|n| re.is_match(n) && !db.is_system(n))
+                .map(String::from)
+                .collect();
+            matched.sort();
+            selected.extend(matched);
+        } else {
+            let name = db.resolve_name(part);
+            if !db.contains(&name) {
+                return Err(AliasError::NotFound(part.to_string()).into());
+            }
+            db.check_writable(&name)?;
+            selected.push(name);
+        }
+    }
+
+    if let Some(filter) = filter {
+        let matches = filter_matcher(filter)?;
+        selected.retain(|n| db.get(n).is_some_and(|a| matches(a)));
+    }
+
+    let mut seen = std::collections::HashSet::new();
+    selected.retain(|n| seen.insert(n.clone()));
+    Ok(selected)
+}
+
+/// Whether a name pattern contains glob characters
+pub fn is_glob(pattern: &str) -> bool {
+    pattern.contains(['*', '?'])
+}
+
+/// Compile a name glob: `*` matches any run of characters, `?` exactly one
+pub fn name_glob(pattern: &str) -> Result<Regex, String> {
+    let mut re = String::from("^");
+    for c in pattern.chars() {
+        match c {
+            '*' => re.push_str(".*"),
+            '?' => re.push('.'),
+            _ => re.push_str(&regex::escape(&c.to_string())),
+        }
+    }
+    re.push('$');
+    Regex::new(&re).map_err(|e| format!("invalid pattern '{}': {}", pattern, e))
+}
+
+/// Build a predicate from a `--filter=` value
+fn filter_matcher(filter: &str) -> Result<Box<dyn Fn(&Alias) -> bool>, String> {
+    if let Some(pattern) = filter.strip_prefix("path:") {
+        let re = path_matcher(pattern)?;
+        return Ok(Box::new(move |a| re.is_match(&a.resolved_path())));
+    }
+
+    let filter = filter.strip_prefix("tag:").unwrap_or(filter).to_string();
+    Ok(Box::new(move |a| a.matches_filter(&filter)))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    fn create_test_db() -> (Database, tempfile::TempDir) {
+        let dir = tempdir().unwrap();
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        db.insert(Alias::new("api", "/srv/work/api").unwrap());
+        db.insert(Alias::new("web", "/srv/work/web").unwrap());
+        let mut docs = Alias::new("docs", "/srv/home/docs").unwrap();
+        docs.add_tag("personal");
+        docs.meta.insert("owner".to_string(), "antti".to_string());
+        db.insert(docs);
+        (db, dir)
+    }
+
+    #[test]
+    fn test_select_list() {
+        let (db, _dir) = create_test_db();
+        assert_eq!(select_aliases(&db, "web,api", None).unwrap(), vec!["web", "api"]);
+        assert!(select_aliases(&db, "api,missing", None).is_err());
+    }
+
+    #[test]
+    fn test_select_glob() {
+        let (db, _dir) = create_test_db();
+        assert_eq!(select_aliases(&db, "*", None).unwrap(), vec!["api", "docs", "web"]);
+        assert_eq!(select_aliases(&db, "?eb", None).unwrap(), vec!["web"]);
+        assert!(select_aliases(&db, "zz*", None).unwrap().is_empty());
+    }
+
+    #[test]
+    fn test_select_with_filter() {
+        let (db, _dir) = create_test_db();
+        assert_eq!(
+            select_aliases(&db, "*", Some("path:/srv/work/**")).unwrap(),
+            vec!["api", "web"]
+        );
+        assert_eq!(select_aliases(&db, "*", Some("personal")).unwrap(), vec!["docs"]);
+        assert_eq!(select_aliases(&db, "*", Some("tag:personal")).unwrap(), vec!["docs"]);
+        assert_eq!(select_aliases(&db, "*", Some("owner=antti")).unwrap(), vec!["docs"]);
+    }
+}
@@ -0,0 +1,529 @@
+//! HTTP API for launchers and editor plugins (`goto --serve`)
+//!
+//! A deliberately small HTTP/1.1 server on std's `TcpListener`: one request
+//! per connection, JSON in and out, bearer-token auth. The database is loaded
+//! fresh for every request so changes made by the CLI show up immediately and
+//! are never overwritten by a stale copy.
+//!
+//! Each connection is read on its own thread, within size limits and one
+//! deadline for the whole request, so a slow or oversized client can't hold
+//! up anyone else. Requests are still handled one at a time.
+
+use std::error::Error;
+use std::io::{self, BufRead, BufReader, Read, Write};
+use std::net::{TcpListener, TcpStream};
+use std::sync::Mutex;
+use std::thread;
+use std::time::{Duration, Instant};
+
+use chrono::{DateTime, Utc};
+use serde::Deserialize;
+use serde_json::{json, Value};
+
+use crate::commands::navigate::resolve;
+use crate::commands::register::{register_with_rules, IfExists, RegisterOptions};
+use crate::config::Config;
+use crate::database::Database;
+
+/// Address used when `--listen` is not given
+pub const DEFAULT_LISTEN: &str = "127.0.0.1:7777";
+
+/// Largest request body accepted
+const MAX_BODY: usize = 64 * 1024;
+
+/// Longest request line or header line accepted
+const MAX_LINE: u64 = 8 * 1024;
+
+/// Most bytes of request line and headers together
+const MAX_HEAD: usize = 32 * 1024;
+
+/// How long a client may take to send its whole request
+const READ_TIMEOUT: Duration = Duration::from_secs(5);
+
+/// A parsed HTTP request
+#[derive(Debug, Default)]
+pub struct Request {
+    pub method: String,
+    /// Path without the query string
+    pub path: String,
+    /// Decoded query parameters in order
+    pub query: Vec<(String, String)>,
+    /// Token from an `Authorization: Bearer` header
+    pub token: Option<String>,
+    pub body: Vec<u8>,
+}
+
+impl Request {
+    fn param(&self, key: &str) -> Option<&str> {
+        self.query.iter().find(|(k, _)| k == key).map(|(_, v)| v.as_str())
+    }
+}
+
+/// Body of `POST /aliases`
+#[derive(Debug, Deserialize)]
+struct RegisterBody {
+    name: String,
+    path: String,
+    #[serde(default)]
+    tags: Vec<String>,
+    #[serde(default)]
+    description: Option<String>,
+    /// RFC 3339 time after which the alias stops working for navigation
+    #[serde(default)]
+    expires_at: Option<DateTime<Utc>>,
+}
+
+/// Serve the API on `listen` until the process is stopped
+///
+/// The token comes from `GOTO_API_TOKEN`; without it a random one is
+/// generated and printed to stderr.
+pub fn serve(config: &Config, listen: &str) -> Result<(), Box<dyn Error>> {
+    let token = match std::env::var("GOTO_API_TOKEN") {
+        Ok(token) if !token.is_empty() => token,
+        _ => {
+            let token = random_token()?;
+            eprintln!("No GOTO_API_TOKEN set; using generated token: {}", token);
+            token
+        }
+    };
+
+    let listener = TcpListener::bind(listen).map_err(|e| format!("cannot listen on {}: {}", listen, e))?;
+    eprintln!("goto API listening on http://{} (Ctrl-C to stop)", listener.local_addr()?);
+
+    // Reading runs in parallel; each request loads, changes and saves the database alone
+    let handling = Mutex::new(());
+    thread::scope(|scope| {
+        for stream in listener.incoming() {
+            let mut stream = match stream {
+                Ok(stream) => stream,
+                Err(e) => {
+                    eprintln!("connection failed: {}", e);
+                    continue;
+                }
+            };
+            let (token, handling) = (&token, &handling);
+            scope.spawn(move || {
+                let (status, body) = match read_request(&stream, READ_TIMEOUT) {
+                    Ok(request) => {
+                        let _handling = handling.lock().unwrap_or_else(|e| e.into_inner());
+                        handle(config, token, &request)
+                    }
+                    Err(e) => (400, json!({ "error": e.to_string() })),
+                };
+                if let Err(e) = write_response(&mut stream, status, &body) {
+                    eprintln!("failed to send response: {}", e);
+                }
+            });
+        }
+    });
+    Ok(())
+}
+
+/// Route a request and produce a status code and JSON body
+///
+/// Endpoints:
+/// - `GET /aliases` lists all aliases
+/// - `GET /resolve?name=<name>` resolves a name the way `goto <name>` would
+/// - `POST /aliases` with `{"name", "path", "tags"}` registers an alias
+/// - `POST /aliases/<name>/use` records a use, as a jump would
+pub fn handle(config: &Config, token: &str, request: &Request) -> (u16, Value) {
+    if !request.token.as_deref().is_some_and(|t| tokens_match(t, token)) {
+        return (401, json!({ "error": "missing or invalid token" }));
+    }
+
+    let result = Database::load(config)
+        .map_err(|e| -> Box<dyn Error> { e.into() })
+        .and_then(|mut db| route(config, &mut db, request));
+    match result {
+        Ok(response) => response,
+        Err(e) => {
+            let message = e.to_string();
+            (status_for(&message), json!({ "error": message }))
+        }
+    }
+}
+
+fn route(config: &Config, db: &mut Database, request: &Request) -> Result<(u16, Value), Box<dyn Error>> {
+    let segments: Vec<&str> = request.path.trim_matches('/').split('/').collect();
+
+    match (request.method.as_str(), segments.as_slice()) {
+        ("GET", ["aliases"]) => {
+            let mut aliases: Vec<_> = db.all().collect();
+            aliases.sort_by(|a, b| a.name.cmp(&b.name));
+            Ok((200, json!({ "aliases": aliases })))
+        }
+
+        ("GET", ["resolve"]) => {
+            let name = request.param("name").ok_or("missing 'name' parameter")?;
+            let resolved = resolve(db, name)
+                .resolved
+                .ok_or_else(|| format!("alias '{}' not found", name))?;
+            let alias = db.get(&resolved).ok_or_else(|| format!("alias '{}' not found", name))?;
+            Ok((200, json!({ "name": resolved, "path": alias.resolved_path(), "alias": alias })))
+        }
+
+        ("POST", ["aliases"]) => {
+            let body: RegisterBody =
+                serde_json::from_slice(&request.body).map_err(|e| format!("invalid request body: {}", e))?;
+            // force: the API has no terminal to confirm new tags on
+            let options = RegisterOptions {
+                tags: &body.tags,
+                description: body.description.as_deref(),
+                expires: body.expires_at,
+                force: true,
+                rules: &config.user.autotag,
+                if_exists: IfExists::Fail,
+            };
+            register_with_rules(db, &body.name, &body.path, &options)?;
+            Ok((201, json!({ "alias": db.get(&body.name) })))
+        }
+
+        ("POST", ["aliases", name, "use"]) => {
+            let name = db.resolve_name(&percent_decode(name));
+            db.record_usage(&name)?;
+            db.save()?;
+            Ok((200, json!({ "alias": db.get(&name) })))
+        }
+
+        (_, ["aliases"] | ["resolve"] | ["aliases", _, "use"]) => Ok((405, json!({ "error": "method not allowed" }))),
+
+        _ => Ok((404, json!({ "error": format!("no such endpoint: {}", request.path) }))),
+    }
+}
+
+/// HTTP status for an error message, following the CLI's exit code mapping
+fn status_for(message: &str) -> u16 {
+    if message.contains("not found") {
+        404
+    } else if message.contains("already exists") {
+        409
+    } else if message.contains("read-only") || message.contains("permission denied") {
+        403
+    } else if message.contains("invalid") || message.contains("missing") || message.contains("does not exist") {
+        400
+    } else {
+        500
+    }
+}
+
+/// Compare tokens without returning early on the first differing byte
+fn tokens_match(given: &str, expected: &str) -> bool {
+    given.len() == expected.len()
+        && given
+            .bytes()
+            .zip(expected.bytes())
+            .fold(0u8, |diff, (a, b)| diff | (a ^ b))
+            == 0
+}
+
+/// 32 hex characters from the OS random source
+fn random_token() -> Result<String, Box<dyn Error>> {
+    let mut bytes = [0u8; 16];
+    getrandom::getrandom(&mut bytes).map_err(|e| format!("no random source for the token: {}", e))?;
+    Ok(bytes.iter().map(|b| format!("{:02x}", b)).collect())
+}
+
+/// A connection that stops reading once `deadline` has passed, however the
+/// client spreads out what it sends
+struct DeadlineStream<'a> {
+    stream: &'a TcpStream,
+    deadline: Instant,
+}
+
+impl Read for DeadlineStream<'_> {
+    fn read(&mut self, buf: &mut [u8]) -> io::Result<usize> {
+        let left = self.deadline.saturating_duration_since(Instant::now());
+        if left.is_zero() {
+            return Err(io::Error::new(io::ErrorKind::TimedOut, "request took too long to arrive"));
+        }
+        self.stream.set_read_timeout(Some(left))?;
+        let mut stream = self.stream;
+        stream.read(buf)
+    }
+}
+
+/// Read one line of the request head into `line`, counting it against `head`
+///
+/// Returns the number of bytes read; 0 at the end of the stream.
+fn read_head_line<R: BufRead>(reader: &mut R, line: &mut String, head: &mut usize) -> Result<usize, Box<dyn Error>> {
+    line.clear();
+    let read = reader.take(MAX_LINE).read_line(line)?;
+    if read as u64 == MAX_LINE && !line.ends_with('\n') {
+        return Err(format!("request line or header longer than {} bytes", MAX_LINE).into());
+    }
+    *head += read;
+    if *head > MAX_HEAD {
+        return Err(format!("request headers larger than {} bytes", MAX_HEAD).into());
+    }
+    Ok(read)
+}
+
+/// Read one request: request line, headers and a Content-Length body, all
+/// within `timeout`
+fn read_request(stream: &TcpStream, timeout: Duration) -> Result<Request, Box<dyn Error>> {
+    let mut reader = BufReader::new(DeadlineStream { stream, deadline: Instant::now() + timeout });
+
+    let mut line = String::new();
+    let mut head = 0;
+    read_head_line(&mut reader, &mut line, &mut head)?;
+    let mut parts = line.split_whitespace();
+    let (Some(method), Some(target)) = (parts.next(), parts.next()) else {
+        return Err("malformed request line".into());
+    };
+    let (path, query) = target.split_once('?').unwrap_or((target, ""));
+    let mut request = Request {
+        method: method.to_string(),
+        path: path.to_string(),
+        query: parse_query(query),
+        ..Default::default()
+    };
+
+    let mut length = 0;
+    loop {
+        if read_head_line(&mut reader, &mut line, &mut head)? == 0 || line.trim().is_empty() {
+            break;
+        }
+        let Some((key, value)) = line.split_once(':') else {
+            continue;
+        };
+        let value = value.trim();
+        match key.trim().to_ascii_lowercase().as_str() {
+            "content-length" => length = value.parse().map_err(|_| "invalid Content-Length")?,
+            "authorization" => request.token = value.strip_prefix("Bearer ").map(|t| t.trim().to_string()),
+            _ => {}
+        }
+    }
+
+    if length > MAX_BODY {
+        return Err(format!("request body larger than {} bytes", MAX_BODY).into());
+    }
+    request.body = vec![0; length];
+    reader.read_exact(&mut request.body)?;
+    Ok(request)
+}
+
+fn write_response(stream: &mut TcpStream, status: u16, body: &Value) -> std::io::Result<()> {
+    let reason = match status {
+        200 => "OK",
+        201 => "Created",
+        400 => "Bad Request",
+        401 => "Unauthorized",
+        403 => "Forbidden",
+        404 => "Not Found",
+        405 => "Method Not Allowed",
+        409 => "Conflict",
+        _ => "Internal Server Error",
+    };
+    let body = body.to_string();
+    write!(
+        stream,
+        "HTTP/1.1 {} {}\r\nContent-Type: application/json\r\nContent-Length: {}\r\nConnection: close\r\n\r\n{}",
+        status,
+        reason,
+        body.len(),
+        body
+    )?;
+    stream.flush()
+}
+
+/// Split `a=1&b=2` into decoded pairs
+fn parse_query(query: &str) -> Vec<(String, String)> {
+    query
+        .split('&')
+        .filter(|p| !p.is_empty())
+        .map(|p| {
+            let (k, v) = p.split_once('=').unwrap_or((p, ""));
+            (percent_decode(k), percent_decode(v))
+        })
+        .collect()
+}
+
+/// Decode `%XX` escapes and `+` as space
+fn percent_decode(s: &str) -> String {
+    let bytes = s.as_bytes();
+    let mut out = Vec::with_capacity(bytes.len());
+    let mut i = 0;
+    while i < bytes.len() {
+        let escaped = bytes
+            .get(i + 1..i + 3)
+            .and_then(|hex| std::str::from_utf8(hex).ok())
+            .and_then(|hex| u8::from_str_radix(hex, 16).ok());
+        match (bytes[i], escaped) {
+            (b'%', Some(b)) => {
+                out.push(b);
+                i += 3;
+                continue;
+            }
+            (b'+', _) => out.push(b' '),
+            (b, _) => out.push(b),
+        }
+        i += 1;
+    }
+    String::from_utf8_lossy(&out).to_string()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    const TOKEN: &str = "secret";
+
+    fn test_config(dir: &std::path::Path) -> Config {
+        Config {
+            database_path: dir.to_path_buf(),
+            stack_path: dir.join("goto_stack"),
+            history_path: dir.join("dir_history"),
+            context_path: dir.join("context"),
+            listing_path: dir.join("last_listing"),
+            config_path: dir.join("config.toml"),
+            aliases_path: dir.join("aliases.toml"),
+            user: Default::default(),
+        }
+    }
+
+    fn request(method: &str, target: &str, body: &str) -> Request {
+        let (path, query) = target.split_once('?').unwrap_or((target, ""));
+        Request {
+            method: method.to_string(),
+            path: path.to_string(),
+            query: parse_query(query),
+            token: Some(TOKEN.to_string()),
+            body: body.as_bytes().to_vec(),
+        }
+    }
+
+    #[test]
+    fn test_random_token() {
+        let (a, b) = (random_token().unwrap(), random_token().unwrap());
+        assert_eq!(a.len(), 32);
+        assert!(a.chars().all(|c| c.is_ascii_hexdigit()));
+        assert_ne!(a, b);
+    }
+
+    #[test]
+    fn test_requires_token() {
+        let dir = tempdir().unwrap();
+        let config = test_config(dir.path());
+        let mut req = request("GET", "/aliases", "");
+        req.token = Some("wrong".to_string());
+        assert_eq!(handle(&config, TOKEN, &req).0, 401);
+        req.token = None;
+        assert_eq!(handle(&config, TOKEN, &req).0, 401);
+    }
+
+    #[test]
+    fn test_register_list_resolve_use() {
+        let dir = tempdir().unwrap();
+        let config = test_config(dir.path());
+        let target = dir.path().canonicalize().unwrap();
+        let body = json!({ "name": "proj", "path": target, "tags": ["work"] }).to_string();
+
+        let (status, _) = handle(&config, TOKEN, &request("POST", "/aliases", &body));
+        assert_eq!(status, 201);
+        assert_eq!(handle(&config, TOKEN, &request("POST", "/aliases", &body)).0, 409);
+
+        let (status, list) = handle(&config, TOKEN, &request("GET", "/aliases", ""));
+        assert_eq!(status, 200);
+        assert_eq!(list["aliases"][0]["name"], "proj");
+        assert_eq!(list["aliases"][0]["tags"][0], "work");
+
+        let (status, found) = handle(&config, TOKEN, &request("GET", "/resolve?name=proj", ""));
+        assert_eq!(status, 200);
+        assert_eq!(found["path"], target.to_string_lossy().as_ref());
+        assert_eq!(handle(&config, TOKEN, &request("GET", "/resolve?name=zzzz", "")).0, 404);
+
+        let (status, used) = handle(&config, TOKEN, &request("POST", "/aliases/proj/use", ""));
+        assert_eq!(status, 200);
+        assert_eq!(used["alias"]["use_count"], 1);
+    }
+
+    #[test]
+    fn test_bad_requests() {
+        let dir = tempdir().unwrap();
+        let config = test_config(dir.path());
+        assert_eq!(handle(&config, TOKEN, &request("POST", "/aliases", "not json")).0, 400);
+        assert_eq!(handle(&config, TOKEN, &request("DELETE", "/aliases", "")).0, 405);
+        assert_eq!(handle(&config, TOKEN, &request("GET", "/nope", "")).0, 404);
+        assert_eq!(handle(&config, TOKEN, &request("GET", "/resolve", "")).0, 400);
+    }
+
+    /// Feed `send` to [`read_request`] over a real connection
+    fn read_sent(send: impl FnOnce(&mut TcpStream) + Send + 'static, timeout: Duration) -> Result<Request, String> {
+        let listener = TcpListener::bind("127.0.0.1:0").unwrap();
+        let addr = listener.local_addr().unwrap();
+        let client = thread::spawn(move || {
+            let mut stream = TcpStream::connect(addr).unwrap();
+            send(&mut stream);
+            // Hold the connection open until the server has given up
+            let _ = stream.read(&mut [0u8; 1]);
+        });
+        let (stream, _) = listener.accept().unwrap();
+        let result = read_request(&stream, timeout).map_err(|e| e.to_string());
+        drop(stream);
+        client.join().unwrap();
+        result
+    }
+
+    #[test]
+    fn test_read_request() {
+        let sent = b"POST /aliases?x=1 HTTP/1.1\r\nAuthorization: Bearer t\r\nContent-Length: 2\r\n\r\n{}";
+        let request = read_sent(|s| s.write_all(sent).unwrap(), READ_TIMEOUT).unwrap();
+        assert_eq!((request.method.as_str(), request.path.as_str()), ("POST", "/aliases"));
+        assert_eq!(request.token.as_deref(), Some("t"));
+        assert_eq!(request.body, b"{}");
+    }
+
+    #[test]
+    fn test_read_request_limits() {
+        // One endless header line
+        let err = read_sent(
+            |s| {
+                s.write_all(b"GET /aliases HTTP/1.1\r\nX-Junk: ").unwrap();
+                let _ = s.write_all(&[b'a'; 64 * 1024]);
+            },
+            READ_TIMEOUT,
+        )
+        .unwrap_err();
+        assert!(err.contains("longer than"), "{}", err);
+
+        // Many headers that are each short enough
+        let err = read_sent(
+            |s| {
+                s.write_all(b"GET /aliases HTTP/1.1\r\n").unwrap();
+                for _ in 0..64 {
+                    let _ = s.write_all(format!("X-Junk: {}\r\n", "a".repeat(1000)).as_bytes());
+                }
+            },
+            READ_TIMEOUT,
+        )
+        .unwrap_err();
+        assert!(err.contains("headers larger than"), "{}", err);
+
+        // A trickle of bytes doesn't stretch the deadline
+        let started = Instant::now();
+        let err = read_sent(
+            |s| {
+                for _ in 0..20 {
+                    if s.write_all(b"G").is_err() {
+                        break;
+                    }
+                    thread::sleep(Duration::from_millis(50));
+                }
+            },
+            Duration::from_millis(200),
+        )
+        .unwrap_err();
+        assert!(err.contains("too long"), "{}", err);
+        assert!(started.elapsed() < Duration::from_secs(2));
+    }
+
+    #[test]
+    fn test_helpers() {
+        assert_eq!(percent_decode("work%3Aapi+x"), "work:api x");
+        assert_eq!(percent_decode("100%"), "100%");
+        assert!(tokens_match("abc", "abc"));
+        assert!(!tokens_match("abc", "abd"));
+        assert!(!tokens_match("ab", "abc"));
+    }
+}
@@ -0,0 +1,276 @@
+//! Session commands: save the directory stack and a set of directories under a
+//! name, and bring them back later (after a reboot, say)
+//!
+//! Sessions live in [`FILE_NAME`] next to the database. Restoring puts the
+//! saved stack back, pushes the chosen directories on top (or opens a tmux
+//! window for each with `--tmux`), and returns to the directory the session
+//! was saved from.
+
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+use std::collections::BTreeMap;
+use std::error::Error;
+use std::fs;
+use std::path::Path;
+use std::process::Command;
+
+use crate::alias::{validate_tag, AliasError};
+use crate::commands::register::resolve_directory;
+use crate::commands::stats::format_time_ago;
+use crate::config::Config;
+use crate::database::Database;
+use crate::stack::Stack;
+
+/// File name of the saved sessions, next to the database
+pub const FILE_NAME: &str = "sessions.toml";
+
+/// One saved session
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Session {
+    pub saved_at: DateTime<Utc>,
+    /// Directory the session was saved from; restoring returns to it
+    pub cwd: String,
+    /// The directory stack, bottom first
+    #[serde(default)]
+    pub stack: Vec<String>,
+    /// Directories chosen when saving
+    #[serde(default)]
+    pub dirs: Vec<String>,
+}
+
+#[derive(Debug, Default, Serialize, Deserialize)]
+struct SessionFile {
+    #[serde(default)]
+    sessions: BTreeMap<String, Session>,
+}
+
+/// Save the current directory, the stack and `targets` (aliases or directories) as `name`
+pub fn save_session(config: &Config, db: &Database, name: &str, targets: &[String]) -> Result<(), Box<dyn Error>> {
+    if validate_tag(name).is_err() {
+        return Err(format!(
+            "invalid session name '{}': use letters, digits, '-' and '_'",
+            name
+        )
+        .into());
+    }
+
+    let mut dirs = Vec::new();
+    for target in targets {
+        let dir = match db.get(&db.resolve_name(target)) {
+            Some(alias) => alias.directory(),
+            None => resolve_directory(target)?,
+        };
+        if !dirs.contains(&dir) {
+            dirs.push(dir);
+        }
+    }
+
+    let session = Session {
+        saved_at: Utc::now(),
+        cwd: std::env::current_dir()?.to_string_lossy().to_string(),
+        stack: Stack::new(config.stack_path.clone()).entries()?,
+        dirs,
+    };
+    let mut sessions = load_sessions(config)?;
+    println!(
+        "Saved session '{}': {} on the stack, {} director{}",
+        name,
+        session.stack.len(),
+        session.dirs.len(),
+        if session.dirs.len() == 1 { "y" } else { "ies" }
+    );
+    sessions.insert(name.to_string(), session);
+    save_sessions(config, &sessions)
+}
+
+/// Restore session `name`: refill the stack, then print the saved directory for the shell to cd to
+///
+/// Directories that no longer exist are skipped with a note.
+pub fn restore_session(config: &Config, name: &str, tmux: bool) -> Result<(), Box<dyn Error>> {
+    let sessions = load_sessions(config)?;
+    let session = sessions.get(name).ok_or_else(|| AliasError::Missing(format!("session '{}'", name)))?;
+    if tmux && std::env::var_os("TMUX").is_none() {
+        return Err("--tmux only works inside a tmux session".into());
+    }
+
+    let existing = |dirs: &[String]| -> Vec<String> {
+        dirs.iter()
+            .filter(|dir| {
+                let found = Path::new(dir).is_dir();
+                if !found {
+                    eprintln!("Skipping missing directory: {}", dir);
+                }
+                found
+            })
+            .cloned()
+            .collect()
+    };
+    let mut stack = existing(&session.stack);
+    let dirs = existing(&session.dirs);
+
+    if tmux {
+        open_tmux_windows(&dirs)?;
+    } else {
+        stack.extend(dirs);
+    }
+    Stack::new(config.stack_path.clone()).set(&stack)?;
+
+    if Path::new(&session.cwd).is_dir() {
+        println!("{}", session.cwd);
+    } else {
+        eprintln!("Skipping missing directory: {}", session.cwd);
+    }
+    Ok(())
+}
+
+/// List saved sessions, newest first
+pub fn list_sessions(config: &Config) -> Result<(), Box<dyn Error>> {
+    let sessions = load_sessions(config)?;
+    if sessions.is_empty() {
+        println!("No saved sessions (save one with 'goto --session save <name>')");
+        return Ok(());
+    }
+
+    let mut sessions: Vec<(&String, &Session)> = sessions.iter().collect();
+    sessions.sort_by(|a, b| b.1.saved_at.cmp(&a.1.saved_at));
+    for (name, session) in sessions {
+        println!(
+            "{}  {}  {} ({} on the stack, {} more)",
+            name,
+            format_time_ago(Some(session.saved_at)),
+            session.cwd,
+            session.stack.len(),
+            session.dirs.len()
+        );
+    }
+    Ok(())
+}
+
+/// Forget session `name`
+pub fn delete_session(config: &Config, name: &str) -> Result<(), Box<dyn Error>> {
+    let mut sessions = load_sessions(config)?;
+    if sessions.remove(name).is_none() {
+        return Err(AliasError::Missing(format!("session '{}'", name)).into());
+    }
+    save_sessions(config, &sessions)?;
+    println!("Deleted session '{}'", name);
+    Ok(())
+}
+
+/// Open a detached tmux window in each directory, named after it
+fn open_tmux_windows(dirs: &[String]) -> Result<(), Box<dyn Error>> {
+    for dir in dirs {
+        let window = Path::new(dir)
+            .file_name()
+            .map(|n| n.to_string_lossy().to_string())
+            .unwrap_or_else(|| dir.clone());
+        let status = Command::new("tmux")
+            .args(["new-window", "-d", "-c", dir, "-n", &window])
+            .status()
+            .map_err(|e| format!("failed to run tmux: {}", e))?;
+        if !status.success() {
+            return Err(format!("tmux could not open a window in {}", dir).into());
+        }
+    }
+    Ok(())
+}
+
+fn load_sessions(config: &Config) -> Result<BTreeMap<String, Session>, Box<dyn Error>> {
+    let path = config.database_path.join(FILE_NAME);
+    if !path.exists() {
+        return Ok(BTreeMap::new());
+    }
+    let file: SessionFile = toml::from_str(&fs::read_to_string(path)?)?;
+    Ok(file.sessions)
+}
+
+fn save_sessions(config: &Config, sessions: &BTreeMap<String, Session>) -> Result<(), Box<dyn Error>> {
+    config.ensure_dirs()?;
+    let file = SessionFile { sessions: sessions.clone() };
+    fs::write(config.database_path.join(FILE_NAME), toml::to_string_pretty(&file)?)?;
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::alias::{Alias, AliasKind};
+    use tempfile::tempdir;
+
+    fn test_config(dir: &Path) -> Config {
+        Config {
+            database_path: dir.to_path_buf(),
+            stack_path: dir.join("goto_stack"),
+            history_path: dir.join("dir_history"),
+            context_path: dir.join("context"),
+            listing_path: dir.join("last_listing"),
+            config_path: dir.join("config.toml"),
+            aliases_path: dir.join("aliases.toml"),
+            user: Default::default(),
+        }
+    }
+
+    #[test]
+    fn test_save_and_restore() {
+        let dir = tempdir().unwrap();
+        let config = test_config(dir.path());
+        let root = dir.path().to_string_lossy().to_string();
+        let api = dir.path().join("api");
+        let gone = dir.path().join("gone");
+        fs::create_dir(&api).unwrap();
+        fs::create_dir(&gone).unwrap();
+        let api = api.to_string_lossy().to_string();
+        let gone = gone.to_string_lossy().to_string();
+
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        db.insert(Alias::new("api", &api).unwrap());
+        let stack = Stack::new(config.stack_path.clone());
+        stack.set(&[root.clone(), gone.clone()]).unwrap();
+
+        save_session(&config, &db, "work", &["api".to_string(), root.clone(), "api".to_string()]).unwrap();
+        let saved = &load_sessions(&config).unwrap()["work"];
+        assert_eq!(saved.stack, vec![root.clone(), gone.clone()]);
+        assert_eq!(saved.dirs, vec![api.clone(), root.clone()]);
+
+        stack.clear().unwrap();
+        fs::remove_dir(&gone).unwrap();
+        restore_session(&config, "work", false).unwrap();
+        assert_eq!(stack.entries().unwrap(), vec![root.clone(), api, root]);
+
+        assert!(restore_session(&config, "play", false).is_err());
+        delete_session(&config, "work").unwrap();
+        assert!(load_sessions(&config).unwrap().is_empty());
+        assert!(delete_session(&config, "work").is_err());
+    }
+
+    #[test]
+    fn test_save_file_bookmark_as_its_directory() {
+        let dir = tempdir().unwrap();
+        let config = test_config(dir.path());
+        let notes = dir.path().join("notes.md");
+        fs::write(&notes, "").unwrap();
+
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        let mut alias = Alias::new("notes", &notes.to_string_lossy()).unwrap();
+        alias.kind = AliasKind::File;
+        db.insert(alias);
+
+        // The stack only takes directories, so a file bookmark restores as its own
+        save_session(&config, &db, "docs", &["notes".to_string()]).unwrap();
+        let root = dir.path().to_string_lossy().to_string();
+        assert_eq!(load_sessions(&config).unwrap()["docs"].dirs, vec![root.clone()]);
+        restore_session(&config, "docs", false).unwrap();
+        assert_eq!(Stack::new(config.stack_path.clone()).entries().unwrap().last(), Some(&root));
+    }
+
+    #[test]
+    fn test_save_rejects_bad_input() {
+        let dir = tempdir().unwrap();
+        let config = test_config(dir.path());
+        let db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+
+        assert!(save_session(&config, &db, "my work", &[]).is_err());
+        assert!(save_session(&config, &db, "work", &["/nonexistent/path/12345".to_string()]).is_err());
+        assert!(!dir.path().join(FILE_NAME).exists());
+    }
+}
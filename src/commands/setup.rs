@@ -0,0 +1,323 @@
+//! First-run setup wizard: shell integration, importing from other jumpers, starter aliases
+
+use std::error::Error;
+use std::fs;
+use std::io::{self, IsTerminal};
+use std::path::{Path, PathBuf};
+use std::process::Command;
+
+use crate::alias::{validate_alias, Alias};
+use crate::commands::install::{install_to, InstallOptions, ShellType};
+use crate::config::Config;
+use crate::database::Database;
+use crate::{confirm, prompt_selection};
+
+/// How many directories to offer from zoxide/autojump
+const IMPORT_LIMIT: usize = 10;
+
+/// Directories under $HOME offered as starter aliases when they exist
+const STARTER_DIRS: &[&str] = &["Documents", "Downloads", "projects", "code", "src", "dev"];
+
+/// True when goto has never been used: no database and no config file
+pub fn is_first_run(config: &Config) -> bool {
+    !config.aliases_path.exists()
+        && !config.database_path.join("aliases").exists()
+        && !config.config_path.exists()
+}
+
+/// Offer the setup wizard on the very first interactive run
+///
+/// Writing the default config file afterwards means the offer is made only once,
+/// whether or not it was accepted.
+pub fn offer_first_run_setup(config: &Config, db: &mut Database) -> Result<(), Box<dyn Error>> {
+    if !is_first_run(config) || !io::stdin().is_terminal() {
+        return Ok(());
+    }
+
+    let accepted = confirm("Welcome to goto! Run first-time setup?", true)?;
+    config.create_default_config_file()?;
+    if accepted {
+        setup(config, db)?;
+    }
+    Ok(())
+}
+
+/// Run the interactive setup wizard
+///
+/// All output goes to stderr so the wizard can run from inside the shell
+/// wrapper, which captures stdout.
+pub fn setup(config: &Config, db: &mut Database) -> Result<(), Box<dyn Error>> {
+    if !io::stdin().is_terminal() {
+        return Err("setup requires an interactive terminal".into());
+    }
+    config.create_default_config_file()?;
+
+    // Step 1: shell integration
+    eprintln!("[1/3] Shell integration");
+    let shell = match ShellType::detect() {
+        Ok(shell) => Some(shell),
+        Err(_) => {
+            eprintln!("Which shell do you use?");
+            let shells = [ShellType::Bash, ShellType::Zsh, ShellType::Fish];
+            prompt_selection(&["bash", "zsh", "fish"], None)?.map(|i| shells[i])
+        }
+    };
+    match shell {
+        Some(shell) if confirm(&format!("Install the wrapper and completions for {:?}?", shell), true)? => {
+            install_to(&InstallOptions::new(shell), &mut io::stderr())?;
+        }
+        _ => eprintln!("  Skipped (run 'goto-bin --install' later)"),
+    }
+    eprintln!();
+
+    // Step 2: import from other directory jumpers
+    eprintln!("[2/3] Import from other tools");
+    let mut added = 0;
+    let mut found_any = false;
+    for (source, dirs) in import_candidates() {
+        let dirs: Vec<String> = dirs
+            .into_iter()
+            .filter(|d| Path::new(d).is_dir() && !has_path(db, d))
+            .take(IMPORT_LIMIT)
+            .collect();
+        if dirs.is_empty() {
+            continue;
+        }
+        found_any = true;
+
+        eprintln!("Top directories from {}:", source);
+        for dir in &dirs {
+            eprintln!("  {}", dir);
+        }
+        if confirm(&format!("Register these {} directories?", dirs.len()), true)? {
+            added += register_all(db, &dirs)?;
+        }
+    }
+    if !found_any {
+        eprintln!("  No zoxide or autojump history found");
+    }
+    eprintln!();
+
+    // Step 3: starter directories
+    eprintln!("[3/3] Starter aliases");
+    let starters: Vec<String> = starter_dirs()
+        .into_iter()
+        .filter(|d| !has_path(db, d))
+        .collect();
+    if starters.is_empty() {
+        eprintln!("  Nothing to add");
+    } else {
+        for dir in &starters {
+            eprintln!("  {}", dir);
+        }
+        if confirm("Register these starter directories?", true)? {
+            added += register_all(db, &starters)?;
+        }
+    }
+
+    db.save()?;
+    eprintln!();
+    eprintln!(
+        "Setup complete: {} alias{} registered. Try 'goto -l'.",
+        added,
+        if added == 1 { "" } else { "es" }
+    );
+    Ok(())
+}
+
+/// Register each directory under a proposed name, returning how many were added
+fn register_all(db: &mut Database, dirs: &[String]) -> Result<usize, Box<dyn Error>> {
+    let mut added = 0;
+    for dir in dirs {
+        let Some(name) = propose_name(db, dir) else {
+            continue;
+        };
+        if let Err(e) = db.check_path_allowed(dir) {
+            eprintln!("  skipping {}: {}", dir, e);
+            continue;
+        }
+        db.add(Alias::new(&name, dir)?)?;
+        eprintln!("  {} -> {}", name, dir);
+        added += 1;
+    }
+    Ok(added)
+}
+
+/// Check whether any alias already points at `path`
+fn has_path(db: &Database, path: &str) -> bool {
+    db.all().any(|a| a.path == path)
+}
+
+/// Propose an unused alias name for a directory, based on its last component
+///
+/// Starts from [`base_name`], adding a numeric suffix on conflicts.
+pub fn propose_name(db: &Database, path: &str) -> Option<String> {
+    let base = base_name(path)?;
+    if !db.contains(&base) {
+        return Some(base);
+    }
+    (2..10)
+        .map(|n| format!("{}-{}", base, n))
+        .find(|name| !db.contains(name))
+}
+
+/// The alias name a directory would get if nothing else were registered
+///
+/// The home directory becomes "home"; other names are lowercased, with
+/// unsupported characters replaced by '-'.
+pub fn base_name(path: &str) -> Option<String> {
+    let is_home = dirs::home_dir().is_some_and(|h| h == Path::new(path));
+    let base = if is_home {
+        "home".to_string()
+    } else {
+        let last = Path::new(path).file_name()?.to_string_lossy().to_lowercase();
+        let cleaned: String = last
+            .chars()
+            .map(|c| {
+                if c.is_ascii_alphanumeric() || matches!(c, '_' | '.' | '-') {
+                    c
+                } else {
+                    '-'
+                }
+            })
+            .collect();
+        cleaned
+            .trim_start_matches(|c: char| !c.is_ascii_alphanumeric())
+            .to_string()
+    };
+    validate_alias(&base).ok()?;
+    Some(base)
+}
+
+/// Ranked directories from zoxide and autojump, keyed by tool name
+fn import_candidates() -> Vec<(&'static str, Vec<String>)> {
+    let mut sources = Vec::new();
+
+    if let Ok(output) = Command::new("zoxide").args(["query", "--list", "--score"]).output() {
+        if output.status.success() {
+            let entries = parse_zoxide(&String::from_utf8_lossy(&output.stdout));
+            sources.push(("zoxide", entries.into_iter().map(|(_, p)| p).collect()));
+        }
+    }
+
+    if let Some(content) = autojump_data_file().and_then(|p| fs::read_to_string(p).ok()) {
+        let entries = parse_autojump(&content);
+        sources.push(("autojump", entries.into_iter().map(|(_, p)| p).collect()));
+    }
+
+    sources
+}
+
+/// Location of autojump's database
+fn autojump_data_file() -> Option<PathBuf> {
+    let home = dirs::home_dir()?;
+    [
+        home.join(".local/share/autojump/autojump.txt"),
+        home.join("Library/autojump/autojump.txt"),
+    ]
+    .into_iter()
+    .find(|p| p.exists())
+}
+
+/// Parse `zoxide query --list --score` output ("  12.5 /path"), highest score first
+pub fn parse_zoxide(output: &str) -> Vec<(f64, String)> {
+    let mut entries: Vec<(f64, String)> = output
+        .lines()
+        .filter_map(|line| {
+            let (score, path) = line.trim().split_once(' ')?;
+            Some((score.parse().ok()?, path.trim().to_string()))
+        })
+        .collect();
+    entries.sort_by(|a, b| b.0.total_cmp(&a.0));
+    entries
+}
+
+/// Parse autojump's data file ("weight<TAB>path" per line), highest weight first
+pub fn parse_autojump(content: &str) -> Vec<(f64, String)> {
+    let mut entries: Vec<(f64, String)> = content
+        .lines()
+        .filter_map(|line| {
+            let (weight, path) = line.split_once('\t')?;
+            Some((weight.trim().parse().ok()?, path.to_string()))
+        })
+        .collect();
+    entries.sort_by(|a, b| b.0.total_cmp(&a.0));
+    entries
+}
+
+/// Home plus common project/document directories that exist
+fn starter_dirs() -> Vec<String> {
+    let Some(home) = dirs::home_dir() else {
+        return Vec::new();
+    };
+    std::iter::once(home.clone())
+        .chain(STARTER_DIRS.iter().map(|d| home.join(d)))
+        .filter(|p| p.is_dir())
+        .map(|p| p.to_string_lossy().to_string())
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    fn empty_db(dir: &Path) -> Database {
+        Database::load_from_path(&dir.join("aliases")).unwrap()
+    }
+
+    #[test]
+    fn test_parse_zoxide() {
+        let entries = parse_zoxide("   4.0 /home/u/a\n  28.5 /home/u/my dir\ngarbage\n");
+        assert_eq!(entries.len(), 2);
+        assert_eq!(entries[0], (28.5, "/home/u/my dir".to_string()));
+        assert_eq!(entries[1].1, "/home/u/a");
+    }
+
+    #[test]
+    fn test_parse_autojump() {
+        let entries = parse_autojump("10.0\t/srv/a\n42.1\t/srv/b\nbad line\n");
+        assert_eq!(entries.len(), 2);
+        assert_eq!(entries[0].1, "/srv/b");
+    }
+
+    #[test]
+    fn test_propose_name() {
+        let dir = tempdir().unwrap();
+        let mut db = empty_db(dir.path());
+
+        assert_eq!(propose_name(&db, "/srv/My Project"), Some("my-project".to_string()));
+        assert_eq!(propose_name(&db, "/srv/.config"), Some("config".to_string()));
+        assert_eq!(propose_name(&db, "/"), None);
+
+        db.insert(Alias::new("api", "/a/api").unwrap());
+        assert_eq!(propose_name(&db, "/b/api"), Some("api-2".to_string()));
+    }
+
+    #[test]
+    fn test_propose_name_home() {
+        let dir = tempdir().unwrap();
+        let db = empty_db(dir.path());
+        let home = dirs::home_dir().unwrap();
+        assert_eq!(propose_name(&db, home.to_str().unwrap()), Some("home".to_string()));
+    }
+
+    #[test]
+    fn test_is_first_run() {
+        let dir = tempdir().unwrap();
+        let config = Config {
+            database_path: dir.path().to_path_buf(),
+            stack_path: dir.path().join("goto_stack"),
+            history_path: dir.path().join("dir_history"),
+            context_path: dir.path().join("context"),
+            listing_path: dir.path().join("last_listing"),
+            config_path: dir.path().join("config.toml"),
+            aliases_path: dir.path().join("aliases.toml"),
+            user: Default::default(),
+        };
+        assert!(is_first_run(&config));
+
+        config.create_default_config_file().unwrap();
+        assert!(!is_first_run(&config));
+    }
+}
@@ -0,0 +1,161 @@
+//! Show command: print everything known about a single alias
+
+use chrono::Utc;
+use std::fmt::Write;
+use std::path::Path;
+
+use crate::alias::{Alias, AliasError, AliasKind};
+use crate::commands::register::format_expiry;
+use crate::commands::stats::format_time_ago;
+use crate::database::Database;
+
+/// Print all metadata for one alias
+pub fn show(db: &Database, name: &str) -> Result<(), Box<dyn std::error::Error>> {
+    let name = &db.resolve_name(name);
+    let alias = db
+        .get(name)
+        .ok_or_else(|| AliasError::NotFound(name.to_string()))?;
+
+    print!("{}", format_details(alias, db.is_system(name)));
+    Ok(())
+}
+
+/// Render the details block shown by `goto --show`
+pub fn format_details(alias: &Alias, system: bool) -> String {
+    let mut out = String::new();
+    let resolved = alias.resolved_path();
+    let status = if alias.skip_check {
+        "not checked"
+    } else if alias.kind == AliasKind::File && Path::new(&resolved).is_file() {
+        "file"
+    } else if alias.kind == AliasKind::Dir && Path::new(&resolved).is_dir() {
+        "exists"
+    } else {
+        "missing"
+    };
+
+    writeln!(out, "Name:       {}", alias.name).unwrap();
+    if let Some(description) = &alias.description {
+        writeln!(out, "About:      {}", description).unwrap();
+    }
+    writeln!(out, "Path:       {} ({})", resolved, status).unwrap();
+    if let Some(kind) = &alias.project_type {
+        writeln!(out, "Type:       {}", kind).unwrap();
+    }
+    if resolved != alias.path {
+        writeln!(out, "Stored:     {}", alias.path).unwrap();
+    }
+    for (host, path) in &alias.paths {
+        writeln!(out, "  {:<9} {}", format!("{}:", host), path).unwrap();
+    }
+
+    let tags = if alias.tags.is_empty() {
+        "(none)".to_string()
+    } else {
+        alias.tags.join(", ")
+    };
+    writeln!(out, "Tags:       {}", tags).unwrap();
+    if !alias.meta.is_empty() {
+        writeln!(out, "Metadata:").unwrap();
+        for (key, value) in &alias.meta {
+            writeln!(out, "  {} = {}", key, value).unwrap();
+        }
+    }
+    writeln!(
+        out,
+        "Created:    {} ({})",
+        alias.created_at.format("%Y-%m-%d %H:%M"),
+        format_time_ago(Some(alias.created_at))
+    )
+    .unwrap();
+    match alias.last_used {
+        Some(t) => writeln!(
+            out,
+            "Last used:  {} ({})",
+            t.format("%Y-%m-%d %H:%M"),
+            format_time_ago(Some(t))
+        )
+        .unwrap(),
+        None => writeln!(out, "Last used:  {}", format_time_ago(None)).unwrap(),
+    }
+    writeln!(out, "Uses:       {}", alias.use_count).unwrap();
+    if let Some(at) = alias.expires_at {
+        let now = Utc::now();
+        let when = if at <= now {
+            "expired".to_string()
+        } else {
+            match (at - now).num_days() {
+                0 => "today".to_string(),
+                1 => "in 1 day".to_string(),
+                n => format!("in {} days", n),
+            }
+        };
+        writeln!(out, "Expires:    {} ({})", format_expiry(at), when).unwrap();
+    }
+    if system {
+        writeln!(out, "Source:     system aliases file, GOTO_PATH database or included config (read-only)").unwrap();
+    }
+
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    #[test]
+    fn test_format_details() {
+        let dir = tempdir().unwrap();
+        let mut alias = Alias::new("proj", dir.path().to_str().unwrap()).unwrap();
+        alias.add_tag("work");
+        alias.meta.insert("owner".to_string(), "antti".to_string());
+        alias.use_count = 3;
+        alias.description = Some("The main project".to_string());
+
+        let out = format_details(&alias, false);
+        assert!(out.contains("About:      The main project\n"));
+        assert!(out.contains("Name:       proj"));
+        assert!(out.contains("(exists)"));
+        assert!(out.contains("Tags:       work"));
+        assert!(out.contains("  owner = antti"));
+        assert!(out.contains("Last used:  never"));
+        assert!(out.contains("Uses:       3"));
+        assert!(!out.contains("Source:"));
+        assert!(!out.contains("Expires:"));
+
+        alias.expires_at = Some(Utc::now() + chrono::Duration::hours(36));
+        assert!(format_details(&alias, false).contains(" (in 1 day)\n"));
+        alias.expires_at = Some(Utc::now() - chrono::Duration::hours(1));
+        assert!(format_details(&alias, false).contains(" (expired)\n"));
+    }
+
+    #[test]
+    fn test_format_details_file() {
+        let dir = tempdir().unwrap();
+        let file = dir.path().join("notes.md");
+        std::fs::write(&file, "").unwrap();
+        let mut alias = Alias::new("notes", file.to_str().unwrap()).unwrap();
+        assert!(format_details(&alias, false).contains("(missing)"));
+        alias.kind = AliasKind::File;
+        assert!(format_details(&alias, false).contains("(file)"));
+    }
+
+    #[test]
+    fn test_format_details_missing_and_system() {
+        let alias = Alias::new("gone", "/nonexistent/path/12345").unwrap();
+        let out = format_details(&alias, true);
+        assert!(out.contains("(missing)"));
+        assert!(out.contains("Tags:       (none)"));
+        assert!(!out.contains("About:"));
+        assert!(out.contains("read-only"));
+    }
+
+    #[test]
+    fn test_show_not_found() {
+        let dir = tempdir().unwrap();
+        let db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        let err = show(&db, "missing").unwrap_err();
+        assert!(err.to_string().contains("not found"));
+    }
+}
@@ -5,37 +5,34 @@ use std::path::Path;
 use crate::alias::AliasError;
 use crate::config::Config;
 use crate::database::Database;
+use crate::hooks::run_pre_navigate;
 use crate::stack::Stack;
 
 /// Push current directory to stack and navigate to alias
 /// Prints the path for the shell function to cd to
 pub fn push(config: &Config, db: &mut Database, alias: &str) -> Result<(), Box<dyn std::error::Error>> {
+    let alias = &db.resolve_name(alias);
+
     // Get the alias path - first check existence, then modify
     let path = {
         let entry = db.get(alias).ok_or_else(|| AliasError::NotFound(alias.to_string()))?;
-        entry.path.clone()
+        // Verify the target like navigation does: honoring --no-check, the timeout and skip_check
+        db.check_target(entry)?;
+        // A file bookmark pushes its directory
+        entry.directory()
     };
-
-    // Verify target directory exists
-    let target_path = Path::new(&path);
-    if !target_path.exists() {
-        return Err(AliasError::DirectoryNotFound(path).into());
-    }
-    if !target_path.is_dir() {
-        return Err(format!("not a directory: {}", path).into());
-    }
+    // A hook can veto a push like any other jump, before the stack changes
+    run_pre_navigate(db.hooks(), alias, &path)?;
 
     // Get current directory
     let current = std::env::current_dir()?;
 
     // Push to stack (new API handles persistence automatically)
-    let stack = Stack::new(config.stack_path.clone());
+    let stack = Stack::new(config.stack_path.clone()).with_dedupe(config.user.stack.dedupe);
     stack.push(&current.to_string_lossy())?;
 
     // Record use after pushing to stack (so we don't record if push fails)
-    if let Some(entry) = db.get_mut(alias) {
-        entry.record_use();
-    }
+    db.record_usage(alias)?;
     db.save()?;
 
     // Print path for shell to cd to
@@ -48,7 +45,7 @@ pub fn push(config: &Config, db: &mut Database, alias: &str) -> Result<(), Box<d
 pub fn pop(config: &Config) -> Result<(), Box<dyn std::error::Error>> {
     let stack = Stack::new(config.stack_path.clone());
 
-    let path = stack.pop().map_err(|_| "stack is empty")?;
+    let path = stack.pop()?;
 
     // Verify the directory still exists
     let dir_path = Path::new(&path);
@@ -56,9 +53,33 @@ pub fn pop(config: &Config) -> Result<(), Box<dyn std::error::Error>> {
         return Err(AliasError::DirectoryNotFound(path).into());
     }
     if !dir_path.is_dir() {
-        return Err(format!("not a directory: {}", path).into());
+        return Err(AliasError::NotADirectory(path).into());
+    }
+
+    println!("{}", path);
+    Ok(())
+}
+
+/// Swap the current directory with the top of the stack
+/// Prints the old top for the shell function to cd to; the current directory takes its place,
+/// so running it again swaps back (like `cd -`, but on the stack)
+pub fn swap(config: &Config) -> Result<(), Box<dyn std::error::Error>> {
+    let stack = Stack::new(config.stack_path.clone()).with_dedupe(config.user.stack.dedupe);
+
+    let path = stack.peek()?;
+
+    // Verify the directory still exists before touching the stack
+    let dir_path = Path::new(&path);
+    if !dir_path.exists() {
+        return Err(AliasError::DirectoryNotFound(path).into());
+    }
+    if !dir_path.is_dir() {
+        return Err(AliasError::NotADirectory(path).into());
     }
 
+    let current = std::env::current_dir()?;
+    stack.replace(&current.to_string_lossy())?;
+
     println!("{}", path);
     Ok(())
 }
@@ -77,6 +98,9 @@ mod tests {
         let config = Config {
             database_path: temp_dir.path().to_path_buf(),
             stack_path: temp_dir.path().join("goto_stack"),
+            history_path: temp_dir.path().join("dir_history"),
+            context_path: temp_dir.path().join("context"),
+            listing_path: temp_dir.path().join("last_listing"),
             config_path: temp_dir.path().join("config.toml"),
             aliases_path: temp_dir.path().join("aliases.toml"),
             user: UserConfig::default(),
@@ -114,6 +138,44 @@ mod tests {
                 "Expected directory error in: {}", err);
     }
 
+    #[test]
+    fn test_push_no_check() {
+        let (config, _temp) = create_test_config();
+        let mut db = Database::load_from_path(&config.aliases_path).unwrap();
+        db.insert(Alias::new("nas", "/nonexistent/mount/12345").unwrap());
+        db.set_path_check(crate::pathcheck::PathCheck::Skip);
+
+        assert!(push(&config, &mut db, "nas").is_ok());
+        assert_eq!(db.get("nas").unwrap().use_count, 1);
+    }
+
+    #[test]
+    fn test_push_skip_check_alias() {
+        let (config, _temp) = create_test_config();
+        let mut db = Database::load_from_path(&config.aliases_path).unwrap();
+        let mut alias = Alias::new("autofs", "/nonexistent/autofs/12345").unwrap();
+        alias.skip_check = true;
+        db.insert(alias);
+
+        // The path is never stat'ed, so a mount-on-access path doesn't block or fail
+        assert!(push(&config, &mut db, "autofs").is_ok());
+    }
+
+    #[test]
+    fn test_push_vetoed_by_hook() {
+        let (config, _temp) = create_test_config();
+        let mut db = create_test_db(&config.aliases_path);
+        db.set_hooks(crate::hooks::HooksConfig {
+            pre_navigate: "test \"$GOTO_ALIAS\" != test".to_string(),
+            ..Default::default()
+        });
+
+        let err = push(&config, &mut db, "test").unwrap_err().to_string();
+        assert!(err.contains("cancelled by pre-navigate hook"), "{}", err);
+        assert!(Stack::new(config.stack_path.clone()).pop().is_err());
+        assert_eq!(db.get("test").unwrap().use_count, 0);
+    }
+
     #[test]
     fn test_push_not_a_directory() {
         let (config, temp) = create_test_config();
@@ -211,6 +273,48 @@ mod tests {
         assert_eq!(popped, cwd.to_string_lossy());
     }
 
+    #[test]
+    fn test_swap() {
+        let (config, temp) = create_test_config();
+        assert!(swap(&config).unwrap_err().to_string().contains("stack is empty"));
+
+        let other = temp.path().join("other");
+        fs::create_dir(&other).unwrap();
+        let stack = Stack::new(config.stack_path.clone());
+        stack.push(&other.to_string_lossy()).unwrap();
+
+        swap(&config).unwrap();
+        let cwd = std::env::current_dir().unwrap();
+        assert_eq!(stack.size().unwrap(), 1);
+        assert_eq!(stack.peek().unwrap(), cwd.to_string_lossy());
+    }
+
+    #[test]
+    fn test_swap_dedupe() {
+        let (mut config, temp) = create_test_config();
+        config.user.stack.dedupe = true;
+        let cwd = std::env::current_dir().unwrap();
+        let other = temp.path().join("other");
+        fs::create_dir(&other).unwrap();
+        let stack = Stack::new(config.stack_path.clone());
+        stack.push(&cwd.to_string_lossy()).unwrap();
+        stack.push(&other.to_string_lossy()).unwrap();
+
+        // The current directory was already below the top: it moves up rather than doubling
+        swap(&config).unwrap();
+        assert_eq!(stack.entries().unwrap(), vec![cwd.to_string_lossy().to_string()]);
+    }
+
+    #[test]
+    fn test_swap_missing_directory_keeps_stack() {
+        let (config, _temp) = create_test_config();
+        let stack = Stack::new(config.stack_path.clone());
+        stack.push("/nonexistent/path/12345").unwrap();
+
+        assert!(swap(&config).unwrap_err().to_string().contains("directory does not exist"));
+        assert_eq!(stack.peek().unwrap(), "/nonexistent/path/12345");
+    }
+
     #[test]
     fn test_push_pop_multiple() {
         let (config, temp) = create_test_config();
@@ -1,9 +1,14 @@
-//! Statistics commands: stats, recent, clear_recent
+//! Statistics commands: stats, recent, select_recent, clear_recent, touch, reset_stats
 
-use chrono::{DateTime, Utc};
+use chrono::{DateTime, Duration, NaiveDate, Utc};
 
+use crate::alias::AliasError;
 use crate::config::Config;
 use crate::database::Database;
+use crate::i18n::{fill, tr};
+use crate::listing;
+use crate::project;
+use crate::{print_record, prompt_selection};
 use crate::table::{TableStyle, create_table};
 
 /// Recent entry for display
@@ -64,10 +69,64 @@ pub fn stats(db: &Database, config: &Config) -> Result<(), Box<dyn std::error::E
     Ok(())
 }
 
+/// Parse a --since value: a relative window (`30m`, `12h`, `7d`, `2w`) or a date (`2024-06-01`)
+pub fn parse_since(value: &str) -> Result<DateTime<Utc>, String> {
+    let invalid = || format!("invalid --since value: {} (use e.g. 7d, 12h, 2w or 2024-06-01)", value);
+
+    if let Ok(date) = NaiveDate::parse_from_str(value, "%Y-%m-%d") {
+        return Ok(date.and_hms_opt(0, 0, 0).ok_or_else(invalid)?.and_utc());
+    }
+
+    Ok(Utc::now() - parse_window(value).ok_or_else(invalid)?)
+}
+
+/// Parse a length of time like `30m`, `12h`, `7d` or `2w`
+pub fn parse_window(value: &str) -> Option<Duration> {
+    if value.len() < 2 {
+        return None;
+    }
+    let (amount, unit) = value.split_at(value.len() - 1);
+    let amount: i64 = amount.parse().ok()?;
+    match unit {
+        "m" => Some(Duration::minutes(amount)),
+        "h" => Some(Duration::hours(amount)),
+        "d" => Some(Duration::days(amount)),
+        "w" => Some(Duration::weeks(amount)),
+        _ => None,
+    }
+}
+
 /// Get recently visited aliases sorted by last_used descending
 pub fn recent(db: &Database, limit: Option<usize>) -> Result<Vec<RecentEntry>, Box<dyn std::error::Error>> {
-    // Filter to only entries that have been used
-    let mut used_entries: Vec<_> = db.all().filter(|e| e.last_used.is_some()).collect();
+    recent_since(db, limit, None)
+}
+
+/// Get recently visited aliases, optionally only those used at or after `since`
+pub fn recent_since(
+    db: &Database,
+    limit: Option<usize>,
+    since: Option<DateTime<Utc>>,
+) -> Result<Vec<RecentEntry>, Box<dyn std::error::Error>> {
+    recent_matching(db, limit, since, None)
+}
+
+/// Like [`recent_since`], keeping only aliases that match `filter` (a tag or key=value)
+pub fn recent_matching(
+    db: &Database,
+    limit: Option<usize>,
+    since: Option<DateTime<Utc>>,
+    filter: Option<&str>,
+) -> Result<Vec<RecentEntry>, Box<dyn std::error::Error>> {
+    // Filter to only entries that have been used (within the window)
+    let mut used_entries: Vec<_> = db
+        .all()
+        .filter(|e| match (e.last_used, since) {
+            (Some(t), Some(since)) => t >= since,
+            (last_used, None) => last_used.is_some(),
+            (None, _) => false,
+        })
+        .filter(|e| filter.is_none_or(|f| e.matches_filter(f)))
+        .collect();
 
     if used_entries.is_empty() {
         return Ok(Vec::new());
@@ -85,7 +144,7 @@ pub fn recent(db: &Database, limit: Option<usize>) -> Result<Vec<RecentEntry>, B
         .into_iter()
         .map(|e| RecentEntry {
             alias: e.name.clone(),
-            path: e.path.clone(),
+            path: e.resolved_path(),
             last_used: e.last_used.unwrap(),
         })
         .collect())
@@ -93,11 +152,32 @@ pub fn recent(db: &Database, limit: Option<usize>) -> Result<Vec<RecentEntry>, B
 
 /// Display recently visited aliases
 pub fn show_recent(db: &Database, config: &Config, limit: usize) -> Result<(), Box<dyn std::error::Error>> {
+    show_recent_since(db, config, limit, None, None, false)
+}
+
+/// Display recently visited aliases, optionally restricted to a time window and a tag
+pub fn show_recent_since(
+    db: &Database,
+    config: &Config,
+    limit: usize,
+    since: Option<DateTime<Utc>>,
+    filter: Option<&str>,
+    print0: bool,
+) -> Result<(), Box<dyn std::error::Error>> {
     let limit = if limit == 0 { 10 } else { limit };
-    let entries = recent(db, Some(limit))?;
+    let entries = recent_matching(db, Some(limit), since, filter)?;
+
+    if print0 {
+        for entry in &entries {
+            print_record(&entry.path, true);
+        }
+        remember_listing(config, &entries);
+        return Ok(());
+    }
 
     if entries.is_empty() {
         println!("No recently visited directories");
+        forget_listing(config);
         return Ok(());
     }
 
@@ -116,10 +196,27 @@ pub fn show_recent(db: &Database, config: &Config, limit: usize) -> Result<(), B
     }
 
     println!("{table}");
+    remember_listing(config, &entries);
 
     Ok(())
 }
 
+/// Save the rows just printed, so `%N` jumps to the Nth recent alias
+fn remember_listing(config: &Config, entries: &[RecentEntry]) {
+    let rows: Vec<(usize, &str)> =
+        entries.iter().enumerate().map(|(i, e)| (i + 1, e.alias.as_str())).collect();
+    if let Err(e) = listing::save(&config.listing_path, &rows) {
+        eprintln!("Warning: could not save listing for %N shortcuts: {}", e);
+    }
+}
+
+/// Drop the saved listing, so `%N` can't resolve against rows no longer on screen
+fn forget_listing(config: &Config) {
+    if let Err(e) = listing::clear(&config.listing_path) {
+        eprintln!("Warning: could not clear listing for %N shortcuts: {}", e);
+    }
+}
+
 /// Navigate to the Nth most recent alias
 pub fn navigate_to_recent(db: &mut Database, index: usize) -> Result<(), Box<dyn std::error::Error>> {
     let entries = recent(db, None)?;
@@ -141,6 +238,36 @@ pub fn navigate_to_recent(db: &mut Database, index: usize) -> Result<(), Box<dyn
     crate::commands::navigate::navigate(db, &entries[index - 1].alias)
 }
 
+/// Show recent aliases as a numbered menu and navigate to the chosen one
+pub fn select_recent(
+    db: &mut Database,
+    limit: usize,
+    since: Option<DateTime<Utc>>,
+    filter: Option<&str>,
+) -> Result<(), Box<dyn std::error::Error>> {
+    let limit = if limit == 0 { 10 } else { limit };
+    let entries = recent_matching(db, Some(limit), since, filter)?;
+
+    if entries.is_empty() {
+        return Err("no recently visited directories".into());
+    }
+
+    let labels: Vec<String> = entries
+        .iter()
+        .map(|e| {
+            let kind = db.get(&e.alias).and_then(|a| a.project_type.as_deref());
+            format!("{} -> {}{} ({})", e.alias, e.path, project::label(kind), format_time_ago(Some(e.last_used)))
+        })
+        .collect();
+    let options: Vec<&str> = labels.iter().map(String::as_str).collect();
+
+    eprintln!("Recently visited:");
+    match prompt_selection(&options, None)? {
+        Some(idx) => crate::commands::navigate::navigate(db, &entries[idx].alias),
+        None => Err(AliasError::Cancelled("Navigation".to_string()).into()),
+    }
+}
+
 /// Clear recent history (reset last_used for all aliases)
 pub fn clear_recent(db: &mut Database) -> Result<(), Box<dyn std::error::Error>> {
     db.clear_recent_history()?;
@@ -149,60 +276,93 @@ pub fn clear_recent(db: &mut Database) -> Result<(), Box<dyn std::error::Error>>
     Ok(())
 }
 
+/// Zero usage counters for one alias or all aliases
+///
+/// With `clear_last_used`, also forgets when the aliases were last visited.
+pub fn reset_stats(
+    db: &mut Database,
+    alias: Option<&str>,
+    clear_last_used: bool,
+) -> Result<(), Box<dyn std::error::Error>> {
+    let alias = alias.map(|name| db.resolve_name(name));
+    let count = db.reset_usage(alias.as_deref(), clear_last_used)?;
+    db.save()?;
+
+    match alias {
+        Some(name) => println!("Reset usage statistics for '{}'", name),
+        None => println!(
+            "Reset usage statistics for {} alias{}",
+            count,
+            if count == 1 { "" } else { "es" }
+        ),
+    }
+    Ok(())
+}
+
+/// Record a use of an alias without navigating (for external tools)
+pub fn touch(db: &mut Database, alias: &str) -> Result<(), Box<dyn std::error::Error>> {
+    if !db.tracks_usage() {
+        eprintln!("Usage tracking is disabled; nothing recorded");
+    }
+    db.record_usage(&db.resolve_name(alias))?;
+    db.save()?;
+    Ok(())
+}
+
 /// Format a timestamp as a human-readable "time ago" string
-fn format_time_ago(t: Option<DateTime<Utc>>) -> String {
+pub fn format_time_ago(t: Option<DateTime<Utc>>) -> String {
     let t = match t {
         Some(t) => t,
-        None => return "never".to_string(),
+        None => return tr("never").to_string(),
     };
 
     let duration = Utc::now().signed_duration_since(t);
 
     if duration.num_seconds() < 60 {
-        return "just now".to_string();
+        return tr("just now").to_string();
     }
 
     let minutes = duration.num_minutes();
     if minutes < 60 {
         return if minutes == 1 {
-            "1 minute ago".to_string()
+            tr("1 minute ago").to_string()
         } else {
-            format!("{} minutes ago", minutes)
+            fill(tr("{} minutes ago"), &[&minutes])
         };
     }
 
     let hours = duration.num_hours();
     if hours < 24 {
         return if hours == 1 {
-            "1 hour ago".to_string()
+            tr("1 hour ago").to_string()
         } else {
-            format!("{} hours ago", hours)
+            fill(tr("{} hours ago"), &[&hours])
         };
     }
 
     let days = duration.num_days();
     if days < 7 {
         return if days == 1 {
-            "1 day ago".to_string()
+            tr("1 day ago").to_string()
         } else {
-            format!("{} days ago", days)
+            fill(tr("{} days ago"), &[&days])
         };
     }
 
     let weeks = days / 7;
     if weeks < 4 {
         return if weeks == 1 {
-            "1 week ago".to_string()
+            tr("1 week ago").to_string()
         } else {
-            format!("{} weeks ago", weeks)
+            fill(tr("{} weeks ago"), &[&weeks])
         };
     }
 
     let months = days / 30;
     if months == 1 {
-        "1 month ago".to_string()
+        tr("1 month ago").to_string()
     } else {
-        format!("{} months ago", months)
+        fill(tr("{} months ago"), &[&months])
     }
 }
 
@@ -211,7 +371,6 @@ mod tests {
     use super::*;
     use crate::alias::Alias;
     use crate::config::Config;
-    use chrono::Duration;
     use tempfile::NamedTempFile;
 
     fn create_test_db() -> (Database, NamedTempFile) {
@@ -243,6 +402,28 @@ mod tests {
         assert!(result.is_ok());
     }
 
+    #[test]
+    fn test_recent_listing_saved_or_cleared() {
+        let (db, _file) = create_test_db();
+        let dir = tempfile::tempdir().unwrap();
+        let config = Config::load_from(dir.path().to_path_buf()).unwrap();
+        let shortcut = |n: &str| listing::expand_shortcut(&config.listing_path, n);
+
+        // -0 prints bare paths, but %N still follows the rows in order; "never" has no row
+        show_recent_since(&db, &config, 10, None, None, true).unwrap();
+        let first = recent(&db, None).unwrap()[0].alias.clone();
+        assert_eq!(shortcut("%1").unwrap(), first);
+        assert!(shortcut("%3").is_err());
+
+        show_recent_since(&db, &config, 10, None, Some("missing"), false).unwrap();
+        assert!(shortcut("%1").is_err());
+
+        show_recent_since(&db, &config, 10, None, None, false).unwrap();
+        assert_eq!(shortcut("%1").unwrap(), first);
+        show_recent_since(&db, &config, 10, None, Some("missing"), true).unwrap();
+        assert!(shortcut("%1").is_err());
+    }
+
     #[test]
     fn test_stats_empty() {
         let file = NamedTempFile::new().unwrap();
@@ -282,6 +463,18 @@ mod tests {
         assert_eq!(entries[1].alias, "first");
     }
 
+    #[test]
+    fn test_recent_shows_host_path() {
+        let file = NamedTempFile::new().unwrap();
+        let mut db = Database::load_from_path(file.path()).unwrap();
+        let mut alias = Alias::new("proj", "/tmp/proj").unwrap();
+        alias.paths.insert("default".to_string(), "/srv/proj".to_string());
+        alias.record_use();
+        db.insert(alias);
+
+        assert_eq!(recent(&db, None).unwrap()[0].path, "/srv/proj");
+    }
+
     #[test]
     fn test_recent_with_limit() {
         let (db, _file) = create_test_db();
@@ -297,6 +490,65 @@ mod tests {
         assert!(entries.is_empty());
     }
 
+    #[test]
+    fn test_recent_matching_filter() {
+        let file = NamedTempFile::new().unwrap();
+        let mut db = Database::load_from_path(file.path()).unwrap();
+
+        for (name, tag) in [("api", "work"), ("blog", "personal"), ("infra", "work")] {
+            let mut alias = Alias::new(name, "/tmp").unwrap();
+            alias.add_tag(tag);
+            alias.record_use();
+            db.insert(alias);
+        }
+
+        let names: Vec<String> = recent_matching(&db, None, None, Some("work"))
+            .unwrap()
+            .into_iter()
+            .map(|e| e.alias)
+            .collect();
+        assert_eq!(names.len(), 2);
+        assert!(!names.contains(&"blog".to_string()));
+        assert!(recent_matching(&db, None, None, Some("nope")).unwrap().is_empty());
+    }
+
+    #[test]
+    fn test_recent_since_filters_old_entries() {
+        let file = NamedTempFile::new().unwrap();
+        let mut db = Database::load_from_path(file.path()).unwrap();
+
+        let mut old = Alias::new("old", "/tmp/old").unwrap();
+        old.last_used = Some(Utc::now() - Duration::days(30));
+        db.insert(old);
+
+        let mut fresh = Alias::new("fresh", "/tmp/fresh").unwrap();
+        fresh.record_use();
+        db.insert(fresh);
+
+        let entries = recent_since(&db, None, Some(parse_since("7d").unwrap())).unwrap();
+        assert_eq!(entries.len(), 1);
+        assert_eq!(entries[0].alias, "fresh");
+
+        assert_eq!(recent_since(&db, None, None).unwrap().len(), 2);
+    }
+
+    #[test]
+    fn test_parse_since() {
+        let week_ago = parse_since("1w").unwrap();
+        let expected = Utc::now() - Duration::weeks(1);
+        assert!((week_ago - expected).num_seconds().abs() < 5);
+
+        let date = parse_since("2024-06-01").unwrap();
+        assert_eq!(date.to_rfc3339(), "2024-06-01T00:00:00+00:00");
+
+        assert!(parse_since("12h").is_ok());
+        assert!(parse_since("30m").is_ok());
+        assert!(parse_since("7").is_err());
+        assert!(parse_since("7y").is_err());
+        assert!(parse_since("yesterday").is_err());
+        assert!(parse_since("2024-13-01").is_err());
+    }
+
     #[test]
     fn test_show_recent() {
         let (db, _file) = create_test_db();
@@ -339,6 +591,25 @@ mod tests {
         assert!(result.unwrap_err().to_string().contains("no recently visited"));
     }
 
+    #[test]
+    fn test_select_recent_non_interactive_cancels() {
+        let (mut db, _file) = create_test_db();
+
+        // Non-interactive stdin: the menu is cancelled, nothing is recorded
+        let result = select_recent(&mut db, 10, None, None);
+        assert!(result.unwrap_err().to_string().contains("cancelled"));
+        assert_eq!(db.get("sometimes").unwrap().use_count, 3);
+    }
+
+    #[test]
+    fn test_select_recent_empty() {
+        let file = NamedTempFile::new().unwrap();
+        let mut db = Database::load_from_path(file.path()).unwrap();
+
+        let result = select_recent(&mut db, 10, None, None);
+        assert!(result.unwrap_err().to_string().contains("no recently visited"));
+    }
+
     #[test]
     fn test_clear_recent() {
         let (mut db, _file) = create_test_db();
@@ -356,6 +627,59 @@ mod tests {
         assert!(entries.is_empty());
     }
 
+    #[test]
+    fn test_reset_stats_keeps_recent() {
+        let (mut db, _file) = create_test_db();
+
+        reset_stats(&mut db, None, false).unwrap();
+
+        assert!(db.all().all(|a| a.use_count == 0));
+        // Timestamps survive, so recent history is unchanged
+        assert_eq!(recent(&db, None).unwrap().len(), 2);
+    }
+
+    #[test]
+    fn test_reset_stats_single_with_last_used() {
+        let (mut db, _file) = create_test_db();
+
+        reset_stats(&mut db, Some("often"), true).unwrap();
+
+        let often = db.get("often").unwrap();
+        assert_eq!(often.use_count, 0);
+        assert!(often.last_used.is_none());
+        assert_eq!(db.get("sometimes").unwrap().use_count, 3);
+    }
+
+    #[test]
+    fn test_reset_stats_resolves_name() {
+        let (mut db, _file) = create_test_db();
+        let mut api = Alias::new("work:api", "/tmp").unwrap();
+        api.record_use();
+        db.insert(api);
+        db.set_default_namespace("work");
+
+        reset_stats(&mut db, Some("api"), false).unwrap();
+        assert_eq!(db.get("work:api").unwrap().use_count, 0);
+    }
+
+    #[test]
+    fn test_touch_records_usage() {
+        let (mut db, _file) = create_test_db();
+
+        touch(&mut db, "never").unwrap();
+
+        let alias = db.get("never").unwrap();
+        assert_eq!(alias.use_count, 1);
+        assert!(alias.last_used.is_some());
+    }
+
+    #[test]
+    fn test_touch_not_found() {
+        let (mut db, _file) = create_test_db();
+        let result = touch(&mut db, "nonexistent");
+        assert!(result.unwrap_err().to_string().contains("not found"));
+    }
+
     #[test]
     fn test_format_time_ago_none() {
         assert_eq!(format_time_ago(None), "never");
@@ -0,0 +1,200 @@
+//! Suggest commands: record_dir, suggest
+
+use std::collections::HashSet;
+use std::error::Error;
+use std::io::{self, IsTerminal};
+use std::path::Path;
+
+use crate::alias::Alias;
+use crate::commands::setup::propose_name;
+use crate::config::Config;
+use crate::database::Database;
+use crate::filelock;
+use crate::history::DirHistory;
+use crate::prompt_selection;
+use crate::table::{TableStyle, create_table};
+
+/// Directories visited fewer times than this are not suggested
+const MIN_VISITS: u64 = 3;
+
+/// How many suggestions to show at once
+const MAX_SUGGESTIONS: usize = 10;
+
+/// A frequently visited directory without an alias
+#[derive(Debug, Clone, PartialEq)]
+pub struct Suggestion {
+    pub name: String,
+    pub path: String,
+    pub visits: u64,
+}
+
+/// Record a visit to `dir` (called by the shell wrapper's cd hook)
+///
+/// Nothing is written while `general.track_usage` is off.
+pub fn record_dir(config: &Config, dir: &str) -> Result<(), Box<dyn Error>> {
+    if !config.user.general.track_usage {
+        return Ok(());
+    }
+    // The wrappers record in the background, so prompts can overlap
+    let _lock = filelock::lock(&config.history_path)?;
+    let mut history = DirHistory::load(config.history_path.clone())?;
+    history.record(dir);
+    history.save()?;
+    Ok(())
+}
+
+/// Frequently visited directories that are not aliased yet, most visited first
+///
+/// The home and root directories are never suggested.
+pub fn suggestions(db: &Database, history: &DirHistory) -> Vec<Suggestion> {
+    let aliased: HashSet<String> = db.all().map(|a| a.resolved_path()).collect();
+    let home = dirs::home_dir().map(|h| h.to_string_lossy().to_string());
+
+    let mut result: Vec<Suggestion> = Vec::new();
+    for visit in history.visits() {
+        if result.len() == MAX_SUGGESTIONS || visit.count < MIN_VISITS {
+            break;
+        }
+        if visit.path == "/"
+            || home.as_deref() == Some(visit.path.as_str())
+            || aliased.contains(&visit.path)
+            || !Path::new(&visit.path).is_dir()
+        {
+            continue;
+        }
+        let Some(base) = propose_name(db, &visit.path) else {
+            continue;
+        };
+        // Keep proposed names unique within this batch too
+        let mut name = base.clone();
+        let mut n = 2;
+        while result.iter().any(|s| s.name == name) || db.contains(&name) {
+            name = format!("{}-{}", base, n);
+            n += 1;
+        }
+        result.push(Suggestion {
+            name,
+            path: visit.path.clone(),
+            visits: visit.count,
+        });
+    }
+    result
+}
+
+/// Show suggestions; on a terminal, pick one by number to register it
+pub fn suggest(db: &mut Database, config: &Config) -> Result<(), Box<dyn Error>> {
+    let history = DirHistory::load(config.history_path.clone())?;
+    let mut pending = suggestions(db, &history);
+
+    if pending.is_empty() {
+        println!("No suggestions yet (directories you visit often will show up here)");
+        return Ok(());
+    }
+
+    if !io::stdin().is_terminal() {
+        let style = TableStyle::from(config.user.display.table_style.as_str());
+        let mut table = create_table(style);
+        table.set_header(vec!["Name", "Path", "Visits"]);
+        for s in &pending {
+            table.add_row(vec![s.name.clone(), s.path.clone(), s.visits.to_string()]);
+        }
+        println!("{table}");
+        return Ok(());
+    }
+
+    while !pending.is_empty() {
+        eprintln!("Frequently visited directories without an alias:");
+        let labels: Vec<String> = pending
+            .iter()
+            .map(|s| format!("{} -> {} ({} visits)", s.name, s.path, s.visits))
+            .collect();
+        let label_refs: Vec<&str> = labels.iter().map(String::as_str).collect();
+
+        let Some(index) = prompt_selection(&label_refs, None)? else {
+            break;
+        };
+        let chosen = pending.remove(index);
+        db.add(Alias::new(&chosen.name, &chosen.path)?)?;
+        db.save()?;
+        eprintln!("Registered '{}' -> {}", chosen.name, chosen.path);
+    }
+
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    #[test]
+    fn test_suggestions_skip_aliased_and_rare() {
+        let dir = tempdir().unwrap();
+        let often = dir.path().join("often");
+        let rare = dir.path().join("rare");
+        let aliased = dir.path().join("aliased");
+        for d in [&often, &rare, &aliased] {
+            std::fs::create_dir(d).unwrap();
+        }
+
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        db.insert(Alias::new("mine", aliased.to_str().unwrap()).unwrap());
+
+        let mut history = DirHistory::load(dir.path().join("dir_history")).unwrap();
+        for _ in 0..5 {
+            history.record(often.to_str().unwrap());
+            history.record(aliased.to_str().unwrap());
+        }
+        history.record(rare.to_str().unwrap());
+        history.record("/nonexistent/path/12345");
+
+        let result = suggestions(&db, &history);
+        assert_eq!(result.len(), 1);
+        assert_eq!(result[0].name, "often");
+        assert_eq!(result[0].visits, 5);
+    }
+
+    #[test]
+    fn test_record_dir_respects_track_usage() {
+        let dir = tempdir().unwrap();
+        let mut config = Config {
+            database_path: dir.path().to_path_buf(),
+            stack_path: dir.path().join("goto_stack"),
+            history_path: dir.path().join("dir_history"),
+            context_path: dir.path().join("context"),
+            listing_path: dir.path().join("last_listing"),
+            config_path: dir.path().join("config.toml"),
+            aliases_path: dir.path().join("aliases.toml"),
+            user: Default::default(),
+        };
+
+        config.user.general.track_usage = false;
+        record_dir(&config, "/srv/app").unwrap();
+        assert!(!config.history_path.exists());
+
+        config.user.general.track_usage = true;
+        record_dir(&config, "/srv/app").unwrap();
+        assert!(config.history_path.exists());
+    }
+
+    #[test]
+    fn test_suggestions_unique_names() {
+        let dir = tempdir().unwrap();
+        let a = dir.path().join("a").join("api");
+        let b = dir.path().join("b").join("api");
+        std::fs::create_dir_all(&a).unwrap();
+        std::fs::create_dir_all(&b).unwrap();
+
+        let db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        let mut history = DirHistory::load(dir.path().join("dir_history")).unwrap();
+        for _ in 0..4 {
+            history.record(a.to_str().unwrap());
+            history.record(b.to_str().unwrap());
+        }
+
+        let names: Vec<String> = suggestions(&db, &history).into_iter().map(|s| s.name).collect();
+        assert_eq!(names.len(), 2);
+        assert!(names.contains(&"api".to_string()));
+        assert!(names.contains(&"api-2".to_string()));
+    }
+}
@@ -1,7 +1,8 @@
-//! Tag commands: tag, untag, list_tags
+//! Tag commands: tag, tag_many, untag, set_meta, list_tags, retag_auto
 
-use crate::alias::validate_tag;
-use crate::config::Config;
+use crate::alias::{validate_tag, AliasError};
+use crate::commands::select::select_aliases;
+use crate::config::{autotags_for, Config};
 use crate::confirm;
 use crate::database::Database;
 use crate::table::{create_table, TableStyle};
@@ -33,18 +34,76 @@ pub fn tag(db: &mut Database, alias: &str, tag_name: &str, force: bool) -> Resul
     if is_new_tag && has_any_tags && !force {
         let message = format!("Tag '{}' doesn't exist. Create it?", tag_name);
         if !confirm(&message, false)? {
-            return Err("Tag creation cancelled".into());
+            return Err(AliasError::Cancelled("Tag creation".to_string()).into());
         }
     }
 
+    let alias = &db.resolve_name(alias);
+    db.check_writable(alias)?;
+
     if let Some(entry) = db.get_mut(alias) {
         entry.add_tag(&tag_name);
         db.save()?;
         println!("Added tag '{}' to alias '{}'", tag_name, alias);
         Ok(())
     } else {
-        Err(format!("alias '{}' not found", alias).into())
+        Err(AliasError::NotFound(alias.to_string()).into())
+    }
+}
+
+/// Add a tag to several aliases at once
+///
+/// `selector` is a comma-separated list of names or name globs, optionally
+/// narrowed by `filter` (see [`select_aliases`]). Prints how many changed.
+pub fn tag_many(
+    db: &mut Database,
+    selector: &str,
+    tag_name: &str,
+    force: bool,
+    filter: Option<&str>,
+) -> Result<(), Box<dyn std::error::Error>> {
+    let tag_name = tag_name.trim().to_lowercase();
+    validate_tag(&tag_name)?;
+
+    let names = select_aliases(db, selector, filter)?;
+    if names.is_empty() {
+        return Err(AliasError::NotFound(selector.to_string()).into());
+    }
+
+    let existing_tags = db.get_all_tags();
+    if !existing_tags.contains_key(&tag_name) && !existing_tags.is_empty() && !force {
+        let message = format!(
+            "Tag '{}' doesn't exist. Create it on {} alias{}?",
+            tag_name,
+            names.len(),
+            if names.len() == 1 { "" } else { "es" }
+        );
+        if !confirm(&message, false)? {
+            return Err(AliasError::Cancelled("Tag creation".to_string()).into());
+        }
+    }
+
+    let mut changed = 0;
+    for name in &names {
+        if db.get(name).is_some_and(|a| !a.has_tag(&tag_name)) {
+            db.add_tag(name, &tag_name)?;
+            changed += 1;
+        }
+    }
+    db.save()?;
+
+    print!(
+        "Added tag '{}' to {} alias{}",
+        tag_name,
+        changed,
+        if changed == 1 { "" } else { "es" }
+    );
+    let unchanged = names.len() - changed;
+    if unchanged > 0 {
+        print!(" ({} already tagged)", unchanged);
     }
+    println!();
+    Ok(())
 }
 
 /// Remove a tag from an alias
@@ -53,6 +112,9 @@ pub fn tag(db: &mut Database, alias: &str, tag_name: &str, force: bool) -> Resul
 pub fn untag(db: &mut Database, alias: &str, tag_name: &str) -> Result<(), Box<dyn std::error::Error>> {
     let tag_name = tag_name.trim().to_lowercase();
 
+    let alias = &db.resolve_name(alias);
+    db.check_writable(alias)?;
+
     if let Some(entry) = db.get_mut(alias) {
         if entry.remove_tag(&tag_name) {
             db.save()?;
@@ -62,10 +124,38 @@ pub fn untag(db: &mut Database, alias: &str, tag_name: &str) -> Result<(), Box<d
         }
         Ok(())
     } else {
-        Err(format!("alias '{}' not found", alias).into())
+        Err(AliasError::NotFound(alias.to_string()).into())
     }
 }
 
+/// Set key=value metadata on an alias
+///
+/// Each pair is `key=value`; an empty value (`key=`) removes the key.
+pub fn set_meta(db: &mut Database, alias: &str, pairs: &[String]) -> Result<(), Box<dyn std::error::Error>> {
+    let alias = db.resolve_name(alias);
+    if !db.contains(&alias) {
+        return Err(AliasError::NotFound(alias).into());
+    }
+
+    for pair in pairs {
+        let (key, value) = pair
+            .split_once('=')
+            .ok_or_else(|| format!("invalid metadata '{}': expected key=value", pair))?;
+        let key = key.trim();
+        let value = value.trim();
+        if value.is_empty() {
+            db.set_meta(&alias, key, None)?;
+            println!("Removed '{}' from alias '{}'", key, alias);
+        } else {
+            db.set_meta(&alias, key, Some(value))?;
+            println!("Set {}={} on alias '{}'", key, value, alias);
+        }
+    }
+
+    db.save()?;
+    Ok(())
+}
+
 /// List all unique tags with their counts
 pub fn list_tags(db: &Database, config: &Config) -> Result<(), Box<dyn std::error::Error>> {
     let tag_counts = db.get_all_tags();
@@ -107,6 +197,18 @@ pub fn list_tags_raw(db: &Database) -> Result<(), Box<dyn std::error::Error>> {
     Ok(())
 }
 
+/// List the tags on one alias, one per line (for shell completion of `--untag`)
+pub fn list_tags_of(db: &Database, alias: &str) -> Result<(), Box<dyn std::error::Error>> {
+    let name = db.resolve_name(alias);
+    let alias = db.get(&name).ok_or_else(|| AliasError::NotFound(alias.to_string()))?;
+
+    for tag in &alias.tags {
+        println!("{}", tag);
+    }
+
+    Ok(())
+}
+
 /// Rename or merge a tag across all aliases
 ///
 /// If target tag doesn't exist: simple rename
@@ -137,13 +239,13 @@ pub fn rename_tag(
     // Check if old_tag exists
     let all_tags = db.get_all_tags();
     if !all_tags.contains_key(&old_tag) {
-        return Err(format!("tag '{}' not found", old_tag).into());
+        return Err(AliasError::NoneTagged(old_tag).into());
     }
 
     // Find affected aliases
     let affected: Vec<String> = db
         .all()
-        .filter(|a| a.has_tag(&old_tag))
+        .filter(|a| !db.is_system(&a.name) && a.has_tag(&old_tag))
         .map(|a| a.name.clone())
         .collect();
 
@@ -167,7 +269,7 @@ pub fn rename_tag(
             if affected.len() == 1 { "" } else { "es" }
         );
         if !confirm(&message, false)? {
-            return Err("Tag rename cancelled".into());
+            return Err(AliasError::Cancelled("Tag rename".to_string()).into());
         }
     }
 
@@ -236,6 +338,53 @@ pub fn rename_tag(
     Ok(())
 }
 
+/// Apply the configured auto-tag rules to every existing alias
+pub fn retag_auto(db: &mut Database, config: &Config, dry_run: bool) -> Result<(), Box<dyn std::error::Error>> {
+    let rules = &config.user.autotag;
+    if rules.is_empty() {
+        println!("No [[autotag]] rules configured");
+        return Ok(());
+    }
+
+    // Collect (alias, missing tags) first; the database can't be borrowed while iterating
+    let mut changes: Vec<(String, Vec<String>)> = Vec::new();
+    for alias in db.all().filter(|a| !db.is_system(&a.name)) {
+        let missing: Vec<String> = autotags_for(rules, &alias.resolved_path())?
+            .into_iter()
+            .filter(|t| !alias.has_tag(t))
+            .collect();
+        if !missing.is_empty() {
+            changes.push((alias.name.clone(), missing));
+        }
+    }
+    changes.sort();
+
+    for (name, tags) in &changes {
+        for tag in tags {
+            validate_tag(tag)?;
+        }
+        if dry_run {
+            println!("Would tag '{}' with {}", name, tags.join(", "));
+        } else {
+            for tag in tags {
+                db.add_tag(name, tag)?;
+            }
+            println!("Tagged '{}' with {}", name, tags.join(", "));
+        }
+    }
+
+    if !dry_run {
+        db.save()?;
+    }
+    println!(
+        "{} {} alias{}",
+        if dry_run { "Would auto-tag" } else { "Auto-tagged" },
+        changes.len(),
+        if changes.len() == 1 { "" } else { "es" }
+    );
+    Ok(())
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -335,6 +484,19 @@ mod tests {
         assert!(result.is_err());
     }
 
+    #[test]
+    fn test_tag_resolves_name() {
+        let (mut db, _file) = create_test_db();
+        db.insert(Alias::new("work:api", "/tmp").unwrap());
+        db.insert(Alias::new("projëkt", "/tmp").unwrap());
+        db.set_default_namespace("work");
+
+        tag(&mut db, "api", "backend", true).unwrap();
+        assert!(db.get("work:api").unwrap().has_tag("backend"));
+        tag(&mut db, "proje\u{0308}kt", "backend", true).unwrap();
+        assert!(db.get("projëkt").unwrap().has_tag("backend"));
+    }
+
     #[test]
     fn test_untag() {
         let (mut db, _file) = create_test_db();
@@ -347,6 +509,40 @@ mod tests {
         assert!(!alias.has_tag("work"));
     }
 
+    #[test]
+    fn test_untag_resolves_name() {
+        let (mut db, _file) = create_test_db();
+        let mut api = Alias::new("work:api", "/tmp").unwrap();
+        api.add_tag("backend");
+        db.insert(api);
+        let mut projekt = Alias::new("projëkt", "/tmp").unwrap();
+        projekt.add_tag("backend");
+        db.insert(projekt);
+        db.set_default_namespace("work");
+
+        untag(&mut db, "api", "backend").unwrap();
+        assert!(!db.get("work:api").unwrap().has_tag("backend"));
+        untag(&mut db, "proje\u{0308}kt", "backend").unwrap();
+        assert!(!db.get("projëkt").unwrap().has_tag("backend"));
+    }
+
+    #[test]
+    fn test_set_meta() {
+        let (mut db, _file) = create_test_db();
+
+        set_meta(&mut db, "test", &["owner=antti".to_string(), "ticket=ACME-12".to_string()]).unwrap();
+        let alias = db.get("test").unwrap();
+        assert_eq!(alias.meta.get("owner").map(String::as_str), Some("antti"));
+        assert_eq!(alias.meta.get("ticket").map(String::as_str), Some("ACME-12"));
+
+        set_meta(&mut db, "test", &["ticket=".to_string()]).unwrap();
+        assert!(!db.get("test").unwrap().meta.contains_key("ticket"));
+
+        assert!(set_meta(&mut db, "test", &["novalue".to_string()]).is_err());
+        assert!(set_meta(&mut db, "test", &["bad key=x".to_string()]).is_err());
+        assert!(set_meta(&mut db, "missing", &["owner=antti".to_string()]).is_err());
+    }
+
     #[test]
     fn test_untag_normalizes_to_lowercase() {
         let (mut db, _file) = create_test_db();
@@ -422,6 +618,15 @@ mod tests {
         assert!(result.is_ok());
     }
 
+    #[test]
+    fn test_list_tags_of() {
+        let (mut db, _file) = create_test_db();
+        tag(&mut db, "test", "work", true).unwrap();
+
+        assert!(list_tags_of(&db, "test").is_ok());
+        assert!(list_tags_of(&db, "missing").is_err());
+    }
+
     #[test]
     fn test_list_tags_raw_empty() {
         let (db, _file) = create_test_db();
@@ -601,4 +806,41 @@ mod tests {
         assert!(!db.get_all_tags().contains_key("job"));
         assert!(db.get("test").unwrap().has_tag("work"));
     }
+
+    #[test]
+    fn test_tag_many() {
+        let (mut db, _file) = create_test_db_with_multiple_aliases();
+        tag(&mut db, "proj1", "work", true).unwrap();
+
+        tag_many(&mut db, "proj*", "work", true, None).unwrap();
+        assert!(db.get("proj2").unwrap().has_tag("work"));
+        assert!(!db.get("docs").unwrap().has_tag("work"));
+
+        tag_many(&mut db, "*", "tmp", true, Some("path:/tmp/docs")).unwrap();
+        assert!(db.get("docs").unwrap().has_tag("tmp"));
+        assert!(!db.get("proj1").unwrap().has_tag("tmp"));
+
+        tag_many(&mut db, "proj1,docs", "shared", true, None).unwrap();
+        assert!(db.get("docs").unwrap().has_tag("shared"));
+
+        assert!(tag_many(&mut db, "zz*", "work", true, None).is_err());
+    }
+
+    #[test]
+    fn test_retag_auto() {
+        let (mut db, _file) = create_test_db_with_multiple_aliases();
+        let mut config = Config::load().unwrap();
+        config.user.autotag = vec![crate::config::AutoTagRule {
+            pattern: "/tmp/proj*".to_string(),
+            tags: vec!["project".to_string()],
+        }];
+
+        retag_auto(&mut db, &config, true).unwrap();
+        assert!(!db.get("proj1").unwrap().has_tag("project"));
+
+        retag_auto(&mut db, &config, false).unwrap();
+        assert!(db.get("proj1").unwrap().has_tag("project"));
+        assert!(db.get("proj2").unwrap().has_tag("project"));
+        assert!(!db.get("docs").unwrap().has_tag("project"));
+    }
 }
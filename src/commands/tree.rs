@@ -0,0 +1,191 @@
+//! Tree command: preview the subdirectories below an alias, and complete `alias/subdir`
+
+use std::fs;
+use std::path::Path;
+
+use crate::alias::AliasError;
+use crate::config::Config;
+use crate::database::Database;
+use crate::table::TableStyle;
+
+/// Entries shown per directory before the rest are summarised
+const MAX_ENTRIES: usize = 50;
+
+/// Print the subdirectory tree under an alias's path, `depth` levels deep
+pub fn tree(db: &Database, config: &Config, name: &str, depth: usize) -> Result<(), Box<dyn std::error::Error>> {
+    let name = &db.resolve_name(name);
+    let alias = db.get(name).ok_or_else(|| AliasError::NotFound(name.to_string()))?;
+    let root = alias.resolved_path();
+    db.path_check().run(&root)?;
+
+    let style = TableStyle::from(config.user.display.table_style.as_str());
+    println!("{}", root);
+    print!("{}", render_tree(Path::new(&root), depth, style));
+    eprintln!("Jump with: goto {}/<subdir>", name);
+    Ok(())
+}
+
+/// Print the completions for a partially typed `alias/subpath`, one per line
+///
+/// Used by the shell completions for `goto dev/src/<TAB>`; `partial` is the part
+/// after the first slash.
+pub fn complete_subpath(db: &Database, alias: &str, partial: &str) -> Result<(), Box<dyn std::error::Error>> {
+    for word in subpath_completions(db, alias, partial)? {
+        println!("{}", word);
+    }
+    Ok(())
+}
+
+/// Subdirectories matching `partial` below an alias, as `alias/sub/dir/` words
+///
+/// Hidden directories are only offered once the last component starts with '.'.
+pub fn subpath_completions(db: &Database, alias: &str, partial: &str) -> Result<Vec<String>, AliasError> {
+    let name = db.resolve_name(alias);
+    let entry = db.get(&name).ok_or_else(|| AliasError::NotFound(alias.to_string()))?;
+
+    let (parent, prefix) = partial.rsplit_once('/').unwrap_or(("", partial));
+    let dir = Path::new(&entry.resolved_path()).join(parent);
+    let typed = if parent.is_empty() {
+        alias.to_string()
+    } else {
+        format!("{}/{}", alias, parent)
+    };
+
+    Ok(subdirs(&dir, prefix.starts_with('.'))
+        .into_iter()
+        .filter(|(sub, _)| sub.starts_with(prefix))
+        .map(|(sub, _)| format!("{}/{}/", typed, sub))
+        .collect())
+}
+
+/// Subdirectories of `dir` with whether each is a symlink, sorted by name
+///
+/// Symlinks are listed but not followed, so a link loop can't run away.
+fn subdirs(dir: &Path, hidden: bool) -> Vec<(String, bool)> {
+    let Ok(entries) = fs::read_dir(dir) else {
+        return Vec::new();
+    };
+    let mut dirs: Vec<(String, bool)> = entries
+        .filter_map(Result::ok)
+        .filter_map(|e| {
+            let name = e.file_name().to_string_lossy().to_string();
+            let link = e.file_type().ok()?.is_symlink();
+            ((hidden || !name.starts_with('.')) && e.path().is_dir()).then_some((name, link))
+        })
+        .collect();
+    dirs.sort();
+    dirs
+}
+
+/// Render the subdirectories below `root` as a tree, without the root line
+pub fn render_tree(root: &Path, depth: usize, style: TableStyle) -> String {
+    let mut out = String::new();
+    render_level(root, depth, style, "", &mut out);
+    out
+}
+
+fn render_level(dir: &Path, depth: usize, style: TableStyle, prefix: &str, out: &mut String) {
+    if depth == 0 {
+        return;
+    }
+    let (branch, last, pipe) = match style {
+        TableStyle::Unicode => ("├── ", "└── ", "│   "),
+        _ => ("|-- ", "`-- ", "|   "),
+    };
+
+    let dirs = subdirs(dir, false);
+    let hidden = dirs.len().saturating_sub(MAX_ENTRIES);
+    let shown = &dirs[..dirs.len() - hidden];
+    for (i, (name, link)) in shown.iter().enumerate() {
+        let is_last = i + 1 == shown.len() && hidden == 0;
+        out.push_str(prefix);
+        out.push_str(if is_last { last } else { branch });
+        out.push_str(name);
+        out.push_str(if *link { "@\n" } else { "/\n" });
+        if !link {
+            let child = format!("{}{}", prefix, if is_last { "    " } else { pipe });
+            render_level(&dir.join(name), depth - 1, style, &child, out);
+        }
+    }
+    if hidden > 0 {
+        out.push_str(&format!("{}{}… {} more\n", prefix, last, hidden));
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    #[test]
+    fn test_render_tree() {
+        let dir = tempdir().unwrap();
+        for sub in ["src/bin", "src/lib/deep", "docs", ".git/objects"] {
+            fs::create_dir_all(dir.path().join(sub)).unwrap();
+        }
+        fs::write(dir.path().join("README.md"), "").unwrap();
+
+        assert_eq!(
+            render_tree(dir.path(), 2, TableStyle::Unicode),
+            "├── docs/\n\
+             └── src/\n    \
+                 ├── bin/\n    \
+                 └── lib/\n"
+        );
+        assert_eq!(render_tree(dir.path(), 1, TableStyle::Ascii), "|-- docs/\n`-- src/\n");
+        assert_eq!(render_tree(dir.path(), 0, TableStyle::Ascii), "");
+    }
+
+    #[test]
+    fn test_render_tree_nested_prefix() {
+        let dir = tempdir().unwrap();
+        for sub in ["a/x", "b"] {
+            fs::create_dir_all(dir.path().join(sub)).unwrap();
+        }
+        assert_eq!(render_tree(dir.path(), 3, TableStyle::Ascii), "|-- a/\n|   `-- x/\n`-- b/\n");
+    }
+
+    #[test]
+    fn test_render_tree_caps_entries() {
+        let dir = tempdir().unwrap();
+        for i in 0..MAX_ENTRIES + 3 {
+            fs::create_dir(dir.path().join(format!("d{:03}", i))).unwrap();
+        }
+        let out = render_tree(dir.path(), 1, TableStyle::Unicode);
+        assert_eq!(out.lines().count(), MAX_ENTRIES + 1);
+        assert!(out.ends_with("└── … 3 more\n"));
+    }
+
+    #[test]
+    fn test_subpath_completions() {
+        let dir = tempdir().unwrap();
+        for sub in ["src/api", "src/app", "scripts", ".github"] {
+            fs::create_dir_all(dir.path().join(sub)).unwrap();
+        }
+        fs::write(dir.path().join("src/apx"), "").unwrap();
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        db.insert(crate::alias::Alias::new("dev", dir.path().to_str().unwrap()).unwrap());
+
+        assert_eq!(subpath_completions(&db, "dev", "").unwrap(), vec!["dev/scripts/", "dev/src/"]);
+        assert_eq!(subpath_completions(&db, "dev", "sr").unwrap(), vec!["dev/src/"]);
+        assert_eq!(
+            subpath_completions(&db, "dev", "src/ap").unwrap(),
+            vec!["dev/src/api/", "dev/src/app/"]
+        );
+        assert_eq!(subpath_completions(&db, "dev", ".").unwrap(), vec!["dev/.github/"]);
+        assert!(subpath_completions(&db, "dev", "missing/").unwrap().is_empty());
+        assert!(subpath_completions(&db, "nope", "").is_err());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn test_render_tree_does_not_follow_symlinks() {
+        let dir = tempdir().unwrap();
+        fs::create_dir_all(dir.path().join("real/inner")).unwrap();
+        std::os::unix::fs::symlink(dir.path(), dir.path().join("loop")).unwrap();
+        assert_eq!(
+            render_tree(dir.path(), 3, TableStyle::Ascii),
+            "|-- loop@\n`-- real/\n    `-- inner/\n"
+        );
+    }
+}
@@ -405,16 +405,63 @@ pub fn current_version() -> &'static str {
     CURRENT_VERSION
 }
 
+/// Where this binary came from, as embedded by build.rs
+#[derive(Debug, Clone, Serialize)]
+pub struct BuildInfo {
+    pub version: &'static str,
+    pub commit: &'static str,
+    /// RFC 3339 time of the build (or of `SOURCE_DATE_EPOCH`)
+    pub build_date: String,
+    pub rustc: &'static str,
+    pub schema_version: u32,
+}
+
+impl BuildInfo {
+    pub fn current() -> Self {
+        let build_date = env!("GOTO_BUILD_TIME")
+            .parse::<i64>()
+            .ok()
+            .and_then(|secs| DateTime::from_timestamp(secs, 0))
+            .map(|t| t.to_rfc3339_opts(chrono::SecondsFormat::Secs, true))
+            .unwrap_or_else(|| "unknown".to_string());
+        Self {
+            version: CURRENT_VERSION,
+            commit: env!("GOTO_BUILD_COMMIT"),
+            build_date,
+            rustc: env!("GOTO_RUSTC_VERSION"),
+            schema_version: crate::database::SCHEMA_VERSION,
+        }
+    }
+}
+
+/// Build information as pretty-printed JSON (`goto --version --json`)
+pub fn version_json() -> String {
+    serde_json::to_string_pretty(&BuildInfo::current()).unwrap_or_default()
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
     use crate::config::UserConfig;
 
+    #[test]
+    fn test_version_json() {
+        let value: serde_json::Value = serde_json::from_str(&version_json()).unwrap();
+        assert_eq!(value["version"], CURRENT_VERSION);
+        assert_eq!(value["schema_version"], crate::database::SCHEMA_VERSION);
+        for key in ["commit", "build_date", "rustc"] {
+            assert!(value[key].as_str().is_some_and(|s| !s.is_empty()), "missing {}", key);
+        }
+    }
+
     /// Create a test config with a temp directory
     fn test_config(temp_dir: &std::path::Path) -> Config {
         Config {
             database_path: temp_dir.to_path_buf(),
             stack_path: temp_dir.join("goto_stack"),
+            history_path: temp_dir.join("dir_history"),
+            context_path: temp_dir.join("context"),
+            listing_path: temp_dir.join("last_listing"),
             config_path: temp_dir.join("config.toml"),
             aliases_path: temp_dir.join("aliases.toml"),
             user: UserConfig::default(),
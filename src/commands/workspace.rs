@@ -0,0 +1,432 @@
+//! Workspace discovery: register every member of a go.work, npm or Cargo workspace
+//!
+//! Members are registered as `<namespace>:<member>`, tagged with their ecosystem
+//! ("go", "node" or "rust") plus any `-t` tags and matching auto-tag rules.
+
+use std::collections::HashSet;
+use std::fs;
+use std::path::{Path, PathBuf};
+
+use crate::alias::Alias;
+use crate::commands::import_export::ImportResult;
+use crate::commands::register::{resolve_directory, validate_and_normalize_tags};
+use crate::commands::setup::base_name;
+use crate::config::{autotags_for, path_matcher, AutoTagRule};
+use crate::database::Database;
+
+/// The kinds of workspace manifest goto understands
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum WorkspaceKind {
+    /// `go.work` with `use` directives
+    Go,
+    /// `package.json` with a `workspaces` field (npm, yarn)
+    Npm,
+    /// `Cargo.toml` with a `[workspace]` table
+    Cargo,
+}
+
+impl WorkspaceKind {
+    /// The tag given to members of this kind of workspace
+    pub fn tag(self) -> &'static str {
+        match self {
+            WorkspaceKind::Go => "go",
+            WorkspaceKind::Npm => "node",
+            WorkspaceKind::Cargo => "rust",
+        }
+    }
+}
+
+/// A workspace member directory
+#[derive(Debug, Clone, PartialEq)]
+pub struct Member {
+    pub path: PathBuf,
+    pub kind: WorkspaceKind,
+}
+
+/// Directories below `dir` matching a workspace pattern like `packages/*`
+///
+/// `*` and `?` match within one component and `**` any number of them; hidden
+/// directories, `node_modules` and `target` are never descended into.
+fn expand_pattern(dir: &Path, pattern: &str) -> Vec<PathBuf> {
+    let mut current = vec![dir.to_path_buf()];
+    for component in pattern.split('/').filter(|c| !c.is_empty() && *c != ".") {
+        let mut next = Vec::new();
+        for base in &current {
+            if component == "**" {
+                collect_descendants(base, &mut next);
+            } else if component.contains(['*', '?']) {
+                let Ok(matcher) = path_matcher(component) else {
+                    continue;
+                };
+                next.extend(
+                    child_dirs(base)
+                        .into_iter()
+                        .filter(|p| p.file_name().is_some_and(|n| matcher.is_match(&n.to_string_lossy()))),
+                );
+            } else if base.join(component).is_dir() {
+                next.push(base.join(component));
+            }
+        }
+        current = next;
+    }
+    current
+}
+
+/// How far below its starting point [`collect_descendants`] goes
+const MAX_DEPTH: usize = 32;
+
+/// Subdirectories of `dir` worth looking into for workspace members
+///
+/// Symlinks are not followed, so a link back up the tree can't loop.
+fn child_dirs(dir: &Path) -> Vec<PathBuf> {
+    let Ok(entries) = fs::read_dir(dir) else {
+        return Vec::new();
+    };
+    let mut dirs: Vec<PathBuf> = entries
+        .filter_map(Result::ok)
+        .filter(|e| {
+            let name = e.file_name().to_string_lossy().to_string();
+            !name.starts_with('.') && name != "node_modules" && name != "target"
+        })
+        .filter(|e| e.file_type().is_ok_and(|t| t.is_dir()))
+        .map(|e| e.path())
+        .collect();
+    dirs.sort();
+    dirs
+}
+
+/// `dir` and every directory below it, down to [`MAX_DEPTH`] levels (see [`child_dirs`])
+pub fn collect_descendants(dir: &Path, out: &mut Vec<PathBuf>) {
+    collect_to_depth(dir, MAX_DEPTH, out);
+}
+
+fn collect_to_depth(dir: &Path, depth: usize, out: &mut Vec<PathBuf>) {
+    out.push(dir.to_path_buf());
+    if depth == 0 {
+        return;
+    }
+    for child in child_dirs(dir) {
+        collect_to_depth(&child, depth - 1, out);
+    }
+}
+
+/// The directories listed by `use` in a go.work file
+fn parse_go_work(content: &str) -> Vec<String> {
+    let mut dirs = Vec::new();
+    let mut in_block = false;
+    for line in content.lines() {
+        let line = line.split("//").next().unwrap_or_default().trim();
+        if in_block {
+            if line == ")" {
+                in_block = false;
+            } else if !line.is_empty() {
+                dirs.push(line.trim_matches(['"', '`']).to_string());
+            }
+        } else if let Some(rest) = line.strip_prefix("use").filter(|r| r.starts_with([' ', '\t', '('])) {
+            match rest.trim() {
+                "(" => in_block = true,
+                dir => dirs.push(dir.trim_matches(['"', '`']).to_string()),
+            }
+        }
+    }
+    dirs
+}
+
+/// Member patterns from package.json `workspaces` (an array, or `{ "packages": [...] }`)
+fn parse_npm_workspaces(content: &str) -> Result<Vec<String>, String> {
+    let json: serde_json::Value =
+        serde_json::from_str(content).map_err(|e| format!("invalid package.json: {}", e))?;
+    let workspaces = match &json["workspaces"] {
+        serde_json::Value::Object(obj) => obj.get("packages").cloned().unwrap_or_default(),
+        other => other.clone(),
+    };
+    Ok(workspaces
+        .as_array()
+        .map(|patterns| patterns.iter().filter_map(|p| p.as_str().map(str::to_string)).collect())
+        .unwrap_or_default())
+}
+
+/// Member and exclude patterns from the `[workspace]` table of a Cargo.toml, if it has one
+fn parse_cargo_workspace(content: &str) -> Result<Option<(Vec<String>, Vec<String>)>, String> {
+    let manifest: toml::Value = toml::from_str(content).map_err(|e| format!("invalid Cargo.toml: {}", e))?;
+    let Some(workspace) = manifest.get("workspace") else {
+        return Ok(None);
+    };
+    let list = |key: &str| -> Vec<String> {
+        workspace
+            .get(key)
+            .and_then(|v| v.as_array())
+            .map(|a| a.iter().filter_map(|v| v.as_str().map(str::to_string)).collect())
+            .unwrap_or_default()
+    };
+    Ok(Some((list("members"), list("exclude"))))
+}
+
+/// Expand include patterns, drop excluded directories and those without `marker` (if any)
+fn resolve_members(
+    root: &Path,
+    include: &[String],
+    exclude: &[String],
+    marker: Option<&str>,
+    kind: WorkspaceKind,
+) -> Vec<Member> {
+    let excluded: HashSet<PathBuf> = exclude
+        .iter()
+        .flat_map(|p| expand_pattern(root, p))
+        .filter_map(|p| fs::canonicalize(p).ok())
+        .collect();
+    include
+        .iter()
+        .flat_map(|p| expand_pattern(root, p))
+        .filter(|p| marker.is_none_or(|m| p.join(m).is_file()))
+        .filter_map(|p| fs::canonicalize(p).ok())
+        .filter(|p| !excluded.contains(p))
+        .map(|path| Member { path, kind })
+        .collect()
+}
+
+/// Find the members of every workspace manifest in `root`, sorted by path
+///
+/// The root directory itself is never a member, even when a manifest lists it.
+pub fn find_members(root: &Path) -> Result<Vec<Member>, String> {
+    let mut members = Vec::new();
+    let mut found = false;
+
+    if let Ok(content) = fs::read_to_string(root.join("go.work")) {
+        found = true;
+        members.extend(resolve_members(root, &parse_go_work(&content), &[], None, WorkspaceKind::Go));
+    }
+    if let Ok(content) = fs::read_to_string(root.join("package.json")) {
+        let patterns = parse_npm_workspaces(&content)?;
+        found |= !patterns.is_empty();
+        let (exclude, include): (Vec<String>, Vec<String>) = patterns.into_iter().partition(|p| p.starts_with('!'));
+        let exclude: Vec<String> = exclude.iter().map(|p| p[1..].to_string()).collect();
+        members.extend(resolve_members(root, &include, &exclude, Some("package.json"), WorkspaceKind::Npm));
+    }
+    if let Ok(content) = fs::read_to_string(root.join("Cargo.toml")) {
+        if let Some((include, exclude)) = parse_cargo_workspace(&content)? {
+            found = true;
+            members.extend(resolve_members(root, &include, &exclude, Some("Cargo.toml"), WorkspaceKind::Cargo));
+        }
+    }
+
+    if !found {
+        return Err(format!(
+            "workspace not found in {} (looked for go.work, package.json workspaces and a Cargo.toml [workspace])",
+            root.display()
+        ));
+    }
+
+    let root = fs::canonicalize(root).unwrap_or_else(|_| root.to_path_buf());
+    let mut seen = HashSet::new();
+    members.retain(|m| m.path != root && seen.insert(m.path.clone()));
+    members.sort_by(|a, b| a.path.cmp(&b.path));
+    Ok(members)
+}
+
+/// Register every workspace member below `root` as `<namespace>:<member>`
+///
+/// The namespace defaults to the root directory's name. Members that already
+/// have an alias are skipped; a taken name gets a numeric suffix. With `dry_run`
+/// the aliases are only printed.
+pub fn discover_workspaces(
+    db: &mut Database,
+    root: &str,
+    namespace: Option<&str>,
+    tags: &[String],
+    rules: &[AutoTagRule],
+    dry_run: bool,
+) -> Result<ImportResult, Box<dyn std::error::Error>> {
+    let root = resolve_directory(root)?;
+    let namespace = match namespace {
+        Some(ns) => ns.to_string(),
+        None => base_name(&root).ok_or_else(|| format!("no usable namespace for {}; pass --namespace=", root))?,
+    };
+    let tags = validate_and_normalize_tags(tags)?;
+    let mut result = ImportResult::default();
+    let mut taken: HashSet<String> = db.names().map(str::to_string).collect();
+
+    for member in find_members(Path::new(&root))? {
+        let path = member.path.to_string_lossy().to_string();
+        if let Some(existing) = db.all().find(|a| a.path == path) {
+            result.warnings.push(format!("skipping {}: already registered as '{}'", path, existing.name));
+            result.skipped += 1;
+            continue;
+        }
+        if let Err(e) = db.check_path_allowed(&path) {
+            result.warnings.push(format!("skipping {}: {}", path, e));
+            result.skipped += 1;
+            continue;
+        }
+        let Some(base) = base_name(&path) else {
+            result.warnings.push(format!("skipping {}: no usable alias name", path));
+            result.skipped += 1;
+            continue;
+        };
+        let wanted = format!("{}:{}", namespace, base);
+        let Some(name) = std::iter::once(wanted.clone())
+            .chain((2..10).map(|n| format!("{}-{}", wanted, n)))
+            .find(|name| !taken.contains(name))
+        else {
+            result.warnings.push(format!("skipping {}: '{}' and its numbered variants are taken", path, wanted));
+            result.skipped += 1;
+            continue;
+        };
+
+        let mut alias_tags = tags.clone();
+        let extra = [vec![member.kind.tag().to_string()], autotags_for(rules, &path)?].concat();
+        for tag in validate_and_normalize_tags(&extra)? {
+            if !alias_tags.contains(&tag) {
+                alias_tags.push(tag);
+            }
+        }
+
+        if name == wanted {
+            result.imported += 1;
+        } else {
+            result.warnings.push(format!("'{}' is taken; registering {} as '{}'", wanted, path, name));
+            result.renamed += 1;
+        }
+        if dry_run {
+            println!("Would register '{}' -> {} [{}]", name, path, alias_tags.join(", "));
+        } else {
+            db.add_with_tags(Alias::new(&name, &path)?, alias_tags)?;
+        }
+        taken.insert(name);
+    }
+
+    if !dry_run {
+        db.save()?;
+    }
+    Ok(result)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    fn touch(root: &Path, file: &str, content: &str) {
+        let path = root.join(file);
+        fs::create_dir_all(path.parent().unwrap()).unwrap();
+        fs::write(path, content).unwrap();
+    }
+
+    fn names(members: &[Member], root: &Path) -> Vec<String> {
+        let root = fs::canonicalize(root).unwrap();
+        members
+            .iter()
+            .map(|m| m.path.strip_prefix(&root).unwrap().to_string_lossy().to_string())
+            .collect()
+    }
+
+    #[test]
+    fn test_parse_go_work() {
+        let content = "go 1.22\n\nuse ./tools // helpers\n\nuse (\n\t./api\n\t\"./web\"\n)\n";
+        assert_eq!(parse_go_work(content), vec!["./tools", "./api", "./web"]);
+        assert!(parse_go_work("user ./x\n").is_empty());
+    }
+
+    #[test]
+    fn test_parse_npm_workspaces() {
+        assert_eq!(parse_npm_workspaces(r#"{"workspaces": ["packages/*"]}"#).unwrap(), vec!["packages/*"]);
+        assert_eq!(
+            parse_npm_workspaces(r#"{"workspaces": {"packages": ["apps/*", "!apps/old"]}}"#).unwrap(),
+            vec!["apps/*", "!apps/old"]
+        );
+        assert!(parse_npm_workspaces(r#"{"name": "app"}"#).unwrap().is_empty());
+        assert!(parse_npm_workspaces("{").is_err());
+    }
+
+    #[test]
+    fn test_find_members_cargo() {
+        let dir = tempdir().unwrap();
+        touch(dir.path(), "Cargo.toml", "[workspace]\nmembers = [\"crates/*\", \".\"]\nexclude = [\"crates/old\"]\n");
+        touch(dir.path(), "crates/core/Cargo.toml", "");
+        touch(dir.path(), "crates/cli/Cargo.toml", "");
+        touch(dir.path(), "crates/old/Cargo.toml", "");
+        fs::create_dir_all(dir.path().join("crates/notes")).unwrap();
+
+        let members = find_members(dir.path()).unwrap();
+        assert_eq!(names(&members, dir.path()), vec!["crates/cli", "crates/core"]);
+        assert!(members.iter().all(|m| m.kind == WorkspaceKind::Cargo));
+    }
+
+    #[test]
+    fn test_find_members_npm_and_go() {
+        let dir = tempdir().unwrap();
+        touch(dir.path(), "package.json", r#"{"workspaces": ["packages/**", "!packages/legacy"]}"#);
+        touch(dir.path(), "packages/ui/package.json", "{}");
+        touch(dir.path(), "packages/tools/lint/package.json", "{}");
+        touch(dir.path(), "packages/legacy/package.json", "{}");
+        touch(dir.path(), "packages/ui/node_modules/dep/package.json", "{}");
+        touch(dir.path(), "go.work", "use (\n\t./svc\n\t./missing\n)\n");
+        fs::create_dir_all(dir.path().join("svc")).unwrap();
+
+        let members = find_members(dir.path()).unwrap();
+        assert_eq!(
+            names(&members, dir.path()),
+            vec!["packages/tools/lint", "packages/ui", "svc"]
+        );
+        assert_eq!(members[2].kind, WorkspaceKind::Go);
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn test_collect_descendants_symlink_loop() {
+        let dir = tempdir().unwrap();
+        touch(dir.path(), "packages/ui/package.json", "{}");
+        std::os::unix::fs::symlink(dir.path(), dir.path().join("packages/ui/back")).unwrap();
+
+        let mut dirs = Vec::new();
+        collect_descendants(dir.path(), &mut dirs);
+        assert_eq!(dirs.len(), 3);
+        assert!(!dirs.iter().any(|d| d.ends_with("back")));
+    }
+
+    #[test]
+    fn test_collect_descendants_depth_cap() {
+        let dir = tempdir().unwrap();
+        let deep: PathBuf = (0..MAX_DEPTH + 5).map(|_| "d").collect();
+        fs::create_dir_all(dir.path().join(deep)).unwrap();
+
+        let mut dirs = Vec::new();
+        collect_descendants(dir.path(), &mut dirs);
+        assert_eq!(dirs.len(), MAX_DEPTH + 1);
+    }
+
+    #[test]
+    fn test_find_members_no_workspace() {
+        let dir = tempdir().unwrap();
+        touch(dir.path(), "package.json", r#"{"name": "app"}"#);
+        let err = find_members(dir.path()).unwrap_err();
+        assert!(err.starts_with("workspace not found"), "{}", err);
+    }
+
+    #[test]
+    fn test_discover_workspaces() {
+        let dir = tempdir().unwrap();
+        let root = dir.path().join("shop");
+        touch(&root, "Cargo.toml", "[workspace]\nmembers = [\"api\", \"web\", \"libs/web\"]\n");
+        for member in ["api", "web", "libs/web"] {
+            touch(&root, &format!("{}/Cargo.toml", member), "");
+        }
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        let api = fs::canonicalize(root.join("api")).unwrap();
+        db.insert(Alias::new("old-api", api.to_str().unwrap()).unwrap());
+
+        let root_str = root.to_str().unwrap();
+        let result = discover_workspaces(&mut db, root_str, None, &["work".to_string()], &[], true).unwrap();
+        assert_eq!((result.imported, result.renamed, result.skipped), (1, 1, 1));
+        assert!(!db.contains("shop:web"));
+
+        let result = discover_workspaces(&mut db, root_str, None, &["work".to_string()], &[], false).unwrap();
+        assert_eq!((result.imported, result.renamed, result.skipped), (1, 1, 1));
+        assert_eq!(db.get("shop:web").unwrap().tags, vec!["rust", "work"]);
+        assert!(db.contains("shop:web-2"));
+        assert!(!db.contains("shop:api"));
+
+        let result = discover_workspaces(&mut db, root_str, Some("s"), &[], &[], false).unwrap();
+        assert_eq!(result.skipped, 3);
+    }
+}
@@ -1,10 +1,13 @@
 //! Configuration loading and path handling
 
+use regex::Regex;
 use serde::{Deserialize, Serialize};
 use std::fs;
-use std::path::PathBuf;
+use std::path::{Path, PathBuf};
 use thiserror::Error;
 
+use crate::hooks::HooksConfig;
+
 /// Errors that can occur during configuration
 #[derive(Error, Debug)]
 pub enum ConfigError {
@@ -16,6 +19,12 @@ pub enum ConfigError {
 
     #[error("TOML parse error: {0}")]
     TomlParse(#[from] toml::de::Error),
+
+    #[error("included config {0}: {1}")]
+    Include(String, String),
+
+    #[error("invalid value for {0}: {1}")]
+    Env(String, String),
 }
 
 /// General application settings
@@ -26,21 +35,94 @@ pub struct GeneralConfig {
 
     #[serde(default = "default_sort")]
     pub default_sort: String,
+
+    /// Namespace tried for names given without one (e.g. "work" makes `api` find `work:api`)
+    #[serde(default)]
+    pub default_namespace: String,
+
+    /// Record use counts, last-used times and visited directories
+    #[serde(default = "default_track_usage")]
+    pub track_usage: bool,
+
+    /// Ask before unregister, cleanup and overwriting imports on a terminal
+    #[serde(default = "default_confirm_destructive")]
+    pub confirm_destructive: bool,
+
+    /// Give up checking a target directory after this many milliseconds (0: wait)
+    #[serde(default = "default_check_timeout_ms")]
+    pub check_timeout_ms: u64,
+
+    /// Similar names offered when an alias isn't found (0: none)
+    #[serde(default = "default_max_suggestions")]
+    pub max_suggestions: usize,
+
+    /// Jump straight to the only suggestion scoring `auto_accept_threshold` or more
+    #[serde(default)]
+    pub auto_accept: bool,
+
+    /// Ask "Did you mean 'x'?" on a terminal when only one suggestion scores
+    /// `auto_accept_threshold` or more (false: always list the suggestions)
+    #[serde(default = "default_confirm_typos")]
+    pub confirm_typos: bool,
+
+    #[serde(default = "default_auto_accept_threshold")]
+    pub auto_accept_threshold: f64,
+
+    /// Allow letters from any script in new alias names (false: ASCII only)
+    #[serde(default = "default_unicode_names")]
+    pub unicode_names: bool,
 }
 
 fn default_fuzzy_threshold() -> f64 {
-    0.3
+    0.7
 }
 
 fn default_sort() -> String {
     "alpha".to_string()
 }
 
+fn default_track_usage() -> bool {
+    true
+}
+
+fn default_confirm_destructive() -> bool {
+    true
+}
+
+fn default_check_timeout_ms() -> u64 {
+    2000
+}
+
+fn default_max_suggestions() -> usize {
+    3
+}
+
+fn default_confirm_typos() -> bool {
+    true
+}
+
+fn default_auto_accept_threshold() -> f64 {
+    0.85
+}
+
+fn default_unicode_names() -> bool {
+    true
+}
+
 impl Default for GeneralConfig {
     fn default() -> Self {
         Self {
             fuzzy_threshold: default_fuzzy_threshold(),
             default_sort: default_sort(),
+            default_namespace: String::new(),
+            track_usage: default_track_usage(),
+            confirm_destructive: default_confirm_destructive(),
+            check_timeout_ms: default_check_timeout_ms(),
+            max_suggestions: default_max_suggestions(),
+            auto_accept: false,
+            confirm_typos: default_confirm_typos(),
+            auto_accept_threshold: default_auto_accept_threshold(),
+            unicode_names: default_unicode_names(),
         }
     }
 }
@@ -115,6 +197,14 @@ pub struct PruneConfig {
     /// How often to check for stale aliases (in hours)
     #[serde(default = "default_prune_check_interval")]
     pub check_interval_hours: u64,
+
+    /// Warn after a jump when aliases in the same parent directory are broken
+    #[serde(default)]
+    pub warn_on_navigate: bool,
+
+    /// Warn after a jump when `goto --cleanup` hasn't run for this many days (0 = never)
+    #[serde(default)]
+    pub cleanup_reminder_days: u64,
 }
 
 fn default_prune_auto_check() -> bool {
@@ -130,13 +220,135 @@ impl Default for PruneConfig {
         Self {
             auto_check: default_prune_auto_check(),
             check_interval_hours: default_prune_check_interval(),
+            warn_on_navigate: false,
+            cleanup_reminder_days: 0,
+        }
+    }
+}
+
+/// Database storage settings
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct StorageConfig {
+    /// Whether to encrypt the alias database at rest (key from GOTO_KEY or keyring)
+    #[serde(default)]
+    pub encrypt: bool,
+
+    /// How many rotating backups (aliases.toml.1, .2, ...) to keep; 0 disables them
+    #[serde(default = "default_backups")]
+    pub backups: usize,
+
+    /// Give up reading or writing the database after this many milliseconds; 0 waits
+    #[serde(default)]
+    pub io_timeout_ms: u64,
+
+    /// Alias databases consulted in order, the first taking changes ($GOTO_PATH wins)
+    #[serde(default)]
+    pub databases: Vec<String>,
+}
+
+fn default_backups() -> usize {
+    3
+}
+
+impl Default for StorageConfig {
+    fn default() -> Self {
+        Self {
+            encrypt: false,
+            backups: default_backups(),
+            io_timeout_ms: 0,
+            databases: Vec::new(),
         }
     }
 }
 
+/// Directory stack settings
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+pub struct StackConfig {
+    /// Pushing a directory already on the stack moves it to the top instead of adding it again
+    #[serde(default)]
+    pub dedupe: bool,
+}
+
+/// Which directories may be registered, for teams sharing a machine
+///
+/// Patterns are prefixes or globs as in `[[autotag]]`. `deny` wins over `allow`;
+/// an empty `allow` allows everything not denied.
+#[derive(Debug, Clone, Serialize, Deserialize, Default, PartialEq)]
+pub struct RegisterConfig {
+    #[serde(default)]
+    pub allow: Vec<String>,
+
+    #[serde(default)]
+    pub deny: Vec<String>,
+}
+
+impl RegisterConfig {
+    /// Why `path` may not be registered, if it may not
+    ///
+    /// The path is also checked with symlinks resolved, so a link can't reach a denied tree.
+    pub fn refusal(&self, path: &str) -> Option<String> {
+        let mut forms = vec![path.to_string()];
+        if let Ok(real) = fs::canonicalize(path) {
+            let real = real.to_string_lossy().to_string();
+            if real != path {
+                forms.push(real);
+            }
+        }
+
+        for pattern in &self.deny {
+            match path_matcher(pattern) {
+                Ok(re) if forms.iter().any(|p| re.is_match(p)) => return Some(format!("under denied '{}'", pattern)),
+                Ok(_) => {}
+                Err(e) => return Some(e),
+            }
+        }
+        if self.allow.is_empty() {
+            return None;
+        }
+        for pattern in &self.allow {
+            match path_matcher(pattern) {
+                Ok(re) if forms.iter().any(|p| re.is_match(p)) => return None,
+                Ok(_) => {}
+                Err(e) => return Some(e),
+            }
+        }
+        Some(format!("outside the allowed {}", self.allow.join(", ")))
+    }
+}
+
+/// Tags added automatically to aliases whose path matches `pattern`
+#[derive(Debug, Clone, Serialize, Deserialize, PartialEq)]
+pub struct AutoTagRule {
+    /// Path prefix or glob, as accepted by `goto -l --path=`
+    pub pattern: String,
+    #[serde(default)]
+    pub tags: Vec<String>,
+}
+
+/// Collect the tags of every rule matching `path`, in rule order without duplicates
+pub fn autotags_for(rules: &[AutoTagRule], path: &str) -> Result<Vec<String>, String> {
+    let mut tags: Vec<String> = Vec::new();
+    for rule in rules {
+        if path_matcher(&rule.pattern)?.is_match(path) {
+            for tag in &rule.tags {
+                let tag = tag.trim().to_lowercase();
+                if !tags.contains(&tag) {
+                    tags.push(tag);
+                }
+            }
+        }
+    }
+    Ok(tags)
+}
+
 /// User-configurable settings loaded from TOML
 #[derive(Debug, Clone, Serialize, Deserialize, Default)]
 pub struct UserConfig {
+    /// Config files merged beneath this one; after loading, every file included
+    /// (nested ones too) as an absolute path
+    #[serde(default)]
+    pub include: Vec<String>,
+
     #[serde(default)]
     pub general: GeneralConfig,
 
@@ -148,6 +360,21 @@ pub struct UserConfig {
 
     #[serde(default)]
     pub prune: PruneConfig,
+
+    #[serde(default)]
+    pub storage: StorageConfig,
+
+    #[serde(default)]
+    pub stack: StackConfig,
+
+    #[serde(default)]
+    pub register: RegisterConfig,
+
+    #[serde(default)]
+    pub autotag: Vec<AutoTagRule>,
+
+    #[serde(default)]
+    pub hooks: HooksConfig,
 }
 
 /// Application configuration
@@ -157,6 +384,12 @@ pub struct Config {
     pub database_path: PathBuf,
     /// Path to the directory stack file
     pub stack_path: PathBuf,
+    /// Path to the visited-directory history recorded by the shell hook
+    pub history_path: PathBuf,
+    /// Path to the file holding the active context set by `goto --context use`
+    pub context_path: PathBuf,
+    /// Path to the rows of the last `-l`/`--recent` listing, for `goto %N`
+    pub listing_path: PathBuf,
     /// Path to the config.toml file
     pub config_path: PathBuf,
     /// Path to the aliases database file
@@ -168,28 +401,76 @@ pub struct Config {
 impl Config {
     /// Load configuration from environment and defaults
     pub fn load() -> Result<Self, ConfigError> {
-        let base_path = get_database_path()?;
+        Self::load_from(get_database_path()?)
+    }
 
+    /// Load configuration for an explicit database directory (as `--db` gives)
+    pub fn load_from(base_path: PathBuf) -> Result<Self, ConfigError> {
         let config_path = base_path.join("config.toml");
         let stack_path = base_path.join("goto_stack");
+        let history_path = base_path.join("dir_history");
+        let context_path = base_path.join("context");
+        let listing_path = base_path.join("last_listing");
         let aliases_path = base_path.join("aliases.toml");
 
-        let user = if config_path.exists() {
-            let content = fs::read_to_string(&config_path)?;
-            toml::from_str(&content)?
+        let mut seen = Vec::new();
+        let mut table = if config_path.exists() {
+            seen.push(fs::canonicalize(&config_path)?);
+            load_with_includes(&config_path, &mut seen)?
         } else {
-            UserConfig::default()
+            toml::Table::new()
         };
+        apply_env_overrides(&mut table)?;
+        let mut user: UserConfig = toml::Value::Table(table).try_into()?;
+        user.include = seen.iter().skip(1).map(|p| p.to_string_lossy().to_string()).collect();
+        let aliases_path = database_search_path(&user.storage.databases)?
+            .into_iter()
+            .next()
+            .unwrap_or(aliases_path);
 
         Ok(Config {
             database_path: base_path,
             stack_path,
+            history_path,
+            context_path,
+            listing_path,
             config_path,
             aliases_path,
             user,
         })
     }
 
+    /// The active context: `$GOTO_CONTEXT` if set, else the persisted one
+    ///
+    /// A context is a namespace that bare alias names resolve in, overriding
+    /// `general.default_namespace`.
+    pub fn active_context(&self) -> Option<String> {
+        env_context().or_else(|| self.saved_context())
+    }
+
+    /// The context persisted by `goto --context use`, ignoring `$GOTO_CONTEXT`
+    pub fn saved_context(&self) -> Option<String> {
+        fs::read_to_string(&self.context_path)
+            .ok()
+            .map(|c| c.trim().to_string())
+            .filter(|c| !c.is_empty())
+    }
+
+    /// Read-only alias databases layered under `aliases_path`, from `$GOTO_PATH`
+    /// or `storage.databases`, highest precedence first
+    pub fn database_layers(&self) -> Result<Vec<PathBuf>, ConfigError> {
+        Ok(database_search_path(&self.user.storage.databases)?
+            .into_iter()
+            .filter(|p| *p != self.aliases_path)
+            .collect())
+    }
+
+    /// Namespace that bare alias names fall back to
+    pub fn effective_namespace(&self) -> String {
+        self.active_context()
+            .unwrap_or_else(|| self.user.general.default_namespace.clone())
+    }
+
     /// Ensure the config directory exists
     pub fn ensure_dirs(&self) -> Result<(), ConfigError> {
         fs::create_dir_all(&self.database_path)?;
@@ -204,9 +485,23 @@ impl Config {
 
         self.ensure_dirs()?;
 
-        let default_config = r#"[general]
+        let default_config = r#"# Config files merged beneath this one, e.g. a team's version-controlled settings;
+# their [[aliases]] are available read-only
+# include = ["~/team/goto-shared.toml"]
+# Any section can have per-OS overrides: [general.linux], [general.darwin], [display.windows], ...
+
+[general]
 fuzzy_threshold = 0.6
-default_sort = "alpha"  # alpha, usage, recent
+default_sort = "alpha"  # alpha, usage, recent, created, path, size
+default_namespace = ""  # e.g. "work" so `goto api` also finds work:api
+track_usage = true      # Record use counts and visited directories (false: never write on jump)
+confirm_destructive = true  # Ask before unregister/cleanup/overwriting import (same as --yes when false)
+check_timeout_ms = 2000 # Give up on an unresponsive (network) mount before jumping; 0 waits
+max_suggestions = 3     # Similar names offered when an alias isn't found (0: none)
+auto_accept = false     # Jump to the only suggestion scoring auto_accept_threshold or more, without asking
+confirm_typos = true    # Otherwise ask "Did you mean 'x'?" about it (false: list the suggestions)
+auto_accept_threshold = 0.85
+unicode_names = true    # Allow names like "projëkt" (false: ASCII letters only)
 
 [display]
 show_stats = false
@@ -220,6 +515,34 @@ check_interval_hours = 24
 [prune]
 auto_check = true        # Show notification when stale aliases exist
 check_interval_hours = 24
+warn_on_navigate = false # After a jump, mention broken aliases next to the target
+cleanup_reminder_days = 0 # After a jump, remind when --cleanup hasn't run in N days (0 = off)
+
+[storage]
+encrypt = false          # Encrypt aliases.toml (key from GOTO_KEY or keyring)
+backups = 3              # Rotating backups kept before each save (0 disables)
+io_timeout_ms = 0        # Give up on a slow database file after this long (0 waits)
+# Alias databases looked up in order; changes go to the first (like $GOTO_PATH)
+# databases = ["~/.config/goto", "~/team/goto/aliases.toml", "/etc/goto"]
+
+[stack]
+dedupe = false           # Pushing a directory already on the stack moves it to the top
+
+# Directories that may be registered (prefixes or globs); deny wins, empty allow allows all
+# [register]
+# allow = ["~/work", "~/code"]
+# deny = ["/tmp", "/proc"]
+
+# Tag aliases automatically by path (at registration, or later with --retag-auto)
+# [[autotag]]
+# pattern = "~/work/**"
+# tags = ["work"]
+
+# Commands run before a jump; a non-zero exit cancels it (GOTO_ALIAS, GOTO_PATH are set)
+# [hooks]
+# pre_navigate = "~/bin/goto-check"
+# [hooks.alias]
+# prod-deploy = "~/bin/work-hours-only"
 "#;
 
         fs::write(&self.config_path, default_config)?;
@@ -228,11 +551,51 @@ check_interval_hours = 24
 
     /// Format the current configuration as a string
     pub fn format_config(&self) -> String {
-        format!(
-            "Configuration file: {}\n\n\
-             [general]\n\
+        let context = match self.active_context() {
+            Some(c) if env_context().is_some() => format!("{} (from GOTO_CONTEXT)", c),
+            Some(c) => c,
+            None => "(none)".to_string(),
+        };
+        let include = if self.user.include.is_empty() {
+            String::new()
+        } else {
+            let files: Vec<String> = self.user.include.iter().map(|f| format!("{:?}", f)).collect();
+            format!("include = [{}]\n\n", files.join(", "))
+        };
+        let databases = match database_search_path(&self.user.storage.databases) {
+            Ok(paths) if !paths.is_empty() => {
+                let paths: Vec<String> = paths.iter().map(|p| format!("{:?}", p.display().to_string())).collect();
+                let source = if std::env::var("GOTO_PATH").is_ok_and(|v| !v.is_empty()) {
+                    "  # from GOTO_PATH"
+                } else {
+                    ""
+                };
+                format!("databases = [{}]{}\n", paths.join(", "), source)
+            }
+            _ => String::new(),
+        };
+        let overrides = env_overrides();
+        let overrides = if overrides.is_empty() {
+            String::new()
+        } else {
+            format!("Environment overrides: {}\n", overrides.join(", "))
+        };
+        let mut out = format!(
+            "Configuration file: {}\n\
+             Active context: {}\n\
+             {}\n\
+             {}[general]\n\
              fuzzy_threshold = {:.1}\n\
-             default_sort = \"{}\"\n\n\
+             default_sort = \"{}\"\n\
+             default_namespace = \"{}\"\n\
+             track_usage = {}\n\
+             confirm_destructive = {}\n\
+             check_timeout_ms = {}\n\
+             max_suggestions = {}\n\
+             auto_accept = {}\n\
+             confirm_typos = {}\n\
+             auto_accept_threshold = {:.2}\n\
+             unicode_names = {}\n\n\
              [display]\n\
              show_stats = {}\n\
              show_tags = {}\n\
@@ -242,10 +605,31 @@ check_interval_hours = 24
              check_interval_hours = {}\n\n\
              [prune]\n\
              auto_check = {}\n\
-             check_interval_hours = {}\n",
+             check_interval_hours = {}\n\
+             warn_on_navigate = {}\n\
+             cleanup_reminder_days = {}\n\n\
+             [storage]\n\
+             encrypt = {}\n\
+             backups = {}\n\
+             io_timeout_ms = {}\n\
+             {}\n\
+             [stack]\n\
+             dedupe = {}\n",
             self.config_path.display(),
+            context,
+            overrides,
+            include,
             self.user.general.fuzzy_threshold,
             self.user.general.default_sort,
+            self.user.general.default_namespace,
+            self.user.general.track_usage,
+            self.user.general.confirm_destructive,
+            self.user.general.check_timeout_ms,
+            self.user.general.max_suggestions,
+            self.user.general.auto_accept,
+            self.user.general.confirm_typos,
+            self.user.general.auto_accept_threshold,
+            self.user.general.unicode_names,
             self.user.display.show_stats,
             self.user.display.show_tags,
             self.user.display.table_style,
@@ -253,10 +637,55 @@ check_interval_hours = 24
             self.user.update.check_interval_hours,
             self.user.prune.auto_check,
             self.user.prune.check_interval_hours,
-        )
+            self.user.prune.warn_on_navigate,
+            self.user.prune.cleanup_reminder_days,
+            self.user.storage.encrypt,
+            self.user.storage.backups,
+            self.user.storage.io_timeout_ms,
+            databases,
+            self.user.stack.dedupe,
+        );
+
+        let register = &self.user.register;
+        let list = |items: &[String]| items.iter().map(|i| toml_string(i)).collect::<Vec<_>>().join(", ");
+        if !register.allow.is_empty() || !register.deny.is_empty() {
+            out.push_str(&format!(
+                "\n[register]\nallow = [{}]\ndeny = [{}]\n",
+                list(&register.allow),
+                list(&register.deny)
+            ));
+        }
+
+        for rule in &self.user.autotag {
+            out.push_str(&format!(
+                "\n[[autotag]]\npattern = {}\ntags = [{}]\n",
+                toml_string(&rule.pattern),
+                list(&rule.tags)
+            ));
+        }
+
+        let hooks = &self.user.hooks;
+        if !hooks.pre_navigate.is_empty() || !hooks.alias.is_empty() {
+            out.push_str("\n[hooks]\n");
+            if !hooks.pre_navigate.is_empty() {
+                out.push_str(&format!("pre_navigate = {}\n", toml_string(&hooks.pre_navigate)));
+            }
+            if !hooks.alias.is_empty() {
+                out.push_str("\n[hooks.alias]\n");
+                for (alias, cmd) in &hooks.alias {
+                    out.push_str(&format!("{} = {}\n", toml_string(alias), toml_string(cmd)));
+                }
+            }
+        }
+        out
     }
 }
 
+/// `s` as a quoted TOML string, escaped so the printed config parses back
+fn toml_string(s: &str) -> String {
+    toml::Value::String(s.to_string()).to_string()
+}
+
 /// Get the database path based on priority:
 /// 1. $GOTO_DB environment variable
 /// 2. $XDG_CONFIG_HOME/goto
@@ -278,6 +707,255 @@ fn get_database_path() -> Result<PathBuf, ConfigError> {
         .ok_or(ConfigError::NoHomeDir)
 }
 
+/// The alias database files to consult, in order: `$GOTO_PATH` split like
+/// `$PATH`, or else `configured`
+///
+/// An entry is an aliases.toml-style file or a directory holding `aliases.toml`.
+/// The first entry is kept even when it doesn't exist yet, since changes are
+/// written there (a missing entry not ending in `.toml` is taken as a directory);
+/// later entries without an existing file are skipped.
+fn database_search_path(configured: &[String]) -> Result<Vec<PathBuf>, ConfigError> {
+    let entries: Vec<PathBuf> = match std::env::var_os("GOTO_PATH").filter(|v| !v.is_empty()) {
+        Some(value) => std::env::split_paths(&value)
+            .filter(|p| !p.as_os_str().is_empty())
+            .map(|p| expand_path(&p.to_string_lossy()))
+            .collect::<Result<_, _>>()?,
+        None => configured.iter().map(|p| expand_path(p)).collect::<Result<_, _>>()?,
+    };
+
+    let mut files: Vec<PathBuf> = Vec::new();
+    for (i, entry) in entries.into_iter().enumerate() {
+        let is_file = entry.is_file() || (!entry.exists() && entry.extension().is_some_and(|e| e == "toml"));
+        let file = if is_file { entry } else { entry.join("aliases.toml") };
+        if (i == 0 || file.is_file()) && !files.contains(&file) {
+            files.push(file);
+        }
+    }
+    Ok(files)
+}
+
+/// Build a matcher for a path pattern. A plain path matches itself and everything
+/// below it; `*` and `?` stay within one component, `**` spans any depth.
+pub fn path_matcher(pattern: &str) -> Result<Regex, String> {
+    let mut pattern = pattern.to_string();
+    if pattern == "~" || pattern.starts_with("~/") {
+        let home = dirs::home_dir().ok_or("could not determine home directory")?;
+        pattern = format!("{}{}", home.to_string_lossy(), &pattern[1..]);
+    }
+    if pattern.len() > 1 {
+        pattern = pattern.trim_end_matches('/').to_string();
+    }
+
+    let mut re = String::from("^");
+    if !pattern.contains(['*', '?']) {
+        re.push_str(&regex::escape(&pattern));
+        re.push_str("(?:/.*)?");
+    } else {
+        let mut rest = pattern.as_str();
+        while let Some(c) = rest.chars().next() {
+            if let Some(tail) = rest.strip_prefix("/**") {
+                // "/**" also matches the directory itself
+                re.push_str("(?:/.*)?");
+                rest = tail;
+            } else if let Some(tail) = rest.strip_prefix("**") {
+                re.push_str(".*");
+                rest = tail;
+            } else {
+                match c {
+                    '*' => re.push_str("[^/]*"),
+                    '?' => re.push_str("[^/]"),
+                    _ => re.push_str(&regex::escape(&c.to_string())),
+                }
+                rest = &rest[c.len_utf8()..];
+            }
+        }
+    }
+    re.push('$');
+
+    Regex::new(&re).map_err(|e| format!("invalid path pattern '{}': {}", pattern, e))
+}
+
+/// Read a config file with the files its `include` list names merged beneath it
+///
+/// Includes are resolved relative to the including file and may nest. `seen`
+/// starts with the top-level file; every file included is appended to it, and
+/// one already there is skipped, so cycles end.
+fn load_with_includes(path: &Path, seen: &mut Vec<PathBuf>) -> Result<toml::Table, ConfigError> {
+    let mut table: toml::Table = toml::from_str(&fs::read_to_string(path)?)?;
+    apply_os_overrides(&mut table, std::env::consts::OS);
+    let mut merged = toml::Table::new();
+
+    let includes = match table.remove("include") {
+        None => Vec::new(),
+        Some(toml::Value::Array(items)) => items,
+        Some(_) => return Err(ConfigError::Include("include".to_string(), "must be a list of paths".to_string())),
+    };
+    let base_dir = path.parent().unwrap_or(Path::new("."));
+    for item in includes {
+        let name = item
+            .as_str()
+            .ok_or_else(|| ConfigError::Include(item.to_string(), "not a path".to_string()))?;
+        let include_path = if name.starts_with(['~', '$']) || Path::new(name).is_absolute() {
+            expand_path(name)?
+        } else {
+            expand_path(&base_dir.join(name).to_string_lossy())?
+        };
+        if seen.contains(&include_path) {
+            continue;
+        }
+        seen.push(include_path.clone());
+        let shared = load_with_includes(&include_path, seen)
+            .map_err(|e| ConfigError::Include(include_path.display().to_string(), e.to_string()))?;
+        merge_tables(&mut merged, shared);
+    }
+
+    merge_tables(&mut merged, table);
+    Ok(merged)
+}
+
+/// How an environment override's value is read
+#[derive(Clone, Copy)]
+enum EnvKind {
+    Bool,
+    Int,
+    Float,
+    Str,
+}
+
+/// Environment variables that override config keys: (variable, section, key, kind)
+///
+/// `[general]` and `[display]` keys go by their bare name, the rest carry their section.
+const ENV_OVERRIDES: &[(&str, &str, &str, EnvKind)] = &[
+    ("GOTO_FUZZY_THRESHOLD", "general", "fuzzy_threshold", EnvKind::Float),
+    ("GOTO_DEFAULT_SORT", "general", "default_sort", EnvKind::Str),
+    ("GOTO_DEFAULT_NAMESPACE", "general", "default_namespace", EnvKind::Str),
+    ("GOTO_TRACK_USAGE", "general", "track_usage", EnvKind::Bool),
+    ("GOTO_CONFIRM_DESTRUCTIVE", "general", "confirm_destructive", EnvKind::Bool),
+    ("GOTO_CHECK_TIMEOUT_MS", "general", "check_timeout_ms", EnvKind::Int),
+    ("GOTO_MAX_SUGGESTIONS", "general", "max_suggestions", EnvKind::Int),
+    ("GOTO_AUTO_ACCEPT", "general", "auto_accept", EnvKind::Bool),
+    ("GOTO_CONFIRM_TYPOS", "general", "confirm_typos", EnvKind::Bool),
+    ("GOTO_AUTO_ACCEPT_THRESHOLD", "general", "auto_accept_threshold", EnvKind::Float),
+    ("GOTO_UNICODE_NAMES", "general", "unicode_names", EnvKind::Bool),
+    ("GOTO_SHOW_STATS", "display", "show_stats", EnvKind::Bool),
+    ("GOTO_SHOW_TAGS", "display", "show_tags", EnvKind::Bool),
+    ("GOTO_TABLE_STYLE", "display", "table_style", EnvKind::Str),
+    ("GOTO_UPDATE_AUTO_CHECK", "update", "auto_check", EnvKind::Bool),
+    ("GOTO_UPDATE_CHECK_INTERVAL_HOURS", "update", "check_interval_hours", EnvKind::Int),
+    ("GOTO_PRUNE_AUTO_CHECK", "prune", "auto_check", EnvKind::Bool),
+    ("GOTO_PRUNE_CHECK_INTERVAL_HOURS", "prune", "check_interval_hours", EnvKind::Int),
+    ("GOTO_PRUNE_WARN_ON_NAVIGATE", "prune", "warn_on_navigate", EnvKind::Bool),
+    ("GOTO_PRUNE_CLEANUP_REMINDER_DAYS", "prune", "cleanup_reminder_days", EnvKind::Int),
+    ("GOTO_STORAGE_ENCRYPT", "storage", "encrypt", EnvKind::Bool),
+    ("GOTO_STORAGE_BACKUPS", "storage", "backups", EnvKind::Int),
+    ("GOTO_STORAGE_IO_TIMEOUT_MS", "storage", "io_timeout_ms", EnvKind::Int),
+    ("GOTO_STACK_DEDUPE", "stack", "dedupe", EnvKind::Bool),
+];
+
+/// Names of the override variables set (non-empty) in the environment
+pub fn env_overrides() -> Vec<&'static str> {
+    ENV_OVERRIDES
+        .iter()
+        .map(|(var, ..)| *var)
+        .filter(|var| std::env::var(var).is_ok_and(|v| !v.is_empty()))
+        .collect()
+}
+
+/// Set the config keys whose override variables are set, over whatever the files said
+fn apply_env_overrides(table: &mut toml::Table) -> Result<(), ConfigError> {
+    for (var, section, key, kind) in ENV_OVERRIDES {
+        let Ok(raw) = std::env::var(var) else {
+            continue;
+        };
+        if raw.is_empty() {
+            continue;
+        }
+        let value = parse_env_value(raw.trim(), *kind).map_err(|e| ConfigError::Env(var.to_string(), e))?;
+        let section = table
+            .entry(section.to_string())
+            .or_insert_with(|| toml::Value::Table(toml::Table::new()));
+        if let toml::Value::Table(section) = section {
+            section.insert(key.to_string(), value);
+        }
+    }
+    Ok(())
+}
+
+fn parse_env_value(raw: &str, kind: EnvKind) -> Result<toml::Value, String> {
+    match kind {
+        EnvKind::Bool => match raw.to_lowercase().as_str() {
+            "1" | "true" | "yes" | "on" => Ok(toml::Value::Boolean(true)),
+            "0" | "false" | "no" | "off" => Ok(toml::Value::Boolean(false)),
+            _ => Err(format!("'{}' is not a boolean (use true/false, 1/0, yes/no)", raw)),
+        },
+        EnvKind::Int => raw
+            .parse::<i64>()
+            .ok()
+            .filter(|n| *n >= 0)
+            .map(toml::Value::Integer)
+            .ok_or_else(|| format!("'{}' is not a non-negative whole number", raw)),
+        EnvKind::Float => raw
+            .parse::<f64>()
+            .map(toml::Value::Float)
+            .map_err(|_| format!("'{}' is not a number", raw)),
+        EnvKind::Str => Ok(toml::Value::String(raw.to_string())),
+    }
+}
+
+/// Operating system names that can key an override table, as in `[general.linux]`
+const OS_SECTIONS: &[&str] = &["linux", "darwin", "macos", "windows", "freebsd", "openbsd", "netbsd"];
+
+/// Merge each section's table for `os` (as `std::env::consts::OS` names it) into
+/// the section, and drop the tables for other systems
+///
+/// macOS answers to both "darwin" and "macos".
+fn apply_os_overrides(table: &mut toml::Table, os: &str) {
+    for (_, section) in table.iter_mut() {
+        let toml::Value::Table(section) = section else {
+            continue;
+        };
+        for name in OS_SECTIONS {
+            if !section.get(*name).is_some_and(toml::Value::is_table) {
+                continue;
+            }
+            let Some(toml::Value::Table(overrides)) = section.remove(*name) else {
+                continue;
+            };
+            if *name == os || (os == "macos" && *name == "darwin") {
+                merge_tables(section, overrides);
+            }
+        }
+    }
+}
+
+/// Merge `over` into `base`: tables merge key by key, arrays of tables (like
+/// `[[autotag]]`) are appended, and anything else is replaced
+fn merge_tables(base: &mut toml::Table, over: toml::Table) {
+    for (key, value) in over {
+        match (base.get_mut(&key), value) {
+            (Some(toml::Value::Table(base_table)), toml::Value::Table(table)) => merge_tables(base_table, table),
+            (Some(toml::Value::Array(base_items)), toml::Value::Array(items))
+                if items.iter().chain(base_items.iter()).all(toml::Value::is_table) =>
+            {
+                base_items.extend(items)
+            }
+            (_, value) => {
+                base.insert(key, value);
+            }
+        }
+    }
+}
+
+/// Get the system-wide aliases file path:
+/// 1. $GOTO_SYSTEM_ALIASES environment variable
+/// 2. /etc/goto/aliases.toml
+pub fn system_aliases_path() -> PathBuf {
+    match std::env::var("GOTO_SYSTEM_ALIASES") {
+        Ok(path) if !path.is_empty() => PathBuf::from(path),
+        _ => PathBuf::from("/etc/goto/aliases.toml"),
+    }
+}
+
 /// Expand ~, environment variables, and convert to absolute path
 pub fn expand_path(path: &str) -> Result<PathBuf, ConfigError> {
     let expanded = if path.starts_with('~') {
@@ -296,6 +974,71 @@ pub fn expand_path(path: &str) -> Result<PathBuf, ConfigError> {
     Ok(std::fs::canonicalize(&expanded).unwrap_or(expanded))
 }
 
+/// Rewrite a path under the home directory as `~/...` (inverse of `expand_path`)
+pub fn collapse_home(path: &str) -> String {
+    let home = match dirs::home_dir() {
+        Some(home) => home,
+        None => return path.to_string(),
+    };
+
+    match Path::new(path).strip_prefix(&home) {
+        Ok(rest) if rest.as_os_str().is_empty() => "~".to_string(),
+        Ok(rest) => format!("~/{}", rest.display()),
+        Err(_) => path.to_string(),
+    }
+}
+
+/// Get the current machine's hostname:
+/// 1. $GOTO_HOSTNAME environment variable
+/// 2. /etc/hostname
+/// 3. output of `hostname`
+///
+/// The system lookup runs once per process; every alias resolved after that
+/// reuses it.
+pub fn hostname() -> Option<String> {
+    if let Ok(name) = std::env::var("GOTO_HOSTNAME") {
+        if !name.is_empty() {
+            return Some(name);
+        }
+    }
+
+    static SYSTEM: std::sync::OnceLock<Option<String>> = std::sync::OnceLock::new();
+    SYSTEM.get_or_init(system_hostname).clone()
+}
+
+fn system_hostname() -> Option<String> {
+    let name = fs::read_to_string("/etc/hostname").ok().or_else(|| {
+        std::process::Command::new("hostname")
+            .output()
+            .ok()
+            .filter(|out| out.status.success())
+            .map(|out| String::from_utf8_lossy(&out.stdout).into_owned())
+    })?;
+
+    let name = name.trim();
+    if name.is_empty() {
+        None
+    } else {
+        Some(name.to_string())
+    }
+}
+
+/// Whether `name` can be a context: a namespace name without a colon
+pub fn valid_context_name(name: &str) -> bool {
+    !name.contains(':') && crate::alias::validate_alias(name).is_ok()
+}
+
+/// The context selected by `$GOTO_CONTEXT` (e.g. from a direnv `.envrc`)
+///
+/// Empty and invalid values are ignored, so a typo can't send bare names
+/// into a namespace that can't exist; `goto --context` points them out.
+pub fn env_context() -> Option<String> {
+    std::env::var("GOTO_CONTEXT")
+        .ok()
+        .map(|c| c.trim().to_string())
+        .filter(|c| valid_context_name(c))
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -359,7 +1102,7 @@ mod tests {
     #[test]
     fn test_default_user_config() {
         let user = UserConfig::default();
-        assert!((user.general.fuzzy_threshold - 0.3).abs() < f64::EPSILON);
+        assert!((user.general.fuzzy_threshold - 0.7).abs() < f64::EPSILON);
         assert_eq!(user.general.default_sort, "alpha");
         assert!(!user.display.show_stats);
         assert!(user.display.show_tags);
@@ -375,6 +1118,38 @@ mod tests {
         assert_eq!(expanded, home.join("test"));
     }
 
+    #[test]
+    fn test_system_aliases_path() {
+        with_env_vars(&[("GOTO_SYSTEM_ALIASES", None)], || {
+            assert_eq!(system_aliases_path(), PathBuf::from("/etc/goto/aliases.toml"));
+        });
+        with_env_vars(&[("GOTO_SYSTEM_ALIASES", Some("/opt/goto/aliases.toml"))], || {
+            assert_eq!(system_aliases_path(), PathBuf::from("/opt/goto/aliases.toml"));
+        });
+    }
+
+    #[test]
+    fn test_hostname_env_override() {
+        with_env_vars(&[("GOTO_HOSTNAME", Some("worklaptop"))], || {
+            assert_eq!(hostname().as_deref(), Some("worklaptop"));
+        });
+    }
+
+    #[test]
+    fn test_collapse_home() {
+        let home = dirs::home_dir().unwrap();
+        let home_str = home.to_string_lossy().to_string();
+
+        assert_eq!(collapse_home(&home_str), "~");
+        assert_eq!(collapse_home(&format!("{}/dev/goto", home_str)), "~/dev/goto");
+        assert_eq!(collapse_home("/opt/elsewhere"), "/opt/elsewhere");
+        // Sibling directories sharing the prefix are not under home
+        assert_eq!(
+            collapse_home(&format!("{}-other/x", home_str)),
+            format!("{}-other/x", home_str)
+        );
+    }
+
     #[test]
     fn test_expand_path_env_var() {
         with_env_vars(&[("TEST_EXPAND_VAR", Some("/tmp/test"))], || {
@@ -422,6 +1197,9 @@ fuzzy_threshold = 0.7
         let config = Config {
             database_path: temp_dir.path().to_path_buf(),
             stack_path: temp_dir.path().join("goto_stack"),
+            history_path: temp_dir.path().join("dir_history"),
+            context_path: temp_dir.path().join("context"),
+            listing_path: temp_dir.path().join("last_listing"),
             config_path: temp_dir.path().join("config.toml"),
             aliases_path: temp_dir.path().join("aliases.toml"),
             user: UserConfig::default(),
@@ -487,6 +1265,219 @@ show_tags = false
         );
     }
 
+    #[test]
+    fn test_config_load_with_include() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        let team_dir = temp_dir.path().join("team");
+        fs::create_dir(&team_dir).unwrap();
+        fs::write(
+            team_dir.join("shared.toml"),
+            "include = [\"base.toml\", \"../config.toml\"]\n\n[general]\ndefault_sort = \"usage\"\n\
+             check_timeout_ms = 500\n\n[[autotag]]\npattern = \"~/team/**\"\ntags = [\"team\"]\n",
+        )
+        .unwrap();
+        fs::write(team_dir.join("base.toml"), "[display]\nshow_stats = true\ntable_style = \"ascii\"\n").unwrap();
+        fs::write(
+            temp_dir.path().join("config.toml"),
+            "include = [\"team/shared.toml\"]\n\n[general]\ncheck_timeout_ms = 100\n\n\
+             [display]\ntable_style = \"minimal\"\n\n[[autotag]]\npattern = \"~/me/**\"\ntags = [\"me\"]\n",
+        )
+        .unwrap();
+
+        let config = Config::load_from(temp_dir.path().to_path_buf()).unwrap();
+        let user = &config.user;
+        // Included values fill in, the including file wins
+        assert_eq!(user.general.default_sort, "usage");
+        assert_eq!(user.general.check_timeout_ms, 100);
+        assert!(user.display.show_stats);
+        assert_eq!(user.display.table_style, "minimal");
+        // Auto-tag rules from every file apply
+        let patterns: Vec<&str> = user.autotag.iter().map(|r| r.pattern.as_str()).collect();
+        assert_eq!(patterns, vec!["~/team/**", "~/me/**"]);
+        // Nested includes are listed; the cycle back to config.toml is not followed
+        assert_eq!(user.include.len(), 2);
+        assert!(user.include[0].ends_with("shared.toml"));
+        assert!(user.include[1].ends_with("base.toml"));
+        assert!(config.format_config().contains("include = ["));
+    }
+
+    #[test]
+    fn test_register_config_refusal() {
+        let dir = tempfile::tempdir().unwrap();
+        let root = dir.path().to_string_lossy().to_string();
+        fs::create_dir_all(dir.path().join("work/api")).unwrap();
+        fs::create_dir_all(dir.path().join("scratch")).unwrap();
+
+        let rules = RegisterConfig {
+            allow: vec![format!("{}/work", root)],
+            deny: vec![format!("{}/work/secret*", root)],
+        };
+        assert_eq!(rules.refusal(&format!("{}/work/api", root)), None);
+        assert_eq!(
+            rules.refusal(&format!("{}/work/secrets", root)),
+            Some(format!("under denied '{}/work/secret*'", root))
+        );
+        assert_eq!(
+            rules.refusal(&format!("{}/scratch", root)),
+            Some(format!("outside the allowed {}/work", root))
+        );
+        assert_eq!(RegisterConfig::default().refusal("/tmp"), None);
+
+        // A link into a denied tree is refused too
+        #[cfg(unix)]
+        {
+            std::os::unix::fs::symlink(dir.path().join("work"), dir.path().join("link")).unwrap();
+            let rules = RegisterConfig {
+                allow: Vec::new(),
+                deny: vec![format!("{}/work", fs::canonicalize(&root).unwrap().display())],
+            };
+            assert!(rules.refusal(&format!("{}/link/api", root)).is_some());
+        }
+    }
+
+    #[test]
+    fn test_apply_os_overrides() {
+        let content = "[general]\ncheck_timeout_ms = 2000\n\n[general.darwin]\ncheck_timeout_ms = 5000\n\n\
+                       [general.linux]\ncheck_timeout_ms = 100\n\n[display.windows]\ntable_style = \"ascii\"\n";
+
+        let mut table: toml::Table = toml::from_str(content).unwrap();
+        apply_os_overrides(&mut table, "macos");
+        let user: UserConfig = toml::Value::Table(table).try_into().unwrap();
+        assert_eq!(user.general.check_timeout_ms, 5000);
+        assert_eq!(user.display.table_style, "unicode");
+
+        let mut table: toml::Table = toml::from_str(content).unwrap();
+        apply_os_overrides(&mut table, "windows");
+        assert!(table["general"].get("linux").is_none());
+        let user: UserConfig = toml::Value::Table(table).try_into().unwrap();
+        assert_eq!(user.general.check_timeout_ms, 2000);
+        assert_eq!(user.display.table_style, "ascii");
+    }
+
+    #[test]
+    fn test_config_goto_path() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        let base = temp_dir.path().join("config");
+        let personal = temp_dir.path().join("personal");
+        let team = temp_dir.path().join("team.toml");
+        fs::create_dir_all(&base).unwrap();
+        fs::create_dir_all(&personal).unwrap();
+        fs::write(personal.join("aliases.toml"), "").unwrap();
+        fs::write(&team, "").unwrap();
+
+        let goto_path = std::env::join_paths([
+            personal.clone(),
+            temp_dir.path().join("missing"),
+            team.clone(),
+        ])
+        .unwrap();
+        with_env_vars(&[("GOTO_PATH", Some(goto_path.to_str().unwrap()))], || {
+            let config = Config::load_from(base.clone()).unwrap();
+            assert_eq!(config.aliases_path, personal.join("aliases.toml"));
+            assert_eq!(config.database_layers().unwrap(), vec![team.clone()]);
+            assert!(config.format_config().contains("# from GOTO_PATH"));
+        });
+
+        // A missing first entry is still where changes go; later missing ones are skipped
+        let fresh = temp_dir.path().join("fresh");
+        let goto_path =
+            std::env::join_paths([fresh.clone(), temp_dir.path().join("missing.toml"), team.clone()]).unwrap();
+        with_env_vars(&[("GOTO_PATH", Some(goto_path.to_str().unwrap()))], || {
+            let config = Config::load_from(base.clone()).unwrap();
+            assert_eq!(config.aliases_path, fresh.join("aliases.toml"));
+            assert_eq!(config.database_layers().unwrap(), vec![team.clone()]);
+        });
+        let goto_path = std::env::join_paths([temp_dir.path().join("new.toml"), team.clone()]).unwrap();
+        with_env_vars(&[("GOTO_PATH", Some(goto_path.to_str().unwrap()))], || {
+            let config = Config::load_from(base.clone()).unwrap();
+            assert_eq!(config.aliases_path, temp_dir.path().join("new.toml"));
+        });
+
+        // Without GOTO_PATH, storage.databases applies; with neither, the usual file
+        fs::write(
+            base.join("config.toml"),
+            format!("[storage]\ndatabases = [{:?}]\n", team.to_str().unwrap()),
+        )
+        .unwrap();
+        with_env_vars(&[("GOTO_PATH", None)], || {
+            let config = Config::load_from(base.clone()).unwrap();
+            assert_eq!(config.aliases_path, team);
+            assert!(config.database_layers().unwrap().is_empty());
+        });
+        fs::remove_file(base.join("config.toml")).unwrap();
+        with_env_vars(&[("GOTO_PATH", None)], || {
+            let config = Config::load_from(base.clone()).unwrap();
+            assert_eq!(config.aliases_path, base.join("aliases.toml"));
+        });
+    }
+
+    #[test]
+    fn test_env_fuzzy_threshold_changes_matching() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        fs::write(temp_dir.path().join("config.toml"), "[general]\nfuzzy_threshold = 0.8\n").unwrap();
+
+        // "myprojet" scores 0.89: found with the file's 0.8, not with the override
+        let resolved = || {
+            let config = Config::load_from(temp_dir.path().to_path_buf()).unwrap();
+            let db = crate::database::Database::load(&config).unwrap();
+            crate::commands::navigate::resolve(&db, "myprojet").resolved
+        };
+        with_env_vars(&[("GOTO_FUZZY_THRESHOLD", None)], || {
+            let config = Config::load_from(temp_dir.path().to_path_buf()).unwrap();
+            let mut db = crate::database::Database::load(&config).unwrap();
+            db.insert(crate::alias::Alias::new("myproject", "/tmp").unwrap());
+            db.save().unwrap();
+            assert_eq!(resolved().as_deref(), Some("myproject"));
+        });
+        with_env_vars(&[("GOTO_FUZZY_THRESHOLD", Some("0.95"))], || {
+            assert_eq!(resolved(), None);
+        });
+    }
+
+    #[test]
+    fn test_config_env_overrides() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        fs::write(
+            temp_dir.path().join("config.toml"),
+            "[general]\nfuzzy_threshold = 0.6\n\n[display]\nshow_stats = false\n",
+        )
+        .unwrap();
+
+        with_env_vars(
+            &[
+                ("GOTO_FUZZY_THRESHOLD", Some("0.9")),
+                ("GOTO_SHOW_STATS", Some("yes")),
+                ("GOTO_STACK_DEDUPE", Some("1")),
+                ("GOTO_DEFAULT_SORT", Some("")),
+            ],
+            || {
+                let config = Config::load_from(temp_dir.path().to_path_buf()).unwrap();
+                assert_eq!(config.user.general.fuzzy_threshold, 0.9);
+                assert!(config.user.display.show_stats);
+                assert!(config.user.stack.dedupe);
+                assert_eq!(config.user.general.default_sort, "alpha");
+                assert!(config.format_config().contains(
+                    "Environment overrides: GOTO_FUZZY_THRESHOLD, GOTO_SHOW_STATS, GOTO_STACK_DEDUPE\n"
+                ));
+            },
+        );
+
+        with_env_vars(&[("GOTO_CHECK_TIMEOUT_MS", Some("soon"))], || {
+            let err = Config::load_from(temp_dir.path().to_path_buf()).unwrap_err();
+            assert!(matches!(err, ConfigError::Env(..)), "{}", err);
+            assert!(err.to_string().contains("GOTO_CHECK_TIMEOUT_MS"));
+        });
+    }
+
+    #[test]
+    fn test_config_load_with_missing_include() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        fs::write(temp_dir.path().join("config.toml"), "include = [\"nope.toml\"]\n").unwrap();
+        let err = Config::load_from(temp_dir.path().to_path_buf()).unwrap_err();
+        assert!(matches!(err, ConfigError::Include(..)), "{}", err);
+        assert!(err.to_string().contains("nope.toml"));
+    }
+
     #[test]
     fn test_ensure_dirs_creates_directory() {
         let temp_dir = tempfile::tempdir().unwrap();
@@ -495,6 +1486,9 @@ show_tags = false
         let config = Config {
             database_path: nested_path.clone(),
             stack_path: nested_path.join("goto_stack"),
+            history_path: nested_path.join("dir_history"),
+            context_path: nested_path.join("context"),
+            listing_path: nested_path.join("last_listing"),
             config_path: nested_path.join("config.toml"),
             aliases_path: nested_path.join("aliases.toml"),
             user: UserConfig::default(),
@@ -513,6 +1507,9 @@ show_tags = false
         let config = Config {
             database_path: temp_dir.path().to_path_buf(),
             stack_path: temp_dir.path().join("goto_stack"),
+            history_path: temp_dir.path().join("dir_history"),
+            context_path: temp_dir.path().join("context"),
+            listing_path: temp_dir.path().join("last_listing"),
             config_path: config_path.clone(),
             aliases_path: temp_dir.path().join("aliases.toml"),
             user: UserConfig::default(),
@@ -542,6 +1539,9 @@ show_tags = false
         let config = Config {
             database_path: temp_dir.path().to_path_buf(),
             stack_path: temp_dir.path().join("goto_stack"),
+            history_path: temp_dir.path().join("dir_history"),
+            context_path: temp_dir.path().join("context"),
+            listing_path: temp_dir.path().join("last_listing"),
             config_path: config_path.clone(),
             aliases_path: temp_dir.path().join("aliases.toml"),
             user: UserConfig::default(),
@@ -564,6 +1564,9 @@ show_tags = false
         let config = Config {
             database_path: nested_dir.clone(),
             stack_path: nested_dir.join("goto_stack"),
+            history_path: nested_dir.join("dir_history"),
+            context_path: nested_dir.join("context"),
+            listing_path: nested_dir.join("last_listing"),
             config_path: config_path.clone(),
             aliases_path: nested_dir.join("aliases.toml"),
             user: UserConfig::default(),
@@ -613,7 +1616,7 @@ show_tags = false
                 let config = Config::load().unwrap();
 
                 // Should use defaults
-                assert!((config.user.general.fuzzy_threshold - 0.3).abs() < f64::EPSILON);
+                assert!((config.user.general.fuzzy_threshold - 0.7).abs() < f64::EPSILON);
                 assert_eq!(config.user.general.default_sort, "alpha");
                 assert!(!config.user.display.show_stats);
                 assert!(config.user.display.show_tags);
@@ -650,6 +1653,9 @@ show_stats = true
         let config = Config {
             database_path: temp_dir.path().to_path_buf(),
             stack_path: temp_dir.path().join("goto_stack"),
+            history_path: temp_dir.path().join("dir_history"),
+            context_path: temp_dir.path().join("context"),
+            listing_path: temp_dir.path().join("last_listing"),
             config_path: config_path.clone(),
             aliases_path: temp_dir.path().join("aliases.toml"),
             user: UserConfig::default(),
@@ -668,6 +1674,9 @@ show_stats = true
         let config = Config {
             database_path: temp_dir.path().to_path_buf(),
             stack_path: temp_dir.path().join("goto_stack"),
+            history_path: temp_dir.path().join("dir_history"),
+            context_path: temp_dir.path().join("context"),
+            listing_path: temp_dir.path().join("last_listing"),
             config_path: temp_dir.path().join("config.toml"),
             aliases_path: temp_dir.path().join("aliases.toml"),
             user: UserConfig::default(),
@@ -683,10 +1692,14 @@ show_stats = true
 [prune]
 auto_check = false
 check_interval_hours = 48
+warn_on_navigate = true
+cleanup_reminder_days = 30
 "#;
         let config: UserConfig = toml::from_str(toml_str).unwrap();
         assert!(!config.prune.auto_check);
         assert_eq!(config.prune.check_interval_hours, 48);
+        assert!(config.prune.warn_on_navigate);
+        assert_eq!(config.prune.cleanup_reminder_days, 30);
     }
 
     #[test]
@@ -699,6 +1712,8 @@ fuzzy_threshold = 0.5
         let config: UserConfig = toml::from_str(toml_str).unwrap();
         assert!(config.prune.auto_check);
         assert_eq!(config.prune.check_interval_hours, 24);
+        assert!(!config.prune.warn_on_navigate);
+        assert_eq!(config.prune.cleanup_reminder_days, 0);
     }
 
     #[test]
@@ -709,6 +1724,9 @@ fuzzy_threshold = 0.5
         let config = Config {
             database_path: temp_dir.path().to_path_buf(),
             stack_path: temp_dir.path().join("goto_stack"),
+            history_path: temp_dir.path().join("dir_history"),
+            context_path: temp_dir.path().join("context"),
+            listing_path: temp_dir.path().join("last_listing"),
             config_path: config_path.clone(),
             aliases_path: temp_dir.path().join("aliases.toml"),
             user: UserConfig::default(),
@@ -727,6 +1745,9 @@ fuzzy_threshold = 0.5
         let config = Config {
             database_path: temp_dir.path().to_path_buf(),
             stack_path: temp_dir.path().join("goto_stack"),
+            history_path: temp_dir.path().join("dir_history"),
+            context_path: temp_dir.path().join("context"),
+            listing_path: temp_dir.path().join("last_listing"),
             config_path: temp_dir.path().join("config.toml"),
             aliases_path: temp_dir.path().join("aliases.toml"),
             user: UserConfig::default(),
@@ -736,4 +1757,86 @@ fuzzy_threshold = 0.5
         assert!(formatted.contains("auto_check = true"));
         assert!(formatted.contains("check_interval_hours = 24"));
     }
+
+    #[test]
+    fn test_path_matcher_prefix() {
+        let m = path_matcher("/home/user/code/").unwrap();
+        assert!(m.is_match("/home/user/code"));
+        assert!(m.is_match("/home/user/code/goto"));
+        assert!(!m.is_match("/home/user/codex"));
+        assert!(!m.is_match("/home/user"));
+    }
+
+    #[test]
+    fn test_path_matcher_glob() {
+        let m = path_matcher("/home/user/code/**").unwrap();
+        assert!(m.is_match("/home/user/code"));
+        assert!(m.is_match("/home/user/code/a/b/c"));
+        assert!(!m.is_match("/home/user/codex"));
+
+        let m = path_matcher("/srv/*/logs").unwrap();
+        assert!(m.is_match("/srv/web/logs"));
+        assert!(!m.is_match("/srv/web/app/logs"));
+
+        let m = path_matcher("/srv/**/logs").unwrap();
+        assert!(m.is_match("/srv/web/app/logs"));
+
+        let m = path_matcher("/tmp/app?").unwrap();
+        assert!(m.is_match("/tmp/app1"));
+        assert!(!m.is_match("/tmp/app12"));
+    }
+
+    #[test]
+    fn test_path_matcher_expands_home() {
+        let home = dirs::home_dir().unwrap();
+        let m = path_matcher("~/code/**").unwrap();
+        assert!(m.is_match(&format!("{}/code/goto", home.display())));
+    }
+
+
+    #[test]
+    fn test_autotag_rules() {
+        let user: UserConfig = toml::from_str(
+            r#"
+[[autotag]]
+pattern = "/srv/work/**"
+tags = ["work"]
+
+[[autotag]]
+pattern = "/srv/work/clients"
+tags = ["Clients", "work"]
+"#,
+        )
+        .unwrap();
+        assert_eq!(user.autotag.len(), 2);
+
+        let tags = autotags_for(&user.autotag, "/srv/work/clients/acme").unwrap();
+        assert_eq!(tags, vec!["work", "clients"]);
+        assert!(autotags_for(&user.autotag, "/srv/home").unwrap().is_empty());
+    }
+
+    #[test]
+    fn test_format_config_autotag_round_trips() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        let mut user = UserConfig::default();
+        user.autotag.push(AutoTagRule {
+            pattern: r#"C:\work\"quoted"\**"#.to_string(),
+            tags: vec!["work".to_string()],
+        });
+        let config = Config {
+            database_path: temp_dir.path().to_path_buf(),
+            stack_path: temp_dir.path().join("goto_stack"),
+            history_path: temp_dir.path().join("dir_history"),
+            context_path: temp_dir.path().join("context"),
+            listing_path: temp_dir.path().join("last_listing"),
+            config_path: temp_dir.path().join("config.toml"),
+            aliases_path: temp_dir.path().join("aliases.toml"),
+            user,
+        };
+
+        let printed = config.format_config();
+        let section = &printed[printed.find("[[autotag]]").unwrap()..];
+        let parsed: UserConfig = toml::from_str(section).unwrap();
+        assert_eq!(parsed.autotag[0].pattern, r#"C:\work\"quoted"\**"#);
+    }
 }
@@ -0,0 +1,235 @@
+//! Optional at-rest encryption for the alias database (NaCl secretbox)
+//!
+//! The secretbox key is derived from the passphrase with scrypt and a random
+//! salt kept in each file's header, so the same passphrase gives a different
+//! key for every database and guessing it offline is expensive.
+
+use crypto_secretbox::aead::rand_core::RngCore;
+use crypto_secretbox::aead::{Aead, AeadCore, KeyInit, OsRng};
+use crypto_secretbox::{Key, Nonce, XSalsa20Poly1305};
+use std::process::Command;
+use std::sync::Mutex;
+use thiserror::Error;
+
+/// Header identifying an encrypted database file, followed by the scrypt
+/// cost, the salt and the nonce
+const MAGIC: &[u8] = b"GOTOENC1";
+
+/// XSalsa20 nonce length in bytes
+const NONCE_LEN: usize = 24;
+
+/// scrypt salt length in bytes
+const SALT_LEN: usize = 16;
+
+/// scrypt cost (log2 of N) for new files: about 32 MiB and a tenth of a
+/// second per derivation. Kept in the header, so it can be raised later.
+const LOG_N: u8 = if cfg!(test) { 10 } else { 15 };
+
+/// Highest scrypt cost a file may ask for (1 GiB); anything above is treated
+/// as corrupt rather than derived, which would take minutes and gigabytes
+const MAX_LOG_N: u8 = 20;
+
+/// Keyring service name used to look up the database key
+const KEYRING_SERVICE: &str = "goto";
+
+/// The passphrase database keys are derived from
+///
+/// The key itself depends on the salt of the file it opens. The last one
+/// derived is kept, so saving a database that was just loaded doesn't pay
+/// for the derivation again.
+pub struct DatabaseKey {
+    secret: String,
+    derived: Mutex<Option<(u8, [u8; SALT_LEN], Key)>>,
+}
+
+impl Clone for DatabaseKey {
+    fn clone(&self) -> Self {
+        Self {
+            secret: self.secret.clone(),
+            derived: Mutex::new(self.derived.lock().unwrap().clone()),
+        }
+    }
+}
+
+impl std::fmt::Debug for DatabaseKey {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        f.write_str("DatabaseKey(..)")
+    }
+}
+
+impl DatabaseKey {
+    /// The key for a file with this cost and salt
+    fn for_salt(&self, log_n: u8, salt: &[u8; SALT_LEN]) -> Result<Key, CryptoError> {
+        let mut derived = self.derived.lock().unwrap();
+        if let Some((n, s, key)) = derived.as_ref() {
+            if *n == log_n && s == salt {
+                return Ok(*key);
+            }
+        }
+        let params = scrypt::Params::new(log_n, 8, 1, 32).map_err(|_| CryptoError::Decrypt)?;
+        let mut key = Key::default();
+        scrypt::scrypt(self.secret.as_bytes(), salt, &params, &mut key).map_err(|_| CryptoError::Decrypt)?;
+        *derived = Some((log_n, *salt, key));
+        Ok(key)
+    }
+
+    /// The cost and salt of the last key derived, or fresh ones for a new file
+    fn current_salt(&self) -> (u8, [u8; SALT_LEN]) {
+        if let Some((log_n, salt, _)) = self.derived.lock().unwrap().as_ref() {
+            return (*log_n, *salt);
+        }
+        let mut salt = [0u8; SALT_LEN];
+        OsRng.fill_bytes(&mut salt);
+        (LOG_N, salt)
+    }
+}
+
+/// Errors that can occur during encryption operations
+#[derive(Error, Debug)]
+pub enum CryptoError {
+    #[error("database is encrypted but no key is available (set GOTO_KEY or add a '{KEYRING_SERVICE}' keyring entry)")]
+    NoKey,
+
+    #[error("failed to decrypt database: wrong key or corrupted file")]
+    Decrypt,
+
+    #[error("failed to encrypt database")]
+    Encrypt,
+}
+
+/// Check whether raw file content is an encrypted database
+pub fn is_encrypted(data: &[u8]) -> bool {
+    data.starts_with(MAGIC)
+}
+
+/// The key for a passphrase; it is derived per file when first used
+pub fn derive_key(secret: &str) -> DatabaseKey {
+    DatabaseKey {
+        secret: secret.to_string(),
+        derived: Mutex::new(None),
+    }
+}
+
+/// Look up the database key:
+/// 1. $GOTO_KEY environment variable
+/// 2. system keyring (macOS `security`, or `secret-tool` elsewhere)
+pub fn load_key() -> Option<DatabaseKey> {
+    if let Ok(secret) = std::env::var("GOTO_KEY") {
+        if !secret.is_empty() {
+            return Some(derive_key(&secret));
+        }
+    }
+
+    keyring_secret().map(|secret| derive_key(&secret))
+}
+
+/// Read the key secret from the system keyring, if one is stored
+fn keyring_secret() -> Option<String> {
+    let output = if cfg!(target_os = "macos") {
+        Command::new("security")
+            .args(["find-generic-password", "-s", KEYRING_SERVICE, "-a", "database", "-w"])
+            .output()
+    } else {
+        Command::new("secret-tool")
+            .args(["lookup", "service", KEYRING_SERVICE, "account", "database"])
+            .output()
+    }
+    .ok()?;
+
+    if !output.status.success() {
+        return None;
+    }
+
+    let secret = String::from_utf8_lossy(&output.stdout).trim().to_string();
+    if secret.is_empty() {
+        None
+    } else {
+        Some(secret)
+    }
+}
+
+/// Encrypt plaintext into the on-disk format: header, cost, salt, nonce, ciphertext
+///
+/// A file that was loaded keeps its salt; a new one gets a random salt.
+pub fn encrypt(plaintext: &[u8], key: &DatabaseKey) -> Result<Vec<u8>, CryptoError> {
+    let (log_n, salt) = key.current_salt();
+    let cipher = XSalsa20Poly1305::new(&key.for_salt(log_n, &salt).map_err(|_| CryptoError::Encrypt)?);
+    let nonce = XSalsa20Poly1305::generate_nonce(&mut OsRng);
+    let ciphertext = cipher.encrypt(&nonce, plaintext).map_err(|_| CryptoError::Encrypt)?;
+
+    let mut out = Vec::with_capacity(MAGIC.len() + 1 + SALT_LEN + NONCE_LEN + ciphertext.len());
+    out.extend_from_slice(MAGIC);
+    out.push(log_n);
+    out.extend_from_slice(&salt);
+    out.extend_from_slice(&nonce);
+    out.extend_from_slice(&ciphertext);
+    Ok(out)
+}
+
+/// Decrypt data produced by `encrypt`
+pub fn decrypt(data: &[u8], key: &DatabaseKey) -> Result<Vec<u8>, CryptoError> {
+    if !is_encrypted(data) || data.len() < MAGIC.len() + 1 + SALT_LEN + NONCE_LEN {
+        return Err(CryptoError::Decrypt);
+    }
+    let log_n = data[MAGIC.len()];
+    if log_n > MAX_LOG_N {
+        return Err(CryptoError::Decrypt);
+    }
+    let (salt, rest) = data[MAGIC.len() + 1..].split_at(SALT_LEN);
+    let secretbox_key = key.for_salt(log_n, salt.try_into().expect("salt length"))?;
+
+    let (nonce, ciphertext) = rest.split_at(NONCE_LEN);
+    let cipher = XSalsa20Poly1305::new(&secretbox_key);
+    cipher
+        .decrypt(Nonce::from_slice(nonce), ciphertext)
+        .map_err(|_| CryptoError::Decrypt)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_round_trip() {
+        let key = derive_key("correct horse");
+        let encrypted = encrypt(b"[[aliases]]", &key).unwrap();
+
+        assert!(is_encrypted(&encrypted));
+        assert!(!encrypted.windows(9).any(|w| w == b"[[aliases"));
+        assert_eq!(decrypt(&encrypted, &key).unwrap(), b"[[aliases]]");
+    }
+
+    #[test]
+    fn test_wrong_key() {
+        let encrypted = encrypt(b"secret", &derive_key("one")).unwrap();
+        let result = decrypt(&encrypted, &derive_key("two"));
+        assert!(matches!(result, Err(CryptoError::Decrypt)));
+    }
+
+    #[test]
+    fn test_salted_per_file() {
+        let one = encrypt(b"same", &derive_key("pass")).unwrap();
+        let two = encrypt(b"same", &derive_key("pass")).unwrap();
+        let salt = |data: &[u8]| data[MAGIC.len() + 1..MAGIC.len() + 1 + SALT_LEN].to_vec();
+        assert_ne!(salt(&one), salt(&two));
+        assert_eq!(one[MAGIC.len()], LOG_N);
+
+        // Decrypting adopts the file's salt, so re-encrypting keeps it
+        let key = derive_key("pass");
+        assert_eq!(decrypt(&two, &key).unwrap(), b"same");
+        assert_eq!(salt(&encrypt(b"again", &key).unwrap()), salt(&two));
+    }
+
+    #[test]
+    fn test_excessive_cost_rejected() {
+        let mut data = encrypt(b"secret", &derive_key("pass")).unwrap();
+        data[MAGIC.len()] = 40;
+        assert!(matches!(decrypt(&data, &derive_key("pass")), Err(CryptoError::Decrypt)));
+    }
+
+    #[test]
+    fn test_plaintext_not_encrypted() {
+        assert!(!is_encrypted(b"[[aliases]]\nname = \"x\""));
+        assert!(matches!(decrypt(b"plain", &derive_key("k")), Err(CryptoError::Decrypt)));
+    }
+}
@@ -2,15 +2,27 @@
 
 use chrono::Utc;
 use serde::{Deserialize, Serialize};
-use std::collections::HashMap;
+use sha2::{Digest, Sha256};
+use std::borrow::Cow;
+use std::collections::{BTreeMap, HashMap};
 use std::fs;
 use std::io;
 use std::path::{Path, PathBuf};
+use std::process;
+use std::sync::atomic::{AtomicUsize, Ordering};
+use std::time::Instant;
 use thiserror::Error;
 
-use crate::alias::{Alias, AliasError};
-use crate::config::{Config, ConfigError};
-use crate::fuzzy;
+use crate::alias::{normalize_name, validate_meta_key, Alias, AliasError, AliasKind};
+use crate::config::{Config, ConfigError, RegisterConfig};
+use crate::crypto::{self, CryptoError, DatabaseKey};
+use crate::deadline::{Deadline, Interrupted};
+use crate::events::{self, Event};
+use crate::filelock;
+use crate::fuzzy::{self, FuzzyPolicy};
+use crate::hooks::HooksConfig;
+use crate::pathcheck::PathCheck;
+use crate::profile;
 
 /// Errors that can occur during database operations
 #[derive(Error, Debug)]
@@ -29,15 +41,41 @@ pub enum DatabaseError {
 
     #[error(transparent)]
     Alias(#[from] AliasError),
+
+    #[error(transparent)]
+    Crypto(#[from] CryptoError),
+
+    #[error("timed out after {1:.1}s on {0} (slow filesystem? see io_timeout_ms under [storage])")]
+    IoTimedOut(String, f64),
+
+    #[error("access to {0} cancelled")]
+    Cancelled(String),
+
+    #[error("alias '{0}' is read-only (defined in the system aliases file, another GOTO_PATH database or an included config)")]
+    ReadOnly(String),
 }
 
+/// Version of the on-disk database layout, shown by `goto --version --json`
+///
+/// 1 was the original line-based text file, which is still migrated on load.
+pub const SCHEMA_VERSION: u32 = 2;
+
 /// Database file format - array-based structure
 #[derive(Debug, Serialize, Deserialize, Default)]
 struct DatabaseFile {
+    /// SHA-256 of the aliases, written on save so `goto --fsck` can spot outside edits or damage
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    checksum: Option<String>,
     #[serde(default)]
     aliases: Vec<Alias>,
 }
 
+/// What the checksum covers: the aliases serialized on their own
+#[derive(Serialize)]
+struct ChecksumBody<'a> {
+    aliases: &'a [Alias],
+}
+
 /// In-memory database with file persistence
 #[derive(Debug)]
 pub struct Database {
@@ -47,21 +85,116 @@ pub struct Database {
     text_path: PathBuf,
     /// Aliases stored by name for fast lookup
     aliases: HashMap<String, Alias>,
+    /// Read-only system-wide aliases, shadowed by user aliases of the same name
+    system: HashMap<String, Alias>,
     /// Whether the database has unsaved changes
     dirty: bool,
+    /// Whether any unsaved change is more than usage counters or cached
+    /// project types, and so worth rotating a backup for
+    backup_due: bool,
+    /// Whether the last save was given up on; its write may still land, so
+    /// dropping the database doesn't try again behind it
+    save_abandoned: bool,
+    /// Encryption key; when set the database is stored encrypted
+    key: Option<DatabaseKey>,
+    /// Namespace tried for names given without one
+    default_namespace: Option<String>,
+    /// Number of rotating backups kept next to the database file
+    backups: usize,
+    /// Give up reading or writing the database file after this many milliseconds (0: wait)
+    io_timeout_ms: u64,
+    /// Whether `record_usage` updates use counts and timestamps
+    track_usage: bool,
+    /// How navigation checks a target directory before jumping
+    path_check: PathCheck,
+    /// Commands run before navigation that can cancel it
+    hooks: HooksConfig,
+    /// Whether and how eagerly navigation offers similar names
+    fuzzy: FuzzyPolicy,
+    /// Directories that may be registered
+    register_rules: RegisterConfig,
+    /// While set, `save` (and the save on drop) writes nothing; see `begin_batch`
+    batch: bool,
+    /// Navigation log that recorded uses are appended to on save, if any
+    event_log: Option<PathBuf>,
+    /// Recorded uses not yet appended to `event_log`
+    pending_events: Vec<Event>,
 }
 
 impl Database {
     /// Load the database from the configured path
+    ///
+    /// Reading the file gives up after `io_timeout_ms` under `[storage]`.
     pub fn load(config: &Config) -> Result<Self, DatabaseError> {
+        Self::load_within(config, &Deadline::from_ms(config.user.storage.io_timeout_ms))
+    }
+
+    /// Like [`Database::load`], giving up reading the file at `deadline` or when it is cancelled
+    pub fn load_within(config: &Config, deadline: &Deadline) -> Result<Self, DatabaseError> {
+        let _phase = profile::phase("database load");
         config.ensure_dirs()?;
-        Self::load_from_path(&config.aliases_path)
+
+        let key = if config.user.storage.encrypt {
+            Some(crypto::load_key().ok_or(CryptoError::NoKey)?)
+        } else {
+            None
+        };
+
+        let mut db = Self::load_from_path_within(&config.aliases_path, key, deadline)?;
+        db.load_system(&crate::config::system_aliases_path())?;
+        db.load_layers(&config.database_layers()?)?;
+        for file in &config.user.include {
+            db.load_shared(Path::new(file))?;
+        }
+        db.set_default_namespace(&config.effective_namespace());
+        db.set_backups(config.user.storage.backups);
+        db.set_io_timeout_ms(config.user.storage.io_timeout_ms);
+        db.set_track_usage(config.user.general.track_usage);
+        // An encrypted database shouldn't leak alias names into a plain-text log
+        if db.key.is_none() {
+            db.set_event_log(config.database_path.join(events::FILE_NAME));
+        }
+        db.set_path_check(PathCheck::with_timeout_ms(config.user.general.check_timeout_ms));
+        db.set_hooks(config.user.hooks.clone());
+        db.set_register_rules(config.user.register.clone());
+        db.set_fuzzy(FuzzyPolicy {
+            threshold: config.user.general.fuzzy_threshold,
+            max_suggestions: config.user.general.max_suggestions,
+            auto_accept: config
+                .user
+                .general
+                .auto_accept
+                .then_some(config.user.general.auto_accept_threshold),
+            confirm: config
+                .user
+                .general
+                .confirm_typos
+                .then_some(config.user.general.auto_accept_threshold),
+            ..Default::default()
+        });
+        Ok(db)
     }
 
     /// Load the database from a specific path
     /// The path should be the base path (e.g., ~/.config/goto/aliases)
     /// The TOML file will be at path + ".toml"
     pub fn load_from_path(path: &Path) -> Result<Self, DatabaseError> {
+        Self::load_from_path_with_key(path, None)
+    }
+
+    /// Load the database from a specific path, storing it encrypted with `key`
+    ///
+    /// An existing file in the other mode is converted on the next save.
+    pub fn load_from_path_with_key(path: &Path, key: Option<DatabaseKey>) -> Result<Self, DatabaseError> {
+        Self::load_from_path_within(path, key, &Deadline::default())
+    }
+
+    /// Like [`Database::load_from_path_with_key`], giving up reading the file at `deadline`
+    pub fn load_from_path_within(
+        path: &Path,
+        key: Option<DatabaseKey>,
+        deadline: &Deadline,
+    ) -> Result<Self, DatabaseError> {
         let toml_path = path.with_extension("toml");
         let text_path = path.to_path_buf();
 
@@ -69,44 +202,258 @@ impl Database {
             toml_path,
             text_path,
             aliases: HashMap::new(),
+            system: HashMap::new(),
             dirty: false,
+            save_abandoned: false,
+            backup_due: false,
+            key,
+            default_namespace: None,
+            backups: 0,
+            io_timeout_ms: 0,
+            track_usage: true,
+            path_check: PathCheck::default(),
+            hooks: HooksConfig::default(),
+            fuzzy: FuzzyPolicy::default(),
+            register_rules: RegisterConfig::default(),
+            batch: false,
+            event_log: None,
+            pending_events: Vec::new(),
         };
 
-        db.load_entries()?;
+        db.load_entries(deadline)?;
         Ok(db)
     }
 
     /// Load entries from storage (TOML or migrate from text)
-    fn load_entries(&mut self) -> Result<(), DatabaseError> {
-        // Check if TOML file exists
-        if self.toml_path.exists() {
-            self.load_toml()?;
-            return Ok(());
+    fn load_entries(&mut self, deadline: &Deadline) -> Result<(), DatabaseError> {
+        // Even looking for the files can hang on a dead mount, so that's bounded too
+        let (toml_path, text_path) = (self.toml_path.clone(), self.text_path.clone());
+        let stored = io_within(&self.toml_path, deadline, move || match fs::read(&toml_path) {
+            Ok(data) => Ok(Stored::Toml(data)),
+            Err(e) if e.kind() == io::ErrorKind::NotFound && text_path.exists() => Ok(Stored::Text),
+            Err(e) if e.kind() == io::ErrorKind::NotFound => Ok(Stored::Nothing),
+            Err(e) => Err(e),
+        })?;
+
+        match stored {
+            Stored::Toml(data) => self.load_toml(data),
+            // Migrate the old text file
+            Stored::Text => self.migrate_from_text_format(),
+            // No database exists, start empty
+            Stored::Nothing => Ok(()),
         }
+    }
 
-        // Check if old text file exists and migrate
-        if self.text_path.exists() {
-            self.migrate_from_text_format()?;
-            return Ok(());
+    /// Load aliases from the contents of the TOML file
+    fn load_toml(&mut self, mut data: Vec<u8>) -> Result<(), DatabaseError> {
+        if crypto::is_encrypted(&data) {
+            data = match &self.key {
+                Some(key) => crypto::decrypt(&data, key)?,
+                // Encryption was turned off: decrypt and rewrite as plaintext
+                None => {
+                    self.dirty = true;
+                    crypto::decrypt(&data, &crypto::load_key().ok_or(CryptoError::NoKey)?)?
+                }
+            };
+        } else if self.key.is_some() {
+            // Encryption was turned on: rewrite encrypted
+            self.dirty = true;
+        }
+
+        let content = String::from_utf8(data).map_err(|e| io::Error::new(io::ErrorKind::InvalidData, e))?;
+        let db_file: DatabaseFile = toml::from_str(&content)?;
+
+        self.aliases.clear();
+        for alias in db_file.aliases {
+            self.aliases.insert(alias.name.clone(), alias);
         }
 
-        // No database exists, start empty
         Ok(())
     }
 
-    /// Load aliases from TOML file
-    fn load_toml(&mut self) -> Result<(), DatabaseError> {
-        let content = fs::read_to_string(&self.toml_path)?;
+    /// Load read-only system-wide aliases merged beneath the user aliases
+    pub fn load_system(&mut self, path: &Path) -> Result<(), DatabaseError> {
+        if !path.exists() {
+            return Ok(());
+        }
+
+        let content = fs::read_to_string(path)?;
         let db_file: DatabaseFile = toml::from_str(&content)?;
 
-        self.aliases.clear();
+        self.system.clear();
         for alias in db_file.aliases {
-            self.aliases.insert(alias.name.clone(), alias);
+            self.system.insert(alias.name.clone(), alias);
+        }
+
+        Ok(())
+    }
+
+    /// Add the aliases of other databases (as `$GOTO_PATH` lists them) to the read-only aliases
+    ///
+    /// Earlier files win over later ones, and all of them over the system aliases file.
+    pub fn load_layers(&mut self, paths: &[PathBuf]) -> Result<(), DatabaseError> {
+        for path in paths.iter().rev() {
+            let content = fs::read_to_string(path)?;
+            let db_file: DatabaseFile = toml::from_str(&content)?;
+            for alias in db_file.aliases {
+                self.system.insert(alias.name.clone(), alias);
+            }
         }
+        Ok(())
+    }
 
+    /// Add the `[[aliases]]` of an included config file to the read-only aliases
+    ///
+    /// The system aliases file and earlier includes win when names clash.
+    pub fn load_shared(&mut self, path: &Path) -> Result<(), DatabaseError> {
+        let content = fs::read_to_string(path)?;
+        let db_file: DatabaseFile = toml::from_str(&content)?;
+        for alias in db_file.aliases {
+            self.system.entry(alias.name.clone()).or_insert(alias);
+        }
         Ok(())
     }
 
+    /// Check if an alias is read-only, from the system aliases file or an include (not shadowed by the user)
+    pub fn is_system(&self, name: &str) -> bool {
+        let name = &*lookup_key(name);
+        !self.aliases.contains_key(name) && self.system.contains_key(name)
+    }
+
+    /// Fail if an alias is a read-only system alias
+    pub fn check_writable(&self, name: &str) -> Result<(), DatabaseError> {
+        if self.is_system(name) {
+            return Err(DatabaseError::ReadOnly(name.to_string()));
+        }
+        Ok(())
+    }
+
+    /// Fail with `PathNotAllowed` if `[register]` forbids aliases to `path`
+    pub fn check_path_allowed(&self, path: &str) -> Result<(), DatabaseError> {
+        match self.register_rules.refusal(path) {
+            Some(reason) => Err(AliasError::PathNotAllowed(path.to_string(), reason).into()),
+            None => Ok(()),
+        }
+    }
+
+    /// Like [`Database::check_path_allowed`], for the alias's `path` and every
+    /// host-specific one: navigation may go to any of them
+    pub fn check_alias_allowed(&self, alias: &Alias) -> Result<(), DatabaseError> {
+        self.check_path_allowed(&alias.path)?;
+        alias.paths.values().try_for_each(|path| self.check_path_allowed(path))
+    }
+
+    /// Set which directories may be registered
+    pub fn set_register_rules(&mut self, rules: RegisterConfig) {
+        self.register_rules = rules;
+    }
+
+    /// Set the namespace tried for names given without one (empty disables it)
+    pub fn set_default_namespace(&mut self, namespace: &str) {
+        self.default_namespace = (!namespace.is_empty()).then(|| namespace.to_string());
+    }
+
+    /// Namespace tried for names given without one, if any
+    pub fn default_namespace(&self) -> Option<&str> {
+        self.default_namespace.as_deref()
+    }
+
+    /// Set how navigation checks a target directory
+    pub fn set_path_check(&mut self, check: PathCheck) {
+        self.path_check = check;
+    }
+
+    /// How navigation checks a target directory
+    pub fn path_check(&self) -> PathCheck {
+        self.path_check
+    }
+
+    /// Set whether and how eagerly navigation offers similar names
+    pub fn set_fuzzy(&mut self, fuzzy: FuzzyPolicy) {
+        self.fuzzy = fuzzy;
+    }
+
+    /// Whether and how eagerly navigation offers similar names
+    pub fn fuzzy(&self) -> FuzzyPolicy {
+        self.fuzzy
+    }
+
+    /// Set the pre-navigation hooks
+    pub fn set_hooks(&mut self, hooks: HooksConfig) {
+        self.hooks = hooks;
+    }
+
+    /// Pre-navigation hooks from the config
+    pub fn hooks(&self) -> &HooksConfig {
+        &self.hooks
+    }
+
+    /// Check an alias target before navigating, unless the alias has `skip_check` set
+    pub fn check_target(&self, alias: &Alias) -> Result<(), AliasError> {
+        if alias.skip_check {
+            return Ok(());
+        }
+        self.path_check.run_kind(&alias.resolved_path(), alias.kind)
+    }
+
+    /// Keep this many rotating backups on save (0 disables them)
+    pub fn set_backups(&mut self, count: usize) {
+        self.backups = count;
+    }
+
+    /// Give up reading or writing the database file after `ms` milliseconds (0 waits)
+    pub fn set_io_timeout_ms(&mut self, ms: u64) {
+        self.io_timeout_ms = ms;
+    }
+
+    /// Append recorded uses to this navigation log when saving (see [`crate::events`])
+    pub fn set_event_log(&mut self, path: PathBuf) {
+        self.event_log = Some(path);
+    }
+
+    /// Enable or disable usage tracking (`record_usage` becomes a no-op when off)
+    pub fn set_track_usage(&mut self, enabled: bool) {
+        self.track_usage = enabled;
+    }
+
+    /// Whether usage tracking is enabled
+    pub fn tracks_usage(&self) -> bool {
+        self.track_usage
+    }
+
+    /// Resolve a name as typed to the stored alias name
+    ///
+    /// An exact match wins; otherwise a name without a namespace is looked up in
+    /// the default namespace. Unknown names are returned unchanged.
+    pub fn resolve_name(&self, name: &str) -> String {
+        let name = &normalize_name(name);
+        if self.contains(name) || name.contains(':') {
+            return name.to_string();
+        }
+        match &self.default_namespace {
+            Some(ns) => {
+                let qualified = format!("{}:{}", ns, name);
+                if self.contains(&qualified) {
+                    qualified
+                } else {
+                    name.to_string()
+                }
+            }
+            None => name.to_string(),
+        }
+    }
+
+    /// Namespaces in use, sorted
+    pub fn namespaces(&self) -> Vec<String> {
+        let mut namespaces: Vec<String> = self
+            .all()
+            .filter_map(|a| a.namespace().map(String::from))
+            .collect();
+        namespaces.sort();
+        namespaces.dedup();
+        namespaces
+    }
+
     /// Migrate from old text format to TOML
     fn migrate_from_text_format(&mut self) -> Result<(), DatabaseError> {
         let content = fs::read_to_string(&self.text_path)?;
@@ -124,17 +471,24 @@ impl Database {
                 let alias = Alias {
                     name: parts[0].to_string(),
                     path: parts[1].to_string(),
+                    kind: AliasKind::Dir,
                     tags: Vec::new(),
+                    description: None,
                     use_count: 0,
                     last_used: None,
                     created_at: now,
+                    paths: BTreeMap::new(),
+                    meta: BTreeMap::new(),
+                    skip_check: false,
+                    project_type: None,
+                    expires_at: None,
                 };
                 self.aliases.insert(alias.name.clone(), alias);
             }
         }
 
         // Save as TOML
-        self.dirty = true;
+        self.mark_changed();
         self.save()?;
 
         // Backup old file
@@ -144,60 +498,118 @@ impl Database {
         Ok(())
     }
 
+    /// Hold back saves until `commit_batch`, so several commands write the file once
+    ///
+    /// If the batch is never committed (an error part-way), nothing is written.
+    pub fn begin_batch(&mut self) {
+        self.batch = true;
+    }
+
+    /// End a batch started with `begin_batch` and save everything it changed
+    pub fn commit_batch(&mut self) -> Result<(), DatabaseError> {
+        self.batch = false;
+        self.save()
+    }
+
     /// Save the database to disk
+    ///
+    /// Writing gives up after the configured `io_timeout_ms`. The file is
+    /// replaced in one step, so readers never see it half-written, but a write
+    /// stuck on a slow disk may still finish after goto has given up on it.
     pub fn save(&mut self) -> Result<(), DatabaseError> {
+        self.save_within(&Deadline::from_ms(self.io_timeout_ms))
+    }
+
+    /// Like [`Database::save`], giving up writing at `deadline` or when it is cancelled
+    pub fn save_within(&mut self, deadline: &Deadline) -> Result<(), DatabaseError> {
+        let _phase = profile::phase("save");
+        if self.batch {
+            return Ok(());
+        }
+        if let Some(log) = &self.event_log {
+            events::append(log, &self.pending_events)?;
+            self.pending_events.clear();
+        }
         if !self.dirty {
             return Ok(());
         }
 
-        // Collect aliases into a vector sorted by name for consistent output
-        let mut aliases: Vec<Alias> = self.aliases.values().cloned().collect();
-        aliases.sort_by(|a, b| a.name.cmp(&b.name));
-
-        let db_file = DatabaseFile { aliases };
-        let content = toml::to_string_pretty(&db_file)?;
-
-        // Ensure parent directory exists
-        if let Some(parent) = self.toml_path.parent() {
-            fs::create_dir_all(parent)?;
-        }
+        let mut file = self.canonical_file();
+        file.checksum = Some(checksum(&file.aliases)?);
+        let content = toml::to_string_pretty(&file)?;
+        let data = match &self.key {
+            Some(key) => crypto::encrypt(content.as_bytes(), key)?,
+            None => content.into_bytes(),
+        };
 
-        fs::write(&self.toml_path, content)?;
+        // Usage bumps alone would soon leave every backup a copy of the same aliases
+        let backups = if self.backup_due { self.backups } else { 0 };
+        let path = self.toml_path.clone();
+        let writer_deadline = deadline.clone();
+        let saved = io_within(&self.toml_path, deadline, move || {
+            // Also creates the parent directory; held against `--maintain` rotating at the same time
+            let _lock = filelock::lock(&path)?;
+            if writer_deadline.check().is_err() {
+                return Err(gave_up());
+            }
+            rotate_backups(&path, backups)?;
+            write_replacing(&path, &data, &writer_deadline)
+        });
+        self.save_abandoned = matches!(saved, Err(DatabaseError::IoTimedOut(..) | DatabaseError::Cancelled(_)));
+        saved?;
         self.dirty = false;
+        self.backup_due = false;
         Ok(())
     }
 
+    /// Like [`Database::check_target`], also giving up at `deadline` or when it is cancelled
+    pub fn check_target_within(&self, alias: &Alias, deadline: &Deadline) -> Result<(), AliasError> {
+        if alias.skip_check {
+            return Ok(());
+        }
+        self.path_check.run_within(&alias.resolved_path(), alias.kind, deadline)
+    }
+
     /// Get an alias by name
     pub fn get(&self, name: &str) -> Option<&Alias> {
-        self.aliases.get(name)
+        let name = &*lookup_key(name);
+        self.aliases.get(name).or_else(|| self.system.get(name))
     }
 
-    /// Get a mutable reference to an alias by name
-    pub fn get_mut(&mut self, name: &str) -> Option<&mut Alias> {
+    /// Note a change to the aliases themselves, which the next save keeps a backup before
+    fn mark_changed(&mut self) {
         self.dirty = true;
-        self.aliases.get_mut(name)
+        self.backup_due = true;
+    }
+
+    /// Get a mutable reference to an alias by name (system aliases are not writable)
+    pub fn get_mut(&mut self, name: &str) -> Option<&mut Alias> {
+        self.mark_changed();
+        self.aliases.get_mut(&*lookup_key(name))
     }
 
     /// Insert or update an alias
     pub fn insert(&mut self, alias: Alias) {
-        self.dirty = true;
+        self.mark_changed();
         self.aliases.insert(alias.name.clone(), alias);
     }
 
-    /// Add a new alias (fails if exists)
+    /// Add a new alias (fails if it exists or `[register]` forbids its path)
     pub fn add(&mut self, alias: Alias) -> Result<(), DatabaseError> {
         if self.aliases.contains_key(&alias.name) {
             return Err(AliasError::AlreadyExists(alias.name).into());
         }
+        self.check_alias_allowed(&alias)?;
         self.insert(alias);
         Ok(())
     }
 
-    /// Add a new alias with tags (fails if exists)
+    /// Add a new alias with tags (fails like `add`)
     pub fn add_with_tags(&mut self, mut alias: Alias, mut tags: Vec<String>) -> Result<(), DatabaseError> {
         if self.aliases.contains_key(&alias.name) {
             return Err(AliasError::AlreadyExists(alias.name).into());
         }
+        self.check_alias_allowed(&alias)?;
         tags.sort();
         alias.tags = tags;
         self.insert(alias);
@@ -206,53 +618,69 @@ impl Database {
 
     /// Remove an alias by name
     pub fn remove(&mut self, name: &str) -> Option<Alias> {
-        self.dirty = true;
-        self.aliases.remove(name)
+        self.mark_changed();
+        self.aliases.remove(&*lookup_key(name))
     }
 
     /// Check if an alias exists
     pub fn contains(&self, name: &str) -> bool {
-        self.aliases.contains_key(name)
+        let name = &*lookup_key(name);
+        self.aliases.contains_key(name) || self.system.contains_key(name)
     }
 
-    /// Get all aliases
+    /// Get all aliases, including system aliases not shadowed by the user
     pub fn all(&self) -> impl Iterator<Item = &Alias> {
-        self.aliases.values()
+        self.aliases
+            .values()
+            .chain(self.system.values().filter(|a| !self.aliases.contains_key(&a.name)))
     }
 
     /// Get all alias names
     pub fn names(&self) -> impl Iterator<Item = &str> {
-        self.aliases.keys().map(|s| s.as_str())
+        self.all().map(|a| a.name.as_str())
     }
 
     /// Get all alias names as a vector
     pub fn list_names(&self) -> Vec<String> {
-        self.aliases.keys().cloned().collect()
+        self.names().map(String::from).collect()
     }
 
     /// Get the number of aliases
     pub fn len(&self) -> usize {
-        self.aliases.len()
+        self.all().count()
     }
 
     /// Check if the database is empty
     pub fn is_empty(&self) -> bool {
-        self.aliases.is_empty()
+        self.aliases.is_empty() && self.system.is_empty()
     }
 
     /// Record usage of an alias (increment use_count, update last_used)
+    ///
+    /// Usage of system aliases is not tracked, and nothing is recorded while
+    /// tracking is disabled.
     pub fn record_usage(&mut self, name: &str) -> Result<(), DatabaseError> {
+        let name = &*lookup_key(name);
         if let Some(alias) = self.aliases.get_mut(name) {
-            alias.record_use();
-            self.dirty = true;
-            Ok(())
-        } else {
-            Err(AliasError::NotFound(name.to_string()).into())
+            if self.track_usage {
+                alias.record_use();
+                self.dirty = true;
+            }
+        } else if !self.system.contains_key(name) {
+            return Err(AliasError::NotFound(name.to_string()).into());
+        }
+        if self.track_usage && self.event_log.is_some() {
+            self.pending_events.push(Event::new(Utc::now(), name));
         }
+        Ok(())
     }
 
     /// Rename an alias while preserving all metadata
     pub fn rename_alias(&mut self, old_name: &str, new_name: &str) -> Result<(), DatabaseError> {
+        let old_name = &*lookup_key(old_name);
+        let new_name = &*lookup_key(new_name);
+        self.check_writable(old_name)?;
+
         // Check new name doesn't exist
         if self.aliases.contains_key(new_name) {
             return Err(AliasError::AlreadyExists(new_name.to_string()).into());
@@ -267,15 +695,17 @@ impl Database {
         // Update name and insert with new key
         alias.name = new_name.to_string();
         self.aliases.insert(new_name.to_string(), alias);
-        self.dirty = true;
+        self.mark_changed();
         Ok(())
     }
 
     /// Add a tag to an alias
     pub fn add_tag(&mut self, alias_name: &str, tag: &str) -> Result<(), DatabaseError> {
+        let alias_name = &*lookup_key(alias_name);
+        self.check_writable(alias_name)?;
         if let Some(alias) = self.aliases.get_mut(alias_name) {
             alias.add_tag(tag);
-            self.dirty = true;
+            self.mark_changed();
             Ok(())
         } else {
             Err(AliasError::NotFound(alias_name.to_string()).into())
@@ -284,9 +714,11 @@ impl Database {
 
     /// Remove a tag from an alias
     pub fn remove_tag(&mut self, alias_name: &str, tag: &str) -> Result<(), DatabaseError> {
+        let alias_name = &*lookup_key(alias_name);
+        self.check_writable(alias_name)?;
         if let Some(alias) = self.aliases.get_mut(alias_name) {
             alias.remove_tag(tag);
-            self.dirty = true;
+            self.mark_changed();
             Ok(())
         } else {
             Err(AliasError::NotFound(alias_name.to_string()).into())
@@ -295,10 +727,42 @@ impl Database {
 
     /// Set all tags on an alias (replacing existing)
     pub fn set_tags(&mut self, alias_name: &str, tags: Vec<String>) -> Result<(), DatabaseError> {
+        let alias_name = &*lookup_key(alias_name);
+        self.check_writable(alias_name)?;
         if let Some(alias) = self.aliases.get_mut(alias_name) {
             alias.tags = tags;
             alias.tags.sort();
-            self.dirty = true;
+            self.mark_changed();
+            Ok(())
+        } else {
+            Err(AliasError::NotFound(alias_name.to_string()).into())
+        }
+    }
+
+    /// Set (or with `None`, remove) a metadata entry on an alias
+    pub fn set_meta(&mut self, alias_name: &str, key: &str, value: Option<&str>) -> Result<(), DatabaseError> {
+        let alias_name = &*lookup_key(alias_name);
+        self.check_writable(alias_name)?;
+        validate_meta_key(key)?;
+        if let Some(alias) = self.aliases.get_mut(alias_name) {
+            match value {
+                Some(value) => alias.meta.insert(key.to_string(), value.to_string()),
+                None => alias.meta.remove(key),
+            };
+            self.mark_changed();
+            Ok(())
+        } else {
+            Err(AliasError::NotFound(alias_name.to_string()).into())
+        }
+    }
+
+    /// Turn the pre-navigation directory check off (`true`) or back on for an alias
+    pub fn set_skip_check(&mut self, alias_name: &str, skip: bool) -> Result<(), DatabaseError> {
+        let alias_name = &*lookup_key(alias_name);
+        self.check_writable(alias_name)?;
+        if let Some(alias) = self.aliases.get_mut(alias_name) {
+            alias.skip_check = skip;
+            self.mark_changed();
             Ok(())
         } else {
             Err(AliasError::NotFound(alias_name.to_string()).into())
@@ -308,7 +772,7 @@ impl Database {
     /// Get all unique tags with their counts
     pub fn get_all_tags(&self) -> HashMap<String, usize> {
         let mut tag_counts = HashMap::new();
-        for alias in self.aliases.values() {
+        for alias in self.all() {
             for tag in &alias.tags {
                 *tag_counts.entry(tag.clone()).or_insert(0) += 1;
             }
@@ -319,8 +783,7 @@ impl Database {
     /// Get all unique tags across all aliases (sorted)
     pub fn all_tags(&self) -> Vec<String> {
         let mut tags: Vec<String> = self
-            .aliases
-            .values()
+            .all()
             .flat_map(|a| a.tags.iter().cloned())
             .collect();
         tags.sort();
@@ -333,10 +796,38 @@ impl Database {
         for alias in self.aliases.values_mut() {
             alias.last_used = None;
         }
-        self.dirty = true;
+        self.mark_changed();
         Ok(())
     }
 
+    /// Reset use_count (and optionally last_used) for one alias, or all when `name` is None
+    ///
+    /// Returns the number of aliases reset.
+    pub fn reset_usage(&mut self, name: Option<&str>, clear_last_used: bool) -> Result<usize, DatabaseError> {
+        let targets: Vec<&mut Alias> = match name {
+            Some(name) => {
+                let name = &*lookup_key(name);
+                self.check_writable(name)?;
+                let alias = self
+                    .aliases
+                    .get_mut(name)
+                    .ok_or_else(|| AliasError::NotFound(name.to_string()))?;
+                vec![alias]
+            }
+            None => self.aliases.values_mut().collect(),
+        };
+
+        let count = targets.len();
+        for alias in targets {
+            alias.use_count = 0;
+            if clear_last_used {
+                alias.last_used = None;
+            }
+        }
+        self.mark_changed();
+        Ok(count)
+    }
+
     /// Find similar alias names using fuzzy matching
     pub fn find_similar(&self, query: &str, threshold: f64) -> Vec<String> {
         let names = self.list_names();
@@ -345,10 +836,27 @@ impl Database {
 
     /// Export the database as TOML string
     pub fn export_toml(&self) -> Result<String, DatabaseError> {
+        Ok(toml::to_string_pretty(&self.canonical_file())?)
+    }
+
+    /// The aliases as written to disk: sorted by name, each with sorted, unique tags
+    ///
+    /// The same aliases always serialize to the same bytes, whatever order they
+    /// were added or tagged in, so a database kept in git diffs cleanly.
+    fn canonical_file(&self) -> DatabaseFile {
         let mut aliases: Vec<Alias> = self.aliases.values().cloned().collect();
         aliases.sort_by(|a, b| a.name.cmp(&b.name));
-        let db_file = DatabaseFile { aliases };
-        Ok(toml::to_string_pretty(&db_file)?)
+        for alias in &mut aliases {
+            alias.tags.sort();
+            alias.tags.dedup();
+        }
+        DatabaseFile { checksum: None, aliases }
+    }
+
+    /// Replace all user aliases, e.g. with a repaired copy of the file's entries
+    pub fn replace_aliases(&mut self, aliases: Vec<Alias>) {
+        self.aliases = aliases.into_iter().map(|a| (a.name.clone(), a)).collect();
+        self.mark_changed();
     }
 
     /// Import aliases from TOML string
@@ -358,15 +866,147 @@ impl Database {
         for alias in db_file.aliases {
             self.aliases.insert(alias.name.clone(), alias);
         }
-        self.dirty = true;
+        self.mark_changed();
         Ok(count)
     }
 }
 
+/// A name as stored: in NFC, so a decomposed spelling finds the same alias
+fn lookup_key(name: &str) -> Cow<'_, str> {
+    if name.is_ascii() {
+        Cow::Borrowed(name)
+    } else {
+        Cow::Owned(normalize_name(name))
+    }
+}
+
+/// Run file I/O on `path` under `deadline`, naming the file if it is given up on
+fn io_within<T, F>(path: &Path, deadline: &Deadline, io: F) -> Result<T, DatabaseError>
+where
+    T: Send + 'static,
+    F: FnOnce() -> io::Result<T> + Send + 'static,
+{
+    let start = Instant::now();
+    match deadline.run(io) {
+        Ok(result) => Ok(result?),
+        Err(Interrupted::TimedOut) => Err(DatabaseError::IoTimedOut(
+            path.display().to_string(),
+            start.elapsed().as_secs_f64(),
+        )),
+        Err(Interrupted::Cancelled) => Err(DatabaseError::Cancelled(path.display().to_string())),
+    }
+}
+
+/// Shift existing backups of `path` up by one and copy the current file to `.1`
+fn rotate_backups(path: &Path, backups: usize) -> io::Result<()> {
+    if backups == 0 || !path.exists() {
+        return Ok(());
+    }
+
+    for n in (1..backups).rev() {
+        let from = backup_path(path, n);
+        if from.exists() {
+            fs::rename(&from, backup_path(path, n + 1))?;
+        }
+    }
+    fs::copy(path, backup_path(path, 1))?;
+    Ok(())
+}
+
+/// Back up the database file at `path` if it changed since the newest backup
+///
+/// The existing backups shift up one as on a save, the oldest beyond `backups`
+/// dropping off. Returns whether a backup was taken.
+pub fn rotate_backups_if_changed(path: &Path, backups: usize) -> io::Result<bool> {
+    if backups == 0 || !path.exists() {
+        return Ok(false);
+    }
+    let _lock = filelock::lock(path)?;
+    if fs::read(backup_path(path, 1)).ok() == Some(fs::read(path)?) {
+        return Ok(false);
+    }
+    rotate_backups(path, backups)?;
+    Ok(true)
+}
+
+/// What [`Database::load_entries`] found on disk
+enum Stored {
+    Toml(Vec<u8>),
+    Text,
+    Nothing,
+}
+
+/// The error a save gets when its deadline passed before it replaced the file
+fn gave_up() -> io::Error {
+    io::Error::new(io::ErrorKind::TimedOut, "gave up before replacing the database")
+}
+
+/// Replace the file at `path` with `data` in one step
+///
+/// The data goes to a temporary file next to the real target (following a
+/// symlinked database) that is then renamed over it, so a write that is
+/// interrupted or given up on never leaves a half-written database. Each
+/// attempt gets its own temporary file, and one whose `deadline` has passed
+/// by then is thrown away rather than renamed.
+fn write_replacing(path: &Path, data: &[u8], deadline: &Deadline) -> io::Result<()> {
+    static ATTEMPTS: AtomicUsize = AtomicUsize::new(0);
+    let target = fs::canonicalize(path).unwrap_or_else(|_| path.to_path_buf());
+    let mut tmp = target.as_os_str().to_os_string();
+    tmp.push(format!(".{}.{}.tmp", process::id(), ATTEMPTS.fetch_add(1, Ordering::SeqCst)));
+    let tmp = PathBuf::from(tmp);
+    fs::write(&tmp, data)?;
+    let replaced = match fs::metadata(&target) {
+        // Keep the permissions of the file being replaced
+        Ok(meta) => fs::set_permissions(&tmp, meta.permissions()),
+        Err(_) => Ok(()),
+    }
+    .and_then(|_| deadline.check().map_err(|_| gave_up()))
+    .and_then(|_| fs::rename(&tmp, &target));
+    if replaced.is_err() {
+        let _ = fs::remove_file(&tmp);
+    }
+    replaced
+}
+
+/// Path of the `n`th backup of a database file (`aliases.toml.1` is the newest)
+pub fn backup_path(toml_path: &Path, n: usize) -> PathBuf {
+    let mut name = toml_path.as_os_str().to_os_string();
+    name.push(format!(".{}", n));
+    PathBuf::from(name)
+}
+
+/// Count the aliases in a database file, decrypting it if needed
+pub fn count_entries(path: &Path) -> Result<usize, DatabaseError> {
+    Ok(read_entries(path)?.0.len())
+}
+
+/// Every entry in a database file as stored (duplicate names included) and
+/// its embedded checksum, decrypting it if needed
+pub fn read_entries(path: &Path) -> Result<(Vec<Alias>, Option<String>), DatabaseError> {
+    let mut data = fs::read(path)?;
+    if crypto::is_encrypted(&data) {
+        let key = crypto::load_key().ok_or(CryptoError::NoKey)?;
+        data = crypto::decrypt(&data, &key)?;
+    }
+
+    let content = String::from_utf8(data).map_err(|e| io::Error::new(io::ErrorKind::InvalidData, e))?;
+    let db_file: DatabaseFile = toml::from_str(&content)?;
+    Ok((db_file.aliases, db_file.checksum))
+}
+
+/// Hex SHA-256 of aliases as the database file stores them
+pub fn checksum(aliases: &[Alias]) -> Result<String, DatabaseError> {
+    let body = toml::to_string_pretty(&ChecksumBody { aliases })?;
+    Ok(Sha256::digest(body.as_bytes()).iter().map(|b| format!("{:02x}", b)).collect())
+}
+
 impl Drop for Database {
     fn drop(&mut self) {
-        // Try to save on drop, but ignore errors
-        let _ = self.save();
+        // Try to save on drop, but ignore errors; after a save was given up
+        // on, a second one would only race the first
+        if !self.save_abandoned {
+            let _ = self.save();
+        }
     }
 }
 
@@ -455,6 +1095,44 @@ mod tests {
         assert!(db.get("test").unwrap().last_used.is_some());
     }
 
+    #[test]
+    fn test_record_usage_disabled() {
+        let (mut db, _dir) = create_test_db();
+        db.insert(Alias::new("test", "/tmp").unwrap());
+        db.save().unwrap();
+
+        db.set_track_usage(false);
+        db.record_usage("test").unwrap();
+
+        let alias = db.get("test").unwrap();
+        assert_eq!(alias.use_count, 0);
+        assert!(alias.last_used.is_none());
+        assert!(!db.dirty);
+        assert!(db.record_usage("missing").is_err());
+    }
+
+    #[test]
+    fn test_record_usage_appends_to_event_log() {
+        let (mut db, dir) = create_test_db();
+        let log = dir.path().join(events::FILE_NAME);
+        db.set_event_log(log.clone());
+        db.insert(Alias::new("test", "/tmp").unwrap());
+
+        db.record_usage("test").unwrap();
+        db.record_usage("test").unwrap();
+        assert!(!log.exists(), "events are written on save");
+        db.save().unwrap();
+        db.save().unwrap();
+        let logged = events::load(&log).unwrap();
+        assert_eq!(logged.len(), 2);
+        assert_eq!(logged[0].alias, "test");
+
+        db.set_track_usage(false);
+        db.record_usage("test").unwrap();
+        db.save().unwrap();
+        assert_eq!(events::load(&log).unwrap().len(), 2);
+    }
+
     #[test]
     fn test_record_usage_not_found() {
         let (mut db, _dir) = create_test_db();
@@ -687,6 +1365,9 @@ created_at = "2024-01-01T00:00:00Z"
         let config = Config {
             database_path: dir.path().to_path_buf(),
             stack_path: dir.path().join("goto_stack"),
+            history_path: dir.path().join("dir_history"),
+            context_path: dir.path().join("context"),
+            listing_path: dir.path().join("last_listing"),
             config_path: dir.path().join("config.toml"),
             aliases_path: dir.path().join("aliases"),
             user: UserConfig::default(),
@@ -727,6 +1408,208 @@ created_at = "2024-01-01T00:00:00Z"
         assert!(matches!(result, Err(DatabaseError::Alias(AliasError::NotFound(_)))));
     }
 
+    #[test]
+    fn test_save_is_deterministic() {
+        let dir = tempdir().unwrap();
+        let write = |names: &[&str], tags: &[&str]| {
+            let path = dir.path().join("aliases");
+            let _ = fs::remove_file(path.with_extension("toml"));
+            let mut db = Database::load_from_path(&path).unwrap();
+            for name in names {
+                let mut alias = Alias::new(name, "/tmp").unwrap();
+                alias.created_at = chrono::DateTime::from_timestamp(0, 0).unwrap();
+                alias.tags = tags.iter().map(|t| t.to_string()).collect();
+                db.insert(alias);
+            }
+            db.save().unwrap();
+            fs::read_to_string(path.with_extension("toml")).unwrap()
+        };
+
+        let first = write(&["zeta", "alpha", "mid"], &["work", "api", "work"]);
+        let second = write(&["mid", "zeta", "alpha"], &["api", "work"]);
+        assert_eq!(first, second);
+        assert!(first.find("alpha").unwrap() < first.find("zeta").unwrap());
+        assert!(first.contains("tags = [\n    \"api\",\n    \"work\",\n]"));
+    }
+
+    #[test]
+    fn test_save_rotates_backups() {
+        let (mut db, dir) = create_test_db();
+        db.set_backups(2);
+        let toml_path = dir.path().join("aliases.toml");
+
+        for name in ["a", "b", "c", "d"] {
+            db.insert(Alias::new(name, "/tmp").unwrap());
+            db.save().unwrap();
+        }
+
+        assert_eq!(count_entries(&toml_path).unwrap(), 4);
+        assert_eq!(count_entries(&backup_path(&toml_path, 1)).unwrap(), 3);
+        assert_eq!(count_entries(&backup_path(&toml_path, 2)).unwrap(), 2);
+        assert!(!backup_path(&toml_path, 3).exists());
+    }
+
+    #[test]
+    fn test_add_checks_host_paths() {
+        let (mut db, _dir) = create_test_db();
+        db.set_register_rules(RegisterConfig {
+            allow: Vec::new(),
+            deny: vec!["/proc".to_string()],
+        });
+        let mut alias = Alias::new("sneaky", "/tmp").unwrap();
+        alias.paths.insert("default".to_string(), "/proc/self".to_string());
+
+        assert!(matches!(
+            db.add(alias.clone()),
+            Err(DatabaseError::Alias(AliasError::PathNotAllowed(..)))
+        ));
+        assert!(db.add_with_tags(alias, Vec::new()).is_err());
+        assert!(!db.contains("sneaky"));
+    }
+
+    #[test]
+    fn test_usage_only_saves_do_not_rotate_backups() {
+        let (mut db, dir) = create_test_db();
+        db.set_backups(2);
+        let toml_path = dir.path().join("aliases.toml");
+        db.insert(Alias::new("a", "/tmp").unwrap());
+        db.save().unwrap();
+        db.insert(Alias::new("b", "/tmp").unwrap());
+        db.save().unwrap();
+
+        for _ in 0..3 {
+            db.record_usage("a").unwrap();
+            db.save().unwrap();
+        }
+        assert_eq!(count_entries(&backup_path(&toml_path, 1)).unwrap(), 1);
+        assert!(!backup_path(&toml_path, 2).exists());
+        assert_eq!(db.get("a").unwrap().use_count, 3);
+    }
+
+    #[test]
+    fn test_no_backups_by_default() {
+        let (mut db, dir) = create_test_db();
+        db.insert(Alias::new("a", "/tmp").unwrap());
+        db.save().unwrap();
+        db.insert(Alias::new("b", "/tmp").unwrap());
+        db.save().unwrap();
+
+        assert!(!backup_path(&dir.path().join("aliases.toml"), 1).exists());
+    }
+
+    #[test]
+    fn test_save_and_load_within_cancelled_deadline() {
+        let (mut db, dir) = create_test_db();
+        let path = dir.path().join("aliases");
+        db.insert(Alias::new("a", "/tmp").unwrap());
+        db.save().unwrap();
+
+        let (deadline, cancel) = Deadline::default().with_cancel();
+        cancel.cancel();
+        db.insert(Alias::new("b", "/tmp").unwrap());
+        assert!(matches!(db.save_within(&deadline), Err(DatabaseError::Cancelled(_))));
+        assert!(matches!(
+            Database::load_from_path_within(&path, None, &deadline),
+            Err(DatabaseError::Cancelled(_))
+        ));
+
+        // The file given up on is untouched and the changes are still pending
+        assert_eq!(count_entries(&dir.path().join("aliases.toml")).unwrap(), 1);
+        db.save_within(&Deadline::from_ms(5000)).unwrap();
+        let db = Database::load_from_path_within(&path, None, &Deadline::from_ms(5000)).unwrap();
+        assert!(db.contains("b"));
+    }
+
+    #[test]
+    fn test_save_replaces_through_symlink() {
+        let (mut db, dir) = create_test_db();
+        let real = dir.path().join("real.toml");
+        db.insert(Alias::new("a", "/tmp").unwrap());
+        db.save().unwrap();
+        fs::rename(dir.path().join("aliases.toml"), &real).unwrap();
+        std::os::unix::fs::symlink(&real, dir.path().join("aliases.toml")).unwrap();
+
+        db.insert(Alias::new("b", "/tmp").unwrap());
+        db.save().unwrap();
+        assert!(fs::symlink_metadata(dir.path().join("aliases.toml")).unwrap().file_type().is_symlink());
+        assert_eq!(count_entries(&real).unwrap(), 2);
+        let leftovers = fs::read_dir(dir.path()).unwrap().filter(|e| {
+            let name = e.as_ref().unwrap().file_name();
+            name.to_string_lossy().ends_with(".tmp")
+        });
+        assert_eq!(leftovers.count(), 0);
+    }
+
+    #[test]
+    fn test_save_waits_for_backup_lock() {
+        let (mut db, dir) = create_test_db();
+        let toml_path = dir.path().join("aliases.toml");
+        db.insert(Alias::new("a", "/tmp").unwrap());
+        db.save().unwrap();
+
+        // `--maintain` rotating backups holds the lock; the save gives up behind it
+        let lock = filelock::lock(&toml_path).unwrap();
+        db.insert(Alias::new("b", "/tmp").unwrap());
+        assert!(matches!(db.save_within(&Deadline::from_ms(100)), Err(DatabaseError::IoTimedOut(..))));
+        assert_eq!(count_entries(&toml_path).unwrap(), 1);
+
+        // Once released, the abandoned attempt sees its deadline has passed and
+        // leaves the file alone, and dropping the database doesn't save again
+        drop(lock);
+        drop(db);
+        std::thread::sleep(std::time::Duration::from_millis(100));
+        assert_eq!(count_entries(&toml_path).unwrap(), 1);
+    }
+
+    #[test]
+    fn test_write_replacing_after_deadline() {
+        let dir = tempdir().unwrap();
+        let path = dir.path().join("aliases.toml");
+        fs::write(&path, "old").unwrap();
+
+        let (deadline, cancel) = Deadline::default().with_cancel();
+        cancel.cancel();
+        assert!(write_replacing(&path, b"new", &deadline).is_err());
+        assert_eq!(fs::read_to_string(&path).unwrap(), "old");
+        assert_eq!(fs::read_dir(dir.path()).unwrap().count(), 1);
+
+        write_replacing(&path, b"new", &Deadline::default()).unwrap();
+        assert_eq!(fs::read_to_string(&path).unwrap(), "new");
+    }
+
+    /// Navigation latency with a large database; run with
+    /// `cargo test --release bench_large_database -- --ignored --nocapture`
+    #[test]
+    #[ignore]
+    fn bench_large_database() {
+        use std::time::Instant;
+
+        let (mut db, dir) = create_test_db();
+        for i in 0..5000 {
+            let mut alias = Alias::new(&format!("alias{}", i), &format!("/srv/projects/{}", i)).unwrap();
+            alias.add_tag(&format!("tag{}", i % 10));
+            db.insert(alias);
+        }
+        db.save().unwrap();
+        let path = dir.path().join("aliases");
+
+        let start = Instant::now();
+        let mut db = Database::load_from_path(&path).unwrap();
+        let load = start.elapsed();
+
+        let start = Instant::now();
+        let name = db.resolve_name("alias4000");
+        assert!(db.get(&name).is_some());
+        let lookup = start.elapsed();
+
+        let start = Instant::now();
+        db.record_usage(&name).unwrap();
+        db.save().unwrap();
+        let save = start.elapsed();
+
+        eprintln!("5000 aliases: load {:?}, lookup {:?}, record+save {:?}", load, lookup, save);
+    }
+
     #[test]
     fn test_auto_saves_on_drop() {
         let dir = tempdir().unwrap();
@@ -796,4 +1679,230 @@ created_at = "2024-01-01T00:00:00Z"
         let result = db.add_with_tags(alias2, vec!["work".to_string()]);
         assert!(matches!(result, Err(DatabaseError::Alias(AliasError::AlreadyExists(_)))));
     }
+
+    #[test]
+    fn test_lookup_by_decomposed_name() {
+        let (mut db, _dir) = create_test_db();
+        db.insert(Alias::new("projëkt", "/tmp").unwrap());
+        let decomposed = "proje\u{0308}kt";
+
+        assert!(db.contains(decomposed));
+        assert_eq!(db.get(decomposed).unwrap().name, "projëkt");
+        assert_eq!(db.resolve_name(decomposed), "projëkt");
+        assert!(db.get_mut(decomposed).is_some());
+        assert!(db.remove(decomposed).is_some());
+        assert!(!db.contains("projëkt"));
+    }
+
+    #[test]
+    fn test_name_arguments_normalized() {
+        let (mut db, _dir) = create_test_db();
+        db.insert(Alias::new("projëkt", "/tmp").unwrap());
+        let decomposed = "proje\u{0308}kt";
+
+        db.record_usage(decomposed).unwrap();
+        db.add_tag(decomposed, "work").unwrap();
+        db.set_meta(decomposed, "owner", Some("antti")).unwrap();
+        db.set_skip_check(decomposed, true).unwrap();
+        let alias = db.get("projëkt").unwrap();
+        assert_eq!(alias.use_count, 1);
+        assert!(alias.has_tag("work"));
+        assert!(alias.skip_check);
+
+        assert_eq!(db.reset_usage(Some(decomposed), false).unwrap(), 1);
+        db.rename_alias(decomposed, "ne\u{0301}").unwrap();
+        assert_eq!(db.get("né").unwrap().name, "né");
+    }
+
+    #[test]
+    fn test_resolve_name_default_namespace() {
+        let (mut db, _dir) = create_test_db();
+        db.insert(Alias::new("work:api", "/srv/work/api").unwrap());
+        db.insert(Alias::new("oss:api", "/srv/oss/api").unwrap());
+        db.insert(Alias::new("docs", "/srv/docs").unwrap());
+
+        assert_eq!(db.resolve_name("api"), "api");
+
+        db.set_default_namespace("work");
+        assert_eq!(db.resolve_name("api"), "work:api");
+        assert_eq!(db.resolve_name("oss:api"), "oss:api");
+        assert_eq!(db.resolve_name("docs"), "docs");
+        assert_eq!(db.resolve_name("missing"), "missing");
+
+        assert_eq!(db.namespaces(), vec!["oss", "work"]);
+    }
+
+    #[test]
+    fn test_reset_usage_single() {
+        let (mut db, _dir) = create_test_db();
+        let mut a = Alias::new("a", "/tmp/a").unwrap();
+        a.record_use();
+        let mut b = Alias::new("b", "/tmp/b").unwrap();
+        b.record_use();
+        db.insert(a);
+        db.insert(b);
+
+        assert_eq!(db.reset_usage(Some("a"), false).unwrap(), 1);
+        assert_eq!(db.get("a").unwrap().use_count, 0);
+        assert!(db.get("a").unwrap().last_used.is_some());
+        assert_eq!(db.get("b").unwrap().use_count, 1);
+    }
+
+    #[test]
+    fn test_reset_usage_all_with_last_used() {
+        let (mut db, _dir) = create_test_db();
+        for name in ["a", "b"] {
+            let mut alias = Alias::new(name, "/tmp").unwrap();
+            alias.record_use();
+            db.insert(alias);
+        }
+
+        assert_eq!(db.reset_usage(None, true).unwrap(), 2);
+        assert!(db.all().all(|a| a.use_count == 0 && a.last_used.is_none()));
+    }
+
+    #[test]
+    fn test_reset_usage_not_found() {
+        let (mut db, _dir) = create_test_db();
+        let result = db.reset_usage(Some("missing"), false);
+        assert!(matches!(result, Err(DatabaseError::Alias(AliasError::NotFound(_)))));
+    }
+
+    #[test]
+    fn test_encrypted_round_trip() {
+        let dir = tempdir().unwrap();
+        let path = dir.path().join("aliases");
+        let key = crypto::derive_key("passphrase");
+
+        {
+            let mut db = Database::load_from_path_with_key(&path, Some(key.clone())).unwrap();
+            db.insert(Alias::new("secret", "/tmp/secret-project").unwrap());
+            db.save().unwrap();
+        }
+
+        let raw = fs::read(path.with_extension("toml")).unwrap();
+        assert!(crypto::is_encrypted(&raw));
+        assert!(!String::from_utf8_lossy(&raw).contains("secret-project"));
+
+        let db = Database::load_from_path_with_key(&path, Some(key.clone())).unwrap();
+        assert_eq!(db.get("secret").unwrap().path, "/tmp/secret-project");
+
+        let result = Database::load_from_path_with_key(&path, Some(crypto::derive_key("wrong")));
+        assert!(matches!(result, Err(DatabaseError::Crypto(CryptoError::Decrypt))));
+    }
+
+    #[test]
+    fn test_enabling_encryption_converts_plaintext() {
+        let dir = tempdir().unwrap();
+        let path = dir.path().join("aliases");
+
+        {
+            let mut db = Database::load_from_path(&path).unwrap();
+            db.insert(Alias::new("test", "/tmp/test").unwrap());
+        }
+
+        // Loading with a key marks the plaintext file for rewrite
+        {
+            let db = Database::load_from_path_with_key(&path, Some(crypto::derive_key("k"))).unwrap();
+            assert!(db.contains("test"));
+        }
+
+        let raw = fs::read(path.with_extension("toml")).unwrap();
+        assert!(crypto::is_encrypted(&raw));
+    }
+
+    #[test]
+    fn test_load_layers_precedence() {
+        let dir = tempdir().unwrap();
+        let write = |name: &str, aliases: &[(&str, &str)]| {
+            let path = dir.path().join(name);
+            let body: String = aliases
+                .iter()
+                .map(|(n, p)| format!("[[aliases]]\nname = \"{}\"\npath = \"{}\"\n\n", n, p))
+                .collect();
+            fs::write(&path, body).unwrap();
+            path
+        };
+        let system = write("system.toml", &[("logs", "/var/log"), ("shared", "/srv/system")]);
+        let team = write("team.toml", &[("shared", "/srv/team"), ("deploy", "/srv/team/deploy")]);
+        let org = write("org.toml", &[("deploy", "/srv/org/deploy"), ("wiki", "/srv/wiki")]);
+
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        db.load_system(&system).unwrap();
+        db.load_layers(&[team, org]).unwrap();
+        db.insert(Alias::new("wiki", "/home/user/wiki").unwrap());
+
+        assert_eq!(db.get("logs").unwrap().path, "/var/log");
+        assert_eq!(db.get("shared").unwrap().path, "/srv/team");
+        assert_eq!(db.get("deploy").unwrap().path, "/srv/team/deploy");
+        assert_eq!(db.get("wiki").unwrap().path, "/home/user/wiki");
+        assert!(db.is_system("deploy"));
+        assert!(matches!(db.add_tag("deploy", "ops"), Err(DatabaseError::ReadOnly(_))));
+    }
+
+    #[test]
+    fn test_system_aliases_merged_read_only() {
+        let dir = tempdir().unwrap();
+        let system_path = dir.path().join("system.toml");
+        fs::write(
+            &system_path,
+            r#"
+[[aliases]]
+name = "logs"
+path = "/var/log"
+
+[[aliases]]
+name = "shared"
+path = "/srv/shared"
+"#,
+        )
+        .unwrap();
+
+        let path = dir.path().join("aliases");
+        {
+            let mut db = Database::load_from_path(&path).unwrap();
+            db.load_system(&system_path).unwrap();
+            db.insert(Alias::new("shared", "/home/user/shared").unwrap());
+
+            assert_eq!(db.len(), 2);
+            assert_eq!(db.get("logs").unwrap().path, "/var/log");
+            // User alias shadows the system alias
+            assert_eq!(db.get("shared").unwrap().path, "/home/user/shared");
+            assert!(db.is_system("logs"));
+            assert!(!db.is_system("shared"));
+
+            assert!(matches!(db.add_tag("logs", "ops"), Err(DatabaseError::ReadOnly(_))));
+            assert!(matches!(db.rename_alias("logs", "l"), Err(DatabaseError::ReadOnly(_))));
+            db.record_usage("logs").unwrap();
+            assert_eq!(db.get("logs").unwrap().use_count, 0);
+        }
+
+        // System aliases are never written to the user database
+        let db = Database::load_from_path(&path).unwrap();
+        assert!(!db.contains("logs"));
+        assert!(db.contains("shared"));
+    }
+
+    #[test]
+    fn test_shared_aliases_from_include() {
+        let dir = tempdir().unwrap();
+        let system_path = dir.path().join("system.toml");
+        fs::write(&system_path, "[[aliases]]\nname = \"logs\"\npath = \"/var/log\"\n").unwrap();
+        let shared_path = dir.path().join("team.toml");
+        fs::write(
+            &shared_path,
+            "[display]\nshow_stats = true\n\n[[aliases]]\nname = \"logs\"\npath = \"/srv/logs\"\n\n\
+             [[aliases]]\nname = \"wiki\"\npath = \"/srv/wiki\"\n",
+        )
+        .unwrap();
+
+        let mut db = Database::load_from_path(&dir.path().join("aliases")).unwrap();
+        db.load_system(&system_path).unwrap();
+        db.load_shared(&shared_path).unwrap();
+
+        assert_eq!(db.get("logs").unwrap().path, "/var/log");
+        assert_eq!(db.get("wiki").unwrap().path, "/srv/wiki");
+        assert!(db.is_system("wiki"));
+        assert!(matches!(db.add_tag("wiki", "docs"), Err(DatabaseError::ReadOnly(_))));
+    }
 }
@@ -0,0 +1,173 @@
+//! Deadlines and cancellation for filesystem and storage operations
+//!
+//! Code using goto as a library passes a [`Deadline`] to bound how long
+//! loading, saving or checking a directory may take, and can hand a
+//! [`CancelHandle`] to another thread to give up early. A system call stuck
+//! on a dead mount can't be interrupted, so bounded work runs on a helper
+//! thread that is left behind when it's abandoned; it ends with the process.
+
+use std::sync::atomic::{AtomicBool, Ordering};
+use std::sync::mpsc::{self, RecvTimeoutError};
+use std::sync::Arc;
+use std::thread;
+use std::time::{Duration, Instant};
+
+/// How often a cancellable operation without a deadline looks at its flag
+const POLL: Duration = Duration::from_millis(50);
+
+/// Why an operation gave up
+#[derive(Debug, Clone, Copy, PartialEq)]
+pub enum Interrupted {
+    TimedOut,
+    Cancelled,
+}
+
+/// When an operation must be finished by, and whether it has been cancelled
+///
+/// The default never expires and can't be cancelled; work under it runs on
+/// the calling thread as if there were no deadline at all.
+#[derive(Debug, Clone, Default)]
+pub struct Deadline {
+    at: Option<Instant>,
+    cancelled: Arc<AtomicBool>,
+    cancellable: bool,
+}
+
+/// Cancels the [`Deadline`] it came from, and every one derived from it
+#[derive(Debug, Clone)]
+pub struct CancelHandle(Arc<AtomicBool>);
+
+impl CancelHandle {
+    pub fn cancel(&self) {
+        self.0.store(true, Ordering::SeqCst);
+    }
+}
+
+impl Deadline {
+    /// A deadline `timeout` from now
+    pub fn after(timeout: Duration) -> Self {
+        Self::default().with_timeout(timeout)
+    }
+
+    /// A deadline in milliseconds from now, as config files give them; 0 never expires
+    pub fn from_ms(ms: u64) -> Self {
+        match ms {
+            0 => Self::default(),
+            ms => Self::after(Duration::from_millis(ms)),
+        }
+    }
+
+    /// This deadline, or `timeout` from now if that comes first
+    ///
+    /// Cancelling this deadline cancels the new one too.
+    pub fn with_timeout(&self, timeout: Duration) -> Self {
+        let at = Instant::now() + timeout;
+        Self {
+            at: Some(self.at.map_or(at, |own| own.min(at))),
+            ..self.clone()
+        }
+    }
+
+    /// This deadline, plus a handle that cancels it
+    pub fn with_cancel(&self) -> (Self, CancelHandle) {
+        let deadline = Self { cancellable: true, ..self.clone() };
+        let handle = CancelHandle(Arc::clone(&deadline.cancelled));
+        (deadline, handle)
+    }
+
+    /// Time left, or `None` without a deadline
+    pub fn remaining(&self) -> Option<Duration> {
+        self.at.map(|at| at.saturating_duration_since(Instant::now()))
+    }
+
+    /// Why work under this deadline should not start (or go on), if it shouldn't
+    pub fn check(&self) -> Result<(), Interrupted> {
+        if self.cancelled.load(Ordering::SeqCst) {
+            Err(Interrupted::Cancelled)
+        } else if self.remaining() == Some(Duration::ZERO) {
+            Err(Interrupted::TimedOut)
+        } else {
+            Ok(())
+        }
+    }
+
+    /// Run `work`, giving up when the deadline passes or it is cancelled
+    ///
+    /// A panic in `work` is passed on to the caller.
+    pub fn run<T, F>(&self, work: F) -> Result<T, Interrupted>
+    where
+        T: Send + 'static,
+        F: FnOnce() -> T + Send + 'static,
+    {
+        self.check()?;
+        if self.at.is_none() && !self.cancellable {
+            return Ok(work());
+        }
+
+        let (tx, rx) = mpsc::channel();
+        let worker = thread::spawn(move || {
+            let _ = tx.send(work());
+        });
+        loop {
+            let wait = match (self.remaining(), self.cancellable) {
+                (Some(left), true) => left.min(POLL),
+                (Some(left), false) => left,
+                (None, _) => POLL,
+            };
+            match rx.recv_timeout(wait) {
+                Ok(value) => return Ok(value),
+                Err(RecvTimeoutError::Timeout) => self.check()?,
+                Err(RecvTimeoutError::Disconnected) => match worker.join() {
+                    Err(panic) => std::panic::resume_unwind(panic),
+                    Ok(()) => unreachable!("worker exited without sending its result"),
+                },
+            }
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_run() {
+        assert_eq!(Deadline::default().run(|| 42), Ok(42));
+        assert_eq!(Deadline::from_ms(5000).run(|| 42), Ok(42));
+
+        let slow = || thread::sleep(Duration::from_secs(5));
+        let started = Instant::now();
+        assert_eq!(Deadline::from_ms(50).run(slow), Err(Interrupted::TimedOut));
+        assert!(started.elapsed() < Duration::from_secs(2));
+    }
+
+    #[test]
+    fn test_cancel() {
+        let (deadline, cancel) = Deadline::default().with_cancel();
+        let child = deadline.with_timeout(Duration::from_secs(60));
+        let canceller = thread::spawn(move || {
+            thread::sleep(Duration::from_millis(50));
+            cancel.cancel();
+        });
+        let result = child.run(|| thread::sleep(Duration::from_secs(5)));
+        canceller.join().unwrap();
+        assert_eq!(result, Err(Interrupted::Cancelled));
+        assert_eq!(deadline.check(), Err(Interrupted::Cancelled));
+    }
+
+    #[test]
+    fn test_with_timeout_keeps_the_earlier_deadline() {
+        let short = Deadline::from_ms(10);
+        assert!(short.with_timeout(Duration::from_secs(60)).remaining().unwrap() <= Duration::from_millis(10));
+        assert!(Deadline::default().remaining().is_none());
+        assert_eq!(Deadline::from_ms(0).check(), Ok(()));
+        thread::sleep(Duration::from_millis(20));
+        assert_eq!(short.check(), Err(Interrupted::TimedOut));
+    }
+
+    #[test]
+    #[should_panic(expected = "boom")]
+    fn test_run_passes_panics_on() {
+        let _ = Deadline::from_ms(5000).run(|| panic!("boom"));
+    }
+}
@@ -0,0 +1,167 @@
+//! Navigation event log: one line per jump, so reports can count navigations over time
+//!
+//! `goto --gc` folds old lines into one per alias and day, so a line can
+//! stand for several jumps. Appends and the compaction both hold the log's
+//! [`filelock`](crate::filelock), so no jump is lost to a concurrent rewrite.
+
+use std::collections::BTreeMap;
+use std::fs::{self, File, OpenOptions};
+use std::io::{self, BufRead, BufReader, Write};
+use std::path::Path;
+
+use chrono::{DateTime, TimeZone, Utc};
+
+use crate::filelock;
+
+/// File name of the log, next to the database
+pub const FILE_NAME: &str = "nav_log";
+
+/// Navigations to an alias: a single jump, or a day's worth after compaction
+#[derive(Debug, Clone, PartialEq)]
+pub struct Event {
+    pub at: DateTime<Utc>,
+    pub alias: String,
+    pub count: u64,
+}
+
+impl Event {
+    /// A single navigation to `alias` at `at`
+    pub fn new(at: DateTime<Utc>, alias: &str) -> Self {
+        Self { at, alias: alias.to_string(), count: 1 }
+    }
+}
+
+/// Log line for an event: "epoch<TAB>alias", plus "<TAB>count" when it stands for several jumps
+pub fn format_line(event: &Event) -> String {
+    if event.count == 1 {
+        format!("{}\t{}\n", event.at.timestamp(), event.alias)
+    } else {
+        format!("{}\t{}\t{}\n", event.at.timestamp(), event.alias, event.count)
+    }
+}
+
+/// Append events to the log
+pub fn append(path: &Path, events: &[Event]) -> io::Result<()> {
+    if events.is_empty() {
+        return Ok(());
+    }
+    if let Some(parent) = path.parent() {
+        fs::create_dir_all(parent)?;
+    }
+    let lines: String = events.iter().map(format_line).collect();
+    let _lock = filelock::lock(path)?;
+    OpenOptions::new().create(true).append(true).open(path)?.write_all(lines.as_bytes())
+}
+
+/// Replace the whole log with `events`, via a temporary file so a crash can't truncate it
+///
+/// Callers that read the log first hold its lock across both steps.
+pub fn write(path: &Path, events: &[Event]) -> io::Result<()> {
+    let lines: String = events.iter().map(format_line).collect();
+    filelock::write_replacing(path, lines.as_bytes())
+}
+
+/// Fold events before `cutoff` into one per alias and UTC day; later events are kept as they are
+pub fn compact(events: &[Event], cutoff: DateTime<Utc>) -> Vec<Event> {
+    let mut daily: BTreeMap<(DateTime<Utc>, &str), u64> = BTreeMap::new();
+    let mut recent = Vec::new();
+    for event in events {
+        if event.at < cutoff {
+            let day = event.at.date_naive().and_hms_opt(0, 0, 0).unwrap().and_utc();
+            *daily.entry((day, event.alias.as_str())).or_default() += event.count;
+        } else {
+            recent.push(event.clone());
+        }
+    }
+
+    let mut compacted: Vec<Event> = daily
+        .into_iter()
+        .map(|((at, alias), count)| Event { at, alias: alias.to_string(), count })
+        .collect();
+    compacted.extend(recent);
+    compacted
+}
+
+/// Read the whole log (a missing file is an empty log); malformed lines are skipped
+pub fn load(path: &Path) -> io::Result<Vec<Event>> {
+    if !path.exists() {
+        return Ok(Vec::new());
+    }
+    let mut events = Vec::new();
+    for line in BufReader::new(File::open(path)?).lines() {
+        if let Some(event) = parse_line(&line?) {
+            events.push(event);
+        }
+    }
+    Ok(events)
+}
+
+/// Parse one "epoch<TAB>alias[<TAB>count]" line
+fn parse_line(line: &str) -> Option<Event> {
+    let mut fields = line.split('\t');
+    let at = Utc.timestamp_opt(fields.next()?.parse().ok()?, 0).single()?;
+    let alias = fields.next().filter(|a| !a.is_empty())?;
+    let count = match fields.next() {
+        Some(count) => count.parse().ok()?,
+        None => 1,
+    };
+    Some(Event { at, alias: alias.to_string(), count })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    #[test]
+    fn test_append_and_load() {
+        let dir = tempdir().unwrap();
+        let path = dir.path().join("sub").join(FILE_NAME);
+        assert!(load(&path).unwrap().is_empty());
+
+        let at = Utc.timestamp_opt(1_700_000_000, 0).unwrap();
+        append(&path, &[Event::new(at, "proj")]).unwrap();
+        append(&path, &[]).unwrap();
+        append(&path, &[Event { at, alias: "work:api".to_string(), count: 4 }]).unwrap();
+        fs::write(&path, fs::read_to_string(&path).unwrap() + "garbage\n\t\n1\tx\tmany\n").unwrap();
+
+        let events = load(&path).unwrap();
+        assert_eq!(events.len(), 2);
+        assert_eq!(events[0], Event::new(at, "proj"));
+        assert_eq!(events[1].alias, "work:api");
+        assert_eq!(events[1].count, 4);
+
+        write(&path, &events[1..]).unwrap();
+        assert_eq!(fs::read_to_string(&path).unwrap(), "1700000000\twork:api\t4\n");
+    }
+
+    #[test]
+    fn test_compact() {
+        let day = Utc.with_ymd_and_hms(2026, 3, 1, 0, 0, 0).unwrap();
+        let hours = |h: i64| day + chrono::Duration::hours(h);
+        let events = vec![
+            Event::new(hours(9), "proj"),
+            Event::new(hours(15), "proj"),
+            Event::new(hours(16), "docs"),
+            Event { at: hours(30), alias: "proj".to_string(), count: 3 },
+            Event::new(hours(50), "proj"),
+            Event::new(hours(73), "proj"),
+        ];
+
+        let compacted = compact(&events, day + chrono::Duration::days(3));
+        assert_eq!(
+            compacted,
+            vec![
+                Event { at: day, alias: "docs".to_string(), count: 1 },
+                Event { at: day, alias: "proj".to_string(), count: 2 },
+                Event { at: hours(24), alias: "proj".to_string(), count: 3 },
+                Event { at: hours(48), alias: "proj".to_string(), count: 1 },
+                Event::new(hours(73), "proj"),
+            ]
+        );
+        let total = |events: &[Event]| events.iter().map(|e| e.count).sum::<u64>();
+        assert_eq!(total(&compacted), total(&events));
+        // Compacting again changes nothing
+        assert_eq!(compact(&compacted, day + chrono::Duration::days(3)), compacted);
+    }
+}
@@ -0,0 +1,82 @@
+//! Advisory locks serializing read-modify-write of goto's small data files
+//!
+//! The shell wrappers run goto in the background on every prompt, so two
+//! invocations can update the same file at once. Each takes the lock in
+//! `<file>.lock` first; the data file itself is still replaced by rename, so
+//! readers that don't lock never see it half-written.
+
+use std::fs::{self, File, OpenOptions};
+use std::io;
+use std::path::{Path, PathBuf};
+
+/// Held while a file is read, changed and written back; dropping it releases the lock
+pub struct FileLock {
+    _file: File,
+}
+
+/// Wait until no other goto process holds the lock for `path`, then take it
+pub fn lock(path: &Path) -> io::Result<FileLock> {
+    if let Some(parent) = path.parent() {
+        fs::create_dir_all(parent)?;
+    }
+    let file = OpenOptions::new()
+        .create(true)
+        .truncate(false)
+        .write(true)
+        .open(sibling(path, ".lock"))?;
+    file.lock()?;
+    Ok(FileLock { _file: file })
+}
+
+/// Replace `path` with `data` by writing `<path>.tmp` and renaming it over
+pub fn write_replacing(path: &Path, data: &[u8]) -> io::Result<()> {
+    let tmp = sibling(path, ".tmp");
+    fs::write(&tmp, data)?;
+    fs::rename(&tmp, path).inspect_err(|_| {
+        let _ = fs::remove_file(&tmp);
+    })
+}
+
+/// `path` with `suffix` appended to its file name
+fn sibling(path: &Path, suffix: &str) -> PathBuf {
+    let mut name = path.as_os_str().to_os_string();
+    name.push(suffix);
+    PathBuf::from(name)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::sync::atomic::{AtomicUsize, Ordering};
+    use std::sync::Arc;
+    use std::thread;
+    use tempfile::tempdir;
+
+    #[test]
+    fn test_lock_serializes_updates() {
+        let dir = tempdir().unwrap();
+        let path = Arc::new(dir.path().join("counter"));
+        fs::write(&*path, "0").unwrap();
+        let inside = Arc::new(AtomicUsize::new(0));
+
+        let workers: Vec<_> = (0..8)
+            .map(|_| {
+                let (path, inside) = (Arc::clone(&path), Arc::clone(&inside));
+                thread::spawn(move || {
+                    let _lock = lock(&path).unwrap();
+                    assert_eq!(inside.fetch_add(1, Ordering::SeqCst), 0);
+                    let n: u32 = fs::read_to_string(&*path).unwrap().parse().unwrap();
+                    thread::sleep(std::time::Duration::from_millis(5));
+                    write_replacing(&path, (n + 1).to_string().as_bytes()).unwrap();
+                    inside.fetch_sub(1, Ordering::SeqCst);
+                })
+            })
+            .collect();
+        for worker in workers {
+            worker.join().unwrap();
+        }
+
+        assert_eq!(fs::read_to_string(&*path).unwrap(), "8");
+        assert!(!dir.path().join("counter.tmp").exists());
+    }
+}
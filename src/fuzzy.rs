@@ -1,5 +1,9 @@
 use std::cmp::min;
 
+use unicode_normalization::UnicodeNormalization;
+
+use crate::profile;
+
 /// Match result with similarity score
 #[derive(Debug, Clone)]
 pub struct Match {
@@ -7,24 +11,77 @@ pub struct Match {
     pub similarity: f64,
 }
 
+/// How navigation treats a name that isn't an alias
+#[derive(Debug, Clone, Copy, PartialEq)]
+pub struct FuzzyPolicy {
+    /// Whether to look for similar names at all (`--no-fuzzy` turns this off)
+    pub enabled: bool,
+    /// Similarity (0.0-1.0) the best candidate needs before it's offered
+    pub threshold: f64,
+    /// How many candidates to offer at most
+    pub max_suggestions: usize,
+    /// Jump without asking when exactly one candidate reaches this similarity
+    pub auto_accept: Option<f64>,
+    /// Ask "Did you mean ...?" about the one candidate reaching this similarity,
+    /// instead of listing them all
+    pub confirm: Option<f64>,
+}
+
+impl Default for FuzzyPolicy {
+    fn default() -> Self {
+        Self {
+            enabled: true,
+            threshold: 0.7,
+            max_suggestions: 3,
+            auto_accept: None,
+            confirm: None,
+        }
+    }
+}
+
+impl FuzzyPolicy {
+    /// The threshold as a score out of 1000, as `find_matches` reports them
+    pub fn min_score(&self) -> i32 {
+        (self.threshold * 1000.0).round() as i32
+    }
+
+    /// The one candidate confident enough to take without asking, if there is exactly one
+    pub fn accepted<'a>(&self, candidates: &'a [(String, i32)]) -> Option<&'a str> {
+        only_above(candidates, self.auto_accept?)
+    }
+
+    /// The one candidate strong enough to ask about on its own, if there is exactly one
+    pub fn to_confirm<'a>(&self, candidates: &'a [(String, i32)]) -> Option<&'a str> {
+        only_above(candidates, self.confirm?)
+    }
+}
+
+/// The name of the only candidate reaching `similarity`, if exactly one does
+fn only_above(candidates: &[(String, i32)], similarity: f64) -> Option<&str> {
+    let score = (similarity * 1000.0).round() as i32;
+    match candidates.iter().filter(|(_, s)| *s >= score).collect::<Vec<_>>()[..] {
+        [(name, _)] => Some(name),
+        _ => None,
+    }
+}
+
 /// Calculate Levenshtein distance between two strings (case-insensitive)
+///
+/// Counted in characters of the NFC forms, so `ë` is one edit however it was typed.
 pub fn levenshtein_distance(s1: &str, s2: &str) -> usize {
-    let s1 = s1.to_lowercase();
-    let s2 = s2.to_lowercase();
+    let s1_chars: Vec<char> = s1.nfc().flat_map(char::to_lowercase).collect();
+    let s2_chars: Vec<char> = s2.nfc().flat_map(char::to_lowercase).collect();
 
-    if s1 == s2 {
+    if s1_chars == s2_chars {
         return 0;
     }
-    if s1.is_empty() {
-        return s2.len();
+    if s1_chars.is_empty() {
+        return s2_chars.len();
     }
-    if s2.is_empty() {
-        return s1.len();
+    if s2_chars.is_empty() {
+        return s1_chars.len();
     }
 
-    let s1_chars: Vec<char> = s1.chars().collect();
-    let s2_chars: Vec<char> = s2.chars().collect();
-
     let mut prev: Vec<usize> = (0..=s2_chars.len()).collect();
     let mut curr = vec![0; s2_chars.len() + 1];
 
@@ -57,7 +114,7 @@ pub fn similarity(s1: &str, s2: &str) -> f64 {
         return 1.0;
     }
 
-    let max_len = s1.len().max(s2.len());
+    let max_len = s1.nfc().count().max(s2.nfc().count());
     if max_len == 0 {
         return 1.0;
     }
@@ -68,6 +125,8 @@ pub fn similarity(s1: &str, s2: &str) -> f64 {
 
 /// Check if query is a substring of target (case-insensitive)
 pub fn is_substring(query: &str, target: &str) -> bool {
+    let query: String = query.nfc().collect();
+    let target: String = target.nfc().collect();
     target.to_lowercase().contains(&query.to_lowercase())
 }
 
@@ -87,7 +146,7 @@ pub fn find_similar(query: &str, candidates: &[String], threshold: f64) -> Vec<M
 
         // Boost score for substring matches
         if is_substring(query, candidate) {
-            let substring_boost = query.len() as f64 / candidate.len() as f64;
+            let substring_boost = query.chars().count() as f64 / candidate.chars().count() as f64;
             sim = sim.max(0.5 + substring_boost * 0.5);
         }
 
@@ -122,6 +181,7 @@ pub fn find_similar_names(query: &str, candidates: &[String], threshold: f64) ->
 /// Returns matches sorted by score (highest first).
 /// This function provides compatibility with code expecting the old interface.
 pub fn find_matches<'a>(query: &str, candidates: impl Iterator<Item = &'a str>) -> Vec<(&'a str, i32)> {
+    let _phase = profile::phase("fuzzy matching");
     if query.is_empty() {
         // Return all candidates with score 0 for empty query
         return candidates.map(|c| (c, 0)).collect();
@@ -134,7 +194,7 @@ pub fn find_matches<'a>(query: &str, candidates: impl Iterator<Item = &'a str>)
 
         // Boost for substring matches
         let boosted_sim = if is_substring(query, candidate) {
-            let substring_boost = query.len() as f64 / candidate.len() as f64;
+            let substring_boost = query.chars().count() as f64 / candidate.chars().count() as f64;
             sim.max(0.5 + substring_boost * 0.5)
         } else {
             sim
@@ -160,6 +220,39 @@ pub fn find_matches<'a>(query: &str, candidates: impl Iterator<Item = &'a str>)
 mod tests {
     use super::*;
 
+    #[test]
+    fn test_policy_accepted_and_to_confirm() {
+        let candidates = vec![("dev".to_string(), 900), ("docs".to_string(), 700)];
+        let policy = FuzzyPolicy::default();
+        assert_eq!(policy.accepted(&candidates), None);
+        assert_eq!(policy.to_confirm(&candidates), None);
+
+        let policy = FuzzyPolicy { auto_accept: Some(0.85), confirm: Some(0.85), ..Default::default() };
+        assert_eq!(policy.accepted(&candidates), Some("dev"));
+        assert_eq!(policy.to_confirm(&candidates), Some("dev"));
+        // Two strong candidates: neither is taken or asked about on its own
+        let policy = FuzzyPolicy { confirm: Some(0.6), ..Default::default() };
+        assert_eq!(policy.to_confirm(&candidates), None);
+    }
+
+    #[test]
+    fn test_levenshtein_counts_characters() {
+        assert_eq!(levenshtein_distance("projëkt", "projekt"), 1);
+        assert_eq!(levenshtein_distance("proje\u{0308}kt", "projëkt"), 0);
+        assert_eq!(levenshtein_distance("ÄRGER", "ärger"), 0);
+        assert_eq!(levenshtein_distance("", "日本"), 2);
+    }
+
+    #[test]
+    fn test_similarity_unicode() {
+        // One substituted letter out of seven, not out of eight bytes
+        assert!((similarity("projekt", "projëkt") - 6.0 / 7.0).abs() < 1e-9);
+        assert_eq!(similarity("proje\u{0308}kt", "projëkt"), 1.0);
+        let matches = find_matches("projekt", ["projëkt", "work"].into_iter());
+        assert_eq!(matches[0].0, "projëkt");
+        assert!(matches[0].1 >= 850);
+    }
+
     #[test]
     fn test_levenshtein_identical() {
         assert_eq!(levenshtein_distance("hello", "hello"), 0);
@@ -0,0 +1,145 @@
+//! Visited-directory history recorded by the shell wrapper's cd hook
+
+use std::collections::HashMap;
+use std::fmt::Write;
+use std::fs::{self, File};
+use std::io::{BufRead, BufReader};
+use std::path::PathBuf;
+
+use chrono::{DateTime, TimeZone, Utc};
+
+/// Keep at most this many directories; the least visited are dropped first
+const MAX_ENTRIES: usize = 1000;
+
+/// Visit counters for one directory
+#[derive(Debug, Clone, PartialEq)]
+pub struct Visit {
+    pub path: String,
+    pub count: u64,
+    pub last_visit: DateTime<Utc>,
+}
+
+/// Directory visit history, one "count<TAB>epoch<TAB>path" line per directory
+pub struct DirHistory {
+    path: PathBuf,
+    visits: HashMap<String, Visit>,
+}
+
+impl DirHistory {
+    /// Load the history file (missing file means empty history)
+    pub fn load(path: PathBuf) -> std::io::Result<Self> {
+        let mut visits = HashMap::new();
+
+        if path.exists() {
+            let reader = BufReader::new(File::open(&path)?);
+            for line in reader.lines() {
+                if let Some(visit) = parse_line(&line?) {
+                    visits.insert(visit.path.clone(), visit);
+                }
+            }
+        }
+
+        Ok(Self { path, visits })
+    }
+
+    /// Count a visit to `dir`
+    pub fn record(&mut self, dir: &str) {
+        let now = Utc::now();
+        let visit = self.visits.entry(dir.to_string()).or_insert_with(|| Visit {
+            path: dir.to_string(),
+            count: 0,
+            last_visit: now,
+        });
+        visit.count += 1;
+        visit.last_visit = now;
+    }
+
+    /// All recorded visits, most visited first
+    pub fn visits(&self) -> Vec<&Visit> {
+        let mut visits: Vec<_> = self.visits.values().collect();
+        visits.sort_by(|a, b| {
+            b.count
+                .cmp(&a.count)
+                .then(b.last_visit.cmp(&a.last_visit))
+                .then(a.path.cmp(&b.path))
+        });
+        visits
+    }
+
+    /// Write the history back to disk, trimmed to the most visited entries
+    ///
+    /// The file is replaced in one step; callers updating it should hold
+    /// [`crate::filelock::lock`] from load to save.
+    pub fn save(&self) -> std::io::Result<()> {
+        if let Some(parent) = self.path.parent() {
+            fs::create_dir_all(parent)?;
+        }
+
+        let mut content = String::new();
+        for visit in self.visits().into_iter().take(MAX_ENTRIES) {
+            writeln!(
+                content,
+                "{}\t{}\t{}",
+                visit.count,
+                visit.last_visit.timestamp(),
+                visit.path
+            )
+            .unwrap();
+        }
+        crate::filelock::write_replacing(&self.path, content.as_bytes())
+    }
+}
+
+/// Parse one history line, skipping anything malformed
+fn parse_line(line: &str) -> Option<Visit> {
+    let mut parts = line.splitn(3, '\t');
+    let count = parts.next()?.parse().ok()?;
+    let last_visit = Utc.timestamp_opt(parts.next()?.parse().ok()?, 0).single()?;
+    let path = parts.next()?.to_string();
+    if path.is_empty() {
+        return None;
+    }
+
+    Some(Visit {
+        path,
+        count,
+        last_visit,
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    #[test]
+    fn test_record_and_reload() {
+        let dir = tempdir().unwrap();
+        let path = dir.path().join("dir_history");
+
+        let mut history = DirHistory::load(path.clone()).unwrap();
+        history.record("/srv/a");
+        history.record("/srv/b");
+        history.record("/srv/b");
+        history.save().unwrap();
+
+        let history = DirHistory::load(path).unwrap();
+        let visits = history.visits();
+        assert_eq!(visits.len(), 2);
+        assert_eq!(visits[0].path, "/srv/b");
+        assert_eq!(visits[0].count, 2);
+        assert_eq!(visits[1].count, 1);
+    }
+
+    #[test]
+    fn test_load_skips_malformed_lines() {
+        let dir = tempdir().unwrap();
+        let path = dir.path().join("dir_history");
+        fs::write(&path, "3\t1700000000\t/srv/with\ttab\nnot a line\n2\tx\t/srv/b\n").unwrap();
+
+        let history = DirHistory::load(path).unwrap();
+        let visits = history.visits();
+        assert_eq!(visits.len(), 1);
+        assert_eq!(visits[0].path, "/srv/with\ttab");
+    }
+}
@@ -0,0 +1,98 @@
+//! Pre-navigation hooks: shell commands that can veto a jump
+
+use std::collections::BTreeMap;
+use std::io;
+use std::process::{Command, Stdio};
+
+use serde::{Deserialize, Serialize};
+
+use crate::alias::AliasError;
+
+/// `[hooks]` section of config.toml
+///
+/// Hooks live in the user's config rather than in the alias database, so
+/// importing someone else's aliases never runs their commands.
+#[derive(Debug, Clone, Serialize, Deserialize, Default, PartialEq)]
+pub struct HooksConfig {
+    /// Run before every jump; a non-zero exit cancels it
+    #[serde(default)]
+    pub pre_navigate: String,
+
+    /// Run before jumping to a specific alias, after the global hook
+    #[serde(default)]
+    pub alias: BTreeMap<String, String>,
+}
+
+impl HooksConfig {
+    /// The hook commands that apply to `alias`, global first
+    pub fn pre_navigate_for(&self, alias: &str) -> Vec<&str> {
+        [Some(self.pre_navigate.as_str()), self.alias.get(alias).map(String::as_str)]
+            .into_iter()
+            .flatten()
+            .map(str::trim)
+            .filter(|cmd| !cmd.is_empty())
+            .collect()
+    }
+}
+
+/// Run the pre-navigate hooks for `alias` before jumping to `path`
+///
+/// Each hook runs with `sh -c`, with `GOTO_ALIAS` and `GOTO_PATH` set. Its
+/// output goes to stderr so the shell wrapper doesn't mistake it for the
+/// target. The first hook that exits non-zero (or can't be started) stops
+/// the jump.
+pub fn run_pre_navigate(hooks: &HooksConfig, alias: &str, path: &str) -> Result<(), Box<dyn std::error::Error>> {
+    for cmd in hooks.pre_navigate_for(alias) {
+        let status = Command::new("sh")
+            .arg("-c")
+            .arg(cmd)
+            .env("GOTO_ALIAS", alias)
+            .env("GOTO_PATH", path)
+            .stdout(Stdio::from(io::stderr()))
+            .status()
+            .map_err(|e| format!("pre-navigate hook '{}' failed to run: {}", cmd, e))?;
+
+        if !status.success() {
+            let code = status
+                .code()
+                .map(|c| format!("exit {}", c))
+                .unwrap_or_else(|| "killed".to_string());
+            return Err(AliasError::HookVetoed(alias.to_string(), cmd.to_string(), code).into());
+        }
+    }
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn hooks(global: &str, alias: &[(&str, &str)]) -> HooksConfig {
+        HooksConfig {
+            pre_navigate: global.to_string(),
+            alias: alias.iter().map(|(a, c)| (a.to_string(), c.to_string())).collect(),
+        }
+    }
+
+    #[test]
+    fn test_pre_navigate_for() {
+        let config = hooks("check-hours", &[("prod", "confirm-prod"), ("blank", "  ")]);
+        assert_eq!(config.pre_navigate_for("prod"), vec!["check-hours", "confirm-prod"]);
+        assert_eq!(config.pre_navigate_for("blank"), vec!["check-hours"]);
+        assert!(HooksConfig::default().pre_navigate_for("prod").is_empty());
+    }
+
+    #[test]
+    fn test_run_pre_navigate() {
+        let config = hooks(
+            "test \"$GOTO_PATH\" = /srv/app",
+            &[("app", "test \"$GOTO_ALIAS\" = app"), ("prod", "exit 3")],
+        );
+        assert!(run_pre_navigate(&config, "app", "/srv/app").is_ok());
+        assert!(run_pre_navigate(&config, "app", "/elsewhere").is_err());
+
+        let err = run_pre_navigate(&config, "prod", "/srv/app").unwrap_err().to_string();
+        assert!(err.contains("cancelled"));
+        assert!(err.contains("exit 3"));
+    }
+}
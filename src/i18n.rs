@@ -0,0 +1,537 @@
+//! Translations of user-facing messages
+//!
+//! The English text is the lookup key, as with gettext: `tr("just now")`
+//! returns the text in the current language, or the key itself when there is
+//! no translation. Formatted messages use `{}` placeholders filled in order.
+
+use std::env;
+use std::fmt::Display;
+use std::sync::OnceLock;
+
+/// A language goto has messages for
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Lang {
+    En,
+    De,
+}
+
+impl Lang {
+    /// Parse a locale like "de", "de_DE.UTF-8" or "de-AT"; None if unsupported
+    pub fn from_locale(locale: &str) -> Option<Self> {
+        let code = locale.split(['_', '-', '.', '@']).next()?.to_ascii_lowercase();
+        match code.as_str() {
+            "en" | "c" | "posix" => Some(Lang::En),
+            "de" => Some(Lang::De),
+            _ => None,
+        }
+    }
+
+    /// Language from GOTO_LANG, then LC_ALL, LC_MESSAGES and LANG
+    ///
+    /// The first variable that is set decides, falling back to English if it
+    /// names a language goto doesn't have.
+    pub fn from_env() -> Self {
+        ["GOTO_LANG", "LC_ALL", "LC_MESSAGES", "LANG"]
+            .iter()
+            .filter_map(|var| env::var(var).ok())
+            .find(|value| !value.is_empty())
+            .and_then(|value| Self::from_locale(&value))
+            .unwrap_or(Lang::En)
+    }
+
+    fn catalog(self) -> &'static [(&'static str, &'static str)] {
+        match self {
+            Lang::En => &[],
+            Lang::De => DE,
+        }
+    }
+}
+
+static LANG: OnceLock<Lang> = OnceLock::new();
+
+/// The language messages are shown in
+pub fn lang() -> Lang {
+    // Unit tests compare against English whatever the developer's locale is
+    if cfg!(test) {
+        return Lang::En;
+    }
+    *LANG.get_or_init(Lang::from_env)
+}
+
+/// Translation of `text` in `lang`, if there is one
+pub fn lookup(lang: Lang, text: &str) -> Option<&'static str> {
+    lang.catalog().iter().find(|(en, _)| *en == text).map(|(_, t)| *t)
+}
+
+/// `text` in the current language
+pub fn tr(text: &'static str) -> &'static str {
+    lookup(lang(), text).unwrap_or(text)
+}
+
+/// Replace each `{}` in `template` with the next argument
+pub fn fill(template: &str, args: &[&dyn Display]) -> String {
+    let mut parts = template.split("{}");
+    let mut out = parts.next().unwrap_or_default().to_string();
+    for (i, part) in parts.enumerate() {
+        if let Some(arg) = args.get(i) {
+            out.push_str(&arg.to_string());
+        }
+        out.push_str(part);
+    }
+    out
+}
+
+/// The arguments that turn `template` into `text`, if it is an instance of it
+fn match_template<'a>(template: &str, text: &'a str) -> Option<Vec<&'a str>> {
+    let parts: Vec<&str> = template.split("{}").collect();
+    let mut rest = text.strip_prefix(parts[0])?;
+    let mut args = Vec::new();
+    for (i, part) in parts.iter().enumerate().skip(1) {
+        if i == parts.len() - 1 {
+            args.push(rest.strip_suffix(part)?);
+            rest = "";
+        } else {
+            let end = rest.find(part)?;
+            args.push(&rest[..end]);
+            rest = &rest[end + part.len()..];
+        }
+    }
+    rest.is_empty().then_some(args)
+}
+
+/// Translate an already formatted message, such as an error's text
+///
+/// Messages that match no catalog entry are returned unchanged.
+pub fn tr_message(text: &str) -> String {
+    tr_message_in(lang(), text)
+}
+
+/// [`tr_message`] for a given language
+pub fn tr_message_in(lang: Lang, text: &str) -> String {
+    if let Some(translated) = lookup(lang, text) {
+        return translated.to_string();
+    }
+    for (template, translated) in lang.catalog().iter().filter(|(en, _)| en.contains("{}")) {
+        if let Some(args) = match_template(template, text) {
+            let args: Vec<&dyn Display> = args.iter().map(|a| a as &dyn Display).collect();
+            return fill(translated, &args);
+        }
+    }
+    text.to_string()
+}
+
+/// Translate the headings and descriptions of help text laid out like `cli::HELP`
+///
+/// Usage columns stay as they are; untranslated lines are kept in English.
+pub fn localize_help(help: &str, lang: Lang) -> String {
+    let mut out = String::with_capacity(help.len());
+    for line in help.lines() {
+        let entry = line.trim_start();
+        let indent = &line[..line.len() - entry.len()];
+        let translated = if let Some(t) = lookup(lang, entry) {
+            // A wrapped description, or a heading
+            format!("{}{}", indent, t)
+        } else if let Some(t) = entry.strip_prefix("- ").and_then(|item| lookup(lang, item)) {
+            format!("{}- {}", indent, t)
+        } else {
+            match entry.find("  ") {
+                Some(i) => {
+                    let description = entry[i..].trim_start();
+                    match lookup(lang, description) {
+                        Some(t) => format!("{}{}", &line[..line.len() - description.len()], t),
+                        None => line.to_string(),
+                    }
+                }
+                None => line.to_string(),
+            }
+        };
+        out.push_str(&translated);
+        out.push('\n');
+    }
+    out
+}
+
+/// German messages
+static DE: &[(&str, &str)] = &[
+    // Time ago
+    ("never", "nie"),
+    ("just now", "gerade eben"),
+    ("1 minute ago", "vor 1 Minute"),
+    ("{} minutes ago", "vor {} Minuten"),
+    ("1 hour ago", "vor 1 Stunde"),
+    ("{} hours ago", "vor {} Stunden"),
+    ("1 day ago", "vor 1 Tag"),
+    ("{} days ago", "vor {} Tagen"),
+    ("1 week ago", "vor 1 Woche"),
+    ("{} weeks ago", "vor {} Wochen"),
+    ("1 month ago", "vor 1 Monat"),
+    ("{} months ago", "vor {} Monaten"),
+    // Errors
+    ("alias '{}' not found", "Alias '{}' nicht gefunden"),
+    ("alias '{}' already exists", "Alias '{}' existiert bereits"),
+    ("invalid alias '{}': {}", "ungültiger Alias '{}': {}"),
+    ("invalid tag '{}': {}", "ungültiger Tag '{}': {}"),
+    ("directory does not exist: {}", "Verzeichnis existiert nicht: {}"),
+    ("not a directory: {}", "kein Verzeichnis: {}"),
+    ("file does not exist: {}", "Datei existiert nicht: {}"),
+    ("not a file: {}", "keine Datei: {}"),
+    (
+        "alias '{}' points to {}, which has no parent",
+        "Alias '{}' zeigt auf {}, das kein übergeordnetes Verzeichnis hat",
+    ),
+    (
+        "alias '{}' is a file bookmark; it has no subdirectories",
+        "Alias '{}' ist ein Datei-Lesezeichen und hat keine Unterverzeichnisse",
+    ),
+    ("permission denied: cannot enter {}", "Zugriff verweigert: {} kann nicht betreten werden"),
+    (
+        "path not allowed: {} is {} (see [register] in config.toml)",
+        "Pfad nicht erlaubt: {} ist {} (siehe [register] in config.toml)",
+    ),
+    (
+        "timed out after {}s checking {} (unresponsive mount? retry with --no-check)",
+        "Zeitüberschreitung nach {}s beim Prüfen von {} (Mount reagiert nicht? Mit --no-check erneut versuchen)",
+    ),
+    (
+        "alias '{}' expired on {} (re-register it with --force to keep it, or remove it with 'goto -c')",
+        "Alias '{}' ist am {} abgelaufen (mit --force neu registrieren, um ihn zu behalten, oder mit 'goto -c' entfernen)",
+    ),
+    ("directory stack is empty", "Verzeichnisstapel ist leer"),
+    ("stack is empty", "Stapel ist leer"),
+    ("Navigation cancelled", "Wechsel abgebrochen"),
+    ("no aliases registered yet", "noch keine Aliase registriert"),
+    ("tag or metadata '{}' not found on any alias", "Tag oder Metadaten '{}' an keinem Alias gefunden"),
+    ("IO error: {}", "E/A-Fehler: {}"),
+    // Help headings
+    ("goto - Navigate to aliased directories", "goto - Per Alias in Verzeichnisse wechseln"),
+    ("Usage:", "Verwendung:"),
+    ("Sort options (use with -l/--list):", "Sortieroptionen (mit -l/--list):"),
+    ("Paging options (use with -l/--list):", "Seitenoptionen (mit -l/--list):"),
+    ("Filter options (use with -l/--list):", "Filteroptionen (mit -l/--list):"),
+    ("Import strategies (use with -i/--import):", "Importstrategien (mit -i/--import):"),
+    ("Formats (use with -e/--export and -i/--import):", "Formate (mit -e/--export und -i/--import):"),
+    ("Install options (use with --install):", "Installationsoptionen (mit --install):"),
+    ("Configuration (edit ~/.config/goto/config.toml):", "Konfiguration (in ~/.config/goto/config.toml):"),
+    ("Tag rules:", "Tag-Regeln:"),
+    ("Examples:", "Beispiele:"),
+    // Help: commands
+    ("Navigate to the directory", "Zum Verzeichnis wechseln"),
+    ("Navigate to a directory below the alias", "Zu einem Verzeichnis unterhalb des Alias wechseln"),
+    (
+        "Navigate to the directory containing the alias (or <alias>..)",
+        "In das Verzeichnis wechseln, das den Alias enthält (oder <alias>..)",
+    ),
+    ("Navigate to row N of the last -l/--recent table", "Zu Zeile N der letzten -l/--recent-Tabelle wechseln"),
+    ("Navigate to a random alias (optionally with a tag)", "Zu einem zufälligen Alias wechseln (optional mit Tag)"),
+    ("Register a new alias", "Neuen Alias registrieren"),
+    ("Register with tags (comma-separated)", "Mit Tags registrieren (durch Komma getrennt)"),
+    ("Overwrite an existing alias, skip tag confirmation", "Bestehenden Alias überschreiben, ohne Tag-Rückfrage"),
+    ("Register with a one-line description", "Mit einzeiliger Beschreibung registrieren"),
+    (
+        "Register a temporary alias (hidden from navigation once expired)",
+        "Temporären Alias registrieren (nach Ablauf nicht mehr anspringbar)",
+    ),
+    ("Create, or repoint if the path differs (idempotent)", "Anlegen oder umleiten, falls der Pfad abweicht (idempotent)"),
+    ("Create only if the alias doesn't exist yet", "Nur anlegen, wenn der Alias noch nicht existiert"),
+    (
+        "Register each go.work/npm/Cargo workspace member as root:member",
+        "Jedes go.work/npm/Cargo-Workspace-Mitglied als root:mitglied registrieren",
+    ),
+    (
+        "Merge the aliases of every .goto-aliases file below root",
+        "Aliase aller .goto-aliases-Dateien unterhalb von root übernehmen",
+    ),
+    (
+        "Run register/tag/unregister/... lines from stdin, saving once",
+        "register/tag/unregister/...-Zeilen von stdin ausführen, einmal speichern",
+    ),
+    (
+        "Register each directory read from stdin (derived names)",
+        "Jedes Verzeichnis von stdin registrieren (abgeleitete Namen)",
+    ),
+    ("Unregister an alias", "Alias entfernen"),
+    ("Unregister every matching alias (after confirming)", "Alle passenden Aliase entfernen (nach Rückfrage)"),
+    ("Unregister every alias with a tag", "Alle Aliase mit einem Tag entfernen"),
+    ("Pick aliases to unregister from a list", "Zu entfernende Aliase aus einer Liste wählen"),
+    ("List all aliases", "Alle Aliase auflisten"),
+    ("List aliases with sorting", "Aliase sortiert auflisten"),
+    ("List aliases with tag", "Aliase mit Tag auflisten"),
+    ("Show a tree of aliases grouped by tag", "Aliase als Baum nach Tag gruppiert anzeigen"),
+    ("Show a tree grouped by shared location", "Baum nach gemeinsamem Ort gruppiert anzeigen"),
+    ("Mark aliases whose directory is missing", "Aliase mit fehlendem Verzeichnis markieren"),
+    ("List only aliases whose directory is missing", "Nur Aliase mit fehlendem Verzeichnis auflisten"),
+    ("Add created, last used, uses, tags and description columns", "Spalten für Erstellung, letzte Nutzung, Nutzungen, Tags und Beschreibung"),
+    ("Add a column with each directory's disk usage", "Spalte mit dem Speicherverbrauch jedes Verzeichnisses"),
+    ("Add a column with the branch and dirty state of git repos", "Spalte mit Branch und Änderungsstatus von Git-Repositories"),
+    ("Add a column with the project type (rust, go, node, ...)", "Spalte mit dem Projekttyp (rust, go, node, ...)"),
+    ("Expand alias to path", "Alias zum Pfad auflösen"),
+    ("Print the path as a file:// URI", "Pfad als file://-URI ausgeben"),
+    (
+        "Copy the alias's path to the clipboard (not --copy)",
+        "Pfad des Alias in die Zwischenablage kopieren (nicht --copy)",
+    ),
+    (
+        "Open the alias's file or directory with the default application",
+        "Datei oder Verzeichnis des Alias mit der Standardanwendung öffnen",
+    ),
+    ("Edit the alias's file in $VISUAL or $EDITOR", "Datei des Alias in $VISUAL oder $EDITOR bearbeiten"),
+    (
+        "no clipboard tool found (install wl-copy, xclip or xsel, or set GOTO_CLIPBOARD)",
+        "kein Zwischenablage-Programm gefunden (wl-copy, xclip oder xsel installieren oder GOTO_CLIPBOARD setzen)",
+    ),
+    ("Explain how a name resolves (no navigation)", "Erklären, wie ein Name aufgelöst wird (ohne Wechsel)"),
+    ("Show all details for an alias", "Alle Details eines Alias anzeigen"),
+    (
+        "Show the subdirectories below an alias (default depth 2)",
+        "Unterverzeichnisse eines Alias anzeigen (Standardtiefe 2)",
+    ),
+    ("Cleanup invalid aliases", "Ungültige Aliase bereinigen"),
+    ("List invalid aliases (don't remove)", "Ungültige Aliase auflisten (nicht entfernen)"),
+    (
+        "Score every alias: missing, duplicate, nested, unused, untagged",
+        "Jeden Alias bewerten: fehlend, doppelt, verschachtelt, ungenutzt, ohne Tags",
+    ),
+    (
+        "Count aliases unused for N days as unused (default 90)",
+        "Aliase ohne Nutzung seit N Tagen als ungenutzt werten (Standard 90)",
+    ),
+    (
+        "Markdown summary of the last 7 days (or since 2w, a date, ...)",
+        "Markdown-Zusammenfassung der letzten 7 Tage (oder seit 2w, einem Datum, ...)",
+    ),
+    (
+        "Compact the navigation log and drop surplus backups (for cron)",
+        "Navigationsprotokoll verdichten und überzählige Backups löschen (für cron)",
+    ),
+    (
+        "Keep the last N days of the log uncompacted (default 30)",
+        "Die letzten N Tage des Protokolls unverdichtet lassen (Standard 30)",
+    ),
+    (
+        "Check every entry and the checksum (and repair trivial problems)",
+        "Jeden Eintrag und die Prüfsumme prüfen (und einfache Fehler beheben)",
+    ),
+    (
+        "Check paths, compact the log, drop backups; write a report file",
+        "Pfade prüfen, Protokoll verdichten, Backups löschen; Bericht in Datei schreiben",
+    ),
+    ("Push current dir, goto alias", "Aktuelles Verzeichnis sichern, zum Alias wechseln"),
+    ("Pop and return to directory", "Zum gesicherten Verzeichnis zurückkehren"),
+    (
+        "Go to the top of the stack, leaving this dir there",
+        "Zum obersten Stapeleintrag wechseln, aktuelles Verzeichnis dort ablegen",
+    ),
+    (
+        "Go to the git/hg repository root (and give it an alias)",
+        "Zur Wurzel des git/hg-Repositorys wechseln (und ihr einen Alias geben)",
+    ),
+    ("Rename an alias", "Alias umbenennen"),
+    ("Copy an alias (same path and tags, fresh usage)", "Alias kopieren (gleicher Pfad und Tags, neue Statistik)"),
+    ("Point alias at a new directory", "Alias auf ein neues Verzeichnis zeigen lassen"),
+    ("Never check the alias directory before jumping", "Verzeichnis des Alias vor dem Wechsel nie prüfen"),
+    ("Rewrite a path prefix in all aliases (--dry-run)", "Pfadpräfix in allen Aliasen ersetzen (--dry-run)"),
+    ("Add tag to alias", "Tag zu Alias hinzufügen"),
+    ("Add tag without confirmation", "Tag ohne Rückfrage hinzufügen"),
+    ("Add tag to several aliases", "Tag zu mehreren Aliasen hinzufügen"),
+    ("Tag every alias under a path", "Alle Aliase unter einem Pfad taggen"),
+    ("Same, shorter", "Dasselbe, kürzer"),
+    ("Remove tag from alias", "Tag von Alias entfernen"),
+    ("Set metadata on alias (key= removes it)", "Metadaten am Alias setzen (key= entfernt sie)"),
+    ("Rename tag across all aliases", "Tag in allen Aliasen umbenennen"),
+    ("Rename without confirmation", "Ohne Rückfrage umbenennen"),
+    ("Preview changes only", "Nur Vorschau der Änderungen"),
+    ("Apply [[autotag]] rules to existing aliases", "[[autotag]]-Regeln auf bestehende Aliase anwenden"),
+    ("List all tags with counts", "Alle Tags mit Anzahl auflisten"),
+    ("Show usage statistics", "Nutzungsstatistik anzeigen"),
+    ("List recently visited directories", "Zuletzt besuchte Verzeichnisse auflisten"),
+    ("Navigate to Nth most recent", "Zum N-letzten Verzeichnis wechseln"),
+    ("List N most recent (never navigates)", "Die N letzten auflisten (wechselt nie)"),
+    ("Navigate to Nth most recent (never lists)", "Zum N-letzten wechseln (listet nie)"),
+    ("Navigate to the most recently used alias", "Zum zuletzt benutzten Alias wechseln"),
+    ("Pick a recent directory from a menu", "Kürzlich besuchtes Verzeichnis aus einem Menü wählen"),
+    ("Only show visits since 7d, 12h, 2w or a date", "Nur Besuche seit 7d, 12h, 2w oder einem Datum"),
+    ("Only show aliases with a tag (or key=value)", "Nur Aliase mit einem Tag (oder key=value) zeigen"),
+    ("Clear recent history", "Verlauf löschen"),
+    ("Record a use without navigating", "Nutzung erfassen, ohne zu wechseln"),
+    ("Zero use counts (one alias or all)", "Nutzungszähler zurücksetzen (ein Alias oder alle)"),
+    ("Also clear last-visited times", "Auch Zeitpunkte des letzten Besuchs löschen"),
+    ("Export aliases to TOML (stdout or file)", "Aliase als TOML exportieren (stdout oder Datei)"),
+    ("Export with paths under $HOME as ~/...", "Pfade unter $HOME als ~/... exportieren"),
+    (
+        "Import aliases from file ('-' for stdin, or an http(s) URL)",
+        "Aliase aus Datei importieren ('-' für stdin oder eine http(s)-URL)",
+    ),
+    ("Show current configuration", "Aktuelle Konfiguration anzeigen"),
+    ("Install shell integration", "Shell-Integration installieren"),
+    (
+        "Print alias parent directories as a CDPATH value",
+        "Elternverzeichnisse der Aliase als CDPATH-Wert ausgeben",
+    ),
+    (
+        "Print aliases as export GOTO_DIR_NAME=\"path\" lines",
+        "Aliase als export GOTO_DIR_NAME=\"pfad\"-Zeilen ausgeben",
+    ),
+    ("Run the first-time setup wizard", "Einrichtungsassistenten starten"),
+    ("Suggest aliases for often-visited directories", "Aliase für häufig besuchte Verzeichnisse vorschlagen"),
+    ("Show the active context (namespace)", "Aktiven Kontext (Namespace) anzeigen"),
+    ("Switch context; bare names resolve in <name>:", "Kontext wechseln; einfache Namen gelten in <name>:"),
+    ("Clear the context / list available contexts", "Kontext zurücksetzen / verfügbare Kontexte auflisten"),
+    (
+        "Save the stack and these directories as a session",
+        "Stapel und diese Verzeichnisse als Sitzung speichern",
+    ),
+    (
+        "Refill the stack (or open tmux windows) and go back",
+        "Stapel wiederherstellen (oder tmux-Fenster öffnen) und zurückkehren",
+    ),
+    ("List sessions / forget one", "Sitzungen auflisten / eine vergessen"),
+    ("Update goto to latest version", "goto auf die neueste Version aktualisieren"),
+    ("Check for available updates", "Nach Updates suchen"),
+    ("Print this help as a man page (goto.1)", "Diese Hilfe als Manpage ausgeben (goto.1)"),
+    ("Snooze stale alias notification for N days", "Hinweis auf veraltete Aliase für N Tage aussetzen"),
+    ("List database backups or restore backup N", "Datenbank-Backups auflisten oder Backup N wiederherstellen"),
+    ("Serve the HTTP API (token from GOTO_API_TOKEN)", "HTTP-API bereitstellen (Token aus GOTO_API_TOKEN)"),
+    ("Navigate without recording usage (any command)", "Ohne Nutzungserfassung wechseln (jeder Befehl)"),
+    ("Use another database directory (like GOTO_DB)", "Anderes Datenbankverzeichnis verwenden (wie GOTO_DB)"),
+    (
+        "Skip confirmation prompts (unregister, cleanup, import)",
+        "Rückfragen überspringen (Entfernen, Bereinigen, Import)",
+    ),
+    ("NUL-separated paths from -l, -x and --recent", "NUL-getrennte Pfade bei -l, -x und --recent"),
+    ("Navigate without checking the directory (slow mounts)", "Ohne Verzeichnisprüfung wechseln (langsame Mounts)"),
+    (
+        "Navigate only on an exact name; never offer similar ones",
+        "Nur bei exaktem Namen wechseln; nie ähnliche anbieten",
+    ),
+    (
+        "Similarity a name needs to be offered (fuzzy_threshold)",
+        "Nötige Ähnlichkeit, um einen Namen anzubieten (fuzzy_threshold)",
+    ),
+    ("Offer at most N similar names (default 3)", "Höchstens N ähnliche Namen anbieten (Standard 3)"),
+    (
+        "Time config load, database load, fuzzy matching and save",
+        "Laden der Konfiguration, Laden der Datenbank, unscharfe Suche und Speichern messen",
+    ),
+    ("Write those timings to a file instead of stderr", "Diese Zeiten in eine Datei statt nach stderr schreiben"),
+    (
+        "Write peak and current memory use to a file (Linux)",
+        "Höchsten und aktuellen Speicherverbrauch in eine Datei schreiben (Linux)",
+    ),
+    ("Show version", "Version anzeigen"),
+    ("Version, commit, build date, rustc and schema as JSON", "Version, Commit, Build-Datum, rustc und Schema als JSON"),
+    ("Show this help", "Diese Hilfe anzeigen"),
+    // Help: options
+    ("Sort alphabetically (default)", "Alphabetisch sortieren (Standard)"),
+    ("Sort by use count (most used first)", "Nach Nutzungsanzahl sortieren (häufigste zuerst)"),
+    ("Sort by last used (most recent first)", "Nach letzter Nutzung sortieren (neueste zuerst)"),
+    ("Sort by registration time (newest first)", "Nach Registrierungszeit sortieren (neueste zuerst)"),
+    ("Sort by directory path", "Nach Verzeichnispfad sortieren"),
+    ("Invert the sort order", "Sortierreihenfolge umkehren"),
+    ("Show at most N aliases", "Höchstens N Aliase anzeigen"),
+    ("Skip the first N aliases", "Die ersten N Aliase überspringen"),
+    ("Show only aliases with tag or metadata", "Nur Aliase mit Tag oder Metadaten anzeigen"),
+    ("Show only aliases named <ns>:<name>", "Nur Aliase namens <ns>:<name> anzeigen"),
+    ("Show only aliases under a path (e.g., '~/code/**')", "Nur Aliase unter einem Pfad anzeigen (z. B. '~/code/**')"),
+    ("Show only aliases whose name matches a regex", "Nur Aliase, deren Name auf einen Regex passt"),
+    ("Also match --regex against paths", "--regex auch auf Pfade anwenden"),
+    ("Skip existing aliases (default)", "Bestehende Aliase überspringen (Standard)"),
+    ("Overwrite existing aliases", "Bestehende Aliase überschreiben"),
+    ("Rename conflicting aliases (add suffix)", "Kollidierende Aliase umbenennen (mit Suffix)"),
+    ("Refuse the import unless the file has this checksum", "Import nur, wenn die Datei diese Prüfsumme hat"),
+    ("TOML (default for export)", "TOML (Standard beim Export)"),
+    ("CSV with a header row", "CSV mit Kopfzeile"),
+    ("The format is detected from the file", "Ohne --format wird das Format an der"),
+    ("extension when --format is omitted", "Dateiendung erkannt"),
+    ("Shell to configure (auto-detects from $SHELL)", "Zu konfigurierende Shell (Standard: aus $SHELL)"),
+    ("Don't modify shell rc file", "Shell-rc-Datei nicht ändern"),
+    ("Show what would be done without making changes", "Zeigen, was passieren würde, ohne etwas zu ändern"),
+    ("Table border style (unicode/ascii/minimal)", "Rahmenstil für Tabellen (unicode/ascii/minimal)"),
+    ("Tags are case-insensitive (stored lowercase)", "Groß-/Kleinschreibung zählt bei Tags nicht (klein gespeichert)"),
+    ("Tags must be alphanumeric with dash/underscore", "Tags bestehen aus Buchstaben, Ziffern, - und _"),
+    ("No spaces in tags", "Keine Leerzeichen in Tags"),
+    // Help: examples
+    ("Register 'dev' alias", "Alias 'dev' registrieren"),
+    ("Register with tags", "Mit Tags registrieren"),
+    ("Navigate to ~/Development", "Zu ~/Development wechseln"),
+    ("List aliases by usage", "Aliase nach Nutzung auflisten"),
+    ("List aliases tagged 'work'", "Aliase mit Tag 'work' auflisten"),
+    ("Add 'golang' tag to 'dev'", "Tag 'golang' zu 'dev' hinzufügen"),
+    ("Remove 'golang' tag from 'dev'", "Tag 'golang' von 'dev' entfernen"),
+    ("Show recently visited aliases", "Zuletzt besuchte Aliase anzeigen"),
+    ("Navigate to 3rd most recent", "Zum drittletzten wechseln"),
+    ("Save location, go to 'work'", "Ort sichern, zu 'work' wechseln"),
+    ("Return to saved location", "Zum gesicherten Ort zurückkehren"),
+    ("Backup aliases to file", "Aliase in Datei sichern"),
+    ("Restore aliases from backup", "Aliase aus Sicherung wiederherstellen"),
+    ("Export aliases for a spreadsheet", "Aliase für eine Tabellenkalkulation exportieren"),
+    ("Copy aliases from another machine", "Aliase von einem anderen Rechner kopieren"),
+];
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_from_locale() {
+        assert_eq!(Lang::from_locale("de_DE.UTF-8"), Some(Lang::De));
+        assert_eq!(Lang::from_locale("de-AT"), Some(Lang::De));
+        assert_eq!(Lang::from_locale("DE"), Some(Lang::De));
+        assert_eq!(Lang::from_locale("en_US.UTF-8"), Some(Lang::En));
+        assert_eq!(Lang::from_locale("C.UTF-8"), Some(Lang::En));
+        assert_eq!(Lang::from_locale("fi_FI"), None);
+    }
+
+    #[test]
+    fn test_fill_and_match() {
+        assert_eq!(fill("vor {} Tagen", &[&3]), "vor 3 Tagen");
+        assert_eq!(match_template("alias '{}' not found", "alias 'x' not found"), Some(vec!["x"]));
+        assert_eq!(
+            match_template("invalid tag '{}': {}", "invalid tag 'a b': no spaces"),
+            Some(vec!["a b", "no spaces"])
+        );
+        assert_eq!(match_template("alias '{}' not found", "alias 'x' not found here"), None);
+        assert_eq!(match_template("directory stack is empty", "directory stack is empty"), Some(vec![]));
+    }
+
+    #[test]
+    fn test_lookup() {
+        assert_eq!(lookup(Lang::De, "just now"), Some("gerade eben"));
+        assert_eq!(lookup(Lang::En, "just now"), None);
+        assert_eq!(tr("just now"), "just now");
+        // Untranslated messages pass through
+        assert_eq!(tr_message("something else went wrong"), "something else went wrong");
+    }
+
+    #[test]
+    fn test_tr_message_in() {
+        assert_eq!(tr_message_in(Lang::De, "alias 'web' not found"), "Alias 'web' nicht gefunden");
+        assert_eq!(
+            tr_message_in(Lang::De, "invalid tag 'a b': must not contain spaces"),
+            "ungültiger Tag 'a b': must not contain spaces"
+        );
+        assert_eq!(tr_message_in(Lang::De, "directory stack is empty"), "Verzeichnisstapel ist leer");
+        assert_eq!(tr_message_in(Lang::En, "alias 'web' not found"), "alias 'web' not found");
+    }
+
+    #[test]
+    fn test_localize_help() {
+        let help = "Usage:\n  goto -l                         List all aliases\n  goto -q     Not in the catalog\n\
+                    \x20                                 The format is detected from the file\n";
+        let de = localize_help(help, Lang::De);
+        let lines: Vec<&str> = de.lines().collect();
+        assert_eq!(lines[0], "Verwendung:");
+        assert_eq!(lines[1], "  goto -l                         Alle Aliase auflisten");
+        assert_eq!(lines[2], "  goto -q     Not in the catalog");
+        assert!(lines[3].starts_with("    "));
+        assert_eq!(lines[3].trim_start(), "Ohne --format wird das Format an der");
+        assert_eq!(localize_help(help, Lang::En), help);
+    }
+
+    #[test]
+    fn test_catalog_templates_match() {
+        // Every translation keeps its English key's placeholders
+        for (en, de) in DE {
+            assert_eq!(en.matches("{}").count(), de.matches("{}").count(), "{}", en);
+        }
+    }
+}
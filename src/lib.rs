@@ -9,8 +9,19 @@ pub mod alias;
 pub mod cli;
 pub mod commands;
 pub mod config;
+pub mod crypto;
 pub mod database;
+pub mod deadline;
+pub mod events;
+pub mod filelock;
 pub mod fuzzy;
+pub mod history;
+pub mod hooks;
+pub mod i18n;
+pub mod listing;
+pub mod pathcheck;
+pub mod profile;
+pub mod project;
 pub mod stack;
 pub mod table;
 
@@ -18,6 +29,7 @@ pub use alias::Alias;
 pub use cli::{parse_args, Args, Command};
 pub use config::Config;
 pub use database::Database;
+pub use deadline::{CancelHandle, Deadline};
 pub use stack::Stack;
 pub use table::{TableStyle, create_table};
 
@@ -57,6 +69,23 @@ pub fn confirm(message: &str, default: bool) -> io::Result<bool> {
     })
 }
 
+/// Print one output record, terminated by NUL with `--print0` or a newline otherwise
+pub fn print_record(value: &str, print0: bool) {
+    if print0 {
+        print!("{}\0", value);
+    } else {
+        println!("{}", value);
+    }
+}
+
+/// Whether a destructive operation should ask before going ahead.
+///
+/// Only on a terminal, and not when `--yes` was given or
+/// `general.confirm_destructive` is off (`assume_yes`).
+pub fn needs_confirmation(assume_yes: bool) -> bool {
+    !assume_yes && io::stdin().is_terminal()
+}
+
 /// Prompt user to select from numbered options.
 ///
 /// Returns the selected index (0-based) on valid input, None on cancel.
@@ -112,3 +141,73 @@ pub fn prompt_selection(
         _ => Ok(None), // Invalid input = cancel
     }
 }
+
+/// Prompt user to pick several of the numbered options.
+///
+/// Accepts numbers and ranges separated by spaces or commas ("1 3 5-7"), or
+/// "all". Returns the selected indices (0-based, sorted) or None on cancel,
+/// invalid input, or when stdin is not a terminal.
+pub fn prompt_multi_selection(options: &[&str]) -> io::Result<Option<Vec<usize>>> {
+    if !io::stdin().is_terminal() {
+        return Ok(None);
+    }
+
+    for (i, option) in options.iter().enumerate() {
+        eprintln!("  [{}] {}", i + 1, option);
+    }
+
+    eprint!("Select (e.g. 1 3 5-7, all) or Enter to cancel: ");
+    io::stderr().flush()?;
+
+    let mut input = String::new();
+    io::stdin().read_line(&mut input)?;
+
+    Ok(parse_selection(&input, options.len()))
+}
+
+/// Parse a multi-selection like "1 3 5-7" or "all" against `count` options
+pub fn parse_selection(input: &str, count: usize) -> Option<Vec<usize>> {
+    let input = input.trim();
+    if input.is_empty() {
+        return None;
+    }
+    if input.eq_ignore_ascii_case("all") {
+        return Some((0..count).collect());
+    }
+
+    let mut selected = Vec::new();
+    for part in input.split([' ', ',']).filter(|p| !p.is_empty()) {
+        let (start, end) = match part.split_once('-') {
+            Some((a, b)) => (a.parse::<usize>().ok()?, b.parse::<usize>().ok()?),
+            None => {
+                let n = part.parse::<usize>().ok()?;
+                (n, n)
+            }
+        };
+        if start < 1 || end > count || start > end {
+            return None;
+        }
+        selected.extend(start - 1..end);
+    }
+    selected.sort_unstable();
+    selected.dedup();
+    Some(selected)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_parse_selection() {
+        assert_eq!(parse_selection("1 3", 5), Some(vec![0, 2]));
+        assert_eq!(parse_selection("2-4,1", 5), Some(vec![0, 1, 2, 3]));
+        assert_eq!(parse_selection("3 3 2-3", 5), Some(vec![1, 2]));
+        assert_eq!(parse_selection("ALL", 3), Some(vec![0, 1, 2]));
+        assert_eq!(parse_selection("", 3), None);
+        assert_eq!(parse_selection("0", 3), None);
+        assert_eq!(parse_selection("4", 3), None);
+        assert_eq!(parse_selection("3-1", 3), None);
+        assert_eq!(parse_selection("x", 3), None);
+    }
+}
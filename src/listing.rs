@@ -0,0 +1,80 @@
+//! Rows of the last printed listing, so `goto %N` can jump to row N
+
+use std::fs;
+use std::io;
+use std::path::Path;
+
+use crate::alias::AliasError;
+
+/// Remember which alias each numbered row of a listing showed
+///
+/// Stored as one "number<TAB>name" line per row, replacing the previous listing.
+pub fn save(path: &Path, rows: &[(usize, &str)]) -> io::Result<()> {
+    if let Some(parent) = path.parent() {
+        fs::create_dir_all(parent)?;
+    }
+    let content: String = rows.iter().map(|(n, name)| format!("{}\t{}\n", n, name)).collect();
+    fs::write(path, content)
+}
+
+/// Forget the last listing, for output whose rows aren't numbered
+pub fn clear(path: &Path) -> io::Result<()> {
+    match fs::remove_file(path) {
+        Err(e) if e.kind() != io::ErrorKind::NotFound => Err(e),
+        _ => Ok(()),
+    }
+}
+
+/// The row number in a `%N` shortcut
+pub fn parse_shortcut(arg: &str) -> Option<usize> {
+    arg.strip_prefix('%')?.parse().ok()
+}
+
+/// Resolve `%N` to the alias shown in row N of the last listing; other names pass through
+pub fn expand_shortcut(path: &Path, arg: &str) -> Result<String, AliasError> {
+    let Some(row) = parse_shortcut(arg) else {
+        return Ok(arg.to_string());
+    };
+    fs::read_to_string(path)
+        .unwrap_or_default()
+        .lines()
+        .filter_map(|line| line.split_once('\t'))
+        .find(|(n, _)| n.parse() == Ok(row))
+        .map(|(_, name)| name.to_string())
+        .ok_or_else(|| AliasError::NoSuchRow(arg.to_string()))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::tempdir;
+
+    #[test]
+    fn test_parse_shortcut() {
+        assert_eq!(parse_shortcut("%3"), Some(3));
+        assert_eq!(parse_shortcut("%"), None);
+        assert_eq!(parse_shortcut("%x"), None);
+        assert_eq!(parse_shortcut("proj"), None);
+    }
+
+    #[test]
+    fn test_save_and_expand() {
+        let dir = tempdir().unwrap();
+        let path = dir.path().join("last_listing");
+
+        assert!(expand_shortcut(&path, "%1").is_err());
+        assert_eq!(expand_shortcut(&path, "proj").unwrap(), "proj");
+
+        save(&path, &[(11, "api"), (12, "work:web")]).unwrap();
+        assert_eq!(expand_shortcut(&path, "%12").unwrap(), "work:web");
+        assert!(expand_shortcut(&path, "%1").unwrap_err().to_string().contains("not found"));
+
+        save(&path, &[(1, "docs")]).unwrap();
+        assert_eq!(expand_shortcut(&path, "%1").unwrap(), "docs");
+        assert!(expand_shortcut(&path, "%11").is_err());
+
+        clear(&path).unwrap();
+        assert!(expand_shortcut(&path, "%1").is_err());
+        clear(&path).unwrap();
+    }
+}
@@ -1,15 +1,24 @@
 //! goto - CLI entry point for the goto directory navigation tool
 
 use std::env;
+use std::path::PathBuf;
 use std::process::ExitCode;
 
+use goto::alias::AliasError;
 use goto::cli::{self, Command};
 use goto::commands;
+use goto::commands::import_export::ImportStrategy;
 use goto::config::Config;
-use goto::database::Database;
+use goto::database::{Database, DatabaseError};
+use goto::listing;
+use goto::pathcheck::PathCheck;
+use goto::profile;
+use goto::stack::StackError;
 
 fn main() -> ExitCode {
-    match run() {
+    let result = run();
+    profile::finish();
+    match result {
         Ok(()) => ExitCode::SUCCESS,
         Err(code) => ExitCode::from(code),
     }
@@ -26,6 +35,9 @@ fn run() -> Result<(), u8> {
             return Err(1);
         }
     };
+    if parsed.profile.enabled() {
+        profile::enable(parsed.profile.clone());
+    }
 
     // Handle commands that don't need config/database
     match &parsed.command {
@@ -33,9 +45,17 @@ fn run() -> Result<(), u8> {
             cli::print_help();
             return Ok(());
         }
-        Command::Version => {
+        Command::GenerateMan => {
+            commands::man::generate_man();
+            return Ok(());
+        }
+        Command::Version { json: true } => {
+            println!("{}", commands::update::version_json());
+            return Ok(());
+        }
+        Command::Version { json: false } => {
             // Try to show version with update status if config is available
-            if let Ok(config) = Config::load() {
+            if let Ok(config) = load_config(&parsed) {
                 println!("{}", commands::update::version_with_update_status(&config));
             } else {
                 println!("goto version {}", cli::version());
@@ -69,10 +89,19 @@ fn run() -> Result<(), u8> {
         _ => {}
     }
 
-    let config = Config::load().map_err(|e| {
+    let loaded = {
+        let _phase = profile::phase("config load");
+        load_config(&parsed)
+    };
+    let mut config = loaded.map_err(|e| {
         eprintln!("Error loading config: {}", e);
         5u8
     })?;
+    if parsed.no_track {
+        config.user.general.track_usage = false;
+    }
+    let assume_yes = parsed.yes || !config.user.general.confirm_destructive;
+    goto::alias::set_unicode_names(config.user.general.unicode_names);
 
     // Handle config command (needs config but not database)
     if matches!(parsed.command, Command::Config) {
@@ -80,6 +109,11 @@ fn run() -> Result<(), u8> {
         return Ok(());
     }
 
+    // The cd hook runs on every directory change: skip loading the database
+    if let Command::RecordDir { dir } = &parsed.command {
+        return commands::suggest::record_dir(&config, dir).map_err(handle_error);
+    }
+
     // Handle update commands
     match &parsed.command {
         Command::Update => {
@@ -115,22 +149,99 @@ fn run() -> Result<(), u8> {
         _ => {}
     }
 
+    // Restoring must work when the database itself no longer loads
+    if let Command::RestoreBackup { index } = &parsed.command {
+        return commands::backup::restore_backup(&config, *index).map_err(handle_error);
+    }
+    if let Command::Serve { listen } = &parsed.command {
+        return commands::serve::serve(&config, listen).map_err(handle_error);
+    }
+    // Housekeeping from cron shouldn't depend on (or rewrite) the database
+    if let Command::Gc { keep_days, dry_run } = parsed.command {
+        return commands::gc::gc(&config, keep_days, dry_run).map_err(handle_error);
+    }
+    // Checking must work on a file that no longer loads, and sees duplicates loading drops
+    if let Command::Fsck { fix } = parsed.command {
+        return commands::fsck::fsck(&config, fix).map_err(handle_error);
+    }
+    // Loads the database itself on every run, so a long-running loop sees changes
+    if let Command::Maintain { interval, keep_days } = parsed.command {
+        return commands::maintain::maintain(&config, interval, keep_days).map_err(handle_error);
+    }
+
     let mut db = Database::load(&config).map_err(|e| {
         eprintln!("Error loading database: {}", e);
+        if matches!(e, DatabaseError::TomlDe(_)) {
+            eprintln!("The alias database looks corrupt. Run 'goto --restore-backup' to restore a backup.");
+        }
         5u8
     })?;
+    if parsed.no_check {
+        db.set_path_check(PathCheck::Skip);
+    }
+    let mut fuzzy = db.fuzzy();
+    fuzzy.enabled &= !parsed.no_fuzzy;
+    fuzzy.threshold = parsed.threshold.unwrap_or(fuzzy.threshold);
+    fuzzy.max_suggestions = parsed.suggestions.unwrap_or(fuzzy.max_suggestions);
+    db.set_fuzzy(fuzzy);
+
+    // Offer the setup wizard once, on first interactive use (not from completions)
+    if !matches!(
+        parsed.command,
+        Command::Setup
+            | Command::ListNames
+            | Command::ListTagsRaw
+            | Command::ListTagsOf { .. }
+            | Command::Complete { .. }
+            | Command::CompleteSubpath { .. }
+            | Command::ListNamespacesRaw
+    ) {
+        if let Err(e) = commands::setup::offer_first_run_setup(&config, &mut db) {
+            eprintln!("Setup failed: {}", e);
+        }
+    }
 
     match parsed.command {
-        Command::Help | Command::Version | Command::Config | Command::Install { .. }
-        | Command::Update | Command::CheckUpdate => unreachable!(),
+        Command::Help | Command::Version { .. } | Command::Config | Command::Install { .. }
+        | Command::Update | Command::CheckUpdate | Command::GenerateMan | Command::RecordDir { .. }
+        | Command::RestoreBackup { .. }
+        | Command::Serve { .. }
+        | Command::Gc { .. }
+        | Command::Fsck { .. }
+        | Command::Maintain { .. } => unreachable!(),
+
+        Command::Setup => commands::setup::setup(&config, &mut db).map_err(handle_error),
+
+        Command::Suggest => commands::suggest::suggest(&mut db, &config).map_err(handle_error),
+
+        Command::ContextShow => commands::context::show_context(&config).map_err(handle_error),
+
+        Command::ContextUse { name } => {
+            commands::context::use_context(&config, &db, &name).map_err(handle_error)
+        }
+
+        Command::ContextClear => commands::context::clear_context(&config).map_err(handle_error),
+
+        Command::ContextList => commands::context::list_contexts(&config, &db).map_err(handle_error),
+
+        Command::SessionSave { name, dirs } => {
+            commands::session::save_session(&config, &db, &name, &dirs).map_err(handle_error)
+        }
+
+        Command::SessionRestore { name, tmux } => {
+            commands::session::restore_session(&config, &name, tmux).map_err(handle_error)
+        }
+
+        Command::SessionList => commands::session::list_sessions(&config).map_err(handle_error),
+
+        Command::SessionDelete { name } => commands::session::delete_session(&config, &name).map_err(handle_error),
 
         Command::PruneSnooze { days } => {
             commands::prune::snooze_notifications(&config, days).map_err(handle_error)
         }
 
-        Command::List { sort, filter } => {
-            let result = commands::list::list_with_options(&db, &config, sort.as_deref(), filter.as_deref())
-                .map_err(handle_error);
+        Command::List { options } => {
+            let result = commands::list::list_aliases(&db, &config, &options).map_err(handle_error);
             if result.is_ok() {
                 commands::prune::notify_if_stale_aliases(&config, &db);
             }
@@ -141,6 +252,16 @@ fn run() -> Result<(), u8> {
 
         Command::ListTagsRaw => commands::tags::list_tags_raw(&db).map_err(handle_error),
 
+        Command::Complete { kind } => commands::list::list_completions(&db, &kind).map_err(handle_error),
+
+        Command::CompleteSubpath { alias, partial } => {
+            commands::tree::complete_subpath(&db, &alias, &partial).map_err(handle_error)
+        }
+
+        Command::ListTagsOf { alias } => commands::tags::list_tags_of(&db, &alias).map_err(handle_error),
+
+        Command::ListNamespacesRaw => commands::list::list_namespaces_raw(&db).map_err(handle_error),
+
         Command::Stats => {
             let result = commands::stats::stats(&db, &config).map_err(handle_error);
             if result.is_ok() {
@@ -149,19 +270,171 @@ fn run() -> Result<(), u8> {
             result
         }
 
-        Command::Register { name, path, tags, force } => {
-            commands::register::register_with_tags(&mut db, &name, &path, &tags, force)
-                .map_err(handle_error)
+        Command::Register { name, path, tags, description, expires, force, if_exists } => {
+            let options = commands::register::RegisterOptions {
+                tags: &tags,
+                description: description.as_deref(),
+                expires,
+                force,
+                rules: &config.user.autotag,
+                if_exists,
+            };
+            commands::register::register_with_rules(&mut db, &name, &path, &options).map_err(handle_error)
+        }
+
+        Command::Batch => {
+            let stdin = std::io::stdin();
+            match commands::batch::run_batch(&mut db, &config, stdin.lock()) {
+                Ok(count) => {
+                    println!("Batch complete: {} command{} applied", count, if count == 1 { "" } else { "s" });
+                    Ok(())
+                }
+                Err(e) => Err(handle_error(e)),
+            }
+        }
+        Command::RegisterStdin { tags } => {
+            let stdin = std::io::stdin();
+            match commands::register::register_from_lines(&mut db, stdin.lock(), &tags, &config.user.autotag) {
+                Ok(result) => {
+                    for warning in &result.warnings {
+                        eprintln!("{}", warning);
+                    }
+                    print!("Registered {} aliases", result.imported + result.renamed);
+                    if result.renamed > 0 {
+                        print!(", {} renamed", result.renamed);
+                    }
+                    if result.skipped > 0 {
+                        print!(", {} skipped", result.skipped);
+                    }
+                    println!();
+                    Ok(())
+                }
+                Err(e) => Err(handle_error(e)),
+            }
+        }
+
+        Command::DiscoverWorkspaces { root, namespace, tags, dry_run } => {
+            match commands::workspace::discover_workspaces(
+                &mut db,
+                &root,
+                namespace.as_deref(),
+                &tags,
+                &config.user.autotag,
+                dry_run,
+            ) {
+                Ok(result) => {
+                    for warning in &result.warnings {
+                        eprintln!("{}", warning);
+                    }
+                    let verb = if dry_run { "Would register" } else { "Registered" };
+                    print!("{} {} aliases", verb, result.imported + result.renamed);
+                    if result.renamed > 0 {
+                        print!(", {} renamed", result.renamed);
+                    }
+                    if result.skipped > 0 {
+                        print!(", {} skipped", result.skipped);
+                    }
+                    println!();
+                    Ok(())
+                }
+                Err(e) => Err(handle_error(e)),
+            }
+        }
+
+        Command::Scan { root, namespace, dry_run } => {
+            match commands::scan::scan(&mut db, &root, namespace.as_deref(), dry_run) {
+                Ok(result) => {
+                    for warning in &result.warnings {
+                        eprintln!("{}", warning);
+                    }
+                    let verb = if dry_run { "Would merge" } else { "Merged" };
+                    print!(
+                        "{} {} .goto-aliases file{}: {} added",
+                        verb,
+                        result.files,
+                        if result.files == 1 { "" } else { "s" },
+                        result.added
+                    );
+                    if result.updated > 0 {
+                        print!(", {} updated", result.updated);
+                    }
+                    if result.skipped > 0 {
+                        print!(", {} skipped", result.skipped);
+                    }
+                    println!();
+                    Ok(())
+                }
+                Err(e) => Err(handle_error(e)),
+            }
         }
 
         Command::Unregister { name } => {
-            commands::register::unregister(&mut db, &name).map_err(handle_error)
+            commands::register::unregister(&mut db, &name, assume_yes).map_err(handle_error)
+        }
+
+        Command::UnregisterInteractive => {
+            commands::register::unregister_interactive(&mut db, assume_yes).map_err(handle_error)
+        }
+
+        Command::UnregisterMany { selector, filter, dry_run } => {
+            commands::register::unregister_matching(
+                &mut db,
+                &selector,
+                filter.as_deref(),
+                dry_run,
+                assume_yes,
+            )
+            .map_err(handle_error)
+        }
+
+        Command::Random { filter } => {
+            commands::navigate::random(&mut db, filter.as_deref()).map_err(handle_error)
+        }
+
+        Command::Which { alias } => commands::navigate::which(&db, &alias).map_err(handle_error),
+
+        Command::Expand { alias, uri } => {
+            let alias = listing::expand_shortcut(&config.listing_path, &alias)
+                .map_err(|e| handle_error(e.into()))?;
+            if uri {
+                commands::navigate::expand_uri(&db, &alias, parsed.print0).map_err(handle_error)
+            } else {
+                commands::navigate::expand(&db, &alias, parsed.print0).map_err(handle_error)
+            }
         }
 
-        Command::Expand { alias } => commands::navigate::expand(&db, &alias).map_err(handle_error),
+        Command::CopyPath { alias } => {
+            let alias = listing::expand_shortcut(&config.listing_path, &alias)
+                .map_err(|e| handle_error(e.into()))?;
+            commands::clipboard::copy_path(&db, &alias).map_err(handle_error)
+        }
+
+        Command::Open { alias } => {
+            let alias = listing::expand_shortcut(&config.listing_path, &alias)
+                .map_err(|e| handle_error(e.into()))?;
+            commands::open::open(&db, &alias).map_err(handle_error)
+        }
+
+        Command::Edit { alias } => {
+            let alias = listing::expand_shortcut(&config.listing_path, &alias)
+                .map_err(|e| handle_error(e.into()))?;
+            commands::open::edit(&db, &alias).map_err(handle_error)
+        }
 
         Command::Cleanup { dry_run } => {
-            commands::cleanup::cleanup(&mut db, &config, dry_run).map_err(handle_error)
+            commands::cleanup::cleanup(&mut db, &config, dry_run, assume_yes).map_err(handle_error)
+        }
+
+        Command::Audit { unused_days, json } => {
+            commands::audit::audit(&db, &config, unused_days, json).map_err(handle_error)
+        }
+
+        Command::Digest { since } => commands::digest::digest(&db, &config, since).map_err(handle_error),
+
+        Command::Cdpath { filter } => commands::cdpath::cdpath(&db, filter.as_deref()).map_err(handle_error),
+
+        Command::EnvFile { filter, make } => {
+            commands::envfile::env_file(&db, filter.as_deref(), make).map_err(handle_error)
         }
 
         Command::Push { alias } => {
@@ -170,23 +443,59 @@ fn run() -> Result<(), u8> {
 
         Command::Pop => commands::stack::pop(&config).map_err(handle_error),
 
+        Command::Swap => commands::stack::swap(&config).map_err(handle_error),
+
+        Command::Root { register, name } => {
+            commands::navigate::root(&mut db, register, name.as_deref(), &config.user.autotag).map_err(handle_error)
+        }
+
         Command::Rename { old_name, new_name } => {
             commands::register::rename(&mut db, &old_name, &new_name).map_err(handle_error)
         }
 
+        Command::Copy { source, target } => {
+            commands::register::copy(&mut db, &source, &target).map_err(handle_error)
+        }
+
+        Command::RewritePaths { old_prefix, new_prefix, dry_run } => {
+            commands::register::rewrite_paths(&mut db, &old_prefix, &new_prefix, dry_run)
+                .map_err(handle_error)
+        }
+
+        Command::Repath { alias, path } => {
+            commands::register::repath(&mut db, &alias, &path).map_err(handle_error)
+        }
+
+        Command::SkipCheck { alias, skip } => {
+            commands::register::skip_check(&mut db, &alias, skip).map_err(handle_error)
+        }
+
         Command::Tag { alias, tag, force } => {
             commands::tags::tag(&mut db, &alias, &tag, force).map_err(handle_error)
         }
 
+        Command::TagMany { selector, tag, force, filter } => {
+            commands::tags::tag_many(&mut db, &selector, &tag, force, filter.as_deref())
+                .map_err(handle_error)
+        }
+
         Command::Untag { alias, tag } => {
             commands::tags::untag(&mut db, &alias, &tag).map_err(handle_error)
         }
 
+        Command::SetMeta { alias, pairs } => {
+            commands::tags::set_meta(&mut db, &alias, &pairs).map_err(handle_error)
+        }
+
         Command::RenameTag { old_tag, new_tag, dry_run, force } => {
             commands::tags::rename_tag(&mut db, &config, &old_tag, &new_tag, dry_run, force)
                 .map_err(handle_error)
         }
 
+        Command::RetagAuto { dry_run } => {
+            commands::tags::retag_auto(&mut db, &config, dry_run).map_err(handle_error)
+        }
+
         Command::ListTags => {
             let result = commands::tags::list_tags(&db, &config).map_err(handle_error);
             if result.is_ok() {
@@ -195,20 +504,46 @@ fn run() -> Result<(), u8> {
             result
         }
 
-        Command::Recent { count, navigate_to } => {
+        Command::Recent { count, navigate_to, since, interactive, filter } => {
             if let Some(n) = navigate_to {
                 commands::stats::navigate_to_recent(&mut db, n).map_err(handle_error)
+            } else if interactive {
+                commands::stats::select_recent(&mut db, count.unwrap_or(10), since, filter.as_deref())
+                    .map_err(handle_error)
             } else {
-                commands::stats::show_recent(&db, &config, count.unwrap_or(10)).map_err(handle_error)
+                let count = count.unwrap_or(10);
+                commands::stats::show_recent_since(&db, &config, count, since, filter.as_deref(), parsed.print0)
+                    .map_err(handle_error)
             }
         }
 
         Command::RecentClear => commands::stats::clear_recent(&mut db).map_err(handle_error),
 
-        Command::Export => commands::import_export::export(&db).map_err(handle_error),
+        Command::ResetStats { alias, last_used } => {
+            commands::stats::reset_stats(&mut db, alias.as_deref(), last_used).map_err(handle_error)
+        }
+
+        Command::Show { alias } => commands::show::show(&db, &alias).map_err(handle_error),
+        Command::Tree { alias, depth } => commands::tree::tree(&db, &config, &alias, depth).map_err(handle_error),
+
+        Command::Touch { alias } => commands::stats::touch(&mut db, &alias).map_err(handle_error),
 
-        Command::Import { file, strategy } => {
-            match commands::import_export::import(&mut db, &file, strategy) {
+        Command::Export { format, output, portable } => match output {
+            Some(file) => commands::import_export::export_to_file(&db, &file, format, portable)
+                .map_err(handle_error),
+            None => commands::import_export::export_with_format(&db, format, portable)
+                .map_err(handle_error),
+        },
+
+        Command::Import { file, strategy, format, sha256 } => {
+            if strategy == ImportStrategy::Overwrite && goto::needs_confirmation(assume_yes) {
+                match goto::confirm("Import may overwrite existing aliases. Continue?", false) {
+                    Ok(true) => {}
+                    Ok(false) => return Err(handle_error(AliasError::Cancelled("Import".to_string()).into())),
+                    Err(e) => return Err(handle_error(e.into())),
+                }
+            }
+            match commands::import_export::import_with_format(&mut db, &file, strategy, format, sha256.as_deref()) {
                 Ok(result) => {
                     for warning in &result.warnings {
                         eprintln!("{}", warning);
@@ -228,32 +563,60 @@ fn run() -> Result<(), u8> {
         }
 
         Command::Navigate { alias } => {
+            let alias = listing::expand_shortcut(&config.listing_path, &alias)
+                .map_err(|e| handle_error(e.into()))?;
             let result = commands::navigate::navigate(&mut db, &alias).map_err(handle_error);
             // Show update notification after successful navigation (goes to stderr)
             if result.is_ok() {
                 commands::update::notify_if_update_available(&config);
+                let name = alias.split_once('/').map_or(alias.as_str(), |(name, _)| name);
+                commands::prune::warn_on_navigate(&config, &db, &db.resolve_name(name));
             }
             result
         }
     }
 }
 
+/// Load the config for `--db` if given, otherwise from GOTO_DB/XDG/home
+fn load_config(parsed: &cli::Args) -> Result<Config, goto::config::ConfigError> {
+    match &parsed.db {
+        Some(dir) => Config::load_from(PathBuf::from(dir)),
+        None => Config::load(),
+    }
+}
+
 fn handle_error(err: Box<dyn std::error::Error>) -> u8 {
-    eprintln!("{}", err);
-
-    // Map error types to exit codes
-    let err_str = err.to_string();
-    if err_str.contains("directory does not exist") {
-        2
-    } else if err_str.contains("invalid alias") || err_str.contains("invalid tag") {
-        3
-    } else if err_str.contains("already exists") {
-        4
-    } else if err_str.contains("not found") || err_str.contains("stack is empty") {
-        1
-    } else if err_str.contains("cancelled") || err_str.contains("aborted") {
-        1
-    } else {
-        5
+    eprintln!("{}", goto::i18n::tr_message(&err.to_string()));
+    exit_code(err.as_ref())
+}
+
+/// The exit code for an error, by its type (see "Exit Codes" in docs/commands.md)
+fn exit_code(err: &(dyn std::error::Error + 'static)) -> u8 {
+    if let Some(e) = err.downcast_ref::<AliasError>() {
+        return match e {
+            AliasError::NotFound(_)
+            | AliasError::NoneTagged(_)
+            | AliasError::NoneMatching(..)
+            | AliasError::Missing(_)
+            | AliasError::NoSuchRow(_)
+            | AliasError::NoRepository(_)
+            | AliasError::Expired(..)
+            | AliasError::Cancelled(_)
+            | AliasError::HookVetoed(..)
+            | AliasError::CheckCancelled(_) => 1,
+            AliasError::DirectoryNotFound(_) | AliasError::FileNotFound(_) => 2,
+            AliasError::InvalidAlias { .. } | AliasError::InvalidTag { .. } | AliasError::InvalidMetaKey(_) => 3,
+            AliasError::AlreadyExists(_) => 4,
+            AliasError::PermissionDenied(_) => 6,
+            AliasError::PathNotAllowed(..) => 7,
+            AliasError::NotADirectory(_) | AliasError::NotAFile(_) | AliasError::CheckTimedOut(..) => 5,
+        };
+    }
+    match err.downcast_ref::<DatabaseError>() {
+        Some(DatabaseError::Alias(e)) => exit_code(e),
+        Some(DatabaseError::Cancelled(_)) => 1,
+        Some(_) => 5,
+        None if matches!(err.downcast_ref::<StackError>(), Some(StackError::Empty)) => 1,
+        None => 5,
     }
 }
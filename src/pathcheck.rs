@@ -0,0 +1,198 @@
+//! Checks that an alias target is a directory the user can enter (or, for a
+//! file bookmark, a file in one)
+
+use std::fs;
+use std::io::ErrorKind;
+use std::path::Path;
+use std::time::{Duration, Instant};
+
+use crate::alias::{AliasError, AliasKind};
+use crate::deadline::{Deadline, Interrupted};
+
+/// How navigation checks a target before jumping to it
+#[derive(Debug, Clone, Copy, PartialEq)]
+pub enum PathCheck {
+    /// Don't touch the target at all (`--no-check`)
+    Skip,
+    /// Check it, giving up after the timeout if there is one
+    Check(Option<Duration>),
+}
+
+impl Default for PathCheck {
+    fn default() -> Self {
+        PathCheck::Check(None)
+    }
+}
+
+impl PathCheck {
+    /// Check with a timeout in milliseconds; 0 waits as long as the filesystem takes
+    pub fn with_timeout_ms(ms: u64) -> Self {
+        PathCheck::Check((ms > 0).then(|| Duration::from_millis(ms)))
+    }
+
+    /// Run the check on `path` (see [`check_dir`])
+    pub fn run(&self, path: &str) -> Result<(), AliasError> {
+        self.run_kind(path, AliasKind::Dir)
+    }
+
+    /// Run the check on a target of the given kind (see [`check_target`])
+    pub fn run_kind(&self, path: &str, kind: AliasKind) -> Result<(), AliasError> {
+        self.run_within(path, kind, &Deadline::default())
+    }
+
+    /// Like [`PathCheck::run_kind`], also giving up at `deadline` or when it is cancelled
+    pub fn run_within(&self, path: &str, kind: AliasKind, deadline: &Deadline) -> Result<(), AliasError> {
+        match *self {
+            PathCheck::Skip => Ok(()),
+            PathCheck::Check(None) => check_within(path, kind, deadline),
+            PathCheck::Check(Some(timeout)) => check_within(path, kind, &deadline.with_timeout(timeout)),
+        }
+    }
+}
+
+/// [`check_dir`] on a helper thread, so a hung network mount can't hang goto
+///
+/// On timeout the helper thread is left behind; it ends with the process.
+pub fn check_dir_timeout(path: &str, timeout: Duration) -> Result<(), AliasError> {
+    check_within(path, AliasKind::Dir, &Deadline::after(timeout))
+}
+
+fn check_within(path: &str, kind: AliasKind, deadline: &Deadline) -> Result<(), AliasError> {
+    let started = Instant::now();
+    let target = path.to_string();
+    match deadline.run(move || check_target(&target, kind)) {
+        Ok(result) => result,
+        Err(Interrupted::TimedOut) => Err(AliasError::CheckTimedOut(path.to_string(), started.elapsed().as_secs_f64())),
+        Err(Interrupted::Cancelled) => Err(AliasError::CheckCancelled(path.to_string())),
+    }
+}
+
+/// Check that `path` is an existing directory that can be entered
+///
+/// A directory the user may not search (no execute permission, or a parent
+/// without it) is reported as `PermissionDenied` rather than missing.
+pub fn check_dir(path: &str) -> Result<(), AliasError> {
+    let target = Path::new(path);
+    match fs::metadata(target) {
+        Ok(meta) if !meta.is_dir() => Err(AliasError::NotADirectory(path.to_string())),
+        // Stat'ing "dir/." needs search permission on dir itself, which `cd` needs too
+        Ok(_) => match fs::metadata(target.join(".")) {
+            Err(e) if e.kind() == ErrorKind::PermissionDenied => {
+                Err(AliasError::PermissionDenied(path.to_string()))
+            }
+            _ => Ok(()),
+        },
+        Err(e) if e.kind() == ErrorKind::PermissionDenied => {
+            Err(AliasError::PermissionDenied(path.to_string()))
+        }
+        Err(_) => Err(AliasError::DirectoryNotFound(path.to_string())),
+    }
+}
+
+/// [`check_dir`] or [`check_file`], depending on what the alias points to
+pub fn check_target(path: &str, kind: AliasKind) -> Result<(), AliasError> {
+    match kind {
+        AliasKind::Dir => check_dir(path),
+        AliasKind::File => check_file(path),
+    }
+}
+
+/// Check that `path` is an existing file in a directory that can be entered
+pub fn check_file(path: &str) -> Result<(), AliasError> {
+    let target = Path::new(path);
+    match fs::metadata(target) {
+        Ok(meta) if meta.is_dir() => Err(AliasError::NotAFile(path.to_string())),
+        Ok(_) => match target.parent() {
+            Some(parent) => check_dir(&parent.to_string_lossy()),
+            None => Ok(()),
+        },
+        Err(e) if e.kind() == ErrorKind::PermissionDenied => {
+            Err(AliasError::PermissionDenied(path.to_string()))
+        }
+        Err(_) => Err(AliasError::FileNotFound(path.to_string())),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::os::unix::fs::PermissionsExt;
+    use tempfile::tempdir;
+
+    #[test]
+    fn test_check_dir() {
+        let dir = tempdir().unwrap();
+        let file = dir.path().join("file");
+        fs::write(&file, "").unwrap();
+
+        assert!(check_dir(dir.path().to_str().unwrap()).is_ok());
+        assert!(matches!(
+            check_dir(file.to_str().unwrap()),
+            Err(AliasError::NotADirectory(_))
+        ));
+        assert!(matches!(
+            check_dir("/nonexistent/path/12345"),
+            Err(AliasError::DirectoryNotFound(_))
+        ));
+    }
+
+    #[test]
+    fn test_check_file() {
+        let dir = tempdir().unwrap();
+        let file = dir.path().join("notes.md");
+        fs::write(&file, "").unwrap();
+
+        assert!(check_file(file.to_str().unwrap()).is_ok());
+        assert!(matches!(
+            check_file(dir.path().to_str().unwrap()),
+            Err(AliasError::NotAFile(_))
+        ));
+        assert!(matches!(
+            check_file("/nonexistent/notes.md"),
+            Err(AliasError::FileNotFound(_))
+        ));
+        assert!(PathCheck::with_timeout_ms(5000).run_kind(file.to_str().unwrap(), AliasKind::File).is_ok());
+        assert!(PathCheck::with_timeout_ms(5000).run(file.to_str().unwrap()).is_err());
+    }
+
+    #[test]
+    fn test_path_check() {
+        let dir = tempdir().unwrap();
+        let path = dir.path().to_str().unwrap();
+
+        assert!(PathCheck::with_timeout_ms(5000).run(path).is_ok());
+        assert!(PathCheck::with_timeout_ms(0).run("/nonexistent/path/12345").is_err());
+        assert!(PathCheck::Skip.run("/nonexistent/path/12345").is_ok());
+        assert_eq!(PathCheck::with_timeout_ms(0), PathCheck::Check(None));
+    }
+
+    #[test]
+    fn test_run_within_cancelled() {
+        let dir = tempdir().unwrap();
+        let path = dir.path().to_str().unwrap();
+        let (deadline, cancel) = Deadline::default().with_cancel();
+        let check = PathCheck::with_timeout_ms(5000);
+
+        assert!(check.run_within(path, AliasKind::Dir, &deadline).is_ok());
+        cancel.cancel();
+        let err = check.run_within(path, AliasKind::Dir, &deadline).unwrap_err();
+        assert!(matches!(err, AliasError::CheckCancelled(_)));
+        assert!(PathCheck::Skip.run_within(path, AliasKind::Dir, &deadline).is_ok());
+    }
+
+    #[test]
+    fn test_check_dir_permission_denied() {
+        let dir = tempdir().unwrap();
+        let locked = dir.path().join("locked");
+        fs::create_dir(&locked).unwrap();
+        fs::set_permissions(&locked, fs::Permissions::from_mode(0o000)).unwrap();
+
+        // root ignores permission bits, so there is nothing to test there
+        let denied = fs::read_dir(&locked).is_err();
+        let result = check_dir(locked.to_str().unwrap());
+        fs::set_permissions(&locked, fs::Permissions::from_mode(0o755)).unwrap();
+        if denied {
+            assert!(matches!(result, Err(AliasError::PermissionDenied(_))));
+        }
+    }
+}
@@ -0,0 +1,172 @@
+//! Timing of one invocation's phases, for `--profile`
+//!
+//! Phases are recorded process-wide, so the database and the fuzzy matcher
+//! can time themselves without a profiler threaded through every call.
+//! Nothing is recorded unless [`enable`] was called.
+
+use std::fmt::Write;
+use std::fs;
+use std::sync::Mutex;
+use std::time::{Duration, Instant};
+
+/// What `--profile`, `--timings-file=` and `--memstats-file=` asked for
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct ProfileOptions {
+    /// `--profile`: print the phase timings on stderr when goto exits
+    pub report: bool,
+    /// `--timings-file=<file>`: write the phase timings to a file
+    pub timings_file: Option<String>,
+    /// `--memstats-file=<file>`: write the memory lines of /proc/self/status to a file
+    pub memstats_file: Option<String>,
+}
+
+impl ProfileOptions {
+    /// Whether anything should be recorded at all
+    pub fn enabled(&self) -> bool {
+        self.report || self.timings_file.is_some() || self.memstats_file.is_some()
+    }
+}
+
+struct Profile {
+    options: ProfileOptions,
+    start: Instant,
+    /// Phase name, total time and number of calls, in the order first seen
+    phases: Vec<(&'static str, Duration, usize)>,
+}
+
+static PROFILE: Mutex<Option<Profile>> = Mutex::new(None);
+
+/// Start recording phases for this process
+pub fn enable(options: ProfileOptions) {
+    *PROFILE.lock().unwrap() = Some(Profile {
+        options,
+        start: Instant::now(),
+        phases: Vec::new(),
+    });
+}
+
+/// A running phase; its time is added when it is dropped
+pub struct Phase {
+    name: &'static str,
+    start: Option<Instant>,
+}
+
+/// Time from now until the returned guard is dropped as `name`
+///
+/// Phases with the same name add up, so a database saved twice shows as one
+/// line with its call count.
+pub fn phase(name: &'static str) -> Phase {
+    let enabled = PROFILE.lock().is_ok_and(|p| p.is_some());
+    Phase { name, start: enabled.then(Instant::now) }
+}
+
+impl Drop for Phase {
+    fn drop(&mut self) {
+        let Some(start) = self.start else {
+            return;
+        };
+        if let Ok(mut profile) = PROFILE.lock() {
+            if let Some(profile) = profile.as_mut() {
+                add(&mut profile.phases, self.name, start.elapsed());
+            }
+        }
+    }
+}
+
+fn add(phases: &mut Vec<(&'static str, Duration, usize)>, name: &'static str, elapsed: Duration) {
+    match phases.iter_mut().find(|(n, _, _)| *n == name) {
+        Some((_, total, calls)) => {
+            *total += elapsed;
+            *calls += 1;
+        }
+        None => phases.push((name, elapsed, 1)),
+    }
+}
+
+/// Print the report and write the profile files that were asked for
+///
+/// Called once, on the way out of `main`.
+pub fn finish() {
+    let Some(profile) = PROFILE.lock().ok().and_then(|mut p| p.take()) else {
+        return;
+    };
+    let report = format_report(&profile.phases, profile.start.elapsed());
+    if profile.options.report {
+        eprint!("{}", report);
+    }
+    if let Some(file) = &profile.options.timings_file {
+        if let Err(e) = fs::write(file, &report) {
+            eprintln!("Could not write {}: {}", file, e);
+        }
+    }
+    if let Some(file) = &profile.options.memstats_file {
+        if let Err(e) = fs::write(file, memory().unwrap_or_else(unavailable)) {
+            eprintln!("Could not write {}: {}", file, e);
+        }
+    }
+}
+
+/// One line per phase with its time in milliseconds, then the total
+pub fn format_report(phases: &[(&'static str, Duration, usize)], total: Duration) -> String {
+    let ms = |d: &Duration| d.as_secs_f64() * 1000.0;
+    let mut out = String::from("Profile:\n");
+    for (name, time, calls) in phases {
+        write!(out, "  {:<16} {:>9.3} ms", name, ms(time)).unwrap();
+        if *calls > 1 {
+            write!(out, " ({} calls)", calls).unwrap();
+        }
+        out.push('\n');
+    }
+    writeln!(out, "  {:<16} {:>9.3} ms", "total", ms(&total)).unwrap();
+    out
+}
+
+fn unavailable() -> String {
+    "(not available on this platform)\n".to_string()
+}
+
+/// The memory lines of /proc/self/status: peak and current virtual and resident size
+fn memory() -> Option<String> {
+    let status = fs::read_to_string("/proc/self/status").ok()?;
+    let lines: Vec<&str> = status
+        .lines()
+        .filter(|l| ["VmPeak:", "VmSize:", "VmHWM:", "VmRSS:"].iter().any(|k| l.starts_with(k)))
+        .collect();
+    (!lines.is_empty()).then(|| lines.join("\n") + "\n")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_format_report() {
+        let mut phases = Vec::new();
+        add(&mut phases, "config load", Duration::from_micros(1500));
+        add(&mut phases, "save", Duration::from_millis(2));
+        add(&mut phases, "save", Duration::from_millis(1));
+
+        let report = format_report(&phases, Duration::from_millis(10));
+        assert_eq!(
+            report,
+            "Profile:\n\
+             \x20 config load          1.500 ms\n\
+             \x20 save                 3.000 ms (2 calls)\n\
+             \x20 total               10.000 ms\n"
+        );
+    }
+
+    #[test]
+    fn test_options_enabled() {
+        assert!(!ProfileOptions::default().enabled());
+        assert!(ProfileOptions { report: true, ..Default::default() }.enabled());
+        assert!(ProfileOptions { memstats_file: Some("m".into()), ..Default::default() }.enabled());
+    }
+
+    #[test]
+    fn test_memory_stats() {
+        if std::path::Path::new("/proc/self/status").exists() {
+            assert!(memory().unwrap().contains("VmRSS:"));
+        }
+    }
+}
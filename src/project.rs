@@ -0,0 +1,73 @@
+//! Project type detection from marker files (`Cargo.toml`, `go.mod`, ...)
+
+use std::path::Path;
+
+/// Marker files and the project type they indicate, checked in order
+const MARKERS: &[(&str, &str)] = &[
+    ("Cargo.toml", "rust"),
+    ("go.mod", "go"),
+    ("pyproject.toml", "python"),
+    ("setup.py", "python"),
+    ("requirements.txt", "python"),
+    ("package.json", "node"),
+    ("deno.json", "deno"),
+    ("Gemfile", "ruby"),
+    ("pom.xml", "java"),
+    ("build.gradle", "java"),
+    ("build.gradle.kts", "kotlin"),
+    ("composer.json", "php"),
+    ("mix.exs", "elixir"),
+    ("Package.swift", "swift"),
+    ("pubspec.yaml", "dart"),
+    ("CMakeLists.txt", "cmake"),
+    ("flake.nix", "nix"),
+];
+
+/// The project type of the directory at `path`, if it holds a known marker file
+///
+/// The first matching marker wins, so a Rust crate with a `package.json` for
+/// its web assets is still reported as `rust`.
+pub fn detect(path: &str) -> Option<String> {
+    let dir = Path::new(path);
+    MARKERS
+        .iter()
+        .find(|(marker, _)| dir.join(marker).is_file())
+        .map(|(_, kind)| kind.to_string())
+}
+
+/// " [rust]"-style suffix naming a project type in pickers, or nothing
+pub fn label(kind: Option<&str>) -> String {
+    kind.map(|kind| format!(" [{}]", kind)).unwrap_or_default()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::fs;
+    use tempfile::tempdir;
+
+    #[test]
+    fn test_detect() {
+        let dir = tempdir().unwrap();
+        let path = dir.path().to_str().unwrap();
+        assert_eq!(detect(path), None);
+
+        fs::write(dir.path().join("package.json"), "{}").unwrap();
+        assert_eq!(detect(path).as_deref(), Some("node"));
+
+        fs::write(dir.path().join("Cargo.toml"), "").unwrap();
+        assert_eq!(detect(path).as_deref(), Some("rust"));
+
+        // A directory named like a marker doesn't count
+        let other = tempdir().unwrap();
+        fs::create_dir(other.path().join("go.mod")).unwrap();
+        assert_eq!(detect(other.path().to_str().unwrap()), None);
+        assert_eq!(detect("/nonexistent/path/12345"), None);
+    }
+
+    #[test]
+    fn test_label() {
+        assert_eq!(label(Some("go")), " [go]");
+        assert_eq!(label(None), "");
+    }
+}
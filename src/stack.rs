@@ -19,16 +19,29 @@ pub enum StackError {
 /// Directory stack for push/pop operations
 pub struct Stack {
     path: PathBuf,
+    /// Whether `push` moves an entry already on the stack to the top
+    dedupe: bool,
 }
 
 impl Stack {
     pub fn new(path: PathBuf) -> Self {
-        Self { path }
+        Self { path, dedupe: false }
+    }
+
+    /// Keep each directory on the stack at most once (`stack.dedupe`)
+    pub fn with_dedupe(mut self, dedupe: bool) -> Self {
+        self.dedupe = dedupe;
+        self
     }
 
     /// Push a directory onto the stack
+    ///
+    /// With dedupe, an earlier copy of `dir` is removed, so it just moves to the top.
     pub fn push(&self, dir: &str) -> Result<(), StackError> {
         let mut entries = self.load()?;
+        if self.dedupe {
+            entries.retain(|e| e != dir);
+        }
         entries.push(dir.to_string());
         self.save(&entries)
     }
@@ -46,6 +59,20 @@ impl Stack {
         Ok(dir)
     }
 
+    /// Replace the top directory with `dir`, returning the one it replaced
+    ///
+    /// With dedupe, earlier copies of `dir` are removed, as `push` would.
+    pub fn replace(&self, dir: &str) -> Result<String, StackError> {
+        let mut entries = self.load()?;
+        let previous = entries.pop().ok_or(StackError::Empty)?;
+        if self.dedupe {
+            entries.retain(|e| e != dir);
+        }
+        entries.push(dir.to_string());
+        self.save(&entries)?;
+        Ok(previous)
+    }
+
     /// Peek at the top directory without removing it
     pub fn peek(&self) -> Result<String, StackError> {
         let entries = self.load()?;
@@ -63,6 +90,16 @@ impl Stack {
         self.save(&[])
     }
 
+    /// All entries, bottom first
+    pub fn entries(&self) -> Result<Vec<String>, StackError> {
+        self.load()
+    }
+
+    /// Replace the whole stack with `entries`, bottom first
+    pub fn set(&self, entries: &[String]) -> Result<(), StackError> {
+        self.save(entries)
+    }
+
     fn load(&self) -> Result<Vec<String>, StackError> {
         if !self.path.exists() {
             return Ok(Vec::new());
@@ -116,6 +153,67 @@ mod tests {
         assert!(matches!(stack.pop(), Err(StackError::Empty)));
     }
 
+    #[test]
+    fn test_push_dedupe() {
+        let dir = tempdir().unwrap();
+        let stack = Stack::new(dir.path().join("stack")).with_dedupe(true);
+
+        stack.push("/a").unwrap();
+        stack.push("/b").unwrap();
+        stack.push("/a").unwrap();
+        stack.push("/a").unwrap();
+
+        assert_eq!(stack.size().unwrap(), 2);
+        assert_eq!(stack.pop().unwrap(), "/a");
+        assert_eq!(stack.pop().unwrap(), "/b");
+
+        let plain = Stack::new(dir.path().join("plain"));
+        plain.push("/a").unwrap();
+        plain.push("/a").unwrap();
+        assert_eq!(plain.size().unwrap(), 2);
+    }
+
+    #[test]
+    fn test_replace() {
+        let dir = tempdir().unwrap();
+        let stack = Stack::new(dir.path().join("stack"));
+        assert!(matches!(stack.replace("/x"), Err(StackError::Empty)));
+
+        stack.push("/a").unwrap();
+        stack.push("/b").unwrap();
+        assert_eq!(stack.replace("/c").unwrap(), "/b");
+        assert_eq!(stack.size().unwrap(), 2);
+        assert_eq!(stack.pop().unwrap(), "/c");
+        assert_eq!(stack.pop().unwrap(), "/a");
+    }
+
+    #[test]
+    fn test_replace_dedupe() {
+        let dir = tempdir().unwrap();
+        let stack = Stack::new(dir.path().join("stack")).with_dedupe(true);
+
+        stack.push("/a").unwrap();
+        stack.push("/b").unwrap();
+        stack.push("/c").unwrap();
+        assert_eq!(stack.replace("/a").unwrap(), "/c");
+        assert_eq!(stack.entries().unwrap(), vec!["/b", "/a"]);
+    }
+
+    #[test]
+    fn test_entries_and_set() {
+        let dir = tempdir().unwrap();
+        let stack = Stack::new(dir.path().join("stack"));
+        assert!(stack.entries().unwrap().is_empty());
+
+        stack.push("/a").unwrap();
+        stack.push("/b").unwrap();
+        assert_eq!(stack.entries().unwrap(), vec!["/a", "/b"]);
+
+        stack.set(&["/x".to_string(), "/y".to_string()]).unwrap();
+        assert_eq!(stack.pop().unwrap(), "/y");
+        assert_eq!(stack.entries().unwrap(), vec!["/x"]);
+    }
+
     #[test]
     fn test_peek() {
         let dir = tempdir().unwrap();
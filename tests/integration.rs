@@ -5,7 +5,10 @@ use std::process::Command;
 use tempfile::tempdir;
 
 fn goto_bin() -> Command {
-    Command::new(env!("CARGO_BIN_EXE_goto-bin"))
+    // Output is checked against the English messages
+    let mut cmd = Command::new(env!("CARGO_BIN_EXE_goto-bin"));
+    cmd.env("GOTO_LANG", "en");
+    cmd
 }
 
 #[test]
@@ -89,6 +92,34 @@ fn test_invalid_alias_name() {
     );
 }
 
+#[test]
+fn test_path_not_allowed_exit_code() {
+    let temp = tempdir().unwrap();
+    let db_dir = temp.path().join("db");
+    let secret = temp.path().join("secret");
+    fs::create_dir(&db_dir).unwrap();
+    fs::create_dir(&secret).unwrap();
+    fs::write(
+        db_dir.join("config.toml"),
+        format!("[register]\ndeny = [\"{}\"]\n", secret.display()),
+    )
+    .unwrap();
+
+    // Forbidden by [register]: its own code, distinct from permission denied (6)
+    let mut cmd = goto_bin();
+    cmd.env("GOTO_DB", &db_dir);
+    cmd.args(["-r", "secret", secret.to_str().unwrap()]);
+    let output = cmd.output().unwrap();
+    assert_eq!(output.status.code(), Some(7));
+    assert!(String::from_utf8_lossy(&output.stderr).contains("path not allowed"));
+
+    // Unknown alias still exits 1
+    let mut cmd = goto_bin();
+    cmd.env("GOTO_DB", &db_dir);
+    cmd.arg("secret");
+    assert_eq!(cmd.output().unwrap().status.code(), Some(1));
+}
+
 #[test]
 fn test_fuzzy_suggestion() {
     let temp = tempdir().unwrap();
@@ -236,6 +267,101 @@ fn test_export_import() {
     );
 }
 
+#[test]
+fn test_import_from_stdin() {
+    use std::io::Write;
+    use std::process::Stdio;
+
+    let temp = tempdir().unwrap();
+    let test_dir = temp.path().join("testdir");
+    fs::create_dir(&test_dir).unwrap();
+
+    let db_dir = temp.path().join("db");
+    fs::create_dir(&db_dir).unwrap();
+
+    let mut cmd = goto_bin();
+    cmd.env("GOTO_DB", &db_dir);
+    cmd.args(["-r", "test", test_dir.to_str().unwrap()]);
+    assert!(cmd.output().unwrap().status.success());
+
+    // Export as JSON to stdout
+    let mut cmd = goto_bin();
+    cmd.env("GOTO_DB", &db_dir);
+    cmd.args(["--export", "--format=json"]);
+    let output = cmd.output().unwrap();
+    assert!(output.status.success());
+
+    // Pipe into import on a fresh database
+    let db_dir2 = temp.path().join("db2");
+    fs::create_dir(&db_dir2).unwrap();
+
+    let mut child = goto_bin()
+        .env("GOTO_DB", &db_dir2)
+        .args(["--import", "-", "--format=json"])
+        .stdin(Stdio::piped())
+        .stdout(Stdio::piped())
+        .stderr(Stdio::piped())
+        .spawn()
+        .unwrap();
+    child.stdin.take().unwrap().write_all(&output.stdout).unwrap();
+    let output = child.wait_with_output().unwrap();
+    assert!(
+        output.status.success(),
+        "Import from stdin failed: {}",
+        String::from_utf8_lossy(&output.stderr)
+    );
+    assert!(String::from_utf8_lossy(&output.stdout).contains("1 imported"));
+
+    let mut cmd = goto_bin();
+    cmd.env("GOTO_DB", &db_dir2);
+    cmd.args(["-x", "test"]);
+    let output = cmd.output().unwrap();
+    assert_eq!(
+        String::from_utf8_lossy(&output.stdout).trim(),
+        test_dir.to_str().unwrap()
+    );
+}
+
+#[test]
+fn test_db_flag_overrides_goto_db() {
+    let temp = tempdir().unwrap();
+    let test_dir = temp.path().join("testdir");
+    fs::create_dir(&test_dir).unwrap();
+
+    let env_db = temp.path().join("env_db");
+    let flag_db = temp.path().join("flag_db");
+    fs::create_dir(&env_db).unwrap();
+    fs::create_dir(&flag_db).unwrap();
+
+    // Register into the --db directory while GOTO_DB points elsewhere
+    let mut cmd = goto_bin();
+    cmd.env("GOTO_DB", &env_db);
+    cmd.args(["--db", flag_db.to_str().unwrap(), "-r", "other", test_dir.to_str().unwrap()]);
+    let output = cmd.output().unwrap();
+    assert!(
+        output.status.success(),
+        "Register failed: {}",
+        String::from_utf8_lossy(&output.stderr)
+    );
+    assert!(flag_db.join("aliases.toml").exists());
+    assert!(!env_db.join("aliases.toml").exists());
+
+    // Visible with --db=..., unknown without it
+    let mut cmd = goto_bin();
+    cmd.env("GOTO_DB", &env_db);
+    cmd.args(["-x", "other", &format!("--db={}", flag_db.display())]);
+    let output = cmd.output().unwrap();
+    assert_eq!(
+        String::from_utf8_lossy(&output.stdout).trim(),
+        test_dir.to_str().unwrap()
+    );
+
+    let mut cmd = goto_bin();
+    cmd.env("GOTO_DB", &env_db);
+    cmd.args(["-x", "other"]);
+    assert!(!cmd.output().unwrap().status.success());
+}
+
 #[test]
 fn test_unregister() {
     let temp = tempdir().unwrap();
@@ -2339,3 +2465,214 @@ fn test_rename_tag_source_not_found() {
         stderr
     );
 }
+
+#[test]
+fn test_root_prints_and_registers_repository_root() {
+    let temp = tempdir().unwrap();
+    let db_dir = temp.path().join("db");
+    let repo = temp.path().join("myrepo");
+    fs::create_dir_all(repo.join(".git")).unwrap();
+    fs::create_dir_all(repo.join("src/deep")).unwrap();
+    fs::create_dir(&db_dir).unwrap();
+    let repo = fs::canonicalize(&repo).unwrap();
+
+    let output = goto_bin()
+        .env("GOTO_DB", &db_dir)
+        .current_dir(repo.join("src/deep"))
+        .args(["--root", "--register"])
+        .output()
+        .unwrap();
+    assert!(output.status.success(), "{}", String::from_utf8_lossy(&output.stderr));
+    assert_eq!(String::from_utf8_lossy(&output.stdout).trim(), repo.to_str().unwrap());
+    assert!(String::from_utf8_lossy(&output.stderr).contains("Registered 'myrepo'"));
+
+    let output = goto_bin().env("GOTO_DB", &db_dir).args(["-x", "myrepo"]).output().unwrap();
+    assert_eq!(String::from_utf8_lossy(&output.stdout).trim(), repo.to_str().unwrap());
+
+    // Outside any repository
+    let output = goto_bin()
+        .env("GOTO_DB", &db_dir)
+        .current_dir(&db_dir)
+        .arg("--root")
+        .output()
+        .unwrap();
+    assert_eq!(output.status.code(), Some(1));
+    assert!(String::from_utf8_lossy(&output.stderr).contains("repository root not found"));
+}
+
+#[test]
+fn test_copy_path_uses_goto_clipboard() {
+    let temp = tempdir().unwrap();
+    let db_dir = temp.path().join("db");
+    let target = temp.path().join("project");
+    let clip = temp.path().join("clip.txt");
+    fs::create_dir_all(&target).unwrap();
+    fs::create_dir(&db_dir).unwrap();
+
+    let output = goto_bin()
+        .env("GOTO_DB", &db_dir)
+        .args(["-r", "proj", target.to_str().unwrap()])
+        .output()
+        .unwrap();
+    assert!(output.status.success(), "{}", String::from_utf8_lossy(&output.stderr));
+
+    let output = goto_bin()
+        .env("GOTO_DB", &db_dir)
+        .env("GOTO_CLIPBOARD", format!("cat > '{}'", clip.display()))
+        .args(["--copy-path", "proj"])
+        .output()
+        .unwrap();
+    assert!(output.status.success(), "{}", String::from_utf8_lossy(&output.stderr));
+    assert_eq!(fs::read_to_string(&clip).unwrap(), target.to_str().unwrap());
+    assert!(String::from_utf8_lossy(&output.stdout).contains("to the clipboard (GOTO_CLIPBOARD)"));
+
+    let output = goto_bin()
+        .env("GOTO_DB", &db_dir)
+        .env("GOTO_CLIPBOARD", "exit 1")
+        .args(["--copy-path", "proj"])
+        .output()
+        .unwrap();
+    assert!(!output.status.success());
+}
+
+#[test]
+fn test_audit_json_report() {
+    let temp = tempdir().unwrap();
+    let db_dir = temp.path().join("db");
+    let target = temp.path().join("project");
+    fs::create_dir_all(&target).unwrap();
+    fs::create_dir(&db_dir).unwrap();
+
+    for (name, tags) in [("proj", "work"), ("twin", "work")] {
+        let output = goto_bin()
+            .env("GOTO_DB", &db_dir)
+            .args(["-r", name, target.to_str().unwrap(), "-t", tags])
+            .output()
+            .unwrap();
+        assert!(output.status.success(), "{}", String::from_utf8_lossy(&output.stderr));
+    }
+
+    let output = goto_bin().env("GOTO_DB", &db_dir).args(["--audit", "--json"]).output().unwrap();
+    assert!(output.status.success(), "{}", String::from_utf8_lossy(&output.stderr));
+    let report: serde_json::Value = serde_json::from_slice(&output.stdout).unwrap();
+    assert_eq!(report["score"], 80);
+    assert_eq!(report["aliases"][0]["name"], "proj");
+    assert_eq!(report["aliases"][0]["issues"][0]["check"], "duplicate");
+    assert_eq!(report["aliases"][0]["issues"][0]["detail"], "same path as twin");
+}
+
+#[test]
+fn test_fsck_reports_and_fixes() {
+    let temp = tempdir().unwrap();
+    let db_dir = temp.path().join("db");
+    let target = temp.path().join("project");
+    fs::create_dir_all(&target).unwrap();
+    fs::create_dir(&db_dir).unwrap();
+
+    let output = goto_bin()
+        .env("GOTO_DB", &db_dir)
+        .args(["-r", "proj", target.to_str().unwrap(), "-t", "work"])
+        .output()
+        .unwrap();
+    assert!(output.status.success(), "{}", String::from_utf8_lossy(&output.stderr));
+
+    let output = goto_bin().env("GOTO_DB", &db_dir).arg("--fsck").output().unwrap();
+    assert!(output.status.success(), "{}", String::from_utf8_lossy(&output.stderr));
+    assert!(String::from_utf8_lossy(&output.stdout).contains("1 aliases, checksum OK"));
+
+    // A hand edit: a repeated tag, which also breaks the checksum
+    let db_file = db_dir.join("aliases.toml");
+    let content = fs::read_to_string(&db_file).unwrap();
+    fs::write(&db_file, content.replace("\"work\"", "\"work\", \"work\"")).unwrap();
+
+    let output = goto_bin().env("GOTO_DB", &db_dir).arg("--fsck").output().unwrap();
+    assert_eq!(output.status.code(), Some(5));
+    let stdout = String::from_utf8_lossy(&output.stdout);
+    assert!(stdout.contains("proj: duplicate tag 'work' [fixable]"), "{}", stdout);
+    assert!(stdout.contains("database: checksum mismatch"), "{}", stdout);
+    assert!(String::from_utf8_lossy(&output.stderr).contains("2 fixable with 'goto --fsck --fix'"));
+
+    let output = goto_bin().env("GOTO_DB", &db_dir).args(["--fsck", "--fix"]).output().unwrap();
+    assert!(output.status.success(), "{}", String::from_utf8_lossy(&output.stderr));
+    assert!(String::from_utf8_lossy(&output.stdout).contains("Fixed 2 problems"));
+
+    let output = goto_bin().env("GOTO_DB", &db_dir).arg("--fsck").output().unwrap();
+    assert!(output.status.success(), "{}", String::from_utf8_lossy(&output.stdout));
+}
+
+#[test]
+fn test_goto_context_from_environment() {
+    let temp = tempdir().unwrap();
+    let db_dir = temp.path().join("db");
+    let acme = temp.path().join("acme");
+    let oss = temp.path().join("oss");
+    fs::create_dir_all(&acme).unwrap();
+    fs::create_dir_all(&oss).unwrap();
+    fs::create_dir(&db_dir).unwrap();
+
+    for (name, dir) in [("acme:api", &acme), ("oss:api", &oss)] {
+        let output = goto_bin()
+            .env("GOTO_DB", &db_dir)
+            .env_remove("GOTO_CONTEXT")
+            .args(["-r", name, dir.to_str().unwrap()])
+            .output()
+            .unwrap();
+        assert!(output.status.success(), "{}", String::from_utf8_lossy(&output.stderr));
+    }
+    let output = goto_bin()
+        .env("GOTO_DB", &db_dir)
+        .env_remove("GOTO_CONTEXT")
+        .args(["--context", "use", "oss"])
+        .output()
+        .unwrap();
+    assert!(output.status.success(), "{}", String::from_utf8_lossy(&output.stderr));
+
+    let expand = |context: Option<&str>| {
+        let mut cmd = goto_bin();
+        cmd.env("GOTO_DB", &db_dir).args(["-x", "api"]);
+        match context {
+            Some(context) => cmd.env("GOTO_CONTEXT", context),
+            None => cmd.env_remove("GOTO_CONTEXT"),
+        };
+        String::from_utf8_lossy(&cmd.output().unwrap().stdout).trim().to_string()
+    };
+    // The environment wins over the saved context; an invalid value is ignored
+    assert_eq!(expand(None), oss.to_str().unwrap());
+    assert_eq!(expand(Some("acme")), acme.to_str().unwrap());
+    assert_eq!(expand(Some("acme:api")), oss.to_str().unwrap());
+
+    let output = goto_bin()
+        .env("GOTO_DB", &db_dir)
+        .env("GOTO_CONTEXT", "acme")
+        .arg("--context")
+        .output()
+        .unwrap();
+    assert_eq!(String::from_utf8_lossy(&output.stdout).trim(), "acme (from GOTO_CONTEXT)");
+}
+
+#[test]
+fn test_list_type_is_read_only() {
+    let temp = tempdir().unwrap();
+    let project = temp.path().join("project");
+    fs::create_dir(&project).unwrap();
+    let db_dir = temp.path().join("db");
+    fs::create_dir(&db_dir).unwrap();
+
+    let output = goto_bin()
+        .env("GOTO_DB", &db_dir)
+        .args(["-r", "proj", project.to_str().unwrap()])
+        .output()
+        .unwrap();
+    assert!(output.status.success());
+    let before = fs::read(db_dir.join("aliases.toml")).unwrap();
+
+    // Registered as a plain directory; the crate appears afterwards
+    fs::write(project.join("Cargo.toml"), "").unwrap();
+    let output = goto_bin().env("GOTO_DB", &db_dir).args(["-l", "--type"]).output().unwrap();
+    assert!(output.status.success(), "{}", String::from_utf8_lossy(&output.stderr));
+    assert!(String::from_utf8_lossy(&output.stdout).contains("rust"));
+
+    // Listing looked the type up without rewriting the database
+    assert_eq!(fs::read(db_dir.join("aliases.toml")).unwrap(), before);
+    assert!(db_dir.join("project_cache.json").exists());
+}